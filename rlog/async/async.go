@@ -0,0 +1,297 @@
+// Package async implements a [slog.Handler] that queues records on a bounded
+// buffer and hands them to a wrapped handler on a background goroutine, so
+// logging calls do not wait on slow disks or pipes. When the buffer is full,
+// the [Policy] decides whether the caller blocks or a record is dropped.
+//
+//	h := async.New(slog.NewJSONHandler(w, rlog.MergeWithCustomLevels(nil)), &async.Options{
+//		BufferSize: 4096,
+//		Policy:     async.DropOldest,
+//	})
+//	defer h.Close()
+//	rlog.SetDefault(rlog.New(slog.New(h)))
+//
+// Records queued before [Handler.Flush] or [Handler.Close] returns have been
+// handled. Each Handler registers its Flush with [rlog.RegisterFlush] until it
+// is closed, so Fatal delivers queued records before the fatal hook or exit.
+package async
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"go.rtnl.ai/x/rlog"
+)
+
+// DefaultBufferSize is the buffer size used when [Options.BufferSize] is zero.
+const DefaultBufferSize = 1024
+
+// Policy decides what Handle does when the buffer is full.
+type Policy uint8
+
+const (
+	// Block waits for room in the buffer, so no record is lost.
+	Block Policy = iota
+	// DropNewest discards the record being handled.
+	DropNewest
+	// DropOldest discards the oldest queued record to make room.
+	DropOldest
+)
+
+// String returns the policy's name, such as "drop_oldest".
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropNewest:
+		return "drop_newest"
+	case DropOldest:
+		return "drop_oldest"
+	default:
+		return fmt.Sprintf("Policy(%d)", uint8(p))
+	}
+}
+
+// Options configure a [Handler]. A nil or zero valued Options blocks on a
+// buffer of [DefaultBufferSize] records.
+type Options struct {
+	// BufferSize is how many records may wait to be handled; zero uses [DefaultBufferSize].
+	BufferSize int
+
+	// Policy is what to do when the buffer is full.
+	Policy Policy
+
+	// OnError, if set, is called on the background goroutine with each error the
+	// wrapped handler returns, since Handle has already returned by then.
+	OnError func(error)
+}
+
+// Stats counts what a [Handler] and the handlers derived from it have done since [New].
+type Stats struct {
+	Queued  int // records waiting in the buffer now
+	Handled int // records passed to the wrapped handler, including those that failed
+	Failed  int // records the wrapped handler returned an error for
+	Dropped int // records discarded because the buffer was full
+}
+
+// Handler is a [slog.Handler] that queues records for a wrapped handler.
+// Handlers derived with WithAttrs and WithGroup share the buffer and goroutine
+// of the handler they came from, so records keep their order across them.
+// Handler is safe for concurrent use. Only Handle is deferred: the wrapped
+// handler's Handle runs on the background goroutine, or on the caller's after
+// Close, while Enabled is passed through on the caller's goroutine.
+type Handler struct {
+	next  slog.Handler
+	queue *queue
+}
+
+// Ensure Handler implements the slog.Handler interface.
+var _ slog.Handler = (*Handler)(nil)
+
+// New returns a [Handler] wrapping next and starts its background goroutine.
+// If opts is nil, all defaults are used. Call [Handler.Close] to stop it.
+func New(next slog.Handler, opts *Options) *Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	size := opts.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+
+	q := &queue{
+		buf:     make([]item, size),
+		policy:  opts.Policy,
+		onError: opts.OnError,
+		exited:  make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	q.unregister = rlog.RegisterFlush(q.flush)
+
+	go q.run()
+	return &Handler{next: next, queue: q}
+}
+
+// Enabled reports whether the wrapped handler is enabled for level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle queues a copy of r and returns; errors from the wrapped handler go to
+// [Options.OnError]. The record keeps ctx's values but not its cancellation.
+// After Close, Handle calls the wrapped handler directly and returns its error.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	it := item{h: h.next, ctx: context.WithoutCancel(ctx), r: r.Clone()}
+	if !h.queue.push(it) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+// WithAttrs returns a [Handler] that queues for next.WithAttrs on the same buffer.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &Handler{next: h.next.WithAttrs(attrs), queue: h.queue}
+}
+
+// WithGroup returns a [Handler] that queues for next.WithGroup on the same buffer.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{next: h.next.WithGroup(name), queue: h.queue}
+}
+
+// Flush waits until every record queued before it was called has been handled
+// or dropped. Records queued meanwhile by other goroutines may still be waiting.
+func (h *Handler) Flush() {
+	h.queue.flush()
+}
+
+// Close flushes the buffer, stops the background goroutine, and removes the
+// handler from [rlog.Flush]. Records handled afterward are written
+// synchronously. Close always returns nil; calling it more than once is safe.
+func (h *Handler) Close() error {
+	h.queue.close()
+	return nil
+}
+
+// Stats returns the counters so far.
+func (h *Handler) Stats() Stats {
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	stats := h.queue.stats
+	stats.Queued = h.queue.n
+	return stats
+}
+
+//=============================================================================
+// Queue
+//=============================================================================
+
+// item is one queued record and the derived handler it was logged through.
+type item struct {
+	h   slog.Handler
+	ctx context.Context
+	r   slog.Record
+}
+
+// queue is a ring buffer of items drained by run. Every change to it is
+// broadcast on cond, which producers, flushers, and the drainer all wait on.
+type queue struct {
+	policy     Policy
+	onError    func(error)
+	unregister func()
+	exited     chan struct{}
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []item
+	head   int    // index of the oldest item
+	n      int    // number of items in buf
+	pushed uint64 // items ever accepted
+	done   uint64 // accepted items handled or dropped
+	closed bool
+	stats  Stats
+}
+
+// push queues it according to the policy. It returns false, without queuing,
+// if the queue is closed.
+func (q *queue) push(it item) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.n == len(q.buf) && q.policy == Block && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return false
+	}
+
+	if q.n == len(q.buf) {
+		if q.policy == DropNewest {
+			q.stats.Dropped++
+			return true
+		}
+		// DropOldest: the oldest item counts as done so flushes waiting on it return.
+		q.buf[q.head] = item{}
+		q.head = (q.head + 1) % len(q.buf)
+		q.n--
+		q.done++
+		q.stats.Dropped++
+	}
+
+	q.buf[(q.head+q.n)%len(q.buf)] = it
+	q.n++
+	q.pushed++
+	q.cond.Broadcast()
+	return true
+}
+
+// run hands queued items to their handlers until the queue is closed and empty.
+func (q *queue) run() {
+	defer close(q.exited)
+
+	q.mu.Lock()
+	for {
+		for q.n == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.n == 0 {
+			q.mu.Unlock()
+			return
+		}
+
+		it := q.buf[q.head]
+		q.buf[q.head] = item{}
+		q.head = (q.head + 1) % len(q.buf)
+		q.n--
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		err := it.h.Handle(it.ctx, it.r)
+		if err != nil && q.onError != nil {
+			q.onError(err)
+		}
+
+		q.mu.Lock()
+		q.done++
+		q.stats.Handled++
+		if err != nil {
+			q.stats.Failed++
+		}
+		q.cond.Broadcast()
+	}
+}
+
+// flush waits until every item accepted before it was called is done.
+func (q *queue) flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	target := q.pushed
+	for q.done < target {
+		q.cond.Wait()
+	}
+}
+
+// close stops accepting items, waits for run to drain the buffer and exit, and
+// unregisters the flush.
+func (q *queue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		<-q.exited
+		return
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	<-q.exited
+	q.unregister()
+}
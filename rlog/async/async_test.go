@@ -0,0 +1,178 @@
+package async_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/rlog"
+	"go.rtnl.ai/x/rlog/async"
+	rlogtesting "go.rtnl.ai/x/rlog/testing"
+)
+
+// gated captures records, but each Handle first signals started (if anyone is listening) and then
+// waits for release.
+type gated struct {
+	*rlogtesting.CapturingTestHandler
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGated() *gated {
+	return &gated{
+		CapturingTestHandler: rlogtesting.NewCapturingTestHandler(nil),
+		started:              make(chan struct{}, 1),
+		release:              make(chan struct{}),
+	}
+}
+
+func (g *gated) Handle(ctx context.Context, r slog.Record) error {
+	select {
+	case g.started <- struct{}{}:
+	default:
+	}
+	<-g.release
+	return g.CapturingTestHandler.Handle(ctx, r)
+}
+
+// messages returns the messages captured so far.
+func messages(h *rlogtesting.CapturingTestHandler) []string {
+	var msgs []string
+	for _, r := range h.Records() {
+		msgs = append(msgs, r.Message)
+	}
+	return msgs
+}
+
+// Records reach the wrapped handler in order, with derived attrs, by the time Flush returns.
+func TestHandler_deliversInOrder(t *testing.T) {
+	capture := rlogtesting.NewCapturingTestHandler(nil)
+	h := async.New(capture, &async.Options{BufferSize: 4})
+	defer h.Close()
+
+	log := rlog.New(slog.New(h))
+	sub := log.WithGroup("g").With("k", "v")
+	for range 50 {
+		log.Info("a")
+		sub.Info("b")
+	}
+	h.Flush()
+
+	records := capture.Records()
+	assert.Len(t, records, 100)
+	for i, r := range records {
+		assert.Equal(t, []string{"a", "b"}[i%2], r.Message)
+	}
+	lines := capture.Lines()
+	assert.Contains(t, lines[1], `"g":{"k":"v"}`)
+	assert.Equal(t, async.Stats{Handled: 100}, h.Stats())
+}
+
+// When the buffer is full, DropNewest keeps the queued records and DropOldest keeps the latest.
+func TestHandler_dropPolicies(t *testing.T) {
+	tests := []struct {
+		policy async.Policy
+		want   []string
+	}{
+		{async.DropNewest, []string{"0", "1", "2"}},
+		{async.DropOldest, []string{"0", "3", "4"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			g := newGated()
+			h := async.New(g, &async.Options{BufferSize: 2, Policy: tc.policy})
+			log := slog.New(h)
+
+			log.Info("0")
+			<-g.started // the drainer holds "0", leaving the buffer empty
+			for _, msg := range []string{"1", "2", "3", "4"} {
+				log.Info(msg)
+			}
+			assert.Equal(t, async.Stats{Queued: 2, Dropped: 2}, h.Stats())
+
+			close(g.release)
+			assert.Ok(t, h.Close())
+			assert.Equal(t, tc.want, messages(g.CapturingTestHandler))
+			assert.Equal(t, async.Stats{Handled: 3, Dropped: 2}, h.Stats())
+		})
+	}
+}
+
+// Block makes callers wait for room rather than lose records.
+func TestHandler_block(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		g := newGated()
+		h := async.New(g, &async.Options{BufferSize: 1})
+		log := slog.New(h)
+
+		log.Info("0")
+		<-g.started
+		log.Info("1")
+
+		var returned atomic.Bool
+		go func() {
+			log.Info("2")
+			returned.Store(true)
+		}()
+		synctest.Wait()
+		assert.False(t, returned.Load(), "Handle returned with the buffer full")
+
+		close(g.release)
+		synctest.Wait()
+		assert.True(t, returned.Load())
+		h.Flush()
+		assert.Ok(t, h.Close())
+		assert.Equal(t, []string{"0", "1", "2"}, messages(g.CapturingTestHandler))
+	})
+}
+
+// Fatal delivers queued records before the fatal hook runs; after Close, records are handled
+// synchronously and the handler is no longer flushed.
+func TestHandler_fatalFlush(t *testing.T) {
+	g := newGated()
+	close(g.release)
+	h := async.New(g, nil)
+	log := rlog.New(slog.New(h))
+
+	var atHook []string
+	rlog.SetFatalHook(func() { atHook = messages(g.CapturingTestHandler) })
+	t.Cleanup(func() { rlog.SetFatalHook(nil) })
+
+	for range 100 {
+		log.Info("queued")
+	}
+	log.Fatal("bye")
+	assert.Len(t, atHook, 101)
+	assert.Equal(t, "bye", atHook[100])
+
+	assert.Ok(t, h.Close())
+	assert.Ok(t, h.Close())
+	log.Info("late")
+	assert.Len(t, g.Records(), 102)
+	assert.Equal(t, 101, h.Stats().Handled)
+}
+
+// Errors from the wrapped handler go to OnError and are counted.
+func TestHandler_OnError(t *testing.T) {
+	failing := errors.New("disk full")
+	var errs []error
+	h := async.New(errHandler{failing}, &async.Options{OnError: func(err error) { errs = append(errs, err) }})
+
+	assert.Ok(t, h.Handle(context.Background(), slog.Record{Message: "x"}))
+	assert.Ok(t, h.Close())
+	assert.Equal(t, []error{failing}, errs)
+	assert.Equal(t, async.Stats{Handled: 1, Failed: 1}, h.Stats())
+	assert.ErrorIs(t, h.Handle(context.Background(), slog.Record{Message: "y"}), failing)
+}
+
+// errHandler fails every record with err.
+type errHandler struct{ err error }
+
+func (e errHandler) Enabled(context.Context, slog.Level) bool  { return true }
+func (e errHandler) Handle(context.Context, slog.Record) error { return e.err }
+func (e errHandler) WithAttrs([]slog.Attr) slog.Handler        { return e }
+func (e errHandler) WithGroup(string) slog.Handler             { return e }
@@ -96,6 +96,15 @@ func (ll LevelDecoder) String() string {
 	return ls
 }
 
+// LevelName names level as [LevelDecoder] does, falling back to [slog.Level.String]
+// for levels without a name, such as "INFO+2".
+func LevelName(level slog.Level) string {
+	if name := LevelDecoder(level).String(); name != "" {
+		return name
+	}
+	return level.String()
+}
+
 // UnmarshalJSON implements json.Unmarshaler
 func (ll *LevelDecoder) UnmarshalJSON(data []byte) error {
 	var ls string
@@ -69,3 +69,19 @@ func TestLevelDecoder_Encode_unknown(t *testing.T) {
 	_, err := ld.Encode()
 	assert.EqualError(t, err, "unknown log level 999")
 }
+
+func TestLevelName(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{rlog.LevelTrace, "TRACE"},
+		{slog.LevelInfo, "INFO"},
+		{rlog.LevelPanic, "PANIC"},
+		{slog.LevelInfo + 2, "INFO+2"},
+		{rlog.LevelTrace - 1, "DEBUG-5"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, rlog.LevelName(tc.level))
+	}
+}
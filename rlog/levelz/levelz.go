@@ -0,0 +1,307 @@
+/*
+Package levelz provides an http handler that reports and changes rlog's global and
+per-logger levels at runtime, so operators can raise the verbosity of a running
+service without restarting it. Changes can revert on their own after a timeout.
+
+The handler serves one path, [Path]. Add it to the same http.ServeMux as the probez
+handlers:
+
+	mux := http.NewServeMux()
+	probez.New().Mux(mux)
+	levelz.New(&levelz.Options{RevertAfter: 15 * time.Minute}).Mux(mux)
+
+GET returns the levels as JSON ([Levels]). PUT applies an [Update] and returns the
+new levels; DELETE reverts a pending temporary update at once. For example:
+
+	curl -X PUT localhost:8080/levelz -d '{"modules":{"vault.storage":"trace"},"revert_after":"10m"}'
+
+This handler changes logging for the whole process; serve it only where operators
+can reach it.
+*/
+package levelz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/x/rlog"
+)
+
+const (
+	Path = "/levelz"
+
+	contentType     = "Content-Type"
+	applicationJSON = "application/json"
+	maxBodyBytes    = 64 << 10
+)
+
+// Levels is the JSON body of every successful response.
+type Levels struct {
+	// Global is the level set with [rlog.SetLevel].
+	Global string `json:"global"`
+
+	// Modules are the levels set per module name with [rlog.SetModuleLevel].
+	Modules map[string]string `json:"modules"`
+
+	// Loggers are the levels that apply to each [rlog.Named] logger: its module's
+	// or nearest ancestor's level, or the global level if none is set.
+	Loggers map[string]string `json:"loggers"`
+
+	// Spec is the global and module levels as an [rlog.ParseLevelSpec] string.
+	Spec string `json:"spec"`
+
+	// RevertAt is when a pending temporary update will be undone, if there is one.
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// Update is the JSON body of a PUT request. Its parts are applied in field
+// order; levels are parsed with [rlog.LevelDecoder]. If any part is invalid,
+// nothing changes.
+type Update struct {
+	// Spec, if set, is parsed with [rlog.ParseLevelSpec]; it replaces all module
+	// levels, and the global level if it has one.
+	Spec *string `json:"spec,omitempty"`
+
+	// Global, if set, is the new global level.
+	Global *rlog.LevelDecoder `json:"global,omitempty"`
+
+	// Modules sets the level of each named module; a null level clears it.
+	Modules map[string]*rlog.LevelDecoder `json:"modules,omitempty"`
+
+	// RevertAfter, a [time.ParseDuration] string such as "10m", undoes the update
+	// after that long. Empty uses [Options.RevertAfter]; "0s" makes the update
+	// permanent.
+	RevertAfter string `json:"revert_after,omitempty"`
+}
+
+// Options configure a [Handler]. A nil or zero valued Options makes updates
+// permanent unless a request asks otherwise.
+type Options struct {
+	// RevertAfter, if positive, undoes each update after this long unless the
+	// request sets its own [Update.RevertAfter].
+	RevertAfter time.Duration
+}
+
+// Handler serves [Path]. Temporary updates are undone together: a revert restores
+// the levels from before the first update still pending, and a permanent update
+// cancels the pending revert, keeping every change so far.
+type Handler struct {
+	opts Options
+
+	mu       sync.Mutex
+	saved    *snapshot   // levels to restore, while a revert is pending
+	timer    *time.Timer // fires the pending revert
+	gen      uint64      // counts scheduled reverts, so a superseded timer does nothing
+	revertAt time.Time
+}
+
+// snapshot holds the levels to restore on revert.
+type snapshot struct {
+	global  slog.Level
+	modules map[string]slog.Level
+}
+
+var _ http.Handler = &Handler{}
+
+// New returns a [Handler]. If opts is nil, all defaults are used.
+func New(opts *Options) *Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &Handler{opts: *opts}
+}
+
+// Mux adds the levelz route to mux.
+func (h *Handler) Mux(mux *http.ServeMux) {
+	mux.Handle(Path, h)
+}
+
+// ServeHTTP implements the http.Handler interface for GET, PUT, and DELETE.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.writeLevels(w)
+	case http.MethodPut:
+		h.put(w, r)
+	case http.MethodDelete:
+		h.Revert()
+		h.writeLevels(w)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// Levels returns the current levels.
+func (h *Handler) Levels() Levels {
+	levels := Levels{
+		Global:  rlog.LevelName(rlog.Level()),
+		Modules: map[string]string{},
+		Loggers: map[string]string{},
+		Spec:    rlog.CurrentLevelSpec(),
+	}
+	for name, level := range rlog.ModuleLevels() {
+		levels.Modules[name] = rlog.LevelName(level)
+	}
+	for _, name := range rlog.NamedLoggers() {
+		level, ok := rlog.ModuleLevel(name)
+		if !ok {
+			level = rlog.Level()
+		}
+		levels.Loggers[name] = rlog.LevelName(level)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.saved != nil {
+		at := h.revertAt
+		levels.RevertAt = &at
+	}
+	return levels
+}
+
+// Apply validates and applies u as a PUT request would.
+func (h *Handler) Apply(u Update) error {
+	var spec rlog.LevelSpec
+	if u.Spec != nil {
+		var err error
+		if spec, err = rlog.ParseLevelSpec(*u.Spec); err != nil {
+			return err
+		}
+	}
+	for name := range u.Modules {
+		if !rlog.ValidModuleName(name) {
+			return fmt.Errorf("invalid logger name %q", name)
+		}
+	}
+
+	revertAfter := h.opts.RevertAfter
+	if u.RevertAfter != "" {
+		d, err := time.ParseDuration(u.RevertAfter)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid revert_after %q", u.RevertAfter)
+		}
+		revertAfter = d
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var saved *snapshot
+	if revertAfter > 0 && h.saved == nil {
+		saved = &snapshot{global: rlog.Level()}
+	}
+
+	// Snapshot and change the module levels in one step, so changes made
+	// concurrently through rlog are not lost.
+	err := rlog.UpdateModuleLevels(func(modules map[string]slog.Level) {
+		if saved != nil {
+			saved.modules = maps.Clone(modules)
+		}
+		if u.Spec != nil {
+			clear(modules)
+			maps.Copy(modules, spec.Modules)
+		}
+		for name, level := range u.Modules {
+			if level == nil {
+				delete(modules, name)
+				continue
+			}
+			modules[name] = level.Level()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if saved != nil {
+		h.saved = saved
+	}
+
+	if u.Spec != nil && spec.Global != nil {
+		rlog.SetLevel(*spec.Global)
+	}
+	if u.Global != nil {
+		rlog.SetLevel(u.Global.Level())
+	}
+
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	if revertAfter > 0 {
+		h.gen++
+		gen := h.gen
+		h.revertAt = time.Now().Add(revertAfter)
+		h.timer = time.AfterFunc(revertAfter, func() { h.expire(gen) })
+	} else {
+		h.saved = nil
+	}
+	return nil
+}
+
+// Revert restores the levels from before the pending temporary updates, if there
+// are any, and cancels their timer.
+func (h *Handler) Revert() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.revert()
+}
+
+// expire reverts if the revert scheduled as gen is still the pending one.
+func (h *Handler) expire(gen uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if gen == h.gen {
+		h.revert()
+	}
+}
+
+// revert restores the saved levels, if any, and stops the timer; the caller holds h.mu.
+func (h *Handler) revert() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	if h.saved == nil {
+		return
+	}
+	// The saved names came from rlog, so they are valid.
+	rlog.SetLevel(h.saved.global)
+	_ = rlog.SetModuleLevels(h.saved.modules)
+	h.saved = nil
+}
+
+// put decodes and applies an [Update].
+func (h *Handler) put(w http.ResponseWriter, r *http.Request) {
+	var u Update
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&u); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("invalid update: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Apply(u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeLevels(w)
+}
+
+// writeLevels writes the current levels as JSON.
+func (h *Handler) writeLevels(w http.ResponseWriter) {
+	w.Header().Set(contentType, applicationJSON)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Levels())
+}
@@ -0,0 +1,175 @@
+package levelz_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/probez"
+	"go.rtnl.ai/x/rlog"
+	"go.rtnl.ai/x/rlog/levelz"
+)
+
+// resetLevels restores rlog's global and module levels after the test.
+func resetLevels(t *testing.T) {
+	t.Helper()
+	global, modules := rlog.Level(), rlog.ModuleLevels()
+	t.Cleanup(func() {
+		rlog.SetLevel(global)
+		assert.Ok(t, rlog.SetModuleLevels(modules))
+	})
+	rlog.SetLevel(slog.LevelInfo)
+	assert.Ok(t, rlog.SetModuleLevels(nil))
+}
+
+// do sends a request to h and decodes a 200 response's levels.
+func do(t *testing.T, h http.Handler, method, body string) (int, levelz.Levels) {
+	t.Helper()
+	req := httptest.NewRequest(method, levelz.Path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var levels levelz.Levels
+	if rec.Code == http.StatusOK {
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Ok(t, json.Unmarshal(rec.Body.Bytes(), &levels))
+	}
+	return rec.Code, levels
+}
+
+// GET reports the global, module, and named logger levels; PUT changes them.
+func TestHandler_getAndPut(t *testing.T) {
+	resetLevels(t)
+	rlog.Named("levelz.test.storage")
+	rlog.Named("levelz.test.other")
+	h := levelz.New(nil)
+
+	code, levels := do(t, h, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "INFO", levels.Global)
+	assert.Equal(t, "INFO", levels.Spec)
+	assert.Equal(t, "INFO", levels.Loggers["levelz.test.storage"])
+	assert.Nil(t, levels.RevertAt)
+
+	code, levels = do(t, h, http.MethodPut, `{"spec":"warn,levelz.test=debug","global":"error","modules":{"levelz.test.storage":"trace","radish":"warn"}}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ERROR", levels.Global)
+	assert.Equal(t, map[string]string{"levelz.test": "DEBUG", "levelz.test.storage": "TRACE", "radish": "WARN"}, levels.Modules)
+	assert.Equal(t, "TRACE", levels.Loggers["levelz.test.storage"])
+	assert.Equal(t, "DEBUG", levels.Loggers["levelz.test.other"])
+	assert.Equal(t, "ERROR,levelz.test=DEBUG,levelz.test.storage=TRACE,radish=WARN", levels.Spec)
+	assert.Equal(t, slog.LevelError, rlog.Level())
+
+	// A null level clears a module.
+	_, levels = do(t, h, http.MethodPut, `{"modules":{"radish":null}}`)
+	assert.Equal(t, "ERROR,levelz.test=DEBUG,levelz.test.storage=TRACE", levels.Spec)
+}
+
+// Invalid updates and methods are rejected without changing anything.
+func TestHandler_invalid(t *testing.T) {
+	resetLevels(t)
+	h := levelz.New(nil)
+
+	for _, body := range []string{
+		`{"global":"loud"}`,
+		`{"spec":"a=b"}`,
+		`{"modules":{"a..b":"info"}}`,
+		`{"modules":{"a b":"info"}}`,
+		`{"spec":"a b=info"}`,
+		`{"modules":{"ok":"info"},"revert_after":"soon"}`,
+		`{"revert_after":"-1m"}`,
+		`{"level":"info"}`,
+		`not json`,
+	} {
+		code, _ := do(t, h, http.MethodPut, body)
+		assert.Equal(t, http.StatusBadRequest, code, body)
+	}
+	code, _ := do(t, h, http.MethodPut, `{"spec":"`+strings.Repeat("a", 70<<10)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	code, _ = do(t, h, http.MethodPost, `{}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	assert.Equal(t, "INFO", rlog.CurrentLevelSpec())
+}
+
+// Temporary updates revert together after the latest timeout; a permanent update cancels the
+// revert, and DELETE reverts at once.
+func TestHandler_revert(t *testing.T) {
+	resetLevels(t)
+	synctest.Test(t, func(t *testing.T) {
+		h := levelz.New(&levelz.Options{RevertAfter: time.Minute})
+
+		_, levels := do(t, h, http.MethodPut, `{"global":"debug"}`)
+		assert.True(t, time.Now().Add(time.Minute).Equal(*levels.RevertAt))
+		time.Sleep(30 * time.Second)
+		do(t, h, http.MethodPut, `{"modules":{"vault":"trace"},"revert_after":"2m"}`)
+
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		assert.Equal(t, "DEBUG,vault=TRACE", rlog.CurrentLevelSpec())
+
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		assert.Equal(t, "INFO", rlog.CurrentLevelSpec())
+		_, levels = do(t, h, http.MethodGet, "")
+		assert.Nil(t, levels.RevertAt)
+
+		// "0s" keeps everything changed so far.
+		do(t, h, http.MethodPut, `{"global":"warn"}`)
+		do(t, h, http.MethodPut, `{"global":"error","revert_after":"0s"}`)
+		time.Sleep(time.Hour)
+		synctest.Wait()
+		assert.Equal(t, "ERROR", rlog.CurrentLevelSpec())
+
+		do(t, h, http.MethodPut, `{"global":"trace"}`)
+		code, levels := do(t, h, http.MethodDelete, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ERROR", levels.Global)
+		assert.Nil(t, levels.RevertAt)
+	})
+}
+
+// Concurrent updates through the handler and rlog all take effect.
+func TestHandler_concurrent(t *testing.T) {
+	resetLevels(t)
+	h := levelz.New(nil)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			level := rlog.LevelDecoder(slog.LevelDebug)
+			if err := h.Apply(levelz.Update{Modules: map[string]*rlog.LevelDecoder{fmt.Sprintf("put.%d", i): &level}}); err != nil {
+				t.Error(err)
+			}
+		})
+		wg.Go(func() {
+			if err := rlog.SetModuleLevel(fmt.Sprintf("set.%d", i), slog.LevelWarn); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	assert.Len(t, rlog.ModuleLevels(), 16)
+}
+
+// The levelz route mounts on the same mux as the probez routes.
+func TestHandler_Mux(t *testing.T) {
+	resetLevels(t)
+	mux := http.NewServeMux()
+	probez.New().Mux(mux)
+	levelz.New(nil).Mux(mux)
+
+	for path, want := range map[string]int{probez.Livez: http.StatusOK, probez.Readyz: http.StatusServiceUnavailable, levelz.Path: http.StatusOK} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, rec.Code, path)
+	}
+}
@@ -70,8 +70,10 @@ package rlog
 import (
 	"context"
 	"log/slog"
+	"maps"
 	"os"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -151,6 +153,12 @@ var (
 	globalLevel *slog.LevelVar = &slog.LevelVar{}
 	// Stores the function to call when a fatal log is written, or nil if no hook is set
 	fatalHookAtomic atomic.Pointer[struct{ fn func() }]
+	// Stores the functions run by [Flush], keyed by registration order
+	flushFuncs = map[uint64]func(){}
+	// The key of the next function passed to [RegisterFlush]
+	flushSeq uint64
+	// Protects flushFuncs and flushSeq
+	flushMu sync.Mutex
 )
 
 // Initializes the global logger to be a console JSON logger with level [slog.LevelInfo].
@@ -207,8 +215,43 @@ func SetFatalHook(fn func()) {
 	fatalHookAtomic.Store(&struct{ fn func() }{fn: fn})
 }
 
-// exitFatal runs the hook from [SetFatalHook] if installed, else [os.Exit](1).
+// RegisterFlush adds fn to the functions [Flush] runs, for handlers that buffer
+// records (such as rlog/async) to deliver them before the process exits. Fatal
+// calls [Flush] after logging and before the fatal hook or [os.Exit]. The returned
+// function removes fn; it is safe to call more than once. Safe to use concurrently.
+func RegisterFlush(fn func()) (unregister func()) {
+	flushMu.Lock()
+	defer flushMu.Unlock()
+	key := flushSeq
+	flushSeq++
+	flushFuncs[key] = fn
+
+	return func() {
+		flushMu.Lock()
+		defer flushMu.Unlock()
+		delete(flushFuncs, key)
+	}
+}
+
+// Flush runs every function registered with [RegisterFlush], in the order they
+// were registered, and returns once they all have. Safe to use concurrently.
+func Flush() {
+	flushMu.Lock()
+	keys := slices.Sorted(maps.Keys(flushFuncs))
+	fns := make([]func(), 0, len(keys))
+	for _, key := range keys {
+		fns = append(fns, flushFuncs[key])
+	}
+	flushMu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// exitFatal runs [Flush], then the hook from [SetFatalHook] if installed, else [os.Exit](1).
 func exitFatal() {
+	Flush()
 	slot := fatalHookAtomic.Load()
 	if slot == nil {
 		os.Exit(1)
@@ -140,6 +140,29 @@ func TestFatal(t *testing.T) {
 	})
 }
 
+// Fatal runs registered flush functions, in registration order, before the fatal hook.
+func TestFatal_flushesFirst(t *testing.T) {
+	var calls []string
+	first := rlog.RegisterFlush(func() { calls = append(calls, "first") })
+	second := rlog.RegisterFlush(func() { calls = append(calls, "second") })
+	removed := rlog.RegisterFlush(func() { calls = append(calls, "removed") })
+	t.Cleanup(first)
+	t.Cleanup(second)
+	removed()
+	removed()
+
+	rlog.SetFatalHook(func() { calls = append(calls, "hook") })
+	t.Cleanup(func() { rlog.SetFatalHook(nil) })
+
+	logger := newTestLogger(t, &bytes.Buffer{}, &slog.HandlerOptions{Level: rlog.LevelFatal})
+	logger.Fatal("bye")
+	assert.Equal(t, []string{"first", "second", "hook"}, calls)
+
+	calls = nil
+	rlog.Flush()
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
 // Panic, PanicContext, and PanicAttrs write at LevelPanic when enabled and panic with the message.
 func TestPanic(t *testing.T) {
 	t.Run("Panic", func(t *testing.T) {
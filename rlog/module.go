@@ -0,0 +1,320 @@
+package rlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// LoggerKey is the attribute key under which [Named] loggers record their name.
+const LoggerKey = "logger"
+
+//=============================================================================
+// Module Levels
+//=============================================================================
+
+var (
+	// Stores the levels set per module name; replaced, never mutated, on change
+	moduleLevels atomic.Pointer[map[string]slog.Level]
+	// Names passed to [Named], for [NamedLoggers]
+	namedLoggers = map[string]struct{}{}
+	// Serializes changes to moduleLevels and protects namedLoggers
+	moduleMu sync.Mutex
+)
+
+// SetModuleLevel sets the level of the module name and of its descendants that
+// have no level of their own: "vault" applies to "vault.storage" but not to
+// "vaults". It returns an error, changing nothing, if [ValidModuleName] rejects
+// name. Safe to use concurrently.
+func SetModuleLevel(name string, level slog.Level) error {
+	if !ValidModuleName(name) {
+		return fmt.Errorf("invalid logger name %q", name)
+	}
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	next := ModuleLevels()
+	next[name] = level
+	moduleLevels.Store(&next)
+	return nil
+}
+
+// ClearModuleLevel removes the level set for the module name, which then follows
+// its nearest ancestor with a level, or its handler. Safe to use concurrently.
+func ClearModuleLevel(name string) {
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	next := ModuleLevels()
+	delete(next, name)
+	moduleLevels.Store(&next)
+}
+
+// SetModuleLevels replaces all module levels with a copy of levels; nil or empty
+// clears them. It returns an error, changing nothing, if [ValidModuleName]
+// rejects any of the names. Safe to use concurrently.
+func SetModuleLevels(levels map[string]slog.Level) error {
+	for name := range levels {
+		if !ValidModuleName(name) {
+			return fmt.Errorf("invalid logger name %q", name)
+		}
+	}
+
+	next := maps.Clone(levels)
+	if next == nil {
+		next = map[string]slog.Level{}
+	}
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	moduleLevels.Store(&next)
+	return nil
+}
+
+// UpdateModuleLevels calls fn with a copy of the module levels and stores the map
+// as changed by fn in their place. No other change to the module levels can come
+// between the read and the store. If fn adds a name that [ValidModuleName]
+// rejects, nothing changes and an error is returned. fn must not call the other
+// module level functions. Safe to use concurrently.
+func UpdateModuleLevels(fn func(levels map[string]slog.Level)) error {
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	next := ModuleLevels()
+	fn(next)
+	for name := range next {
+		if !ValidModuleName(name) {
+			return fmt.Errorf("invalid logger name %q", name)
+		}
+	}
+	moduleLevels.Store(&next)
+	return nil
+}
+
+// ModuleLevels returns a copy of the levels set per module name. Safe to use
+// concurrently.
+func ModuleLevels() map[string]slog.Level {
+	if levels := moduleLevels.Load(); levels != nil {
+		return maps.Clone(*levels)
+	}
+	return map[string]slog.Level{}
+}
+
+// ModuleLevel returns the level that applies to the module name: its own, or
+// that of its nearest ancestor. It returns false if none of them has a level, in
+// which case a [Named] logger logs whatever its handler allows. Safe to use
+// concurrently.
+func ModuleLevel(name string) (slog.Level, bool) {
+	levels := moduleLevels.Load()
+	if levels == nil || len(*levels) == 0 {
+		return 0, false
+	}
+
+	for {
+		if level, ok := (*levels)[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// ValidModuleName reports whether name can have a module level: a dot-separated
+// path of non-empty parts, such as "vault.storage", without spaces, '=', or ','
+// so that it can appear in a level spec.
+func ValidModuleName(name string) bool {
+	if name == "" || strings.ContainsAny(name, "=,") || strings.ContainsFunc(name, unicode.IsSpace) {
+		return false
+	}
+	return !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, ".") && !strings.Contains(name, "..")
+}
+
+// NamedLoggers returns the names passed to [Named] so far, sorted. Safe to use
+// concurrently.
+func NamedLoggers() []string {
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	return slices.Sorted(maps.Keys(namedLoggers))
+}
+
+//=============================================================================
+// Level Specs
+//=============================================================================
+
+// LevelSpec is a parsed level spec string: an optional global level and levels
+// per module name.
+type LevelSpec struct {
+	// Global, if not nil, is the level for [SetLevel].
+	Global *slog.Level
+
+	// Modules are the levels for [SetModuleLevel].
+	Modules map[string]slog.Level
+}
+
+// ParseLevelSpec parses a comma-separated list of name=level entries, such as
+// "vault.storage=trace,radish=warn". An entry without a name, such as "info",
+// sets the global level. Levels are parsed with [LevelDecoder] and names must pass
+// [ValidModuleName]; both are trimmed of spaces, and empty entries are ignored.
+func ParseLevelSpec(spec string) (LevelSpec, error) {
+	out := LevelSpec{Modules: map[string]slog.Level{}}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, hasName := strings.Cut(entry, "=")
+		if !hasName {
+			name, value = "", entry
+		}
+		name = strings.TrimSpace(name)
+		if hasName && !ValidModuleName(name) {
+			return LevelSpec{}, fmt.Errorf("invalid logger name in level spec entry %q", entry)
+		}
+
+		var ld LevelDecoder
+		if err := ld.Decode(value); err != nil {
+			return LevelSpec{}, fmt.Errorf("invalid level spec entry %q: %w", entry, err)
+		}
+		if !hasName {
+			level := ld.Level()
+			out.Global = &level
+			continue
+		}
+		out.Modules[name] = ld.Level()
+	}
+	return out, nil
+}
+
+// String formats the spec as [ParseLevelSpec] reads it, when every level has a
+// name: the global level first, if set, then the modules sorted by name.
+func (s LevelSpec) String() string {
+	var entries []string
+	if s.Global != nil {
+		entries = append(entries, LevelName(*s.Global))
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Modules)) {
+		entries = append(entries, name+"="+LevelName(s.Modules[name]))
+	}
+	return strings.Join(entries, ",")
+}
+
+// SetLevelSpec parses spec with [ParseLevelSpec] and applies it: the global
+// level, if the spec has one, and the module levels, which replace all those set
+// before. On error nothing changes. Safe to use concurrently.
+func SetLevelSpec(spec string) error {
+	parsed, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	if parsed.Global != nil {
+		SetLevel(*parsed.Global)
+	}
+	moduleLevels.Store(&parsed.Modules)
+	return nil
+}
+
+// CurrentLevelSpec returns the global level and the module levels as a spec
+// string, such as "INFO,radish=WARN,vault.storage=TRACE". Safe to use concurrently.
+func CurrentLevelSpec() string {
+	global := Level()
+	return LevelSpec{Global: &global, Modules: ModuleLevels()}.String()
+}
+
+//=============================================================================
+// Named Loggers
+//=============================================================================
+
+// Named returns a [Logger] for the module name, a dot-separated path such as
+// "vault.storage". Its records carry the name under [LoggerKey] and go to the
+// handler of the [Default] logger at the time they are logged, so a package can
+// create its logger in a var before main calls [SetDefault].
+//
+// Once a level is set for the name or an ancestor ([SetModuleLevel],
+// [SetLevelSpec]), it decides which records the logger emits in place of the
+// handler's own level, in either direction. Handlers that also filter inside
+// Handle, such as rlog/fanout's, still do. Do not pass a Named logger to
+// [SetDefault]; it would forward to itself.
+func Named(name string) *Logger {
+	moduleMu.Lock()
+	namedLoggers[name] = struct{}{}
+	moduleMu.Unlock()
+	return New(slog.New(&moduleHandler{name: name}))
+}
+
+// moduleHandler is the handler of a [Named] logger: it applies module levels and
+// forwards to the default logger's handler.
+type moduleHandler struct {
+	name  string
+	ops   []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls, in order
+	cache atomic.Pointer[moduleCache]
+}
+
+// moduleCache is the default handler derived for a moduleHandler, and the
+// default logger it was derived from.
+type moduleCache struct {
+	base    *Logger
+	handler slog.Handler
+}
+
+// Ensure moduleHandler implements the slog.Handler interface.
+var _ slog.Handler = (*moduleHandler)(nil)
+
+// Enabled applies the module's level, or the handler's if it has none.
+func (m *moduleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if threshold, ok := ModuleLevel(m.name); ok {
+		return level >= threshold
+	}
+	return m.handler().Enabled(ctx, level)
+}
+
+// Handle passes r to the default logger's handler.
+func (m *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	return m.handler().Handle(ctx, r)
+}
+
+// WithAttrs returns a moduleHandler that adds attrs after the current ones.
+func (m *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return m
+	}
+	return m.with(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
+}
+
+// WithGroup returns a moduleHandler that opens the group after the current attrs.
+func (m *moduleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return m
+	}
+	return m.with(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
+
+// with returns a copy of m with op added.
+func (m *moduleHandler) with(op func(slog.Handler) slog.Handler) *moduleHandler {
+	ops := append(slices.Clone(m.ops), op)
+	return &moduleHandler{name: m.name, ops: ops}
+}
+
+// handler returns the default logger's handler with the logger name and m's
+// attrs and groups, deriving it again only after [SetDefault].
+func (m *moduleHandler) handler() slog.Handler {
+	base := Default()
+	if c := m.cache.Load(); c != nil && c.base == base {
+		return c.handler
+	}
+
+	h := base.Handler().WithAttrs([]slog.Attr{slog.String(LoggerKey, m.name)})
+	for _, op := range m.ops {
+		h = op(h)
+	}
+	m.cache.Store(&moduleCache{base: base, handler: h})
+	return h
+}
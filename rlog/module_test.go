@@ -0,0 +1,238 @@
+package rlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/rlog"
+)
+
+// useDefault makes a JSON logger at level writing to the returned buffer the default, and restores
+// the default logger, global level, and module levels after the test.
+func useDefault(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	prev, prevLevel := rlog.Default(), rlog.Level()
+	t.Cleanup(func() {
+		rlog.SetDefault(prev)
+		rlog.SetLevel(prevLevel)
+		assert.Ok(t, rlog.SetLevelSpec(""))
+	})
+
+	var buf bytes.Buffer
+	rlog.SetDefault(newTestLogger(t, &buf, &slog.HandlerOptions{Level: level}))
+	return &buf
+}
+
+// mustParse decodes one JSON log line.
+func mustParse(t *testing.T, line string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	assert.Ok(t, json.Unmarshal([]byte(line), &m))
+	return m
+}
+
+func TestParseLevelSpec(t *testing.T) {
+	spec, err := rlog.ParseLevelSpec(" vault.storage = trace, radish=WARN,, info ")
+	assert.Ok(t, err)
+	assert.Equal(t, slog.LevelInfo, *spec.Global)
+	assert.Equal(t, map[string]slog.Level{"vault.storage": rlog.LevelTrace, "radish": slog.LevelWarn}, spec.Modules)
+	assert.Equal(t, "INFO,radish=WARN,vault.storage=TRACE", spec.String())
+
+	again, err := rlog.ParseLevelSpec(spec.String())
+	assert.Ok(t, err)
+	assert.Equal(t, spec, again)
+
+	empty, err := rlog.ParseLevelSpec("")
+	assert.Ok(t, err)
+	assert.Nil(t, empty.Global)
+	assert.Equal(t, "", empty.String())
+	assert.Equal(t, "x=INFO+2", rlog.LevelSpec{Modules: map[string]slog.Level{"x": slog.LevelInfo + 2}}.String())
+}
+
+func TestParseLevelSpec_invalid(t *testing.T) {
+	for spec, want := range map[string]string{
+		"vault=loud":  `invalid level spec entry "vault=loud": unknown log level "LOUD"`,
+		"=debug":      `invalid logger name in level spec entry "=debug"`,
+		"vault.=info": `invalid logger name in level spec entry "vault.=info"`,
+		"a..b=info":   `invalid logger name in level spec entry "a..b=info"`,
+		"verbose":     `invalid level spec entry "verbose": unknown log level "VERBOSE"`,
+	} {
+		_, err := rlog.ParseLevelSpec(spec)
+		assert.EqualError(t, err, want, spec)
+	}
+}
+
+// Named loggers follow their module's level, or their nearest ancestor's, in place of the
+// handler's, and tag records with their name.
+func TestNamed_levels(t *testing.T) {
+	buf := useDefault(t, slog.LevelInfo)
+	storage := rlog.Named("vault.storage")
+	sql := rlog.Named("vault.storage.sql").With("db", "main")
+	radish := rlog.Named("radish")
+	vaults := rlog.Named("vaults")
+
+	storage.Trace("hidden")
+	assert.Equal(t, "", buf.String())
+
+	assert.Ok(t, rlog.SetLevelSpec("vault=trace,vault.storage.sql=error,radish=warn"))
+	storage.Trace("storage-trace")
+	sql.Warn("sql-warn")
+	sql.Error("sql-error")
+	radish.Info("radish-info")
+	vaults.Debug("vaults-debug")
+	vaults.Info("vaults-info")
+
+	var msgs []string
+	for line := range strings.Lines(buf.String()) {
+		m := mustParse(t, line)
+		msgs = append(msgs, m["msg"].(string))
+		switch m["msg"] {
+		case "storage-trace":
+			assert.Equal(t, "TRACE", m["level"])
+			assert.Equal(t, "vault.storage", m[rlog.LoggerKey])
+		case "sql-error":
+			assert.Equal(t, "vault.storage.sql", m[rlog.LoggerKey])
+			assert.Equal(t, "main", m["db"])
+		}
+	}
+	assert.Equal(t, []string{"storage-trace", "sql-error", "vaults-info"}, msgs)
+
+	level, ok := rlog.ModuleLevel("vault.storage.sql.pool")
+	assert.True(t, ok)
+	assert.Equal(t, slog.LevelError, level)
+	_, ok = rlog.ModuleLevel("vaults")
+	assert.False(t, ok)
+
+	// Clearing a module's level hands it to its ancestor.
+	rlog.ClearModuleLevel("vault.storage.sql")
+	assert.Ok(t, rlog.SetModuleLevel("radish", slog.LevelDebug))
+	assert.Equal(t, map[string]slog.Level{"vault": rlog.LevelTrace, "radish": slog.LevelDebug}, rlog.ModuleLevels())
+	buf.Reset()
+	sql.Debug("sql-debug")
+	radish.Debug("radish-debug")
+	assert.Contains(t, buf.String(), "sql-debug")
+	assert.Contains(t, buf.String(), "radish-debug")
+
+	rlog.SetLevel(slog.LevelWarn)
+	assert.Equal(t, "WARN,radish=DEBUG,vault=TRACE", rlog.CurrentLevelSpec())
+	assert.True(t, slices.Contains(rlog.NamedLoggers(), "vault.storage.sql"))
+
+	// A spec with an unknown level changes nothing.
+	assert.NotNil(t, rlog.SetLevelSpec("info,vault=loud"))
+	assert.Equal(t, "WARN,radish=DEBUG,vault=TRACE", rlog.CurrentLevelSpec())
+}
+
+// A named logger created before SetDefault writes to the new default, with its groups.
+func TestNamed_followsDefault(t *testing.T) {
+	useDefault(t, slog.LevelInfo)
+	log := rlog.Named("early").WithGroup("g")
+	log.Info("to-old")
+
+	var buf bytes.Buffer
+	rlog.SetDefault(newTestLogger(t, &buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	log.Info("to-new", "k", "v")
+	m := mustParse(t, buf.String())
+	assert.Equal(t, "early", m[rlog.LoggerKey])
+	assert.Equal(t, map[string]any{"k": "v"}, m["g"])
+}
+
+// Module levels can change while named loggers log.
+func TestNamed_concurrent(t *testing.T) {
+	useDefault(t, slog.LevelInfo)
+	log := rlog.Named("busy")
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 200 {
+				log.Debug("tick")
+			}
+		})
+	}
+	wg.Go(func() {
+		for i := range 200 {
+			if err := rlog.SetModuleLevel("busy", []slog.Level{slog.LevelDebug, slog.LevelError}[i%2]); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	wg.Wait()
+}
+
+// Changes made with UpdateModuleLevels are never lost to concurrent ones.
+func TestUpdateModuleLevels(t *testing.T) {
+	useDefault(t, slog.LevelInfo)
+	assert.Ok(t, rlog.SetModuleLevel("kept", slog.LevelWarn))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			err := rlog.UpdateModuleLevels(func(levels map[string]slog.Level) {
+				levels[fmt.Sprintf("update.%d", i)] = slog.LevelDebug
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		})
+		wg.Go(func() {
+			if err := rlog.SetModuleLevel(fmt.Sprintf("set.%d", i), slog.LevelError); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	levels := rlog.ModuleLevels()
+	assert.Len(t, levels, 17)
+	assert.Equal(t, slog.LevelWarn, levels["kept"])
+
+	// An invalid name changes nothing.
+	err := rlog.UpdateModuleLevels(func(levels map[string]slog.Level) {
+		clear(levels)
+		levels["a b"] = slog.LevelDebug
+	})
+	assert.EqualError(t, err, `invalid logger name "a b"`)
+	assert.Len(t, rlog.ModuleLevels(), 17)
+
+	// fn works on a copy until it returns.
+	err = rlog.UpdateModuleLevels(func(levels map[string]slog.Level) {
+		clear(levels)
+		_, ok := rlog.ModuleLevel("kept")
+		assert.True(t, ok)
+	})
+	assert.Ok(t, err)
+	assert.Len(t, rlog.ModuleLevels(), 0)
+}
+
+func TestValidModuleName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"vault":            true,
+		"vault.storage":    true,
+		"vault-2.sql_pool": true,
+		"":                 false,
+		".vault":           false,
+		"vault.":           false,
+		"vault..storage":   false,
+		"a b":              false,
+		"a\tb":             false,
+		"a=b":              false,
+		"a,b":              false,
+	} {
+		assert.Equal(t, want, rlog.ValidModuleName(name), name)
+	}
+
+	// The same names are rejected everywhere.
+	useDefault(t, slog.LevelInfo)
+	assert.EqualError(t, rlog.SetModuleLevel("a b", slog.LevelDebug), `invalid logger name "a b"`)
+	assert.EqualError(t, rlog.SetModuleLevels(map[string]slog.Level{"ok": slog.LevelDebug, "a b": slog.LevelDebug}), `invalid logger name "a b"`)
+	assert.Len(t, rlog.ModuleLevels(), 0)
+	_, err := rlog.ParseLevelSpec("a b=trace")
+	assert.EqualError(t, err, `invalid logger name in level spec entry "a b=trace"`)
+}
@@ -0,0 +1,8 @@
+package rotate
+
+import "time"
+
+// OpenWithClock is [Open] with a replacement for [time.Now].
+func OpenWithClock(path string, opts *Options, now func() time.Time) (*File, error) {
+	return openFile(path, opts, now)
+}
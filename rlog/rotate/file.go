@@ -0,0 +1,430 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by writes to a closed [File].
+var ErrClosed = errors.New("rotate: file is closed")
+
+// backupTimeFormat stamps backup names; it sorts lexically in time order and is
+// safe in file names on every platform.
+const backupTimeFormat = "20060102T150405.000"
+
+// compressSuffix is appended to backups compressed with gzip.
+const compressSuffix = ".gz"
+
+// File is an [io.WriteCloser] that appends to a log file and rotates it when it
+// grows past [Options.MaxBytes] or has been written to for [Options.MaxAge].
+// Rotation renames the current file to a backup in the same directory, named
+// after the file with the rotation time (UTC) before the extension, such as
+// "app-20261016T120000.000.log", and opens a fresh file at the original path.
+// Backups are compressed and pruned on a background goroutine so writes do not
+// wait on them. Each Write goes to a single file, so a handler that writes one
+// record per call never splits a record across files. File is safe for
+// concurrent use.
+type File struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	last     time.Time // time stamped on the newest backup
+	closed   bool
+
+	mill    chan struct{}
+	done    chan struct{}
+	millMu  sync.Mutex
+	millErr error
+}
+
+// Ensure File implements io.WriteCloser.
+var _ io.WriteCloser = (*File)(nil)
+
+// Open opens path for appending, creating it and its directory if needed, and
+// returns a [File] that rotates it according to opts. Only the rotation fields
+// of opts are used. If opts is nil, the file never rotates.
+func Open(path string, opts *Options) (*File, error) {
+	return openFile(path, opts, time.Now)
+}
+
+// openFile is [Open] with a clock, which tests replace.
+func openFile(path string, opts *Options, now func() time.Time) (*File, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if path == "" {
+		return nil, errors.New("rotate: empty path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("rotate: create log directory: %w", err)
+	}
+
+	f := &File{
+		path: path,
+		opts: *opts,
+		now:  now,
+		mill: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	// Stamp new backups after any an earlier process left.
+	if backups, err := f.backups(); err == nil && len(backups) > 0 {
+		f.last = backups[0].stamp
+	}
+
+	if err := f.open(os.O_APPEND); err != nil {
+		return nil, err
+	}
+
+	go f.runMill()
+
+	// Tidy backups left by an earlier process under the current settings.
+	f.mill <- struct{}{}
+	return f, nil
+}
+
+// Path returns the path of the file being written.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Write appends p to the file, rotating first if p would take the file past
+// [Options.MaxBytes] or the file is older than [Options.MaxAge]. A write larger
+// than MaxBytes goes whole into a fresh file.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, ErrClosed
+	}
+	if f.due(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now, whatever its size or age; for example, on SIGHUP.
+// An empty file is not rotated.
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	if f.size == 0 {
+		return nil
+	}
+	return f.rotate()
+}
+
+// Sync commits the file's contents to stable storage.
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file and waits for pending backup compression and pruning.
+// It returns the first error the background work hit, if any. Calling Close
+// more than once returns [ErrClosed].
+func (f *File) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return ErrClosed
+	}
+	f.closed = true
+	err := f.file.Close()
+	close(f.mill)
+	f.mu.Unlock()
+
+	<-f.done
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+	return errors.Join(err, f.millErr)
+}
+
+// Backups returns the paths of the file's backups, newest first.
+func (f *File) Backups() ([]string, error) {
+	backups, err := f.backups()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(backups))
+	for _, b := range backups {
+		paths = append(paths, filepath.Join(filepath.Dir(f.path), b.name))
+	}
+	return paths, nil
+}
+
+//=============================================================================
+// Rotation
+//=============================================================================
+
+// due reports whether a write of n bytes must go to a fresh file; the caller
+// holds f.mu.
+func (f *File) due(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxBytes > 0 && f.size+int64(n) > f.opts.MaxBytes {
+		return true
+	}
+	return f.opts.MaxAge > 0 && f.now().Sub(f.openedAt) >= f.opts.MaxAge
+}
+
+// rotate renames the current file to a new backup, opens a fresh file, and wakes
+// the mill; the caller holds f.mu.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("rotate: close log file: %w", err)
+	}
+
+	// Stamp each backup strictly later than the last, so two rotations in the
+	// same millisecond do not collide.
+	stamp := f.now().UTC().Truncate(time.Millisecond)
+	if !stamp.After(f.last) {
+		stamp = f.last.Add(time.Millisecond)
+	}
+	f.last = stamp
+
+	if err := os.Rename(f.path, f.backupPath(stamp)); err != nil {
+		// Keep writing to the old file rather than losing records.
+		if reopenErr := f.open(os.O_APPEND); reopenErr != nil {
+			return errors.Join(fmt.Errorf("rotate: rename log file: %w", err), reopenErr)
+		}
+		return fmt.Errorf("rotate: rename log file: %w", err)
+	}
+	if err := f.open(os.O_TRUNC); err != nil {
+		return err
+	}
+
+	select {
+	case f.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// open opens f.path with the extra flag (O_APPEND or O_TRUNC) and resets the
+// size and age. A file appended to keeps its age: it counts from the newest
+// backup, when the file was started, or else from its last modification, so
+// restarts do not put off rotation. The caller holds f.mu or owns f.
+func (f *File) open(flag int) error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|flag, 0o644)
+	if err != nil {
+		return fmt.Errorf("rotate: open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("rotate: stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	if flag == os.O_APPEND && f.size > 0 {
+		started := info.ModTime()
+		if !f.last.IsZero() && f.last.Before(started) {
+			started = f.last
+		}
+		if started.Before(f.openedAt) {
+			f.openedAt = started
+		}
+	}
+	return nil
+}
+
+// nameParts splits the file's base name into the part before the extension and
+// the extension, such as "app" and ".log".
+func (f *File) nameParts() (prefix, ext string) {
+	base := filepath.Base(f.path)
+	ext = filepath.Ext(base)
+	return strings.TrimSuffix(base, ext), ext
+}
+
+// backupPath returns the path of the backup stamped t.
+func (f *File) backupPath(t time.Time) string {
+	prefix, ext := f.nameParts()
+	return filepath.Join(filepath.Dir(f.path), prefix+"-"+t.Format(backupTimeFormat)+ext)
+}
+
+//=============================================================================
+// Backups
+//=============================================================================
+
+// backup is one rotated file in the log directory.
+type backup struct {
+	name       string
+	stamp      time.Time
+	compressed bool
+}
+
+// backups lists the file's backups, newest first. Files that merely share the
+// prefix are ignored.
+func (f *File) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, fmt.Errorf("rotate: list backups: %w", err)
+	}
+
+	prefix, ext := f.nameParts()
+	prefix += "-"
+
+	var backups []backup
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+
+		b := backup{name: e.Name()}
+		stamp, ok := strings.CutPrefix(b.name, prefix)
+		if !ok {
+			continue
+		}
+		if rest, ok := strings.CutSuffix(stamp, compressSuffix); ok {
+			stamp, b.compressed = rest, true
+		}
+		if stamp, ok = strings.CutSuffix(stamp, ext); !ok {
+			continue
+		}
+		if b.stamp, err = time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, b)
+	}
+
+	slices.SortFunc(backups, func(a, b backup) int {
+		if c := b.stamp.Compare(a.stamp); c != 0 {
+			return c
+		}
+		// A compressed copy sorts after the original it was made from.
+		if a.compressed == b.compressed {
+			return 0
+		}
+		if a.compressed {
+			return 1
+		}
+		return -1
+	})
+	return backups, nil
+}
+
+// runMill compresses and prunes backups each time it is woken, until Close.
+func (f *File) runMill() {
+	defer close(f.done)
+	for range f.mill {
+		if err := f.millOnce(); err != nil {
+			f.millMu.Lock()
+			if f.millErr == nil {
+				f.millErr = err
+			}
+			f.millMu.Unlock()
+		}
+	}
+}
+
+// millOnce compresses uncompressed backups if [Options.Compress] is set, then
+// removes all but the newest [Options.MaxBackups].
+func (f *File) millOnce() error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.path)
+	var errs []error
+	kept := backups[:0]
+	for i, b := range backups {
+		// A crash mid-compression leaves both copies; the original wins.
+		if b.compressed && i > 0 && backups[i-1].stamp.Equal(b.stamp) {
+			if err := os.Remove(filepath.Join(dir, b.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("rotate: remove partial backup: %w", err))
+			}
+			continue
+		}
+		if f.opts.Compress && !b.compressed {
+			if err := compress(filepath.Join(dir, b.name)); err != nil {
+				errs = append(errs, err)
+			} else {
+				b.name += compressSuffix
+				b.compressed = true
+			}
+		}
+		kept = append(kept, b)
+	}
+
+	if f.opts.MaxBackups > 0 && len(kept) > f.opts.MaxBackups {
+		for _, b := range kept[f.opts.MaxBackups:] {
+			if err := os.Remove(filepath.Join(dir, b.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("rotate: remove backup: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compress gzips path to path+".gz" and removes path.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(dst.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+	if err = zw.Close(); err != nil {
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+	if err = dst.Sync(); err != nil {
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+
+	src.Close()
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("rotate: remove compressed backup: %w", err)
+	}
+	return nil
+}
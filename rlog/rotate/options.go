@@ -0,0 +1,34 @@
+package rotate
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Options configure a [File] and the [Handler] that writes to it. A nil or zero
+// valued Options writes JSON at [slog.LevelInfo] to a file that never rotates.
+type Options struct {
+	// The [slog.HandlerOptions] for the [Handler]; [New] merges them with
+	// [rlog.MergeWithCustomLevels] so TRACE, FATAL, and PANIC are named.
+	*slog.HandlerOptions
+
+	// Marking true causes the handler to write [slog.TextHandler] lines instead of JSON.
+	Text bool
+
+	// MaxBytes rotates the file before a write would take it past this size. Zero
+	// disables rotation by size.
+	MaxBytes int64
+
+	// MaxAge rotates the file on the first write after it has been written to
+	// this long, counting time before [Open] for a file that already has lines.
+	// Zero disables rotation by age.
+	MaxAge time.Duration
+
+	// MaxBackups is how many rotated files to keep; older ones are removed. Zero
+	// keeps them all.
+	MaxBackups int
+
+	// Marking true causes rotated files to be compressed with gzip, adding ".gz"
+	// to their names.
+	Compress bool
+}
@@ -0,0 +1,62 @@
+// Package rotate writes logs to a file that rotates by size and by age, keeps a
+// limited number of backups, and optionally compresses them with gzip. [Open]
+// returns the rotating [File], an [io.WriteCloser] for any handler; [New] wraps
+// it in a JSON or text [slog.Handler] with rlog's custom level names.
+//
+//	h, err := rotate.New("/var/log/app/app.log", &rotate.Options{
+//		HandlerOptions: rlog.WithGlobalLevel(nil),
+//		MaxBytes:       100 << 20,
+//		MaxAge:         24 * time.Hour,
+//		MaxBackups:     7,
+//		Compress:       true,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer h.Close()
+//	rlog.SetDefault(rlog.New(slog.New(h)))
+package rotate
+
+import (
+	"log/slog"
+
+	"go.rtnl.ai/x/rlog"
+)
+
+// Handler is a [slog.Handler] that writes one line per record to a rotating
+// [File]. Handlers derived with WithAttrs and WithGroup share the file. Handle
+// is safe for concurrent use, and a record is never split across files.
+type Handler struct {
+	slog.Handler
+	file *File
+}
+
+// New opens path with [Open] and returns a [Handler] writing to it. If opts is
+// nil, all defaults are used.
+func New(path string, opts *Options) (*Handler, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	f, err := Open(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hopts := rlog.MergeWithCustomLevels(opts.HandlerOptions)
+	if opts.Text {
+		return &Handler{Handler: slog.NewTextHandler(f, hopts), file: f}, nil
+	}
+	return &Handler{Handler: slog.NewJSONHandler(f, hopts), file: f}, nil
+}
+
+// File returns the rotating file the handler writes to.
+func (h *Handler) File() *File {
+	return h.file
+}
+
+// Close closes the file; see [File.Close]. Records handled afterward fail with
+// [ErrClosed].
+func (h *Handler) Close() error {
+	return h.file.Close()
+}
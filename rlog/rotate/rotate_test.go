@@ -0,0 +1,261 @@
+package rotate_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/rlog"
+	"go.rtnl.ai/x/rlog/rotate"
+	rlogtesting "go.rtnl.ai/x/rlog/testing"
+)
+
+// readLines returns the lines of path, gunzipping it if it ends in ".gz".
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	assert.Ok(t, err)
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		assert.Ok(t, err)
+		r = zr
+	}
+
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	assert.Ok(t, sc.Err())
+	return lines
+}
+
+// Records rotate by size with custom level names, every file stays under MaxBytes, and only
+// MaxBackups backups are kept.
+func TestHandler_rotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	h, err := rotate.New(path, &rotate.Options{
+		HandlerOptions: &slog.HandlerOptions{Level: rlog.LevelTrace},
+		MaxBytes:       512,
+		MaxBackups:     3,
+	})
+	assert.Ok(t, err)
+
+	log := rlog.New(slog.New(h).With("svc", "api"))
+	for i := range 100 {
+		log.Trace("tick", "i", i)
+	}
+	assert.Ok(t, h.Close())
+
+	backups, err := h.File().Backups()
+	assert.Ok(t, err)
+	assert.Len(t, backups, 3)
+
+	// The newest records survive, in order, across the backups and the live file.
+	var lines []string
+	for i := len(backups) - 1; i >= 0; i-- {
+		info, err := os.Stat(backups[i])
+		assert.Ok(t, err)
+		assert.True(t, info.Size() <= 512, "%s is %d bytes", backups[i], info.Size())
+		lines = append(lines, readLines(t, backups[i])...)
+	}
+	lines = append(lines, readLines(t, path)...)
+
+	last := -1
+	for _, line := range lines {
+		m := rlogtesting.MustParseJSONLine(line)
+		assert.Equal(t, "TRACE", m["level"])
+		assert.Equal(t, "api", m["svc"])
+		i := int(m["i"].(float64))
+		if last >= 0 {
+			assert.Equal(t, last+1, i)
+		}
+		last = i
+	}
+	assert.Equal(t, 99, last)
+}
+
+// A file rotates on the first write after MaxAge, whatever its size.
+func TestFile_rotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	f, err := rotate.OpenWithClock(path, &rotate.Options{MaxAge: time.Hour}, func() time.Time { return now })
+	assert.Ok(t, err)
+
+	_, err = f.Write([]byte("first\n"))
+	assert.Ok(t, err)
+	now = now.Add(59 * time.Minute)
+	_, err = f.Write([]byte("second\n"))
+	assert.Ok(t, err)
+	now = now.Add(time.Minute)
+	_, err = f.Write([]byte("third\n"))
+	assert.Ok(t, err)
+	assert.Ok(t, f.Close())
+
+	backups, err := f.Backups()
+	assert.Ok(t, err)
+	assert.Equal(t, []string{filepath.Join(filepath.Dir(path), "app-20261016T130000.000.log")}, backups)
+	assert.Equal(t, []string{"first", "second"}, readLines(t, backups[0]))
+	assert.Equal(t, []string{"third"}, readLines(t, path))
+}
+
+// Compressed backups are gzipped copies, and rotations in the same millisecond get distinct names.
+func TestFile_compress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	f, err := rotate.OpenWithClock(path, &rotate.Options{Compress: true}, func() time.Time { return now })
+	assert.Ok(t, err)
+
+	for i := range 3 {
+		_, err = fmt.Fprintf(f, "line %d\n", i)
+		assert.Ok(t, err)
+		assert.Ok(t, f.Rotate())
+	}
+	assert.Ok(t, f.Rotate(), "an empty file is not rotated")
+	assert.Ok(t, f.Close())
+
+	backups, err := f.Backups()
+	assert.Ok(t, err)
+	assert.Len(t, backups, 3)
+	for i, b := range backups {
+		assert.True(t, strings.HasSuffix(b, ".log.gz"), b)
+		assert.Equal(t, []string{fmt.Sprintf("line %d", 2-i)}, readLines(t, b))
+	}
+	assert.Equal(t, "app-20261016T120000.002.log.gz", filepath.Base(backups[0]))
+}
+
+// Reopening appends to the existing file and counts its size toward MaxBytes; a closed file refuses
+// writes.
+func TestFile_reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	assert.Ok(t, os.WriteFile(path, []byte("old\n"), 0o644))
+	assert.Ok(t, os.WriteFile(path+".unrelated", []byte("x"), 0o644))
+
+	f, err := rotate.Open(path, &rotate.Options{MaxBytes: 8})
+	assert.Ok(t, err)
+	assert.Equal(t, path, f.Path())
+	_, err = f.Write([]byte("new\n"))
+	assert.Ok(t, err)
+	_, err = f.Write([]byte("next\n"))
+	assert.Ok(t, err)
+	assert.Ok(t, f.Close())
+
+	backups, err := f.Backups()
+	assert.Ok(t, err)
+	assert.Len(t, backups, 1)
+	assert.Equal(t, []string{"old", "new"}, readLines(t, backups[0]))
+	assert.Equal(t, []string{"next"}, readLines(t, path))
+
+	_, err = f.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, rotate.ErrClosed)
+	assert.ErrorIs(t, f.Close(), rotate.ErrClosed)
+}
+
+// A reopened file keeps its age, from the newest backup or else its last modification, so restarts
+// more often than MaxAge still rotate it.
+func TestFile_reopenAged(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	opts := &rotate.Options{MaxAge: time.Hour}
+
+	t.Run("modified", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		assert.Ok(t, os.WriteFile(path, []byte("old\n"), 0o644))
+		assert.Ok(t, os.Chtimes(path, now.Add(-2*time.Hour), now.Add(-2*time.Hour)))
+
+		f, err := rotate.OpenWithClock(path, opts, clock)
+		assert.Ok(t, err)
+		_, err = f.Write([]byte("new\n"))
+		assert.Ok(t, err)
+		assert.Ok(t, f.Close())
+
+		backups, err := f.Backups()
+		assert.Ok(t, err)
+		assert.Len(t, backups, 1)
+		assert.Equal(t, []string{"old"}, readLines(t, backups[0]))
+		assert.Equal(t, []string{"new"}, readLines(t, path))
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		stamp := now.Add(-90 * time.Minute).Format("20060102T150405.000")
+		assert.Ok(t, os.WriteFile(filepath.Join(dir, "app-"+stamp+".log"), []byte("older\n"), 0o644))
+		assert.Ok(t, os.WriteFile(path, []byte("old\n"), 0o644))
+		assert.Ok(t, os.Chtimes(path, now.Add(-time.Minute), now.Add(-time.Minute)))
+
+		f, err := rotate.OpenWithClock(path, opts, clock)
+		assert.Ok(t, err)
+		_, err = f.Write([]byte("new\n"))
+		assert.Ok(t, err)
+		assert.Ok(t, f.Close())
+
+		backups, err := f.Backups()
+		assert.Ok(t, err)
+		assert.Len(t, backups, 2)
+		assert.Equal(t, []string{"old"}, readLines(t, backups[0]))
+		assert.Equal(t, []string{"new"}, readLines(t, path))
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		assert.Ok(t, os.WriteFile(path, []byte("old\n"), 0o644))
+		assert.Ok(t, os.Chtimes(path, now.Add(-time.Minute), now.Add(-time.Minute)))
+
+		f, err := rotate.OpenWithClock(path, opts, clock)
+		assert.Ok(t, err)
+		_, err = f.Write([]byte("new\n"))
+		assert.Ok(t, err)
+		assert.Ok(t, f.Close())
+
+		backups, err := f.Backups()
+		assert.Ok(t, err)
+		assert.Len(t, backups, 0)
+		assert.Equal(t, []string{"old", "new"}, readLines(t, path))
+	})
+}
+
+// Concurrent Handle calls through derived handlers never interleave or lose records.
+func TestHandler_concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	h, err := rotate.New(path, &rotate.Options{MaxBytes: 4 << 10, Text: true})
+	assert.Ok(t, err)
+
+	const workers, each = 8, 200
+	var wg sync.WaitGroup
+	for w := range workers {
+		log := slog.New(h.WithAttrs([]slog.Attr{slog.Int("worker", w)}))
+		wg.Go(func() {
+			for i := range each {
+				log.Info("msg", "i", i)
+			}
+		})
+	}
+	wg.Wait()
+	assert.Ok(t, h.Close())
+
+	backups, err := h.File().Backups()
+	assert.Ok(t, err)
+	assert.True(t, len(backups) > 1)
+
+	total := len(readLines(t, path))
+	for _, b := range backups {
+		for _, line := range readLines(t, b) {
+			assert.True(t, strings.HasPrefix(line, "time=") && strings.Contains(line, " worker="), line)
+			total++
+		}
+	}
+	assert.Equal(t, workers*each, total)
+}
@@ -0,0 +1,345 @@
+// Package sample implements a [slog.Handler] wrapper that thins out noisy logs:
+// it can drop records identical to one already logged in the current window,
+// keep only the first N records with the same level and message per window, and
+// keep a random fraction of the records at chosen levels. Drops are counted and,
+// if asked, summarized in a periodic record so they are not silent.
+//
+//	h := sample.New(inner, &sample.Options{
+//		Window:          time.Second,
+//		First:           10,
+//		Dedupe:          true,
+//		Rates:           map[slog.Level]float64{rlog.LevelTrace: 0.01, rlog.LevelDebug: 0.1},
+//		SummaryInterval: time.Minute,
+//	})
+//	defer h.Close()
+//	log := rlog.New(slog.New(h))
+//
+// A Handler can wrap a [fanout.Handler] to thin every sink alike, or be one of
+// its children to thin only that sink.
+package sample
+
+import (
+	"context"
+	"hash/maphash"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/x/rlog"
+)
+
+// SummaryMessage is the message of the records that summarize drops.
+const SummaryMessage = "log records dropped by sampling"
+
+// DefaultWindow is the window used when [Options.Window] is zero.
+const DefaultWindow = time.Second
+
+// Options configure a [Handler]. A nil or zero valued Options drops nothing.
+type Options struct {
+	// Window is the length of the fixed windows Dedupe and First count in; zero
+	// uses [DefaultWindow]. A window starts with the first record after the last
+	// one ended.
+	Window time.Duration
+
+	// First, if positive, keeps at most this many records with the same level and
+	// message per window.
+	First int
+
+	// Marking true drops records whose level, message, and attributes (including
+	// those added with WithAttrs and WithGroup) match one kept in the window.
+	Dedupe bool
+
+	// Rates maps a level to the fraction of its records to keep, from 0 (none)
+	// to 1 (all). Levels that are not in the map are kept. Sampling applies
+	// before Dedupe and First, so a sampled-out record does not count toward them.
+	Rates map[slog.Level]float64
+
+	// Seed seeds the generator for Rates, so tests can be repeated; zero uses a
+	// random seed.
+	Seed uint64
+
+	// SummaryInterval, if positive, logs a record with [SummaryMessage] this often
+	// when records were dropped since the last one, with the count per level. The
+	// summary bypasses sampling and goes to the wrapped handler without the
+	// attributes and groups of derived handlers.
+	SummaryInterval time.Duration
+
+	// SummaryLevel is the level of summary records; the zero value is [slog.LevelInfo].
+	SummaryLevel slog.Level
+}
+
+// Stats counts what a [Handler] and the handlers derived from it have done since [New].
+type Stats struct {
+	Kept    int // records passed to the wrapped handler
+	Sampled int // records dropped by Rates
+	Deduped int // records dropped as duplicates
+	Limited int // records dropped beyond First
+}
+
+// Dropped returns the number of records dropped for any reason.
+func (s Stats) Dropped() int {
+	return s.Sampled + s.Deduped + s.Limited
+}
+
+// Handler wraps a [slog.Handler] and drops records according to [Options].
+// Handlers derived with WithAttrs and WithGroup share the windows, counters,
+// and summaries of the handler they came from. Handler is safe for concurrent
+// use when the wrapped handler is.
+type Handler struct {
+	next  slog.Handler
+	scope string // attrs and groups added by WithAttrs and WithGroup, for Dedupe
+	state *state
+}
+
+// state is shared by a [Handler] and every handler derived from it.
+type state struct {
+	root  slog.Handler
+	opts  Options
+	seed  maphash.Seed
+	stop  chan struct{}
+	done  chan struct{}
+	close sync.Once
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	start   time.Time          // start of the current window
+	counts  map[uint64]int     // records kept per level and message this window
+	seen    map[uint64]bool    // records kept per level, message, and attrs this window
+	dropped map[slog.Level]int // drops per level since the last summary
+	stats   Stats
+}
+
+// Ensure Handler implements the slog.Handler interface.
+var _ slog.Handler = (*Handler)(nil)
+
+// New returns a [Handler] wrapping next. If opts is nil, all defaults are used.
+// If [Options.SummaryInterval] is set, New starts a goroutine that [Handler.Close]
+// stops.
+func New(next slog.Handler, opts *Options) *Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	s := &state{
+		root:    next,
+		opts:    *opts,
+		seed:    maphash.MakeSeed(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		counts:  make(map[uint64]int),
+		seen:    make(map[uint64]bool),
+		dropped: make(map[slog.Level]int),
+	}
+	if s.opts.Window <= 0 {
+		s.opts.Window = DefaultWindow
+	}
+	s.opts.Rates = make(map[slog.Level]float64, len(opts.Rates))
+	for level, rate := range opts.Rates {
+		s.opts.Rates[level] = min(max(rate, 0), 1)
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	s.rng = rand.New(rand.NewPCG(seed, seed))
+
+	if s.opts.SummaryInterval > 0 {
+		go s.summarize()
+	} else {
+		close(s.done)
+	}
+	return &Handler{next: next, state: s}
+}
+
+// Enabled reports whether the wrapped handler is enabled for level; sampling
+// happens in Handle.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes r to the wrapped handler unless it is sampled out, a duplicate,
+// or over the limit for its message in this window.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.state.keep(h.scope, r) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a [Handler] that wraps next.WithAttrs and shares this handler's state.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	scope := []byte(h.scope)
+	for _, a := range attrs {
+		scope = appendAttr(scope, a)
+	}
+	return &Handler{next: h.next.WithAttrs(attrs), scope: string(scope), state: h.state}
+}
+
+// WithGroup returns a [Handler] that wraps next.WithGroup and shares this handler's state.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{next: h.next.WithGroup(name), scope: h.scope + "\x00g" + name, state: h.state}
+}
+
+// Stats returns the counters so far, for every handler sharing this one's state.
+func (h *Handler) Stats() Stats {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	return h.state.stats
+}
+
+// Close stops periodic summaries and logs a final summary of any drops since
+// the last one. The handler still samples records after Close, but no longer
+// summarizes them. Close always returns nil; calling it more than once is safe.
+func (h *Handler) Close() error {
+	h.state.close.Do(func() {
+		close(h.state.stop)
+		<-h.state.done
+		h.state.flushSummary()
+	})
+	return nil
+}
+
+//=============================================================================
+// Sampling
+//=============================================================================
+
+// keep decides whether r, logged through a handler with scope, is passed on,
+// and counts it.
+func (s *state) keep(scope string, r slog.Record) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.start) >= s.opts.Window {
+		s.start = now
+		clear(s.counts)
+		clear(s.seen)
+	}
+
+	if rate, ok := s.opts.Rates[r.Level]; ok && (rate == 0 || s.rng.Float64() >= rate) {
+		s.stats.Sampled++
+		s.dropped[r.Level]++
+		return false
+	}
+
+	var full uint64
+	if s.opts.Dedupe {
+		full = s.hash(scope, r, true)
+		if s.seen[full] {
+			s.stats.Deduped++
+			s.dropped[r.Level]++
+			return false
+		}
+	}
+
+	if s.opts.First > 0 {
+		key := s.hash("", r, false)
+		if s.counts[key] >= s.opts.First {
+			s.stats.Limited++
+			s.dropped[r.Level]++
+			return false
+		}
+		s.counts[key]++
+	}
+
+	if s.opts.Dedupe {
+		s.seen[full] = true
+	}
+	s.stats.Kept++
+	return true
+}
+
+// hash identifies r by its level and message and, if withAttrs, by scope and
+// its attributes too.
+func (s *state) hash(scope string, r slog.Record, withAttrs bool) uint64 {
+	buf := strconv.AppendInt(nil, int64(r.Level), 10)
+	buf = append(buf, 0)
+	buf = append(buf, r.Message...)
+	if withAttrs {
+		buf = append(buf, 0)
+		buf = append(buf, scope...)
+		r.Attrs(func(a slog.Attr) bool {
+			buf = appendAttr(buf, a)
+			return true
+		})
+	}
+	return maphash.Bytes(s.seed, buf)
+}
+
+// appendAttr appends an unambiguous encoding of a to buf.
+func appendAttr(buf []byte, a slog.Attr) []byte {
+	v := a.Value.Resolve()
+	buf = append(buf, "\x00a"...)
+	buf = append(buf, a.Key...)
+	if v.Kind() == slog.KindGroup {
+		buf = append(buf, "\x00{"...)
+		for _, ga := range v.Group() {
+			buf = appendAttr(buf, ga)
+		}
+		return append(buf, "\x00}"...)
+	}
+	buf = append(buf, 0)
+	return append(buf, v.String()...)
+}
+
+//=============================================================================
+// Summaries
+//=============================================================================
+
+// summarize logs a summary every SummaryInterval until stop is closed.
+func (s *state) summarize() {
+	defer close(s.done)
+	t := time.NewTicker(s.opts.SummaryInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			s.flushSummary()
+		}
+	}
+}
+
+// flushSummary logs the drops since the last summary, if there were any.
+func (s *state) flushSummary() {
+	s.mu.Lock()
+	if len(s.dropped) == 0 {
+		s.mu.Unlock()
+		return
+	}
+
+	levels := make([]slog.Level, 0, len(s.dropped))
+	for level := range s.dropped {
+		levels = append(levels, level)
+	}
+	slices.Sort(levels)
+
+	total := 0
+	attrs := make([]any, 0, len(levels))
+	for _, level := range levels {
+		total += s.dropped[level]
+		attrs = append(attrs, slog.Int(rlog.LevelName(level), s.dropped[level]))
+	}
+	clear(s.dropped)
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	if !s.root.Enabled(ctx, s.opts.SummaryLevel) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), s.opts.SummaryLevel, SummaryMessage, 0)
+	r.AddAttrs(slog.Int("dropped", total), slog.Group("levels", attrs...))
+	_ = s.root.Handle(ctx, r)
+}
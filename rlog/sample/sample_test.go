@@ -0,0 +1,172 @@
+package sample_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/rlog"
+	"go.rtnl.ai/x/rlog/fanout"
+	"go.rtnl.ai/x/rlog/sample"
+	rlogtesting "go.rtnl.ai/x/rlog/testing"
+)
+
+// First keeps N records per level and message per window, and a new window starts the count over.
+func TestHandler_First(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		capture := rlogtesting.NewCapturingTestHandler(nil)
+		h := sample.New(capture, &sample.Options{Window: time.Second, First: 2})
+		log := rlog.New(slog.New(h))
+
+		for i := range 5 {
+			log.Info("hot", "i", i)
+			log.Warn("hot", "i", i)
+		}
+		log.Info("cold")
+		time.Sleep(time.Second)
+		log.Info("hot", "i", 5)
+
+		var got []string
+		for _, r := range capture.Records() {
+			got = append(got, r.Level.String()+" "+r.Message)
+		}
+		assert.Equal(t, []string{"INFO hot", "WARN hot", "INFO hot", "WARN hot", "INFO cold", "INFO hot"}, got)
+		assert.Equal(t, sample.Stats{Kept: 6, Limited: 6}, h.Stats())
+		assert.Equal(t, 6, h.Stats().Dropped())
+	})
+}
+
+// Dedupe drops records identical in level, message, and attributes, including derived handler
+// attributes, within a window.
+func TestHandler_Dedupe(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		capture := rlogtesting.NewCapturingTestHandler(nil)
+		h := sample.New(capture, &sample.Options{Dedupe: true})
+		log := rlog.New(slog.New(h))
+		a, b := log.With("conn", "a"), log.WithGroup("g").With("conn", "a")
+
+		log.Info("retry", "n", 1)
+		log.Info("retry", "n", 1)
+		log.Info("retry", "n", 2)
+		log.Debug("retry", "n", 1)
+		a.Info("retry", "n", 1)
+		a.Info("retry", "n", 1)
+		b.Info("retry", "n", 1)
+		time.Sleep(sample.DefaultWindow)
+		log.Info("retry", "n", 1)
+
+		assert.Len(t, capture.Records(), 6)
+		assert.Equal(t, sample.Stats{Kept: 6, Deduped: 2}, h.Stats())
+	})
+}
+
+// Rates keep a seeded fraction of records at their level and leave other levels alone.
+func TestHandler_Rates(t *testing.T) {
+	run := func() []slog.Record {
+		capture := rlogtesting.NewCapturingTestHandler(nil)
+		h := sample.New(capture, &sample.Options{
+			Rates: map[slog.Level]float64{rlog.LevelTrace: 0, slog.LevelDebug: 0.25, slog.LevelWarn: 7},
+			Seed:  42,
+		})
+		log := rlog.New(slog.New(h))
+		for i := range 1000 {
+			log.Trace("t", "i", i)
+			log.Debug("d", "i", i)
+			log.Info("i", "i", i)
+			log.Warn("w", "i", i)
+		}
+
+		stats := h.Stats()
+		assert.Equal(t, 4000, stats.Kept+stats.Sampled)
+		return capture.Records()
+	}
+
+	records := run()
+	counts := map[slog.Level]int{}
+	for _, r := range records {
+		counts[r.Level]++
+	}
+	assert.Equal(t, 0, counts[rlog.LevelTrace])
+	assert.True(t, counts[slog.LevelDebug] > 200 && counts[slog.LevelDebug] < 300, "kept %d debug records", counts[slog.LevelDebug])
+	assert.Equal(t, 1000, counts[slog.LevelInfo])
+	assert.Equal(t, 1000, counts[slog.LevelWarn])
+
+	// The same seed keeps the same records.
+	again := run()
+	assert.Equal(t, len(records), len(again))
+	for i := range records {
+		assert.Equal(t, records[i].Message, again[i].Message)
+	}
+}
+
+// Summaries report drops per level each interval when there were any, and once more on Close.
+func TestHandler_summary(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		capture := rlogtesting.NewCapturingTestHandler(nil)
+		h := sample.New(capture, &sample.Options{
+			First:           1,
+			Rates:           map[slog.Level]float64{rlog.LevelTrace: 0},
+			SummaryInterval: time.Minute,
+			SummaryLevel:    slog.LevelWarn,
+		})
+		log := rlog.New(slog.New(h).With("svc", "api"))
+
+		for range 3 {
+			log.Trace("noisy")
+			log.Debug("noisy")
+		}
+		time.Sleep(time.Minute)
+		synctest.Wait()
+
+		// An interval without drops logs nothing.
+		time.Sleep(time.Minute)
+		synctest.Wait()
+
+		log.Info("once")
+		log.Info("once")
+		assert.Ok(t, h.Close())
+		assert.Ok(t, h.Close())
+
+		maps, err := capture.ResultMaps()
+		assert.Ok(t, err)
+		var summaries []map[string]any
+		for _, m := range maps {
+			if m["msg"] == sample.SummaryMessage {
+				assert.Equal(t, "WARN", m["level"])
+				assert.Nil(t, m["svc"])
+				summaries = append(summaries, m)
+			}
+		}
+		assert.Len(t, summaries, 2)
+		assert.Equal(t, float64(5), summaries[0]["dropped"])
+		assert.Equal(t, map[string]any{"TRACE": float64(3), "DEBUG": float64(2)}, summaries[0]["levels"])
+		assert.Equal(t, float64(1), summaries[1]["dropped"])
+		assert.Equal(t, map[string]any{"INFO": float64(1)}, summaries[1]["levels"])
+	})
+}
+
+// A sampling handler composes with fanout both as the wrapper of all sinks and as one sink.
+func TestHandler_fanout(t *testing.T) {
+	ctx := context.Background()
+	a, b := rlogtesting.NewCapturingTestHandler(nil), rlogtesting.NewCapturingTestHandler(nil)
+
+	outer := sample.New(fanout.New(a, b), &sample.Options{First: 1})
+	log := slog.New(outer)
+	log.InfoContext(ctx, "x")
+	log.InfoContext(ctx, "x")
+	assert.Len(t, a.Records(), 1)
+	assert.Len(t, b.Records(), 1)
+
+	a.Reset()
+	b.Reset()
+	inner := sample.New(a, &sample.Options{First: 1})
+	log = slog.New(fanout.New(inner, b)).With("k", "v")
+	log.InfoContext(ctx, "x")
+	log.InfoContext(ctx, "x")
+	assert.Len(t, a.Records(), 1)
+	assert.Len(t, b.Records(), 2)
+	assert.Equal(t, sample.Stats{Kept: 1, Limited: 1}, inner.Stats())
+}
@@ -0,0 +1,341 @@
+/*
+Package audit records who did what to which secret. [New] wraps any [vault.Vault] and reports one
+[Event] per operation to a [Sink]: the operation, namespace, id, key id, caller, outcome, and
+duration. Plaintext and ciphertext never reach the sink.
+
+The caller is whatever the application attaches with [WithActor] (a user, service account, or
+request id); it is empty when none is set. The key id is the one the wrapped vault reports through
+[vault.WithRowKeyIDs] (the v1 vault does): the key a written row was sealed for, or the key named
+by the metadata of a row that was opened, so reads of rows sealed before a key rotation name the
+retired key. It is empty for operations that seal or open nothing, such as deletes, for failures,
+and when the wrapped vault reports nothing (a [go.rtnl.ai/x/vault/cache] hit, for example).
+
+[LogSink] writes events through [rlog]; [MemSink] keeps them in memory for tests.
+*/
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+)
+
+//=============================================================================
+// Events
+//=============================================================================
+
+// Op names a vault operation.
+type Op string
+
+const (
+	OpStore          Op = "store"
+	OpRetrieve       Op = "retrieve"
+	OpUpdate         Op = "update"
+	OpCompareAndSwap Op = "compare_and_swap"
+	OpMoveNamespace  Op = "move_namespace"
+	OpDelete         Op = "delete"
+	OpList           Op = "list"
+)
+
+// Outcome classifies how an operation ended.
+type Outcome string
+
+const (
+	OutcomeSuccess  Outcome = "success"
+	OutcomeNotFound Outcome = "not_found" // the row did not exist
+	OutcomeConflict Outcome = "conflict"  // compare-and-swap lost or the expected plaintext differed
+	OutcomeDenied   Outcome = "denied"    // an access policy refused the caller
+	OutcomeFailure  Outcome = "failure"   // any other error
+)
+
+// Event describes one vault operation. It deliberately has no field for secret material.
+type Event struct {
+	Time         time.Time     // when the operation started
+	Op           Op            // which operation
+	Actor        string        // caller from [WithActor]; empty if unset
+	Namespace    string        // namespace operated on (the source namespace for moves)
+	NewNamespace string        // destination namespace; set only for [OpMoveNamespace]
+	ID           string        // row id; for [OpStore], the new id (empty if Store failed)
+	KeyID        []byte        // key id of the row sealed or opened, as reported by the wrapped vault
+	Outcome      Outcome       // classification of Err
+	Err          error         // error returned to the caller, nil on success
+	Duration     time.Duration // time spent in the wrapped vault
+}
+
+// Sink receives audit events. Record is called synchronously after each operation returns, on the
+// caller's goroutine, so it should be fast and safe for concurrent use. A sink cannot fail the
+// operation; it must handle its own delivery errors.
+type Sink interface {
+	Record(ctx context.Context, e Event)
+}
+
+// SinkFunc adapts a function to [Sink].
+type SinkFunc func(ctx context.Context, e Event)
+
+// Record calls f(ctx, e).
+func (f SinkFunc) Record(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// classify maps an operation error to an [Outcome].
+func classify(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, verrors.ErrNotFound):
+		return OutcomeNotFound
+	case errors.Is(err, verrors.ErrCASFailed), errors.Is(err, verrors.ErrWrongCurrent):
+		return OutcomeConflict
+	case errors.Is(err, verrors.ErrPermissionDenied):
+		return OutcomeDenied
+	default:
+		return OutcomeFailure
+	}
+}
+
+//=============================================================================
+// Actor
+//=============================================================================
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes vault operations to actor in audit events.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with [WithActor], or "" if none is set.
+func ActorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+//=============================================================================
+// Vault
+//=============================================================================
+
+// Vault wraps a [vault.Vault] and reports each operation to a [Sink]. It implements [vault.Lister]
+// (recording [OpList]), [vault.Expirer] (recording [OpStore] and [OpUpdate]), and
+// [vault.KeyIdentifier] by forwarding to the wrapped vault; when the wrapped vault lacks one, those
+// methods return [verrors.ErrListUnsupported], [verrors.ErrExpiryUnsupported], and nil. It also
+// implements [vault.Batcher], recording one event per item, through the wrapped vault's Batcher or
+// one call per item.
+type Vault struct {
+	v    vault.Vault
+	sink Sink
+	now  func() time.Time
+}
+
+// Compile-time checks.
+var (
+	_ vault.Vault         = (*Vault)(nil)
+	_ vault.Lister        = (*Vault)(nil)
+	_ vault.Expirer       = (*Vault)(nil)
+	_ vault.Batcher       = (*Vault)(nil)
+	_ vault.KeyIdentifier = (*Vault)(nil)
+)
+
+// New wraps v so every operation is reported to sink. A nil v or sink returns
+// [verrors.ErrInvalidNewArgs].
+func New(v vault.Vault, sink Sink) (*Vault, error) {
+	if v == nil || sink == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+	return &Vault{v: v, sink: sink, now: time.Now}, nil
+}
+
+// Store stores plaintext through the wrapped vault and records [OpStore] with the new id.
+func (a *Vault) Store(ctx context.Context, namespace string, plaintext []byte) (id string, err error) {
+	e := a.start(ctx, OpStore, namespace, "")
+	id, err = a.v.Store(a.track(ctx, &e), namespace, plaintext)
+	e.ID = id
+	a.finish(ctx, e, err)
+	return id, err
+}
+
+// Retrieve opens a row through the wrapped vault and records [OpRetrieve].
+func (a *Vault) Retrieve(ctx context.Context, namespace, id string) (plaintext []byte, err error) {
+	e := a.start(ctx, OpRetrieve, namespace, id)
+	plaintext, err = a.v.Retrieve(a.track(ctx, &e), namespace, id)
+	a.finish(ctx, e, err)
+	return plaintext, err
+}
+
+// Update replaces a row through the wrapped vault and records [OpUpdate].
+func (a *Vault) Update(ctx context.Context, namespace, id string, plaintext []byte) error {
+	e := a.start(ctx, OpUpdate, namespace, id)
+	err := a.v.Update(a.track(ctx, &e), namespace, id, plaintext)
+	a.finish(ctx, e, err)
+	return err
+}
+
+// CompareAndSwap swaps a row through the wrapped vault and records [OpCompareAndSwap].
+func (a *Vault) CompareAndSwap(ctx context.Context, namespace, id string, currentPlain, newPlain []byte) error {
+	e := a.start(ctx, OpCompareAndSwap, namespace, id)
+	err := a.v.CompareAndSwap(a.track(ctx, &e), namespace, id, currentPlain, newPlain)
+	a.finish(ctx, e, err)
+	return err
+}
+
+// MoveNamespace moves a row through the wrapped vault and records [OpMoveNamespace].
+func (a *Vault) MoveNamespace(ctx context.Context, oldNamespace, newNamespace, id string) error {
+	e := a.start(ctx, OpMoveNamespace, oldNamespace, id)
+	e.NewNamespace = newNamespace
+	err := a.v.MoveNamespace(a.track(ctx, &e), oldNamespace, newNamespace, id)
+	a.finish(ctx, e, err)
+	return err
+}
+
+// Delete removes a row through the wrapped vault and records [OpDelete].
+func (a *Vault) Delete(ctx context.Context, namespace, id string) error {
+	e := a.start(ctx, OpDelete, namespace, id)
+	err := a.v.Delete(a.track(ctx, &e), namespace, id)
+	a.finish(ctx, e, err)
+	return err
+}
+
+// ListIDs lists ids through the wrapped vault and records [OpList] with an empty id.
+func (a *Vault) ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error) {
+	e := a.start(ctx, OpList, namespace, "")
+	if lister, ok := a.v.(vault.Lister); ok {
+		ids, next, err = lister.ListIDs(ctx, namespace, cursor, limit)
+	} else {
+		err = verrors.ErrListUnsupported
+	}
+	a.finish(ctx, e, err)
+	return ids, next, err
+}
+
+// StoreExpiring stores an expiring row through the wrapped [vault.Expirer] and records [OpStore]
+// with the new id.
+func (a *Vault) StoreExpiring(ctx context.Context, namespace string, plaintext []byte, expiresAt time.Time) (id string, err error) {
+	e := a.start(ctx, OpStore, namespace, "")
+	if ex, ok := a.v.(vault.Expirer); ok {
+		id, err = ex.StoreExpiring(a.track(ctx, &e), namespace, plaintext, expiresAt)
+	} else {
+		err = verrors.ErrExpiryUnsupported
+	}
+	e.ID = id
+	a.finish(ctx, e, err)
+	return id, err
+}
+
+// UpdateExpiring replaces a row and its expiry through the wrapped [vault.Expirer] and records
+// [OpUpdate].
+func (a *Vault) UpdateExpiring(ctx context.Context, namespace, id string, plaintext []byte, expiresAt time.Time) error {
+	e := a.start(ctx, OpUpdate, namespace, id)
+	var err error
+	if ex, ok := a.v.(vault.Expirer); ok {
+		err = ex.UpdateExpiring(a.track(ctx, &e), namespace, id, plaintext, expiresAt)
+	} else {
+		err = verrors.ErrExpiryUnsupported
+	}
+	a.finish(ctx, e, err)
+	return err
+}
+
+// StoreMany stores plaintexts through the wrapped vault and records [OpStore] for each, with its
+// new id. See [vault.StoreMany].
+func (a *Vault) StoreMany(ctx context.Context, namespace string, plaintexts [][]byte) ([]vault.BatchResult, error) {
+	events := a.startMany(ctx, OpStore, namespace, make([]string, len(plaintexts)))
+	kctx, keyIDs := a.trackMany(ctx)
+	results, err := vault.StoreMany(kctx, a.v, namespace, plaintexts)
+	a.finishMany(ctx, events, results, keyIDs, err)
+	return results, err
+}
+
+// RetrieveMany opens rows through the wrapped vault and records [OpRetrieve] for each. See
+// [vault.RetrieveMany].
+func (a *Vault) RetrieveMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	events := a.startMany(ctx, OpRetrieve, namespace, ids)
+	kctx, keyIDs := a.trackMany(ctx)
+	results, err := vault.RetrieveMany(kctx, a.v, namespace, ids)
+	a.finishMany(ctx, events, results, keyIDs, err)
+	return results, err
+}
+
+// DeleteMany removes rows through the wrapped vault and records [OpDelete] for each. See
+// [vault.DeleteMany].
+func (a *Vault) DeleteMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	events := a.startMany(ctx, OpDelete, namespace, ids)
+	kctx, keyIDs := a.trackMany(ctx)
+	results, err := vault.DeleteMany(kctx, a.v, namespace, ids)
+	a.finishMany(ctx, events, results, keyIDs, err)
+	return results, err
+}
+
+// ActiveKeyID returns the wrapped vault's active key id, or nil if it does not expose one.
+func (a *Vault) ActiveKeyID() []byte {
+	if ki, ok := a.v.(vault.KeyIdentifier); ok {
+		return ki.ActiveKeyID()
+	}
+	return nil
+}
+
+// start fills the fields known before the operation runs.
+func (a *Vault) start(ctx context.Context, op Op, namespace, id string) Event {
+	return Event{
+		Time:      a.now(),
+		Op:        op,
+		Actor:     ActorFromContext(ctx),
+		Namespace: namespace,
+		ID:        id,
+	}
+}
+
+// track returns a context in which the wrapped vault's row key id reports set e.KeyID.
+func (a *Vault) track(ctx context.Context, e *Event) context.Context {
+	return vault.WithRowKeyIDs(ctx, func(_ string, keyID []byte) {
+		e.KeyID = bytes.Clone(keyID)
+	})
+}
+
+// trackMany returns a context in which the wrapped vault's row key id reports are collected by row
+// id, for the events of a batch.
+func (a *Vault) trackMany(ctx context.Context) (context.Context, map[string][]byte) {
+	keyIDs := make(map[string][]byte)
+	return vault.WithRowKeyIDs(ctx, func(id string, keyID []byte) {
+		keyIDs[id] = bytes.Clone(keyID)
+	}), keyIDs
+}
+
+// startMany fills an event per item of a batch, all starting together.
+func (a *Vault) startMany(ctx context.Context, op Op, namespace string, ids []string) []Event {
+	e := a.start(ctx, op, namespace, "")
+	events := make([]Event, len(ids))
+	for i, id := range ids {
+		events[i] = e
+		events[i].ID = id
+	}
+	return events
+}
+
+// finishMany records each item's outcome and reported key id; items without a result (the call did
+// not run) get err. Every event carries the duration of the whole batch.
+func (a *Vault) finishMany(ctx context.Context, events []Event, results []vault.BatchResult, keyIDs map[string][]byte, err error) {
+	for i, e := range events {
+		itemErr := err
+		if i < len(results) {
+			e.ID, itemErr = results[i].ID, results[i].Err
+		}
+		if itemErr == nil {
+			e.KeyID = keyIDs[e.ID]
+		}
+		a.finish(ctx, e, itemErr)
+	}
+}
+
+// finish records the outcome and sends the event to the sink.
+func (a *Vault) finish(ctx context.Context, e Event, err error) {
+	e.Duration = a.now().Sub(e.Time)
+	e.Err = err
+	e.Outcome = classify(err)
+	a.sink.Record(ctx, e)
+}
@@ -0,0 +1,321 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/rlog"
+	rlogtesting "go.rtnl.ai/x/rlog/testing"
+	"go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/audit"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestVault_events runs every operation once and checks the recorded events.
+func TestVault_events(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	inner, err := v1.New(priv, storage.NewMemStorage(), identifier.HexIdentifier{})
+	assert.Ok(t, err)
+
+	sink := audit.NewMemSink()
+	v, err := audit.New(inner, sink)
+	assert.Ok(t, err)
+	assert.Equal(t, priv.PublicKey().Bytes(), v.ActiveKeyID())
+
+	ctx := audit.WithActor(context.Background(), "alice")
+	id, err := v.Store(ctx, "ns", []byte("s3cret"))
+	assert.Ok(t, err)
+	_, err = v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Ok(t, v.Update(ctx, "ns", id, []byte("s3cret-2")))
+	assert.Error(t, v.CompareAndSwap(ctx, "ns", id, []byte("wrong"), []byte("x")))
+	assert.Ok(t, v.MoveNamespace(ctx, "ns", "other", id))
+	_, _, err = v.ListIDs(ctx, "other", "", 10)
+	assert.Ok(t, err)
+	assert.Ok(t, v.Delete(ctx, "other", id))
+	_, err = v.Retrieve(context.Background(), "other", id)
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+
+	events := sink.Events()
+	assert.Len(t, events, 8)
+
+	// Only operations that sealed or opened a row carry its key id.
+	keyID := priv.PublicKey().Bytes()
+	want := []struct {
+		op      audit.Op
+		ns      string
+		id      string
+		keyID   []byte
+		outcome audit.Outcome
+	}{
+		{audit.OpStore, "ns", id, keyID, audit.OutcomeSuccess},
+		{audit.OpRetrieve, "ns", id, keyID, audit.OutcomeSuccess},
+		{audit.OpUpdate, "ns", id, keyID, audit.OutcomeSuccess},
+		{audit.OpCompareAndSwap, "ns", id, nil, audit.OutcomeConflict},
+		{audit.OpMoveNamespace, "ns", id, keyID, audit.OutcomeSuccess},
+		{audit.OpList, "other", "", nil, audit.OutcomeSuccess},
+		{audit.OpDelete, "other", id, nil, audit.OutcomeSuccess},
+		{audit.OpRetrieve, "other", id, nil, audit.OutcomeNotFound},
+	}
+	for i, w := range want {
+		e := events[i]
+		assert.Equal(t, w.op, e.Op, "event %d", i)
+		assert.Equal(t, w.ns, e.Namespace, "event %d", i)
+		assert.Equal(t, w.id, e.ID, "event %d", i)
+		assert.Equal(t, w.outcome, e.Outcome, "event %d", i)
+		assert.Equal(t, w.keyID, e.KeyID, "event %d", i)
+		assert.False(t, e.Time.IsZero(), "event %d", i)
+		assert.True(t, e.Duration >= 0, "event %d", i)
+	}
+	assert.Equal(t, "other", events[4].NewNamespace)
+	assert.Equal(t, "alice", events[0].Actor)
+	assert.Equal(t, "", events[7].Actor)
+	assert.Ok(t, events[0].Err)
+	assert.ErrorIs(t, events[7].Err, verrors.ErrNotFound)
+
+	sink.Reset()
+	assert.Len(t, sink.Events(), 0)
+}
+
+// TestVault_rowKeyID verifies events name the key a row was sealed for rather than the active key,
+// so reads after a key rotation are attributed to the retired key.
+func TestVault_rowKeyID(t *testing.T) {
+	oldKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	newKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	before, err := v1.New(oldKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	oldID, err := before.Store(ctx, "ns", []byte("sealed before rotation"))
+	assert.Ok(t, err)
+
+	kr, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+	inner, err := v1.NewWithKeyring(kr, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	sink := audit.NewMemSink()
+	v, err := audit.New(inner, sink)
+	assert.Ok(t, err)
+
+	_, err = v.Retrieve(ctx, "ns", oldID)
+	assert.Ok(t, err)
+	newID, err := v.Store(ctx, "ns", []byte("sealed after rotation"))
+	assert.Ok(t, err)
+	_, err = v.RetrieveMany(ctx, "ns", []string{oldID, newID})
+	assert.Ok(t, err)
+
+	events := sink.Events()
+	assert.Len(t, events, 4)
+	assert.Equal(t, oldKey.PublicKey().Bytes(), events[0].KeyID)
+	assert.Equal(t, newKey.PublicKey().Bytes(), events[1].KeyID)
+	assert.Equal(t, oldKey.PublicKey().Bytes(), events[2].KeyID)
+	assert.Equal(t, newKey.PublicKey().Bytes(), events[3].KeyID)
+	assert.Equal(t, newKey.PublicKey().Bytes(), v.ActiveKeyID())
+}
+
+// TestVault_withoutOptionalInterfaces wraps a vault that neither lists nor exposes a key id.
+func TestVault_withoutOptionalInterfaces(t *testing.T) {
+	sink := audit.NewMemSink()
+	v, err := audit.New(struct{ vault.Vault }{newTestVault(t)}, sink)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte(nil), v.ActiveKeyID())
+
+	_, _, err = v.ListIDs(context.Background(), "ns", "", 1)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+	assert.Equal(t, audit.OutcomeFailure, sink.Events()[0].Outcome)
+
+	_, err = audit.New(nil, sink)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = audit.New(newTestVault(t), nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+}
+
+// TestVault_expiring records expiring writes as stores and updates.
+func TestVault_expiring(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	inner, err := v1.New(priv, storage.NewMemStorage(), identifier.HexIdentifier{})
+	assert.Ok(t, err)
+
+	sink := audit.NewMemSink()
+	v, err := audit.New(inner, sink)
+	assert.Ok(t, err)
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	id, err := v.StoreExpiring(ctx, "ns", []byte("s3cret"), expiresAt)
+	assert.Ok(t, err)
+	assert.Ok(t, v.UpdateExpiring(ctx, "ns", id, []byte("s3cret-2"), expiresAt))
+	assert.ErrorIs(t, v.UpdateExpiring(ctx, "ns", missingID, []byte("x"), expiresAt), verrors.ErrNotFound)
+
+	events := sink.Events()
+	assert.Len(t, events, 3)
+	assert.Equal(t, audit.OpStore, events[0].Op)
+	assert.Equal(t, id, events[0].ID)
+	assert.Equal(t, audit.OutcomeSuccess, events[0].Outcome)
+	assert.Equal(t, audit.OpUpdate, events[1].Op)
+	assert.Equal(t, id, events[1].ID)
+	assert.Equal(t, audit.OpUpdate, events[2].Op)
+	assert.Equal(t, audit.OutcomeNotFound, events[2].Outcome)
+
+	// Without an Expirer to forward to, the writes fail and are still recorded.
+	sink.Reset()
+	plain, err := audit.New(struct{ vault.Vault }{inner}, sink)
+	assert.Ok(t, err)
+	_, err = plain.StoreExpiring(ctx, "ns", []byte("x"), expiresAt)
+	assert.ErrorIs(t, err, verrors.ErrExpiryUnsupported)
+	assert.ErrorIs(t, plain.UpdateExpiring(ctx, "ns", id, []byte("x"), expiresAt), verrors.ErrExpiryUnsupported)
+	assert.Len(t, sink.Events(), 2)
+	assert.Equal(t, audit.OutcomeFailure, sink.Events()[0].Outcome)
+}
+
+// TestVault_batch records one event per batch item.
+func TestVault_batch(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	inner, err := v1.New(priv, storage.NewMemStorage(), identifier.HexIdentifier{})
+	assert.Ok(t, err)
+
+	sink := audit.NewMemSink()
+	v, err := audit.New(inner, sink)
+	assert.Ok(t, err)
+
+	ctx := audit.WithActor(context.Background(), "importer")
+	stored, err := v.StoreMany(ctx, "ns", [][]byte{[]byte("a"), []byte("b")})
+	assert.Ok(t, err)
+	_, err = v.RetrieveMany(ctx, "ns", []string{stored[0].ID, missingID})
+	assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+	_, err = v.DeleteMany(ctx, "ns", []string{stored[1].ID})
+	assert.Ok(t, err)
+
+	events := sink.Events()
+	assert.Len(t, events, 5)
+	want := []struct {
+		op      audit.Op
+		id      string
+		outcome audit.Outcome
+	}{
+		{audit.OpStore, stored[0].ID, audit.OutcomeSuccess},
+		{audit.OpStore, stored[1].ID, audit.OutcomeSuccess},
+		{audit.OpRetrieve, stored[0].ID, audit.OutcomeSuccess},
+		{audit.OpRetrieve, missingID, audit.OutcomeNotFound},
+		{audit.OpDelete, stored[1].ID, audit.OutcomeSuccess},
+	}
+	for i, w := range want {
+		assert.Equal(t, w.op, events[i].Op, "event %d", i)
+		assert.Equal(t, w.id, events[i].ID, "event %d", i)
+		assert.Equal(t, w.outcome, events[i].Outcome, "event %d", i)
+		assert.Equal(t, "importer", events[i].Actor, "event %d", i)
+	}
+	assert.Equal(t, priv.PublicKey().Bytes(), events[1].KeyID)
+	assert.Equal(t, priv.PublicKey().Bytes(), events[2].KeyID)
+	assert.Equal(t, []byte(nil), events[3].KeyID)
+
+	// A batch that did not run records its error for every item; a vault without a Batcher is
+	// called once per item.
+	sink.Reset()
+	failing, err := audit.New(failingBatcher{inner}, sink)
+	assert.Ok(t, err)
+	_, err = failing.DeleteMany(ctx, "ns", []string{stored[0].ID, missingID})
+	assert.ErrorIs(t, err, errBatch)
+	assert.Len(t, sink.Events(), 2)
+	assert.ErrorIs(t, sink.Events()[1].Err, errBatch)
+	assert.Equal(t, missingID, sink.Events()[1].ID)
+
+	sink.Reset()
+	plain, err := audit.New(newTestVault(t), sink)
+	assert.Ok(t, err)
+	stored, err = plain.StoreMany(ctx, "ns", [][]byte{[]byte("c"), []byte("d")})
+	assert.Ok(t, err)
+	assert.Len(t, sink.Events(), 2)
+	assert.Equal(t, stored[1].ID, sink.Events()[1].ID)
+}
+
+// TestLogSink checks the logged fields and levels, and that plaintext never reaches the log.
+func TestLogSink(t *testing.T) {
+	h := rlogtesting.NewCapturingTestHandler(t)
+	v, err := audit.New(newTestVault(t), audit.NewLogSink(rlog.New(slog.New(h))))
+	assert.Ok(t, err)
+
+	ctx := audit.WithActor(context.Background(), "svc-billing")
+	id, err := v.Store(ctx, "ns", []byte("top-secret-plaintext"))
+	assert.Ok(t, err)
+	assert.Error(t, v.MoveNamespace(ctx, "ns", "other", missingID))
+
+	records, lines := h.RecordsAndLines()
+	assert.Len(t, records, 2)
+	assert.Equal(t, slog.LevelInfo, records[0].Level)
+	assert.Equal(t, slog.LevelWarn, records[1].Level)
+	for _, line := range lines {
+		assert.False(t, strings.Contains(line, "top-secret-plaintext"))
+	}
+
+	m := rlogtesting.MustParseJSONLine(lines[0])
+	assert.Equal(t, audit.LogMessage, m["msg"])
+	assert.Equal(t, "store", m["op"])
+	assert.Equal(t, "svc-billing", m["actor"])
+	assert.Equal(t, id, m["id"])
+	assert.Equal(t, "success", m["outcome"])
+	assert.Equal(t, hex.EncodeToString(nil), m["key_id"])
+
+	m = rlogtesting.MustParseJSONLine(lines[1])
+	assert.Equal(t, "move_namespace", m["op"])
+	assert.Equal(t, "other", m["new_namespace"])
+	assert.Equal(t, "not_found", m["outcome"])
+	_, ok := m["error"]
+	assert.True(t, ok)
+}
+
+// TestSinkFunc verifies the adapter forwards events.
+func TestSinkFunc(t *testing.T) {
+	var got bytes.Buffer
+	sink := audit.SinkFunc(func(_ context.Context, e audit.Event) { got.WriteString(string(e.Op)) })
+	v, err := audit.New(newTestVault(t), sink)
+	assert.Ok(t, err)
+	assert.Ok(t, v.Delete(context.Background(), "ns", missingID))
+	assert.Equal(t, "delete", got.String())
+}
+
+var errBatch = errors.New("batch failed")
+
+// failingBatcher is a [vault.Batcher] whose batches never run.
+type failingBatcher struct {
+	vault.Vault
+}
+
+func (failingBatcher) StoreMany(context.Context, string, [][]byte) ([]vault.BatchResult, error) {
+	return nil, errBatch
+}
+
+func (failingBatcher) RetrieveMany(context.Context, string, []string) ([]vault.BatchResult, error) {
+	return nil, errBatch
+}
+
+func (failingBatcher) DeleteMany(context.Context, string, []string) ([]vault.BatchResult, error) {
+	return nil, errBatch
+}
+
+// missingID is a well-formed hex id that is never stored.
+const missingID = "0123456789abcdef0123456789abcdef"
+
+func newTestVault(tb testing.TB) *vaulttest.TestVault {
+	tb.Helper()
+	return vaulttest.NewTestVault(tb, storage.NewMemStorage(), identifier.HexIdentifier{})
+}
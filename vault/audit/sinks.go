@@ -0,0 +1,99 @@
+package audit
+
+// Built-in sinks: structured logging through rlog, and an in-memory sink for tests.
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"go.rtnl.ai/x/rlog"
+)
+
+//=============================================================================
+// LogSink
+//=============================================================================
+
+// LogMessage is the message of every record [LogSink] writes.
+const LogMessage = "vault audit"
+
+// LogSink writes each event as one structured [rlog] record: successes at Info and failures at Warn.
+// The key id is hex encoded and errors are logged by their message.
+type LogSink struct {
+	log *rlog.Logger
+}
+
+// NewLogSink returns a [LogSink] writing to log, or to [rlog.Default] when log is nil.
+func NewLogSink(log *rlog.Logger) *LogSink {
+	if log == nil {
+		log = rlog.Default()
+	}
+	return &LogSink{log: log}
+}
+
+// Record logs e.
+func (s *LogSink) Record(ctx context.Context, e Event) {
+	level := slog.LevelInfo
+	if e.Outcome != OutcomeSuccess {
+		level = slog.LevelWarn
+	}
+
+	attrs := make([]slog.Attr, 0, 10)
+	attrs = append(attrs,
+		slog.String("op", string(e.Op)),
+		slog.String("actor", e.Actor),
+		slog.String("namespace", e.Namespace),
+	)
+	if e.NewNamespace != "" {
+		attrs = append(attrs, slog.String("new_namespace", e.NewNamespace))
+	}
+	attrs = append(attrs,
+		slog.String("id", e.ID),
+		slog.String("key_id", hex.EncodeToString(e.KeyID)),
+		slog.String("outcome", string(e.Outcome)),
+		slog.Duration("duration", e.Duration),
+	)
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+
+	s.log.LogAttrs(ctx, level, LogMessage, attrs...)
+}
+
+//=============================================================================
+// MemSink
+//=============================================================================
+
+// MemSink keeps events in memory in the order they were recorded. It is safe for concurrent use.
+type MemSink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemSink returns an empty [MemSink].
+func NewMemSink() *MemSink {
+	return &MemSink{}
+}
+
+// Record appends e.
+func (s *MemSink) Record(_ context.Context, e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+}
+
+// Events returns a copy of the recorded events.
+func (s *MemSink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+// Reset discards the recorded events.
+func (s *MemSink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+}
@@ -0,0 +1,407 @@
+/*
+Package cache keeps recently retrieved plaintext in memory so hot paths can skip the key exchange and
+decryption on every read. [New] wraps any [vault.Vault]; Retrieve is served from a least-recently-used
+cache bounded by entry count and by a time-to-live measured from when the plaintext was decrypted.
+
+Update, CompareAndSwap, MoveNamespace, Delete, and DeleteMany drop the affected rows before
+returning, whether or not the wrapped call succeeded, and a Retrieve or RetrieveMany racing with one
+of them never caches the values it read. Evicted, expired, and invalidated plaintext is zeroed with [keys.Zero], and expired entries are
+dropped on a timer even when the cache is idle, so no plaintext outlives the TTL by more than the
+timer's latency. Call [Vault.Close] to zero everything when the vault is no longer needed.
+
+The cache only bounds the copies it holds: callers get their own copy of the plaintext on every hit
+and remain responsible for it. Writes made through another vault over the same storage are not seen
+until the entry expires, and a row's own expiry ([vault.Expirer]) is not known to the cache, so a
+cached row can be served for up to the TTL after it expired. Keep the TTL short where that matters.
+*/
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+)
+
+// Defaults for [Options] fields left zero.
+const (
+	DefaultMaxEntries = 1024
+	DefaultTTL        = time.Minute
+)
+
+// Options configures [New]. A nil *Options uses the defaults.
+type Options struct {
+	// MaxEntries bounds the number of cached rows; the least recently used row is evicted first. Zero
+	// or less uses [DefaultMaxEntries].
+	MaxEntries int
+
+	// TTL bounds how long a row's plaintext stays cached after it was decrypted. Hits do not extend
+	// it. Zero or less uses [DefaultTTL].
+	TTL time.Duration
+}
+
+// Stats counts cache activity since the vault was created.
+type Stats struct {
+	Hits      uint64 // rows Retrieve and RetrieveMany served from the cache
+	Misses    uint64 // rows Retrieve and RetrieveMany passed to the wrapped vault
+	Evictions uint64 // entries dropped for space or age (not invalidations)
+	Entries   int    // rows currently cached
+}
+
+//=============================================================================
+// Vault
+//=============================================================================
+
+// Vault wraps a [vault.Vault] with a plaintext cache for Retrieve. It implements [vault.Lister],
+// [vault.KeyIdentifier], and [vault.Expirer] by forwarding to the wrapped vault; when the wrapped vault
+// lacks one, ListIDs returns [verrors.ErrListUnsupported], ActiveKeyID returns nil, and the expiring
+// writes return [verrors.ErrExpiryUnsupported]. It implements [vault.Batcher] through the wrapped
+// vault's Batcher or one call per item. It is safe for concurrent use.
+type Vault struct {
+	v   vault.Vault
+	max int
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[rowKey]*list.Element // values are *entry
+	lru     *list.List               // front is most recently used
+	gen     uint64                   // bumped by every invalidation
+	timer   *time.Timer              // fires at the oldest entry's expiry; nil when idle
+	closed  bool
+	stats   Stats
+}
+
+// Compile-time checks.
+var (
+	_ vault.Vault         = (*Vault)(nil)
+	_ vault.Lister        = (*Vault)(nil)
+	_ vault.KeyIdentifier = (*Vault)(nil)
+	_ vault.Expirer       = (*Vault)(nil)
+	_ vault.Batcher       = (*Vault)(nil)
+)
+
+type rowKey struct {
+	namespace, id string
+}
+
+type entry struct {
+	key     rowKey
+	plain   []byte
+	expires time.Time
+}
+
+// New wraps v with a cache configured by opts. A nil v returns [verrors.ErrInvalidNewArgs].
+func New(v vault.Vault, opts *Options) (*Vault, error) {
+	if v == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	c := &Vault{
+		v:       v,
+		max:     opts.MaxEntries,
+		ttl:     opts.TTL,
+		now:     time.Now,
+		entries: make(map[rowKey]*list.Element),
+		lru:     list.New(),
+	}
+	if c.max <= 0 {
+		c.max = DefaultMaxEntries
+	}
+	if c.ttl <= 0 {
+		c.ttl = DefaultTTL
+	}
+	return c, nil
+}
+
+// Store stores plaintext through the wrapped vault. New rows are not cached until first retrieved.
+func (c *Vault) Store(ctx context.Context, namespace string, plaintext []byte) (string, error) {
+	return c.v.Store(ctx, namespace, plaintext)
+}
+
+// Retrieve returns a copy of the cached plaintext for (namespace, id), or retrieves it through the
+// wrapped vault and caches it. Errors are returned as is and never cached.
+func (c *Vault) Retrieve(ctx context.Context, namespace, id string) ([]byte, error) {
+	key := rowKey{namespace, id}
+
+	c.mu.Lock()
+	if plain, ok := c.lookup(key); ok {
+		c.mu.Unlock()
+		return plain, nil
+	}
+	gen := c.gen
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	plain, err := c.v.Retrieve(ctx, namespace, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A write that invalidated anything while the wrapped vault was reading may have changed this row.
+	if gen == c.gen && !c.closed {
+		c.insert(key, plain)
+	}
+	return plain, nil
+}
+
+// Update replaces the row through the wrapped vault and drops it from the cache.
+func (c *Vault) Update(ctx context.Context, namespace, id string, plaintext []byte) error {
+	defer c.invalidate(rowKey{namespace, id})
+	return c.v.Update(ctx, namespace, id, plaintext)
+}
+
+// CompareAndSwap swaps the row through the wrapped vault and drops it from the cache. The comparison
+// always runs against the stored row, never the cached plaintext.
+func (c *Vault) CompareAndSwap(ctx context.Context, namespace, id string, currentPlain, newPlain []byte) error {
+	defer c.invalidate(rowKey{namespace, id})
+	return c.v.CompareAndSwap(ctx, namespace, id, currentPlain, newPlain)
+}
+
+// MoveNamespace moves the row through the wrapped vault and drops it from the cache under both
+// namespaces.
+func (c *Vault) MoveNamespace(ctx context.Context, oldNamespace, newNamespace, id string) error {
+	defer c.invalidate(rowKey{oldNamespace, id}, rowKey{newNamespace, id})
+	return c.v.MoveNamespace(ctx, oldNamespace, newNamespace, id)
+}
+
+// Delete removes the row through the wrapped vault and drops it from the cache.
+func (c *Vault) Delete(ctx context.Context, namespace, id string) error {
+	defer c.invalidate(rowKey{namespace, id})
+	return c.v.Delete(ctx, namespace, id)
+}
+
+// StoreExpiring stores an expiring row through the wrapped vault's [vault.Expirer].
+func (c *Vault) StoreExpiring(ctx context.Context, namespace string, plaintext []byte, expiresAt time.Time) (string, error) {
+	e, ok := c.v.(vault.Expirer)
+	if !ok {
+		return "", verrors.ErrExpiryUnsupported
+	}
+	return e.StoreExpiring(ctx, namespace, plaintext, expiresAt)
+}
+
+// UpdateExpiring replaces the row through the wrapped vault's [vault.Expirer] and drops it from the cache.
+func (c *Vault) UpdateExpiring(ctx context.Context, namespace, id string, plaintext []byte, expiresAt time.Time) error {
+	e, ok := c.v.(vault.Expirer)
+	if !ok {
+		return verrors.ErrExpiryUnsupported
+	}
+	defer c.invalidate(rowKey{namespace, id})
+	return e.UpdateExpiring(ctx, namespace, id, plaintext, expiresAt)
+}
+
+// StoreMany stores plaintexts through the wrapped vault; see [vault.StoreMany]. New rows are not
+// cached until first retrieved.
+func (c *Vault) StoreMany(ctx context.Context, namespace string, plaintexts [][]byte) ([]vault.BatchResult, error) {
+	return vault.StoreMany(ctx, c.v, namespace, plaintexts)
+}
+
+// RetrieveMany serves each id from the cache as Retrieve does and retrieves the misses through the
+// wrapped vault in one batch (see [vault.RetrieveMany]), caching each row that opened. Item errors
+// are never cached.
+func (c *Vault) RetrieveMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	results := make([]vault.BatchResult, len(ids))
+	var misses []int
+
+	c.mu.Lock()
+	for i, id := range ids {
+		results[i].ID = id
+		if plain, ok := c.lookup(rowKey{namespace, id}); ok {
+			results[i].Plaintext = plain
+			continue
+		}
+		misses = append(misses, i)
+		c.stats.Misses++
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	missIDs := make([]string, len(misses))
+	for j, i := range misses {
+		missIDs[j] = ids[i]
+	}
+	fetched, err := vault.RetrieveMany(ctx, c.v, namespace, missIDs)
+	if len(fetched) != len(missIDs) {
+		for _, res := range results {
+			keys.Zero(res.Plaintext)
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// As in Retrieve, a write that invalidated anything during the batch may have changed these rows.
+	keep := gen == c.gen && !c.closed
+	for j, i := range misses {
+		results[i] = fetched[j]
+		if keep && fetched[j].Err == nil {
+			c.insert(rowKey{namespace, ids[i]}, fetched[j].Plaintext)
+		}
+	}
+	return results, err
+}
+
+// DeleteMany removes the rows through the wrapped vault (see [vault.DeleteMany]) and drops each of
+// them from the cache.
+func (c *Vault) DeleteMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	rows := make([]rowKey, len(ids))
+	for i, id := range ids {
+		rows[i] = rowKey{namespace, id}
+	}
+	defer c.invalidate(rows...)
+	return vault.DeleteMany(ctx, c.v, namespace, ids)
+}
+
+// ListIDs lists ids through the wrapped vault; listing never touches the cache.
+func (c *Vault) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	lister, ok := c.v.(vault.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
+
+// ActiveKeyID returns the wrapped vault's active key id, or nil if it does not expose one.
+func (c *Vault) ActiveKeyID() []byte {
+	if ki, ok := c.v.(vault.KeyIdentifier); ok {
+		return ki.ActiveKeyID()
+	}
+	return nil
+}
+
+// Purge zeroes and drops every cached row. The vault stays usable.
+func (c *Vault) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purge()
+}
+
+// Close zeroes and drops every cached row and stops caching: later calls go straight to the wrapped
+// vault. Close does not close the wrapped vault and always returns nil.
+func (c *Vault) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purge()
+	c.closed = true
+	return nil
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Vault) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.lru.Len()
+	return s
+}
+
+//=============================================================================
+// Cache bookkeeping (callers hold c.mu)
+//=============================================================================
+
+// lookup returns a copy of a live entry and marks it recently used. An expired entry is dropped.
+func (c *Vault) lookup(key rowKey) ([]byte, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		c.stats.Evictions++
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	c.stats.Hits++
+	return append([]byte(nil), e.plain...), true
+}
+
+// insert caches a private copy of plain under key, evicting the least recently used rows over the limit.
+func (c *Vault) insert(key rowKey, plain []byte) {
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	e := &entry{key: key, plain: append([]byte(nil), plain...), expires: c.now().Add(c.ttl)}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.max {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.ttl, c.expire)
+	}
+}
+
+// remove zeroes an entry's plaintext and drops it.
+func (c *Vault) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	keys.Zero(e.plain)
+}
+
+// purge removes every entry and stops the expiry timer.
+func (c *Vault) purge() {
+	for el := c.lru.Front(); el != nil; el = c.lru.Front() {
+		c.remove(el)
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// invalidate drops the given rows and bumps the generation so in-flight misses are not cached.
+func (c *Vault) invalidate(rows ...rowKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, key := range rows {
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// expire runs on the timer: it drops every expired entry and re-arms for the next one to expire.
+func (c *Vault) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timer = nil
+	now := c.now()
+	var next time.Time
+	for el := c.lru.Front(); el != nil; {
+		e, following := el.Value.(*entry), el.Next()
+		switch {
+		case !now.Before(e.expires):
+			c.remove(el)
+			c.stats.Evictions++
+		case next.IsZero() || e.expires.Before(next):
+			next = e.expires
+		}
+		el = following
+	}
+
+	if !next.IsZero() {
+		c.timer = time.AfterFunc(next.Sub(now), c.expire)
+	}
+}
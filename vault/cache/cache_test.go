@@ -0,0 +1,269 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/cache"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestVault_hitsAndInvalidation checks hits skip the wrapped vault, callers get private copies, and
+// every write drops the row.
+func TestVault_hitsAndInvalidation(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingVault{Vault: newTestVault(t)}
+	c, err := cache.New(inner, nil)
+	assert.Ok(t, err)
+
+	id, err := c.Store(ctx, "ns", []byte("a"))
+	assert.Ok(t, err)
+	for range 3 {
+		got, err := c.Retrieve(ctx, "ns", id)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("a"), got)
+		got[0] = 'x' // must not reach the cache
+	}
+	assert.Equal(t, int64(1), inner.retrieves.Load())
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 1, Entries: 1}, c.Stats())
+
+	// Each write runs with the row cached in its current namespace; reading it back must miss.
+	steps := []struct {
+		write    func() error
+		from, to string // namespace before and after the write
+		want     string // plaintext after the write; empty if deleted
+	}{
+		{func() error { return c.Update(ctx, "ns", id, []byte("b")) }, "ns", "ns", "b"},
+		{func() error { return c.CompareAndSwap(ctx, "ns", id, []byte("b"), []byte("c")) }, "ns", "ns", "c"},
+		{func() error { return c.MoveNamespace(ctx, "ns", "other", id) }, "ns", "other", "c"},
+		{func() error { return c.Delete(ctx, "other", id) }, "other", "other", ""},
+	}
+	for i, step := range steps {
+		_, err := c.Retrieve(ctx, step.from, id)
+		assert.Ok(t, err)
+		buf := cache.CachedBytes(c, step.from, id)
+
+		assert.Ok(t, step.write())
+		assert.Equal(t, []byte(nil), cache.CachedBytes(c, step.from, id), "step %d", i)
+		assert.True(t, isZero(buf), "step %d left plaintext in memory", i)
+
+		got, err := c.Retrieve(ctx, step.to, id)
+		if step.want == "" {
+			assert.ErrorIs(t, err, verrors.ErrNotFound, "step %d", i)
+			continue
+		}
+		assert.Ok(t, err)
+		assert.Equal(t, []byte(step.want), got, "step %d", i)
+	}
+}
+
+// TestVault_batch checks RetrieveMany serves hits from the cache and caches the rows it fetched, and
+// DeleteMany drops every row.
+func TestVault_batch(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingVault{Vault: newTestVault(t)}
+	c, err := cache.New(inner, nil)
+	assert.Ok(t, err)
+
+	stored, err := c.StoreMany(ctx, "ns", [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	assert.Ok(t, err)
+	ids := []string{stored[0].ID, stored[1].ID, stored[2].ID}
+	_, err = c.Retrieve(ctx, "ns", ids[0])
+	assert.Ok(t, err)
+
+	results, err := c.RetrieveMany(ctx, "ns", []string{ids[0], ids[1], missingID, ids[2]})
+	assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+	assert.Len(t, results, 4)
+	assert.Equal(t, []byte("a"), results[0].Plaintext)
+	assert.Equal(t, []byte("b"), results[1].Plaintext)
+	assert.Equal(t, missingID, results[2].ID)
+	assert.ErrorIs(t, results[2].Err, verrors.ErrNotFound)
+	assert.Equal(t, []byte("c"), results[3].Plaintext)
+	assert.Equal(t, int64(4), inner.retrieves.Load())
+	assert.Equal(t, cache.Stats{Hits: 1, Misses: 4, Entries: 3}, c.Stats())
+
+	results, err = c.RetrieveMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, int64(4), inner.retrieves.Load())
+
+	buf := cache.CachedBytes(c, "ns", ids[1])
+	results, err = c.DeleteMany(ctx, "ns", ids[:2])
+	assert.Ok(t, err)
+	assert.Len(t, results, 2)
+	assert.True(t, isZero(buf), "DeleteMany left plaintext in memory")
+	assert.Equal(t, 1, c.Stats().Entries)
+	_, err = c.Retrieve(ctx, "ns", ids[1])
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+}
+
+// TestVault_bounds checks LRU eviction, TTL expiry against the clock, and that both zero plaintext.
+func TestVault_bounds(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingVault{Vault: newTestVault(t)}
+	c, err := cache.New(inner, &cache.Options{MaxEntries: 2, TTL: time.Hour})
+	assert.Ok(t, err)
+	defer c.Close()
+
+	now := time.Now()
+	cache.SetClock(c, func() time.Time { return now })
+
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i], err = c.Store(ctx, "ns", []byte{byte('a' + i)})
+		assert.Ok(t, err)
+	}
+
+	// Touch a then b, then read c: a is least recently used and goes.
+	_, err = c.Retrieve(ctx, "ns", ids[0])
+	assert.Ok(t, err)
+	evicted := cache.CachedBytes(c, "ns", ids[0])
+	_, err = c.Retrieve(ctx, "ns", ids[1])
+	assert.Ok(t, err)
+	_, err = c.Retrieve(ctx, "ns", ids[2])
+	assert.Ok(t, err)
+	assert.Equal(t, []byte(nil), cache.CachedBytes(c, "ns", ids[0]))
+	assert.True(t, isZero(evicted))
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+
+	// Past the TTL every entry is a miss again.
+	expired := cache.CachedBytes(c, "ns", ids[1])
+	now = now.Add(time.Hour)
+	before := inner.retrieves.Load()
+	got, err := c.Retrieve(ctx, "ns", ids[1])
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("b"), got)
+	assert.Equal(t, before+1, inner.retrieves.Load())
+	assert.True(t, isZero(expired))
+
+	// Close zeroes what is left and turns the cache off.
+	left := cache.CachedBytes(c, "ns", ids[2])
+	assert.Ok(t, c.Close())
+	assert.True(t, isZero(left))
+	_, err = c.Retrieve(ctx, "ns", ids[2])
+	assert.Ok(t, err)
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+// TestVault_timer checks idle entries are zeroed once their TTL passes.
+func TestVault_timer(t *testing.T) {
+	ctx := context.Background()
+	c, err := cache.New(newTestVault(t), &cache.Options{TTL: 10 * time.Millisecond})
+	assert.Ok(t, err)
+	defer c.Close()
+
+	id, err := c.Store(ctx, "ns", []byte("idle"))
+	assert.Ok(t, err)
+	_, err = c.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	buf := cache.CachedBytes(c, "ns", id)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Stats().Entries > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, 0, c.Stats().Entries)
+	assert.True(t, isZero(buf))
+}
+
+// TestVault_raceWithWrite checks a Retrieve that overlaps a write does not cache what it read.
+func TestVault_raceWithWrite(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingVault{Vault: newTestVault(t)}
+	c, err := cache.New(inner, nil)
+	assert.Ok(t, err)
+
+	id, err := c.Store(ctx, "ns", []byte("old"))
+	assert.Ok(t, err)
+
+	// The write lands after the wrapped Retrieve read "old" but before the cache stores it.
+	inner.afterRetrieve = func() { assert.Ok(t, c.Update(ctx, "ns", id, []byte("new"))) }
+	got, err := c.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("old"), got)
+	inner.afterRetrieve = nil
+
+	got, err = c.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("new"), got)
+}
+
+// TestVault_optionalInterfaces checks forwarding to a v1 vault and fallbacks for a bare vault.
+func TestVault_optionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	inner, err := v1.New(priv, storage.NewMemStorage(), identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	c, err := cache.New(inner, nil)
+	assert.Ok(t, err)
+
+	assert.Equal(t, priv.PublicKey().Bytes(), c.ActiveKeyID())
+	id, err := c.StoreExpiring(ctx, "ns", []byte("a"), time.Now().Add(time.Hour))
+	assert.Ok(t, err)
+	_, err = c.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Ok(t, c.UpdateExpiring(ctx, "ns", id, []byte("b"), time.Now().Add(-time.Hour)))
+	_, err = c.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, verrors.ErrExpired)
+	results, err := c.RetrieveMany(ctx, "ns", []string{id})
+	assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+	assert.ErrorIs(t, results[0].Err, verrors.ErrExpired)
+	ids, _, err := c.ListIDs(ctx, "ns", "", 0)
+	assert.Ok(t, err)
+	assert.Equal(t, []string{id}, ids)
+
+	bare, err := cache.New(struct{ vault.Vault }{newTestVault(t)}, nil)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte(nil), bare.ActiveKeyID())
+	_, _, err = bare.ListIDs(ctx, "ns", "", 0)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+	_, err = bare.StoreExpiring(ctx, "ns", nil, time.Time{})
+	assert.ErrorIs(t, err, verrors.ErrExpiryUnsupported)
+
+	_, err = cache.New(nil, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+}
+
+//=============================================================================
+// Test helpers
+//=============================================================================
+
+// countingVault counts Retrieve calls reaching the wrapped vault and can run a hook after each.
+type countingVault struct {
+	vault.Vault
+	retrieves     atomic.Int64
+	afterRetrieve func()
+}
+
+func (v *countingVault) Retrieve(ctx context.Context, namespace, id string) ([]byte, error) {
+	v.retrieves.Add(1)
+	plain, err := v.Vault.Retrieve(ctx, namespace, id)
+	if v.afterRetrieve != nil {
+		v.afterRetrieve()
+	}
+	return plain, err
+}
+
+// missingID is a well-formed hex id that is never stored.
+const missingID = "0123456789abcdef0123456789abcdef"
+
+func newTestVault(tb testing.TB) *vaulttest.TestVault {
+	tb.Helper()
+	return vaulttest.NewTestVault(tb, storage.NewMemStorage(), identifier.HexIdentifier{})
+}
+
+func isZero(b []byte) bool {
+	return len(b) > 0 && bytes.Count(b, []byte{0}) == len(b)
+}
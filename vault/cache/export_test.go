@@ -0,0 +1,23 @@
+package cache
+
+// Export_test exposes selected internals to tests in package cache_test.
+
+import "time"
+
+// SetClock replaces the clock c judges entry lifetimes against.
+func SetClock(c *Vault, now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// CachedBytes returns the cache's own plaintext buffer for a row (not a copy), or nil if the row is
+// not cached, so tests can check that dropped entries are zeroed.
+func CachedBytes(c *Vault, namespace, id string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[rowKey{namespace, id}]; ok {
+		return el.Value.(*entry).plain
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+// Command vault is an operator tool for v1 vaults backed by [storage.FileStorage] (or a throwaway
+// [storage.MemStorage]): it generates passphrase-protected key files, stores and reads secrets, lists
+// ids, and inspects sealed rows' metadata without decrypting them.
+//
+// Usage:
+//
+//	vault [global flags] <command> [args]
+//
+// Commands:
+//
+//	keygen [-profile constrained|rfc9106] <keyfile>   create a key file and print its key id
+//	store <namespace>                                 seal stdin, print the new id
+//	retrieve <namespace> <id>                         write the plaintext to stdout
+//	update <namespace> <id>                           replace the plaintext with stdin
+//	delete <namespace> <id>                           remove the row
+//	move <old-namespace> <new-namespace> <id>         re-seal the row under a new namespace
+//	list [-cursor c] [-limit n] <namespace>           print ids, one per line
+//	inspect [-in file] [<namespace> <id>]             print a sealed row's metadata
+//
+// Key files are opened with the passphrase in the file named by -passphrase-file, or else the
+// VAULT_PASSPHRASE environment variable. list and inspect do not need a key.
+package main
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/keys"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/v1/models"
+)
+
+const (
+	passphraseEnv = "VAULT_PASSPHRASE"
+	exitUsage     = 2
+)
+
+// errUsage marks argument errors, which exit with status 2.
+var errUsage = errors.New("usage")
+
+// CLI is the parsed global flags plus the streams commands read and write.
+type CLI struct {
+	KeyFile        string
+	PassphraseFile string
+	Storage        string
+	Dir            string
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run parses global flags and dispatches to a command, returning the process exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cli := &CLI{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("vault", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cli.KeyFile, "key", "", "path to the key file")
+	fs.StringVar(&cli.PassphraseFile, "passphrase-file", "", "file holding the key file passphrase (default $"+passphraseEnv+")")
+	fs.StringVar(&cli.Storage, "storage", "file", "storage backend: file or mem (mem starts empty on every run)")
+	fs.StringVar(&cli.Dir, "dir", "", "root directory for file storage")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: vault [global flags] <keygen|store|retrieve|update|delete|move|list|inspect> [args]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	commands := map[string]func(context.Context, []string) error{
+		"keygen":   cli.keygen,
+		"store":    cli.store,
+		"retrieve": cli.retrieve,
+		"update":   cli.update,
+		"delete":   cli.delete,
+		"move":     cli.move,
+		"list":     cli.list,
+		"inspect":  cli.inspect,
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "vault: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+
+	if err := cmd(context.Background(), fs.Args()[1:]); err != nil {
+		fmt.Fprintf(stderr, "vault %s: %v\n", fs.Arg(0), err)
+		if errors.Is(err, errUsage) {
+			return exitUsage
+		}
+		return 1
+	}
+	return 0
+}
+
+//=============================================================================
+// Commands
+//=============================================================================
+
+// keygen creates a new key file holding a random X25519 key and prints the key id.
+func (c *CLI) keygen(_ context.Context, args []string) error {
+	fs := c.flags("keygen")
+	profile := fs.String("profile", "constrained", "Argon2id profile: constrained (64 MiB) or rfc9106 (2 GiB)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	var params keys.Params
+	switch *profile {
+	case "constrained":
+		params = keys.MemoryConstrainedParams()
+	case "rfc9106":
+		params = keys.DefaultParams()
+	default:
+		return fmt.Errorf("%w: unknown profile %q", errUsage, *profile)
+	}
+
+	passphrase, err := c.passphrase()
+	if err != nil {
+		return err
+	}
+	defer keys.Zero(passphrase)
+
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if err := keys.CreateKeyFile(fs.Arg(0), priv, passphrase, params); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout, hex.EncodeToString(priv.PublicKey().Bytes()))
+	return nil
+}
+
+// store seals stdin into a new row and prints its id.
+func (c *CLI) store(ctx context.Context, args []string) error {
+	fs := c.flags("store")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	vlt, err := c.openVault()
+	if err != nil {
+		return err
+	}
+
+	plain, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return err
+	}
+	defer keys.Zero(plain)
+
+	id, err := vlt.Store(ctx, fs.Arg(0), plain)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, id)
+	return nil
+}
+
+// retrieve writes a row's plaintext to stdout.
+func (c *CLI) retrieve(ctx context.Context, args []string) error {
+	fs := c.flags("retrieve")
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+
+	vlt, err := c.openVault()
+	if err != nil {
+		return err
+	}
+
+	plain, err := vlt.Retrieve(ctx, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer keys.Zero(plain)
+
+	_, err = c.stdout.Write(plain)
+	return err
+}
+
+// update replaces a row's plaintext with stdin.
+func (c *CLI) update(ctx context.Context, args []string) error {
+	fs := c.flags("update")
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+
+	vlt, err := c.openVault()
+	if err != nil {
+		return err
+	}
+
+	plain, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return err
+	}
+	defer keys.Zero(plain)
+
+	return vlt.Update(ctx, fs.Arg(0), fs.Arg(1), plain)
+}
+
+// delete removes a row.
+func (c *CLI) delete(ctx context.Context, args []string) error {
+	fs := c.flags("delete")
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+
+	vlt, err := c.openVault()
+	if err != nil {
+		return err
+	}
+	return vlt.Delete(ctx, fs.Arg(0), fs.Arg(1))
+}
+
+// move re-seals a row under a new namespace.
+func (c *CLI) move(ctx context.Context, args []string) error {
+	fs := c.flags("move")
+	if err := parseArgs(fs, args, 3); err != nil {
+		return err
+	}
+
+	vlt, err := c.openVault()
+	if err != nil {
+		return err
+	}
+	return vlt.MoveNamespace(ctx, fs.Arg(0), fs.Arg(1), fs.Arg(2))
+}
+
+// list prints the ids in a namespace. With -limit it prints one page and, if more remain, the cursor
+// for the next page on stderr; otherwise it walks every page.
+func (c *CLI) list(ctx context.Context, args []string) error {
+	fs := c.flags("list")
+	cursor := fs.String("cursor", "", "list ids after this one")
+	limit := fs.Int("limit", 0, "print at most this many ids (default all)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	st, err := c.storage()
+	if err != nil {
+		return err
+	}
+	lister, ok := st.(storage.Lister)
+	if !ok {
+		return errors.New("storage does not support listing")
+	}
+
+	if *limit > 0 {
+		ids, next, err := lister.ListIDs(ctx, fs.Arg(0), *cursor, *limit)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Fprintln(c.stdout, id)
+		}
+		if next != "" {
+			fmt.Fprintf(c.stderr, "next cursor: %s\n", next)
+		}
+		return nil
+	}
+
+	for {
+		ids, next, err := lister.ListIDs(ctx, fs.Arg(0), *cursor, storage.DefaultListLimit)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Fprintln(c.stdout, id)
+		}
+		if next == "" {
+			return nil
+		}
+		*cursor = next
+	}
+}
+
+// inspect prints the metadata of a sealed row read from storage, a file, or stdin ("-in -"). The
+// metadata is only parsed, not authenticated: it describes what the row claims to be.
+func (c *CLI) inspect(ctx context.Context, args []string) error {
+	fs := c.flags("inspect")
+	in := fs.String("in", "", "read the sealed blob from this file (\"-\" for stdin) instead of storage")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var (
+		blob []byte
+		err  error
+		ns   string
+	)
+	switch {
+	case *in != "" && fs.NArg() == 0:
+		if *in == "-" {
+			blob, err = io.ReadAll(c.stdin)
+		} else {
+			blob, err = os.ReadFile(*in)
+		}
+	case *in == "" && fs.NArg() == 2:
+		var st storage.Storage
+		if st, err = c.storage(); err != nil {
+			return err
+		}
+		ns = fs.Arg(0)
+		blob, err = st.Get(ctx, ns, fs.Arg(1))
+	default:
+		return fmt.Errorf("%w: want -in <file> or <namespace> <id>", errUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	var sealed models.Sealed
+	if err := sealed.UnmarshalBinary(blob); err != nil {
+		return err
+	}
+
+	meta := sealed.Meta
+	fmt.Fprintf(c.stdout, "version:      %d\n", sealed.FormatVersion)
+	fmt.Fprintf(c.stdout, "suite:        %s (%d)\n", meta.SuiteID, uint8(meta.SuiteID))
+	fmt.Fprintf(c.stdout, "key id:       %s\n", hex.EncodeToString(meta.KeyID))
+	if len(meta.InnerKeyID) > 0 {
+		fmt.Fprintf(c.stdout, "inner key id: %s\n", hex.EncodeToString(meta.InnerKeyID))
+	}
+	fmt.Fprintf(c.stdout, "namespace:    %s\n", meta.Namespace)
+	fmt.Fprintf(c.stdout, "size:         %d bytes\n", len(blob))
+	if ns != "" && meta.Namespace != ns {
+		fmt.Fprintf(c.stderr, "warning: row is stored under namespace %q but sealed for %q\n", ns, meta.Namespace)
+	}
+	return nil
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// flags returns a flag set for a command that reports errors instead of exiting.
+func (c *CLI) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("vault "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parseArgs parses a command's flags and requires exactly n positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != n {
+		return fmt.Errorf("%w: want %d argument(s), got %d", errUsage, n, fs.NArg())
+	}
+	return nil
+}
+
+// passphrase reads the key file passphrase from -passphrase-file or the environment. A single
+// trailing newline is trimmed from the file.
+func (c *CLI) passphrase() ([]byte, error) {
+	if c.PassphraseFile != "" {
+		data, err := os.ReadFile(c.PassphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"))
+		if len(data) == 0 {
+			return nil, errors.New("passphrase file is empty")
+		}
+		return data, nil
+	}
+
+	if env := os.Getenv(passphraseEnv); env != "" {
+		return []byte(env), nil
+	}
+	return nil, fmt.Errorf("%w: set -passphrase-file or $%s", errUsage, passphraseEnv)
+}
+
+// storage opens the configured backend.
+func (c *CLI) storage() (storage.Storage, error) {
+	switch c.Storage {
+	case "file":
+		if c.Dir == "" {
+			return nil, fmt.Errorf("%w: file storage requires -dir", errUsage)
+		}
+		return storage.NewFileStorage(c.Dir)
+	case "mem":
+		return storage.NewMemStorage(), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown storage %q", errUsage, c.Storage)
+	}
+}
+
+// openVault loads the key file and opens a v1 vault over the configured storage.
+func (c *CLI) openVault() (vault.Vault, error) {
+	if c.KeyFile == "" {
+		return nil, fmt.Errorf("%w: -key is required", errUsage)
+	}
+
+	st, err := c.storage()
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := c.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	defer keys.Zero(passphrase)
+
+	priv, err := keys.LoadKeyFile(c.KeyFile, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return v1.New(priv, st, identifier.HexIdentifier{})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+)
+
+// TestRun_lifecycle drives every command against file storage in a temporary directory.
+func TestRun_lifecycle(t *testing.T) {
+	tmp := t.TempDir()
+	keyFile := filepath.Join(tmp, "vault.key")
+	passFile := filepath.Join(tmp, "passphrase")
+	dir := filepath.Join(tmp, "rows")
+	assert.Ok(t, os.WriteFile(passFile, []byte("correct horse\n"), 0o600))
+
+	global := []string{"-key", keyFile, "-passphrase-file", passFile, "-dir", dir}
+	vault := func(stdin string, args ...string) (code int, stdout, stderr string) {
+		var out, errOut bytes.Buffer
+		code = run(append(global, args...), strings.NewReader(stdin), &out, &errOut)
+		return code, out.String(), errOut.String()
+	}
+
+	code, out, stderr := vault("", "keygen", keyFile)
+	assert.Equal(t, 0, code, stderr)
+	keyID := strings.TrimSpace(out)
+	assert.Len(t, keyID, 64)
+
+	code, out, stderr = vault("hunter2", "store", "passwords")
+	assert.Equal(t, 0, code, stderr)
+	id := strings.TrimSpace(out)
+	assert.NotEqual(t, "", id)
+
+	code, out, stderr = vault("", "retrieve", "passwords", id)
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "hunter2", out)
+
+	code, out, stderr = vault("", "list", "passwords")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, id+"\n", out)
+
+	code, out, stderr = vault("", "inspect", "passwords", id)
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, "key id:       "+keyID)
+	assert.Contains(t, out, "namespace:    passwords")
+
+	code, _, stderr = vault("correct horse battery staple", "update", "passwords", id)
+	assert.Equal(t, 0, code, stderr)
+	code, out, stderr = vault("", "retrieve", "passwords", id)
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "correct horse battery staple", out)
+
+	code, _, stderr = vault("", "move", "passwords", "archive", id)
+	assert.Equal(t, 0, code, stderr)
+	code, out, stderr = vault("", "retrieve", "archive", id)
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "correct horse battery staple", out)
+	code, out, stderr = vault("", "list", "passwords")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "", out)
+
+	code, _, stderr = vault("", "delete", "archive", id)
+	assert.Equal(t, 0, code, stderr)
+	code, _, stderr = vault("", "retrieve", "archive", id)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "vault retrieve: ")
+}
+
+// TestRun_errors checks exit codes and stderr for bad invocations.
+func TestRun_errors(t *testing.T) {
+	tmp := t.TempDir()
+	passFile := filepath.Join(tmp, "passphrase")
+	assert.Ok(t, os.WriteFile(passFile, []byte("pw"), 0o600))
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no command", nil, exitUsage, "usage: vault"},
+		{"unknown command", []string{"frobnicate"}, exitUsage, `vault: unknown command "frobnicate"`},
+		{"unknown flag", []string{"-bogus", "list", "ns"}, exitUsage, "flag provided but not defined: -bogus"},
+		{"missing argument", []string{"-dir", tmp, "store"}, exitUsage, "vault store: usage: want 1 argument(s), got 0"},
+		{"extra argument", []string{"-dir", tmp, "list", "ns", "more"}, exitUsage, "vault list: usage: want 1 argument(s), got 2"},
+		{"no key flag", []string{"-dir", tmp, "retrieve", "ns", "id"}, exitUsage, "vault retrieve: usage: -key is required"},
+		{"no dir", []string{"list", "ns"}, exitUsage, "vault list: usage: file storage requires -dir"},
+		{"unknown storage", []string{"-storage", "s3", "list", "ns"}, exitUsage, `vault list: usage: unknown storage "s3"`},
+		{"unknown profile", []string{"-passphrase-file", passFile, "keygen", "-profile", "tiny", filepath.Join(tmp, "k")}, exitUsage, `vault keygen: usage: unknown profile "tiny"`},
+		{"inspect without row", []string{"-dir", tmp, "inspect"}, exitUsage, "vault inspect: usage: want -in <file> or <namespace> <id>"},
+		{"missing key file", []string{"-key", filepath.Join(tmp, "missing.key"), "-passphrase-file", passFile, "-dir", tmp, "retrieve", "ns", "id"}, 1, "vault retrieve: open "},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(""), io.Discard, &stderr)
+			assert.Equal(t, tc.code, code)
+			assert.Contains(t, stderr.String(), tc.stderr)
+		})
+	}
+}
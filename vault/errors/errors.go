@@ -64,19 +64,55 @@ var (
 	// ErrNotFound means no sealed row exists for the requested namespace and id.
 	ErrNotFound = stderrors.New("vault: secret not found")
 
+	// ErrExpired means the row exists and authenticated, but its expiry has passed; the plaintext is withheld.
+	ErrExpired = stderrors.New("vault: secret expired")
+
 	// ErrCASFailed means CompareAndSwap lost the race: stored plaintext did not match currentPlain.
 	ErrCASFailed = stderrors.New("vault: secret was modified concurrently; compare-and-swap lost")
 
 	// ErrMoveNamespaceIncomplete means MoveNamespace could not finish moving every matching row.
 	ErrMoveNamespaceIncomplete = stderrors.New("vault: namespace relocation incomplete")
 
+	// ErrBatchIncomplete means a batch call ran but at least one item failed; see the per-item results.
+	ErrBatchIncomplete = stderrors.New("vault: batch incomplete")
+
 	// ErrStorage means the underlying storage.Storage implementation returned a failure unrelated to vault logic.
 	ErrStorage = stderrors.New("vault: storage operation failed")
 
+	// ErrListUnsupported means listing was requested but the underlying storage does not implement storage.Lister.
+	ErrListUnsupported = stderrors.New("vault: storage does not support listing")
+
+	// ErrExpiryUnsupported means an expiring write was requested but the wrapped vault does not implement vault.Expirer.
+	ErrExpiryUnsupported = stderrors.New("vault: vault does not support expiring rows")
+
+	// ErrStorageLocked means a single-file storage is already open in another process (or another handle).
+	ErrStorageLocked = stderrors.New("vault: storage file is locked by another process")
+
+	// ErrStorageClosed means the storage was used after Close.
+	ErrStorageClosed = stderrors.New("vault: storage is closed")
+
+	// ErrStorageCorrupt means stored data failed an integrity check (bad header, magic, or checksum).
+	ErrStorageCorrupt = stderrors.New("vault: storage file is corrupt")
+
+	// ErrInvalidTableName means a SQL storage table name is not a plain (optionally schema-qualified) identifier.
+	ErrInvalidTableName = stderrors.New("vault: invalid storage table name")
+
 	// ErrWrongCurrent means CompareAndSwap failed because stored plaintext did not equal expected currentPlain.
 	ErrWrongCurrent = stderrors.New("vault: stored secret does not match expected plaintext")
 )
 
+//=============================================================================
+// Versioned rows ([versioned] at go.rtnl.ai/x/vault/versioned)
+//=============================================================================
+
+var (
+	// ErrVersionNotFound means the requested version of a row is neither current nor kept in its history.
+	ErrVersionNotFound = stderrors.New("vault/versioned: version not found")
+
+	// ErrVersionIndex means a row's stored version index could not be decoded.
+	ErrVersionIndex = stderrors.New("vault/versioned: corrupt version index")
+)
+
 //=============================================================================
 // Keys ([keys] at go.rtnl.ai/x/vault/keys)
 //=============================================================================
@@ -96,6 +132,18 @@ var (
 
 	// ErrRandSalt means reading random bytes for a new salt failed.
 	ErrRandSalt = stderrors.New("vault/keys: failed to read random salt")
+
+	// ErrKeyFileMalformed means a key file is truncated, padded, or not in the key file layout.
+	ErrKeyFileMalformed = stderrors.New("vault/keys: malformed key file")
+
+	// ErrKeyFileVersion means the key file format version is not supported by this module.
+	ErrKeyFileVersion = stderrors.New("vault/keys: unsupported key file version")
+
+	// ErrKeyFileParams means key file Argon2id parameters are zero or exceed the limits [keys.OpenKeyFile] accepts.
+	ErrKeyFileParams = stderrors.New("vault/keys: invalid key file parameters")
+
+	// ErrKeyFilePassphrase means a key file did not decrypt: the passphrase is wrong or the file was altered.
+	ErrKeyFilePassphrase = stderrors.New("vault/keys: wrong passphrase or corrupted key file")
 )
 
 //=============================================================================
@@ -118,3 +166,27 @@ var (
 	// ErrInvalidUTF8 means a string payload is not valid UTF-8 (store input or decrypted bytes).
 	ErrInvalidUTF8 = stderrors.New("vault: plain text is not valid UTF-8")
 )
+
+//=============================================================================
+// Typed wrapper ([typedvault] at go.rtnl.ai/x/vault/typedvault)
+//=============================================================================
+
+var (
+	// ErrCodecMarshal means a typed vault codec failed to encode a value or compare two values.
+	ErrCodecMarshal = stderrors.New("vault/typedvault: codec marshal failed")
+
+	// ErrCodecUnmarshal means a typed vault codec failed to decode decrypted plaintext into a value.
+	ErrCodecUnmarshal = stderrors.New("vault/typedvault: codec unmarshal failed")
+)
+
+//=============================================================================
+// Access policies ([policy] at go.rtnl.ai/x/vault/policy)
+//=============================================================================
+
+var (
+	// ErrPermissionDenied means the policy does not grant the caller's principal the operation on the namespace.
+	ErrPermissionDenied = stderrors.New("vault/policy: permission denied")
+
+	// ErrInvalidPolicy means a policy rule has no principal or grants no known permission.
+	ErrInvalidPolicy = stderrors.New("vault/policy: invalid rule")
+)
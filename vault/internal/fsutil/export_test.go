@@ -0,0 +1,4 @@
+package fsutil
+
+// DirSyncSupported exposes dirSyncSupported to tests.
+const DirSyncSupported = dirSyncSupported
@@ -0,0 +1,21 @@
+// Package fsutil holds file system helpers shared by the vault packages that write files
+// durably.
+package fsutil
+
+import "os"
+
+// SyncDir fsyncs a directory so that creates, renames, and removals inside it are durable. It
+// does nothing on platforms where directories cannot be synced, where durability is best effort.
+// Errors are returned as is.
+func SyncDir(dir string) error {
+	if !dirSyncSupported {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
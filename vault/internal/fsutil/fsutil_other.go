@@ -0,0 +1,7 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package fsutil
+
+// dirSyncSupported reports whether directories can be fsynced to make renames durable; on these
+// platforms opening or syncing a directory is not supported, so rename durability is best effort.
+const dirSyncSupported = false
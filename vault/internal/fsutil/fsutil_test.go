@@ -0,0 +1,21 @@
+package fsutil_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault/internal/fsutil"
+)
+
+func TestSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.Ok(t, fsutil.SyncDir(dir))
+}
+
+func TestSyncDir_missing(t *testing.T) {
+	if !fsutil.DirSyncSupported {
+		t.Skip("directory sync is not supported on this platform")
+	}
+	assert.NotNil(t, fsutil.SyncDir(filepath.Join(t.TempDir(), "missing")))
+}
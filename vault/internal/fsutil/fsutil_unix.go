@@ -0,0 +1,6 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package fsutil
+
+// dirSyncSupported reports whether directories can be fsynced to make renames durable.
+const dirSyncSupported = true
@@ -20,7 +20,8 @@ import (
 
 // Vault embeds a [rtvault.Vault] and exposes the same operation names, using JSON
 // ([any] for store/update; [CompareAndSwap] for compare-and-swap on JSON bytes) instead of opaque plaintext bytes.
-// [MoveNamespace] and [Delete] are promoted from the embedded vault.
+// [MoveNamespace] and [Delete] are promoted from the embedded vault; [Vault.ListIDs] forwards to it
+// when it implements [rtvault.Lister].
 type Vault struct {
 	rtvault.Vault
 }
@@ -96,3 +97,13 @@ func EqualJSON(a, b any) (bool, error) {
 	}
 	return bytes.Equal(ab, bb), nil
 }
+
+// ListIDs lists row ids via the inner vault's [rtvault.Lister], or returns [verrors.ErrListUnsupported]
+// if the inner vault cannot list.
+func (w *Vault) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	lister, ok := w.Vault.(rtvault.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
@@ -0,0 +1,351 @@
+package keys
+
+// Passphrase-protected key files: a versioned, fixed-size encoding of a long-term X25519 private key.
+//
+// Layout (big-endian): magic "VLTK" | version | iterations u32 | memoryKiB u32 | threads u8 | salt (16)
+// | public key (32) | nonce (12) | AES-256-GCM(seed) (48). The AES key is Argon2id(passphrase, salt)
+// with the recorded parameters; every byte before the ciphertext is authenticated as additional data,
+// so parameters, salt, and public key cannot be swapped without the passphrase.
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/internal/fsutil"
+)
+
+const (
+	// KeyFileMagic is the four-byte preamble of a key file.
+	KeyFileMagic = "VLTK"
+
+	// KeyFileVersion is the key file format version written by [SealKeyFile].
+	KeyFileVersion uint8 = 1
+
+	// KeyFileBytes is the exact size of a version 1 key file.
+	KeyFileBytes = keyFileHeaderBytes + keyFileSeedCiphertextBytes
+
+	// MaxKeyFileIterations and MaxKeyFileMemoryKiB bound the Argon2id work a key file may demand, so a
+	// crafted file cannot make [OpenKeyFile] hash for hours or allocate unbounded memory.
+	MaxKeyFileIterations uint32 = 1 << 10
+	MaxKeyFileMemoryKiB  uint32 = 4 << 20 // 4 GiB
+
+	// KeyFileMode is the permission used for key files written by [CreateKeyFile] and [ChangePassphrase].
+	KeyFileMode os.FileMode = 0o600
+)
+
+const (
+	keyFilePubBytes            = 32
+	keyFileNonceBytes          = 12
+	keyFileTagBytes            = 16
+	keyFileSaltOffset          = 4 + 1 + 4 + 4 + 1
+	keyFileHeaderBytes         = keyFileSaltOffset + SaltBytes + keyFilePubBytes + keyFileNonceBytes
+	keyFileSeedCiphertextBytes = DerivedKeyBytes + keyFileTagBytes
+	keyFileTempPattern         = ".keyfile-*.tmp"
+)
+
+// KeyFileInfo is the unencrypted part of a key file, readable without the passphrase.
+type KeyFileInfo struct {
+	Version   uint8
+	Params    Params
+	PublicKey *ecdh.PublicKey // the key's public half; its bytes are the v1 KeyID
+}
+
+//=============================================================================
+// Encoding
+//=============================================================================
+
+// SealKeyFile encrypts priv under passphrase with Argon2id parameters p and a fresh salt, returning
+// the key file bytes. priv must be an X25519 key ([verrors.ErrInvalidWrappingKey] otherwise);
+// parameters outside the limits [OpenKeyFile] accepts return [verrors.ErrKeyFileParams].
+func SealKeyFile(priv *ecdh.PrivateKey, passphrase []byte, p Params) ([]byte, error) {
+	if priv == nil {
+		return nil, verrors.ErrNilPrivateKey
+	}
+	if priv.Curve() != ecdh.X25519() {
+		return nil, verrors.ErrInvalidWrappingKey
+	}
+	if err := validateKeyFileParams(p); err != nil {
+		return nil, err
+	}
+
+	salt, err := RandSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [keyFileNonceBytes]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, verrors.ErrSealFailed
+	}
+
+	header := make([]byte, 0, KeyFileBytes)
+	header = append(header, KeyFileMagic...)
+	header = append(header, KeyFileVersion)
+	header = binary.BigEndian.AppendUint32(header, p.Iterations)
+	header = binary.BigEndian.AppendUint32(header, p.MemoryKiB)
+	header = append(header, p.Threads)
+	header = append(header, salt...)
+	header = append(header, priv.PublicKey().Bytes()...)
+	header = append(header, nonce[:]...)
+
+	aead, err := keyFileAEAD(passphrase, salt, p)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := priv.Bytes()
+	defer Zero(seed)
+	return aead.Seal(header, nonce[:], seed, header), nil
+}
+
+// OpenKeyFile decrypts a key file with passphrase and returns the private key. Framing errors return
+// [verrors.ErrKeyFileMalformed] or [verrors.ErrKeyFileVersion], out-of-range parameters return
+// [verrors.ErrKeyFileParams] before any hashing, and a wrong passphrase or altered file returns
+// [verrors.ErrKeyFilePassphrase].
+func OpenKeyFile(data, passphrase []byte) (*ecdh.PrivateKey, error) {
+	info, err := InspectKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := keyFileAEAD(passphrase, data[keyFileSaltOffset:keyFileSaltOffset+SaltBytes], info.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	header := data[:keyFileHeaderBytes]
+	nonce := header[keyFileHeaderBytes-keyFileNonceBytes:]
+	seed, err := aead.Open(nil, nonce, data[keyFileHeaderBytes:], header)
+	if err != nil {
+		return nil, verrors.ErrKeyFilePassphrase
+	}
+	defer Zero(seed)
+
+	priv, err := FromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The public key is authenticated, so a mismatch means the file was written wrong, not altered.
+	if !bytes.Equal(priv.PublicKey().Bytes(), info.PublicKey.Bytes()) {
+		return nil, verrors.ErrKeyFileMalformed
+	}
+	return priv, nil
+}
+
+// InspectKeyFile decodes the unencrypted header of a key file: version, Argon2id parameters, and
+// public key. It checks framing and parameter limits like [OpenKeyFile] but cannot tell whether the
+// ciphertext is intact; use [OpenKeyFile] or [VerifyKeyFile] for that.
+func InspectKeyFile(data []byte) (KeyFileInfo, error) {
+	if len(data) < 5 || string(data[:4]) != KeyFileMagic {
+		return KeyFileInfo{}, verrors.ErrKeyFileMalformed
+	}
+	if data[4] != KeyFileVersion {
+		return KeyFileInfo{}, verrors.ErrKeyFileVersion
+	}
+	if len(data) != KeyFileBytes {
+		return KeyFileInfo{}, verrors.ErrKeyFileMalformed
+	}
+
+	info := KeyFileInfo{
+		Version: data[4],
+		Params: Params{
+			Iterations: binary.BigEndian.Uint32(data[5:9]),
+			MemoryKiB:  binary.BigEndian.Uint32(data[9:13]),
+			Threads:    data[13],
+		},
+	}
+	if err := validateKeyFileParams(info.Params); err != nil {
+		return KeyFileInfo{}, err
+	}
+
+	off := keyFileSaltOffset + SaltBytes
+	pub, err := ecdh.X25519().NewPublicKey(data[off : off+keyFilePubBytes])
+	if err != nil {
+		return KeyFileInfo{}, verrors.ErrKeyFileMalformed
+	}
+	info.PublicKey = pub
+	return info, nil
+}
+
+// ResealKeyFile opens data with oldPassphrase and seals the same key under newPassphrase with a fresh
+// salt. A nil p keeps the file's Argon2id parameters. Errors are as from [OpenKeyFile] and
+// [SealKeyFile].
+func ResealKeyFile(data, oldPassphrase, newPassphrase []byte, p *Params) ([]byte, error) {
+	info, err := InspectKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := OpenKeyFile(data, oldPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil {
+		p = &info.Params
+	}
+	return SealKeyFile(priv, newPassphrase, *p)
+}
+
+//=============================================================================
+// Files on disk
+//=============================================================================
+
+// CreateKeyFile seals priv with [SealKeyFile] and writes it to a new file at path with
+// [KeyFileMode] and fsyncs its directory, so a new key survives a crash. It refuses to overwrite an
+// existing file (the error matches [os.ErrExist]); on a failed write the partial file is removed.
+// File system errors are returned as is.
+func CreateKeyFile(path string, priv *ecdh.PrivateKey, passphrase []byte, p Params) (err error) {
+	data, err := SealKeyFile(priv, passphrase, p)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, KeyFileMode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return fsutil.SyncDir(filepath.Dir(path))
+}
+
+// LoadKeyFile reads the key file at path and opens it with passphrase; see [OpenKeyFile].
+func LoadKeyFile(path string, passphrase []byte) (*ecdh.PrivateKey, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenKeyFile(data, passphrase)
+}
+
+// VerifyKeyFile checks that the key file at path opens with passphrase and returns its header. The
+// key itself is decrypted to check it and then discarded.
+func VerifyKeyFile(path string, passphrase []byte) (KeyFileInfo, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return KeyFileInfo{}, err
+	}
+	if _, err := OpenKeyFile(data, passphrase); err != nil {
+		return KeyFileInfo{}, err
+	}
+	return InspectKeyFile(data)
+}
+
+// ChangePassphrase re-encrypts the key file at path under newPassphrase (see [ResealKeyFile]) and
+// atomically replaces it, so a crash leaves either the old or the new file. A nil p keeps the file's
+// Argon2id parameters.
+func ChangePassphrase(path string, oldPassphrase, newPassphrase []byte, p *Params) error {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	resealed, err := ResealKeyFile(data, oldPassphrase, newPassphrase, p)
+	if err != nil {
+		return err
+	}
+	return replaceKeyFile(path, resealed)
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// validateKeyFileParams rejects Argon2id parameters that would panic or exceed the key file limits.
+func validateKeyFileParams(p Params) error {
+	if p.Threads == 0 || p.Iterations == 0 || p.Iterations > MaxKeyFileIterations {
+		return verrors.ErrKeyFileParams
+	}
+	if p.MemoryKiB < 8*uint32(p.Threads) || p.MemoryKiB > MaxKeyFileMemoryKiB {
+		return verrors.ErrKeyFileParams
+	}
+	return nil
+}
+
+// keyFileAEAD derives the file encryption key from passphrase and returns its AES-256-GCM AEAD.
+func keyFileAEAD(passphrase, salt []byte, p Params) (cipher.AEAD, error) {
+	key, err := Derive(passphrase, salt, p, DerivedKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer Zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, verrors.ErrInvalidAEADKey
+	}
+	return cipher.NewGCM(block)
+}
+
+// readKeyFile reads at most one byte more than a key file, so oversized files fail as malformed
+// without being read whole.
+func readKeyFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, KeyFileBytes+1))
+}
+
+// replaceKeyFile writes data to a temporary file beside path, fsyncs it, renames it over path, and
+// fsyncs the directory so the rename is durable.
+func replaceKeyFile(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, keyFileTempPattern)
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	if err = f.Chmod(KeyFileMode); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return fsutil.SyncDir(dir)
+}
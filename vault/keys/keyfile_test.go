@@ -0,0 +1,163 @@
+package keys_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	cryptorand "crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+)
+
+// testParams keeps Argon2id cheap; not a production profile.
+var testParams = keys.Params{Iterations: 1, MemoryKiB: 32, Threads: 1}
+
+// TestKeyFile_roundtrip seals and opens a key and checks the header is readable without the passphrase.
+func TestKeyFile_roundtrip(t *testing.T) {
+	priv := testKey(t)
+	data, err := keys.SealKeyFile(priv, []byte("correct horse"), testParams)
+	assert.Ok(t, err)
+	assert.Len(t, data, keys.KeyFileBytes)
+
+	info, err := keys.InspectKeyFile(data)
+	assert.Ok(t, err)
+	assert.Equal(t, keys.KeyFileVersion, info.Version)
+	assert.Equal(t, testParams, info.Params)
+	assert.Equal(t, priv.PublicKey().Bytes(), info.PublicKey.Bytes())
+
+	got, err := keys.OpenKeyFile(data, []byte("correct horse"))
+	assert.Ok(t, err)
+	assert.True(t, priv.Equal(got))
+
+	_, err = keys.OpenKeyFile(data, []byte("wrong"))
+	assert.ErrorIs(t, err, verrors.ErrKeyFilePassphrase)
+
+	again, err := keys.SealKeyFile(priv, []byte("correct horse"), testParams)
+	assert.Ok(t, err)
+	assert.NotEqual(t, data, again)
+}
+
+// TestKeyFile_tampering flips every byte; each must fail to open.
+func TestKeyFile_tampering(t *testing.T) {
+	data, err := keys.SealKeyFile(testKey(t), []byte("pw"), testParams)
+	assert.Ok(t, err)
+
+	for i := range data {
+		bad := bytes.Clone(data)
+		bad[i] ^= 0x01
+		_, err := keys.OpenKeyFile(bad, []byte("pw"))
+		assert.Error(t, err, "flip at %d", i)
+	}
+}
+
+// TestKeyFile_malformed covers framing, version, and parameter limits.
+func TestKeyFile_malformed(t *testing.T) {
+	data, err := keys.SealKeyFile(testKey(t), []byte("pw"), testParams)
+	assert.Ok(t, err)
+
+	for n := range len(data) {
+		_, err := keys.InspectKeyFile(data[:n])
+		assert.Error(t, err, "cut at %d", n)
+	}
+	_, err = keys.InspectKeyFile(append(bytes.Clone(data), 0))
+	assert.ErrorIs(t, err, verrors.ErrKeyFileMalformed)
+
+	bad := bytes.Clone(data)
+	bad[0] = 'X'
+	_, err = keys.InspectKeyFile(bad)
+	assert.ErrorIs(t, err, verrors.ErrKeyFileMalformed)
+
+	bad = bytes.Clone(data)
+	bad[4] = 9
+	_, err = keys.InspectKeyFile(bad)
+	assert.ErrorIs(t, err, verrors.ErrKeyFileVersion)
+
+	// Threads = 0 would panic in Argon2id; huge memory would be a denial of service.
+	bad = bytes.Clone(data)
+	bad[13] = 0
+	_, err = keys.OpenKeyFile(bad, []byte("pw"))
+	assert.ErrorIs(t, err, verrors.ErrKeyFileParams)
+	bad = bytes.Clone(data)
+	bad[9] = 0xff
+	_, err = keys.OpenKeyFile(bad, []byte("pw"))
+	assert.ErrorIs(t, err, verrors.ErrKeyFileParams)
+
+	for _, p := range []keys.Params{
+		{Iterations: 0, MemoryKiB: 32, Threads: 1},
+		{Iterations: keys.MaxKeyFileIterations + 1, MemoryKiB: 32, Threads: 1},
+		{Iterations: 1, MemoryKiB: 7, Threads: 1},
+		{Iterations: 1, MemoryKiB: keys.MaxKeyFileMemoryKiB + 1, Threads: 1},
+	} {
+		_, err := keys.SealKeyFile(testKey(t), []byte("pw"), p)
+		assert.ErrorIs(t, err, verrors.ErrKeyFileParams)
+	}
+
+	_, err = keys.SealKeyFile(nil, []byte("pw"), testParams)
+	assert.ErrorIs(t, err, verrors.ErrNilPrivateKey)
+	p256, err := ecdh.P256().GenerateKey(cryptorand.Reader)
+	assert.Ok(t, err)
+	_, err = keys.SealKeyFile(p256, []byte("pw"), testParams)
+	assert.ErrorIs(t, err, verrors.ErrInvalidWrappingKey)
+	_, err = keys.SealKeyFile(testKey(t), nil, testParams)
+	assert.ErrorIs(t, err, verrors.ErrNilPassword)
+}
+
+// TestKeyFile_onDisk covers create, load, verify, and changing the passphrase through files.
+func TestKeyFile_onDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.key")
+	priv := testKey(t)
+
+	assert.Ok(t, keys.CreateKeyFile(path, priv, []byte("old"), testParams))
+	err := keys.CreateKeyFile(path, priv, []byte("old"), testParams)
+	assert.True(t, errors.Is(err, os.ErrExist))
+
+	fi, err := os.Stat(path)
+	assert.Ok(t, err)
+	assert.Equal(t, keys.KeyFileMode, fi.Mode().Perm())
+
+	got, err := keys.LoadKeyFile(path, []byte("old"))
+	assert.Ok(t, err)
+	assert.True(t, priv.Equal(got))
+
+	info, err := keys.VerifyKeyFile(path, []byte("old"))
+	assert.Ok(t, err)
+	assert.Equal(t, testParams, info.Params)
+
+	// Change the passphrase and strengthen the parameters at the same time.
+	stronger := keys.Params{Iterations: 2, MemoryKiB: 64, Threads: 1}
+	assert.ErrorIs(t, keys.ChangePassphrase(path, []byte("nope"), []byte("new"), nil), verrors.ErrKeyFilePassphrase)
+	assert.Ok(t, keys.ChangePassphrase(path, []byte("old"), []byte("new"), &stronger))
+
+	_, err = keys.LoadKeyFile(path, []byte("old"))
+	assert.ErrorIs(t, err, verrors.ErrKeyFilePassphrase)
+	got, err = keys.LoadKeyFile(path, []byte("new"))
+	assert.Ok(t, err)
+	assert.True(t, priv.Equal(got))
+
+	info, err = keys.VerifyKeyFile(path, []byte("new"))
+	assert.Ok(t, err)
+	assert.Equal(t, stronger, info.Params)
+
+	fi, err = os.Stat(path)
+	assert.Ok(t, err)
+	assert.Equal(t, keys.KeyFileMode, fi.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.Ok(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = keys.LoadKeyFile(filepath.Join(t.TempDir(), "missing"), []byte("new"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func testKey(tb testing.TB) *ecdh.PrivateKey {
+	tb.Helper()
+	priv, err := ecdh.X25519().GenerateKey(cryptorand.Reader)
+	assert.Ok(tb, err)
+	return priv
+}
@@ -0,0 +1,180 @@
+/*
+Package policy restricts which namespaces a caller may use. [New] wraps any [vault.Vault]; every
+operation checks the principal carried in the context ([WithPrincipal]) against a [Policy] of
+[Rule] values granting permissions ([Perm]) on namespaces, and fails with a [*DeniedError] (which
+matches [verrors.ErrPermissionDenied]) before reaching the wrapped vault when the grant is missing.
+
+Policies deny by default: a principal may do only what some rule grants, and a context without a
+principal may do nothing. Rules can name a namespace exactly or by prefix, so one service can be
+read-only on a shared namespace and read-write on its own:
+
+	p, err := policy.NewPolicy(
+		policy.Rule{Principal: "billing", Namespace: "billing/*", Perm: policy.PermAll},
+		policy.Rule{Principal: "billing", Namespace: "shared", Perm: policy.PermReadOnly},
+		policy.Rule{Principal: "*", Namespace: "public", Perm: policy.PermRead},
+	)
+	v, err := policy.New(inner, p, &policy.Options{OnDeny: policy.AuditHook(sink)})
+	secret, err := v.Retrieve(policy.WithPrincipal(ctx, "billing"), "shared", id)
+
+Denials are reported to [Options.OnDeny] when set; [AuditHook] forwards them to an [audit.Sink] as
+events with [audit.OutcomeDenied].
+*/
+package policy
+
+// Principals in the context, permissions, and rule matching.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+)
+
+//=============================================================================
+// Permissions
+//=============================================================================
+
+// Perm is a set of permissions on a namespace. Combine them with |.
+type Perm uint8
+
+const (
+	PermRead   Perm = 1 << iota // Retrieve, and the read half of CompareAndSwap and MoveNamespace
+	PermWrite                   // Store, Update, CompareAndSwap, and the destination of MoveNamespace
+	PermDelete                  // Delete, and the source of MoveNamespace
+	PermList                    // ListIDs
+
+	// PermReadOnly reads and lists rows without changing them.
+	PermReadOnly = PermRead | PermList
+
+	// PermReadWrite is everything but Delete.
+	PermReadWrite = PermReadOnly | PermWrite
+
+	// PermAll is every permission.
+	PermAll = PermReadWrite | PermDelete
+)
+
+var permNames = []struct {
+	perm Perm
+	name string
+}{
+	{PermRead, "read"},
+	{PermWrite, "write"},
+	{PermDelete, "delete"},
+	{PermList, "list"},
+}
+
+// String names the permissions in p joined by "|", such as "read|list", or "none".
+func (p Perm) String() string {
+	if p == 0 {
+		return "none"
+	}
+
+	var names []string
+	for _, n := range permNames {
+		if p&n.perm != 0 {
+			names = append(names, n.name)
+			p &^= n.perm
+		}
+	}
+	if p != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint8(p)))
+	}
+	return strings.Join(names, "|")
+}
+
+//=============================================================================
+// Principal
+//=============================================================================
+
+type principalKey struct{}
+
+// WithPrincipal returns a context whose vault operations are checked as principal (a user, service
+// account, or workload identity). An empty principal is the same as none.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set with [WithPrincipal], or "" if none is set.
+func PrincipalFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+//=============================================================================
+// Policy
+//=============================================================================
+
+// AnyPrincipal as a [Rule.Principal] matches every non-empty principal.
+const AnyPrincipal = "*"
+
+// Rule grants Perm on matching namespaces to a principal.
+type Rule struct {
+	// Principal is the principal the rule applies to, or [AnyPrincipal]. It must not be empty.
+	Principal string
+
+	// Namespace is an exact namespace, a prefix followed by "*" (so "svc/*" matches "svc/a" and
+	// "svc/a/b"), or "*" alone for every namespace.
+	Namespace string
+
+	// Perm is what the rule grants; it must be non-zero and contain only known permissions.
+	Perm Perm
+}
+
+// matches reports whether r applies to principal on namespace.
+func (r Rule) matches(principal, namespace string) bool {
+	if r.Principal != AnyPrincipal && r.Principal != principal {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.Namespace, "*"); ok {
+		return strings.HasPrefix(namespace, prefix)
+	}
+	return r.Namespace == namespace
+}
+
+// Policy is an immutable set of rules. A principal's permissions on a namespace are the union of
+// every matching rule's; there are no deny rules.
+type Policy struct {
+	rules []Rule
+}
+
+// NewPolicy validates and copies rules. A rule with an empty principal, no permissions, or unknown
+// permission bits yields [verrors.ErrInvalidPolicy].
+func NewPolicy(rules ...Rule) (*Policy, error) {
+	for i, r := range rules {
+		switch {
+		case r.Principal == "":
+			return nil, errors.Join(verrors.ErrInvalidPolicy, fmt.Errorf("rule %d: empty principal", i))
+		case r.Perm == 0 || r.Perm&^PermAll != 0:
+			return nil, errors.Join(verrors.ErrInvalidPolicy, fmt.Errorf("rule %d: permissions %s", i, r.Perm))
+		}
+	}
+	return &Policy{rules: append([]Rule(nil), rules...)}, nil
+}
+
+// Allowed returns the permissions principal has on namespace; an empty principal has none.
+func (p *Policy) Allowed(principal, namespace string) Perm {
+	if p == nil || principal == "" {
+		return 0
+	}
+
+	var perm Perm
+	for _, r := range p.rules {
+		if r.matches(principal, namespace) {
+			perm |= r.Perm
+		}
+	}
+	return perm
+}
+
+// Rules returns a copy of the policy's rules.
+func (p *Policy) Rules() []Rule {
+	if p == nil {
+		return nil
+	}
+	return append([]Rule(nil), p.rules...)
+}
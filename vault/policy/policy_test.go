@@ -0,0 +1,260 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	rtvault "go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/audit"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/policy"
+	"go.rtnl.ai/x/vault/storage"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestPolicy_Allowed checks exact, prefix, and wildcard matching and that grants add up.
+func TestPolicy_Allowed(t *testing.T) {
+	p, err := policy.NewPolicy(
+		policy.Rule{Principal: "billing", Namespace: "billing/*", Perm: policy.PermAll},
+		policy.Rule{Principal: "billing", Namespace: "shared", Perm: policy.PermRead},
+		policy.Rule{Principal: "billing", Namespace: "shared", Perm: policy.PermList},
+		policy.Rule{Principal: policy.AnyPrincipal, Namespace: "public", Perm: policy.PermReadOnly},
+		policy.Rule{Principal: "admin", Namespace: "*", Perm: policy.PermAll},
+	)
+	assert.Ok(t, err)
+
+	tests := []struct {
+		principal, namespace string
+		want                 policy.Perm
+	}{
+		{"billing", "billing/invoices", policy.PermAll},
+		{"billing", "billing/", policy.PermAll},
+		{"billing", "billing", 0},
+		{"billing", "shared", policy.PermReadOnly},
+		{"billing", "public", policy.PermReadOnly},
+		{"billing", "other", 0},
+		{"search", "public", policy.PermReadOnly},
+		{"search", "shared", 0},
+		{"admin", "anything", policy.PermAll},
+		{"", "public", 0},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, p.Allowed(tc.principal, tc.namespace), "%q on %q", tc.principal, tc.namespace)
+	}
+	assert.Len(t, p.Rules(), 5)
+	assert.Equal(t, policy.Perm(0), (*policy.Policy)(nil).Allowed("admin", "public"))
+}
+
+// TestNewPolicy_invalid rejects rules without a principal or with no or unknown permissions.
+func TestNewPolicy_invalid(t *testing.T) {
+	for _, r := range []policy.Rule{
+		{Namespace: "ns", Perm: policy.PermRead},
+		{Principal: "svc", Namespace: "ns"},
+		{Principal: "svc", Namespace: "ns", Perm: policy.PermAll + 1},
+	} {
+		_, err := policy.NewPolicy(policy.Rule{Principal: "ok", Namespace: "ns", Perm: policy.PermRead}, r)
+		assert.ErrorIs(t, err, verrors.ErrInvalidPolicy)
+	}
+}
+
+// TestPerm_String names permission sets.
+func TestPerm_String(t *testing.T) {
+	assert.Equal(t, "none", policy.Perm(0).String())
+	assert.Equal(t, "read|list", policy.PermReadOnly.String())
+	assert.Equal(t, "read|write|delete|list", policy.PermAll.String())
+	assert.Equal(t, "write|0x80", (policy.PermWrite | 0x80).String())
+}
+
+// TestVault_enforce runs every operation as a read-only and a read-write principal and checks which
+// ones reach the wrapped vault.
+func TestVault_enforce(t *testing.T) {
+	ctx := context.Background()
+	inner := vaulttest.NewTestVault(t, storage.NewMemStorage(), identifier.HexIdentifier{})
+	id, err := inner.Store(ctx, "shared", []byte("v0"))
+	assert.Ok(t, err)
+
+	p, err := policy.NewPolicy(
+		policy.Rule{Principal: "reader", Namespace: "shared", Perm: policy.PermReadOnly},
+		policy.Rule{Principal: "writer", Namespace: "shared", Perm: policy.PermAll},
+		policy.Rule{Principal: "writer", Namespace: "archive", Perm: policy.PermWrite},
+	)
+	assert.Ok(t, err)
+	v, err := policy.New(inner, p, nil)
+	assert.Ok(t, err)
+
+	reader := policy.WithPrincipal(ctx, "reader")
+	got, err := v.Retrieve(reader, "shared", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("v0"), got)
+	ids, _, err := v.ListIDs(reader, "shared", "", 0)
+	assert.Ok(t, err)
+	assert.Equal(t, []string{id}, ids)
+
+	denied := []error{
+		func() error { _, err := v.Store(reader, "shared", []byte("x")); return err }(),
+		v.Update(reader, "shared", id, []byte("x")),
+		v.CompareAndSwap(reader, "shared", id, []byte("v0"), []byte("x")),
+		v.Delete(reader, "shared", id),
+		v.MoveNamespace(reader, "shared", "archive", id),
+		func() error { _, err := v.StoreExpiring(reader, "shared", []byte("x"), time.Time{}); return err }(),
+		func() error { _, err := v.Retrieve(ctx, "shared", id); return err }(),
+		func() error { _, err := v.Retrieve(reader, "other", id); return err }(),
+	}
+	for i, err := range denied {
+		assert.ErrorIs(t, err, verrors.ErrPermissionDenied, "call %d", i)
+	}
+	got, err = inner.Retrieve(ctx, "shared", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("v0"), got)
+
+	// The writer may change the row and move it to a namespace it can only write.
+	writer := policy.WithPrincipal(ctx, "writer")
+	assert.Ok(t, v.CompareAndSwap(writer, "shared", id, []byte("v0"), []byte("v1")))
+	assert.Ok(t, v.MoveNamespace(writer, "shared", "archive", id))
+	_, err = v.Retrieve(writer, "archive", id)
+	assert.ErrorIs(t, err, verrors.ErrPermissionDenied)
+	assert.ErrorIs(t, v.MoveNamespace(writer, "archive", "shared", id), verrors.ErrPermissionDenied)
+
+	// Allowed calls the wrapped vault cannot serve report that rather than a denial.
+	_, err = v.StoreExpiring(writer, "shared", []byte("x"), time.Time{})
+	assert.ErrorIs(t, err, verrors.ErrExpiryUnsupported)
+}
+
+// TestVault_batch checks a batch is allowed or denied as a whole, with the hook called once.
+func TestVault_batch(t *testing.T) {
+	ctx := context.Background()
+	p, err := policy.NewPolicy(
+		policy.Rule{Principal: "reader", Namespace: "shared", Perm: policy.PermReadOnly},
+		policy.Rule{Principal: "writer", Namespace: "shared", Perm: policy.PermAll},
+	)
+	assert.Ok(t, err)
+
+	var denials []policy.Denial
+	v, err := policy.New(vaulttest.NewTestVault(t, storage.NewMemStorage(), identifier.HexIdentifier{}), p, &policy.Options{
+		OnDeny: func(_ context.Context, d policy.Denial) { denials = append(denials, d) },
+	})
+	assert.Ok(t, err)
+
+	writer := policy.WithPrincipal(ctx, "writer")
+	stored, err := v.StoreMany(writer, "shared", [][]byte{[]byte("a"), []byte("b")})
+	assert.Ok(t, err)
+	ids := []string{stored[0].ID, stored[1].ID}
+
+	reader := policy.WithPrincipal(ctx, "reader")
+	results, err := v.RetrieveMany(reader, "shared", ids)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("b"), results[1].Plaintext)
+
+	results, err = v.DeleteMany(reader, "shared", ids)
+	assert.ErrorIs(t, err, verrors.ErrPermissionDenied)
+	assert.Len(t, results, 0)
+	_, err = v.StoreMany(reader, "shared", [][]byte{[]byte("c")})
+	assert.ErrorIs(t, err, verrors.ErrPermissionDenied)
+	_, err = v.RetrieveMany(writer, "other", ids)
+	assert.ErrorIs(t, err, verrors.ErrPermissionDenied)
+
+	assert.Len(t, denials, 3)
+	assert.Equal(t, audit.OpDelete, denials[0].Op)
+	assert.Equal(t, audit.OpStore, denials[1].Op)
+	assert.Equal(t, audit.OpRetrieve, denials[2].Op)
+
+	results, err = v.DeleteMany(writer, "shared", ids)
+	assert.Ok(t, err)
+	assert.Len(t, results, 2)
+}
+
+// TestVault_denials checks the typed error and that the hook sees each denial once.
+func TestVault_denials(t *testing.T) {
+	ctx := context.Background()
+	p, err := policy.NewPolicy(policy.Rule{Principal: "svc", Namespace: "svc/*", Perm: policy.PermAll})
+	assert.Ok(t, err)
+
+	var denials []policy.Denial
+	v, err := policy.New(vaulttest.NewTestVault(t, storage.NewMemStorage(), identifier.HexIdentifier{}), p, &policy.Options{
+		OnDeny: func(_ context.Context, d policy.Denial) { denials = append(denials, d) },
+	})
+	assert.Ok(t, err)
+
+	svc := policy.WithPrincipal(ctx, "svc")
+	id, err := v.Store(svc, "svc/a", []byte("x"))
+	assert.Ok(t, err)
+
+	err = v.CompareAndSwap(svc, "other", id, nil, nil)
+	var denied *policy.DeniedError
+	assert.True(t, errors.As(err, &denied))
+	assert.Equal(t, policy.DeniedError{Principal: "svc", Op: audit.OpCompareAndSwap, Namespace: "other", Missing: policy.PermRead | policy.PermWrite}, *denied)
+	assert.Equal(t, `vault/policy: permission denied: principal "svc" lacks read|write on namespace "other" for compare_and_swap`, err.Error())
+
+	err = v.MoveNamespace(ctx, "svc/a", "svc/b", id)
+	assert.Equal(t, `vault/policy: permission denied: anonymous caller lacks read|delete on namespace "svc/a" for move_namespace`, err.Error())
+
+	assert.Len(t, denials, 2)
+	assert.Equal(t, id, denials[0].ID)
+	assert.Equal(t, "other", denials[0].Namespace)
+	assert.False(t, denials[0].Time.IsZero())
+	assert.Equal(t, "svc/b", denials[1].NewNamespace)
+	assert.Equal(t, "", denials[1].Principal)
+
+	// A new policy applies to later calls.
+	v.SetPolicy(&policy.Policy{})
+	_, err = v.Retrieve(svc, "svc/a", id)
+	assert.ErrorIs(t, err, verrors.ErrPermissionDenied)
+	v.SetPolicy(nil)
+	assert.Len(t, v.Policy().Rules(), 0)
+}
+
+// TestAuditHook records denials as audit events, and an audit wrapper outside the policy classifies
+// them as denied too.
+func TestAuditHook(t *testing.T) {
+	ctx := policy.WithPrincipal(context.Background(), "svc")
+	p, err := policy.NewPolicy(policy.Rule{Principal: "svc", Namespace: "mine", Perm: policy.PermAll})
+	assert.Ok(t, err)
+
+	hookSink := audit.NewMemSink()
+	pv, err := policy.New(vaulttest.NewTestVault(t, storage.NewMemStorage(), identifier.HexIdentifier{}), p, &policy.Options{
+		OnDeny: policy.AuditHook(hookSink),
+	})
+	assert.Ok(t, err)
+
+	outerSink := audit.NewMemSink()
+	av, err := audit.New(pv, outerSink)
+	assert.Ok(t, err)
+
+	_, err = av.Retrieve(audit.WithActor(ctx, "svc"), "theirs", "0123456789abcdef0123456789abcdef")
+	assert.ErrorIs(t, err, verrors.ErrPermissionDenied)
+
+	events := hookSink.Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, audit.OpRetrieve, events[0].Op)
+	assert.Equal(t, "svc", events[0].Actor)
+	assert.Equal(t, "theirs", events[0].Namespace)
+	assert.Equal(t, audit.OutcomeDenied, events[0].Outcome)
+
+	events = outerSink.Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, audit.OutcomeDenied, events[0].Outcome)
+}
+
+// TestNew_invalidArgs rejects a nil vault or policy, and ListIDs reports a wrapped vault that cannot
+// list once the caller is allowed.
+func TestNew_invalidArgs(t *testing.T) {
+	p, err := policy.NewPolicy(policy.Rule{Principal: "svc", Namespace: "*", Perm: policy.PermList})
+	assert.Ok(t, err)
+	inner := vaulttest.NewTestVault(t, storage.NewMemStorage(), identifier.HexIdentifier{})
+
+	_, err = policy.New(nil, p, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = policy.New(inner, nil, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+
+	v, err := policy.New(struct{ rtvault.Vault }{inner}, p, nil)
+	assert.Ok(t, err)
+	_, _, err = v.ListIDs(policy.WithPrincipal(context.Background(), "svc"), "ns", "", 0)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+	assert.Nil(t, v.ActiveKeyID())
+	assert.Equal(t, "", policy.PrincipalFromContext(context.Background()))
+}
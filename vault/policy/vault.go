@@ -0,0 +1,289 @@
+package policy
+
+// Policy-enforcing [vault.Vault] wrapper, denial errors, and deny hooks.
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/audit"
+	verrors "go.rtnl.ai/x/vault/errors"
+)
+
+//=============================================================================
+// Denials
+//=============================================================================
+
+// DeniedError is returned when the policy does not grant an operation. It matches
+// [verrors.ErrPermissionDenied] with [errors.Is]; use [errors.As] to read the details.
+type DeniedError struct {
+	Principal string   // principal from the context; empty if none was set
+	Op        audit.Op // operation that was refused
+	Namespace string   // namespace the permissions are missing on
+	Missing   Perm     // permissions the operation needs that the principal lacks
+}
+
+// Error describes the refusal without row ids or secret material.
+func (e *DeniedError) Error() string {
+	principal := fmt.Sprintf("principal %q", e.Principal)
+	if e.Principal == "" {
+		principal = "anonymous caller"
+	}
+	return fmt.Sprintf("%v: %s lacks %s on namespace %q for %s", verrors.ErrPermissionDenied, principal, e.Missing, e.Namespace, e.Op)
+}
+
+// Unwrap returns [verrors.ErrPermissionDenied].
+func (e *DeniedError) Unwrap() error {
+	return verrors.ErrPermissionDenied
+}
+
+// Denial describes one refused operation for [Options.OnDeny].
+type Denial struct {
+	*DeniedError           // the error returned to the caller
+	Time         time.Time // when the operation was refused
+	ID           string    // row id; empty for Store and ListIDs
+	NewNamespace string    // destination namespace; set only for [audit.OpMoveNamespace]
+}
+
+// DenyHook receives denials. It is called synchronously on the caller's goroutine before the error
+// is returned, so it should be fast and safe for concurrent use.
+type DenyHook func(ctx context.Context, d Denial)
+
+// AuditHook returns a [DenyHook] that records each denial to sink as an [audit.Event] with
+// [audit.OutcomeDenied] and the principal as the actor.
+func AuditHook(sink audit.Sink) DenyHook {
+	return func(ctx context.Context, d Denial) {
+		sink.Record(ctx, audit.Event{
+			Time:         d.Time,
+			Op:           d.Op,
+			Actor:        d.Principal,
+			Namespace:    d.Namespace,
+			NewNamespace: d.NewNamespace,
+			ID:           d.ID,
+			Outcome:      audit.OutcomeDenied,
+			Err:          d.DeniedError,
+		})
+	}
+}
+
+//=============================================================================
+// Vault
+//=============================================================================
+
+// Options configures [New]. A nil *Options reports denials nowhere.
+type Options struct {
+	// OnDeny, if set, is called for every refused operation.
+	OnDeny DenyHook
+}
+
+// Vault wraps a [vault.Vault] and checks every operation against a [Policy] before forwarding it.
+// It implements [vault.Lister] (needing [PermList]), [vault.Expirer] (needing [PermWrite]), and
+// [vault.KeyIdentifier] by forwarding to the wrapped vault; when the wrapped vault lacks one, an
+// allowed call returns [verrors.ErrListUnsupported] or [verrors.ErrExpiryUnsupported], or nil for
+// ActiveKeyID. Permissions are checked before the wrapped vault sees the call, so a denied caller
+// cannot learn whether a row exists. It implements [vault.Batcher] through the wrapped vault's
+// Batcher or one call per item, checking the namespace once per batch: a denied batch returns the
+// [*DeniedError] and no results. It is safe for concurrent use.
+type Vault struct {
+	v      vault.Vault
+	policy atomic.Pointer[Policy]
+	onDeny DenyHook
+	now    func() time.Time
+}
+
+// Compile-time checks.
+var (
+	_ vault.Vault         = (*Vault)(nil)
+	_ vault.Lister        = (*Vault)(nil)
+	_ vault.Expirer       = (*Vault)(nil)
+	_ vault.Batcher       = (*Vault)(nil)
+	_ vault.KeyIdentifier = (*Vault)(nil)
+)
+
+// New wraps v so every operation is checked against p. A nil v or p returns
+// [verrors.ErrInvalidNewArgs].
+func New(v vault.Vault, p *Policy, opts *Options) (*Vault, error) {
+	if v == nil || p == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	w := &Vault{v: v, onDeny: opts.OnDeny, now: time.Now}
+	w.policy.Store(p)
+	return w, nil
+}
+
+// Policy returns the policy in force.
+func (w *Vault) Policy() *Policy {
+	return w.policy.Load()
+}
+
+// SetPolicy replaces the policy for operations that start after it returns, for example after
+// reloading rules from configuration. A nil p is ignored.
+func (w *Vault) SetPolicy(p *Policy) {
+	if p != nil {
+		w.policy.Store(p)
+	}
+}
+
+// Store stores plaintext if the principal has [PermWrite] on namespace.
+func (w *Vault) Store(ctx context.Context, namespace string, plaintext []byte) (string, error) {
+	if err := w.check(ctx, audit.OpStore, namespace, "", PermWrite); err != nil {
+		return "", err
+	}
+	return w.v.Store(ctx, namespace, plaintext)
+}
+
+// Retrieve opens a row if the principal has [PermRead] on namespace.
+func (w *Vault) Retrieve(ctx context.Context, namespace, id string) ([]byte, error) {
+	if err := w.check(ctx, audit.OpRetrieve, namespace, id, PermRead); err != nil {
+		return nil, err
+	}
+	return w.v.Retrieve(ctx, namespace, id)
+}
+
+// Update replaces a row if the principal has [PermWrite] on namespace.
+func (w *Vault) Update(ctx context.Context, namespace, id string, plaintext []byte) error {
+	if err := w.check(ctx, audit.OpUpdate, namespace, id, PermWrite); err != nil {
+		return err
+	}
+	return w.v.Update(ctx, namespace, id, plaintext)
+}
+
+// CompareAndSwap swaps a row if the principal has [PermRead] and [PermWrite] on namespace; read is
+// needed because the outcome reveals whether currentPlain matched.
+func (w *Vault) CompareAndSwap(ctx context.Context, namespace, id string, currentPlain, newPlain []byte) error {
+	if err := w.check(ctx, audit.OpCompareAndSwap, namespace, id, PermRead|PermWrite); err != nil {
+		return err
+	}
+	return w.v.CompareAndSwap(ctx, namespace, id, currentPlain, newPlain)
+}
+
+// MoveNamespace moves a row if the principal has [PermRead] and [PermDelete] on oldNamespace and
+// [PermWrite] on newNamespace.
+func (w *Vault) MoveNamespace(ctx context.Context, oldNamespace, newNamespace, id string) error {
+	p := w.policy.Load()
+	principal := PrincipalFromContext(ctx)
+	for _, need := range []struct {
+		namespace string
+		perm      Perm
+	}{
+		{oldNamespace, PermRead | PermDelete},
+		{newNamespace, PermWrite},
+	} {
+		if missing := need.perm &^ p.Allowed(principal, need.namespace); missing != 0 {
+			return w.deny(ctx, Denial{
+				DeniedError:  &DeniedError{Principal: principal, Op: audit.OpMoveNamespace, Namespace: need.namespace, Missing: missing},
+				ID:           id,
+				NewNamespace: newNamespace,
+			})
+		}
+	}
+	return w.v.MoveNamespace(ctx, oldNamespace, newNamespace, id)
+}
+
+// Delete removes a row if the principal has [PermDelete] on namespace.
+func (w *Vault) Delete(ctx context.Context, namespace, id string) error {
+	if err := w.check(ctx, audit.OpDelete, namespace, id, PermDelete); err != nil {
+		return err
+	}
+	return w.v.Delete(ctx, namespace, id)
+}
+
+// ListIDs lists ids through the wrapped [vault.Lister] if the principal has [PermList] on namespace.
+func (w *Vault) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	if err := w.check(ctx, audit.OpList, namespace, "", PermList); err != nil {
+		return nil, "", err
+	}
+	lister, ok := w.v.(vault.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
+
+// StoreExpiring stores an expiring row through the wrapped [vault.Expirer] if the principal has
+// [PermWrite] on namespace.
+func (w *Vault) StoreExpiring(ctx context.Context, namespace string, plaintext []byte, expiresAt time.Time) (string, error) {
+	if err := w.check(ctx, audit.OpStore, namespace, "", PermWrite); err != nil {
+		return "", err
+	}
+	e, ok := w.v.(vault.Expirer)
+	if !ok {
+		return "", verrors.ErrExpiryUnsupported
+	}
+	return e.StoreExpiring(ctx, namespace, plaintext, expiresAt)
+}
+
+// UpdateExpiring replaces a row and its expiry through the wrapped [vault.Expirer] if the principal
+// has [PermWrite] on namespace.
+func (w *Vault) UpdateExpiring(ctx context.Context, namespace, id string, plaintext []byte, expiresAt time.Time) error {
+	if err := w.check(ctx, audit.OpUpdate, namespace, id, PermWrite); err != nil {
+		return err
+	}
+	e, ok := w.v.(vault.Expirer)
+	if !ok {
+		return verrors.ErrExpiryUnsupported
+	}
+	return e.UpdateExpiring(ctx, namespace, id, plaintext, expiresAt)
+}
+
+// StoreMany stores plaintexts if the principal has [PermWrite] on namespace; see [vault.StoreMany].
+func (w *Vault) StoreMany(ctx context.Context, namespace string, plaintexts [][]byte) ([]vault.BatchResult, error) {
+	if err := w.check(ctx, audit.OpStore, namespace, "", PermWrite); err != nil {
+		return nil, err
+	}
+	return vault.StoreMany(ctx, w.v, namespace, plaintexts)
+}
+
+// RetrieveMany opens rows if the principal has [PermRead] on namespace; see [vault.RetrieveMany].
+func (w *Vault) RetrieveMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	if err := w.check(ctx, audit.OpRetrieve, namespace, "", PermRead); err != nil {
+		return nil, err
+	}
+	return vault.RetrieveMany(ctx, w.v, namespace, ids)
+}
+
+// DeleteMany removes rows if the principal has [PermDelete] on namespace; see [vault.DeleteMany].
+func (w *Vault) DeleteMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	if err := w.check(ctx, audit.OpDelete, namespace, "", PermDelete); err != nil {
+		return nil, err
+	}
+	return vault.DeleteMany(ctx, w.v, namespace, ids)
+}
+
+// ActiveKeyID returns the wrapped vault's active key id, or nil if it does not expose one. Key ids
+// are public, so no permission is needed.
+func (w *Vault) ActiveKeyID() []byte {
+	if ki, ok := w.v.(vault.KeyIdentifier); ok {
+		return ki.ActiveKeyID()
+	}
+	return nil
+}
+
+// check returns a [*DeniedError] if the context's principal lacks any of need on namespace.
+func (w *Vault) check(ctx context.Context, op audit.Op, namespace, id string, need Perm) error {
+	principal := PrincipalFromContext(ctx)
+	missing := need &^ w.policy.Load().Allowed(principal, namespace)
+	if missing == 0 {
+		return nil
+	}
+	return w.deny(ctx, Denial{
+		DeniedError: &DeniedError{Principal: principal, Op: op, Namespace: namespace, Missing: missing},
+		ID:          id,
+	})
+}
+
+// deny stamps d, reports it to the hook, and returns its error.
+func (w *Vault) deny(ctx context.Context, d Denial) error {
+	d.Time = w.now()
+	if w.onDeny != nil {
+		w.onDeny(ctx, d)
+	}
+	return d.DeniedError
+}
@@ -0,0 +1,11 @@
+package storage
+
+// Export_test exposes selected internals to tests in package storage_test.
+
+// SetLogSyncDir replaces the function s uses to fsync its directory after a compaction renames the
+// staging file into place.
+func SetLogSyncDir(s *LogStorage, fn func(dir string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncDir = fn
+}
@@ -0,0 +1,330 @@
+package storage
+
+// Directory-backed [Storage] with crash-safe writes (temp file, fsync, rename, fsync dir).
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.rtnl.ai/x/locks"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/internal/fsutil"
+)
+
+const (
+	// fileLockName is the per-namespace lock file held during mutations.
+	fileLockName = ".lock"
+
+	// fileTempPattern names staging files; the leading dot keeps them out of row listings.
+	fileTempPattern = ".tmp-*"
+
+	// fileRowPrefix prefixes every row file so it never collides with lock or temp files.
+	fileRowPrefix = "r-"
+
+	fileDirMode  = 0o700
+	fileFileMode = 0o600
+)
+
+// fileIDEncoding maps ids to case-insensitive, path-safe file names (lowercase base32, no padding)
+// so rows cannot collide on case-insensitive filesystems.
+var fileIDEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// FileStorage is a directory-backed [Storage] that writes one file per (namespace, id).
+//
+// Each namespace is a subdirectory named by the hex SHA-256 of the namespace, so any namespace
+// string maps to a fixed-length, path-safe name. Each row is a file named by the base32 encoding
+// of its id. Writes go to a temporary file in the same directory that is fsynced and atomically
+// renamed into place, followed by an fsync of the directory, so readers and crashes observe either
+// the old or the new blob and never a partial one.
+//
+// Mutations (Create, Replace, Delete, CompareAndSwap) hold a per-namespace lock: an in-process
+// key lock for goroutines sharing a [FileStorage] and an advisory file lock (flock on platforms that
+// support it) for other processes using the same directory. Get does not lock because rename is
+// atomic. Ids must be short enough for the encoded file name to fit the filesystem's name limit.
+type FileStorage struct {
+	root  string
+	locks *locks.KeyLock
+}
+
+// FileStorage implements [Storage] and [Lister].
+var (
+	_ Storage = (*FileStorage)(nil)
+	_ Lister  = (*FileStorage)(nil)
+)
+
+// NewFileStorage returns a [FileStorage] rooted at dir, creating the directory (mode 0700) if needed.
+// An empty dir yields [verrors.ErrInvalidNewArgs]; failures to create or stat the directory are
+// joined with [verrors.ErrStorage].
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if dir == "" {
+		return nil, errors.Join(verrors.ErrInvalidNewArgs, errors.New("storage: empty directory"))
+	}
+	if err := os.MkdirAll(dir, fileDirMode); err != nil {
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	return &FileStorage{root: dir, locks: locks.New(0)}, nil
+}
+
+// Dir returns the root directory of the storage.
+func (s *FileStorage) Dir() string {
+	return s.root
+}
+
+// Create inserts a new row; duplicate (namespace, id) returns [verrors.ErrDuplicateKey].
+func (s *FileStorage) Create(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dir := s.namespaceDir(namespace)
+	if err := os.MkdirAll(dir, fileDirMode); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+
+	unlock, err := s.lock(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(dir, rowFileName(id))
+	if _, err := os.Lstat(path); err == nil {
+		return verrors.ErrDuplicateKey
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return writeFileAtomic(dir, path, ciphertext)
+}
+
+// Get returns the stored blob or [verrors.ErrNotFound].
+func (s *FileStorage) Get(ctx context.Context, namespace, id string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return readRowFile(filepath.Join(s.namespaceDir(namespace), rowFileName(id)))
+}
+
+// Replace overwrites ciphertext for an existing row; missing row returns [verrors.ErrNotFound].
+func (s *FileStorage) Replace(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dir := s.namespaceDir(namespace)
+	unlock, err := s.lockExisting(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(dir, rowFileName(id))
+	if _, err := os.Lstat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return verrors.ErrNotFound
+		}
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return writeFileAtomic(dir, path, ciphertext)
+}
+
+// Delete removes a row if present; a missing row (or namespace) returns nil.
+func (s *FileStorage) Delete(ctx context.Context, namespace, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dir := s.namespaceDir(namespace)
+	unlock, err := s.lockExisting(dir)
+	if err != nil {
+		if errors.Is(err, verrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(filepath.Join(dir, rowFileName(id))); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return syncDir(dir)
+}
+
+// CompareAndSwap sets newCiphertext only when the stored blob equals oldCiphertext.
+// Wrong old value returns [verrors.ErrCASFailed]; missing row returns [verrors.ErrNotFound].
+// The compare and the rename happen under the namespace lock, so the swap is atomic with respect
+// to other goroutines and processes using the same directory.
+func (s *FileStorage) CompareAndSwap(ctx context.Context, namespace, id string, oldCiphertext, newCiphertext []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dir := s.namespaceDir(namespace)
+	unlock, err := s.lockExisting(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(dir, rowFileName(id))
+	cur, err := readRowFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(cur, oldCiphertext) {
+		return verrors.ErrCASFailed
+	}
+	return writeFileAtomic(dir, path, newCiphertext)
+}
+
+// ListIDs returns up to limit ids in namespace sorting after cursor, in byte-wise ascending order.
+// A namespace that was never written lists as empty. Each call reads the whole namespace directory
+// (file names are not in id order), so very large namespaces are better served by [SQLStorage].
+func (s *FileStorage) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	entries, err := os.ReadDir(s.namespaceDir(namespace))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", nil
+		}
+		return nil, "", errors.Join(verrors.ErrStorage, err)
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), fileRowPrefix)
+		if !ok || !e.Type().IsRegular() {
+			continue
+		}
+		id, err := fileIDEncoding.DecodeString(name)
+		if err != nil {
+			// Not a row written by FileStorage; ignore it like lock and temp files.
+			continue
+		}
+		ids = append(ids, string(id))
+	}
+
+	ids, next := pageIDs(ids, cursor, limit)
+	return ids, next, nil
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// namespaceDir returns the directory holding rows for namespace.
+func (s *FileStorage) namespaceDir(namespace string) string {
+	sum := sha256.Sum256([]byte(namespace))
+	return filepath.Join(s.root, hex.EncodeToString(sum[:]))
+}
+
+// lock acquires the in-process and cross-process locks for the namespace directory dir, which
+// must exist. The returned function releases both.
+func (s *FileStorage) lock(dir string) (func(), error) {
+	key := []byte(dir)
+	s.locks.Lock(key)
+
+	f, err := os.OpenFile(filepath.Join(dir, fileLockName), os.O_RDWR|os.O_CREATE, fileFileMode)
+	if err != nil {
+		s.locks.Unlock(key)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, verrors.ErrNotFound
+		}
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		s.locks.Unlock(key)
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+		s.locks.Unlock(key)
+	}, nil
+}
+
+// lockExisting is like lock but reports [verrors.ErrNotFound] instead of creating the namespace
+// directory when it does not exist yet (no rows can exist in a missing namespace).
+func (s *FileStorage) lockExisting(dir string) (func(), error) {
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, verrors.ErrNotFound
+		}
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	return s.lock(dir)
+}
+
+// rowFileName maps an id to its row file name inside a namespace directory.
+func rowFileName(id string) string {
+	return fileRowPrefix + fileIDEncoding.EncodeToString([]byte(id))
+}
+
+// readRowFile reads a row file, mapping a missing file to [verrors.ErrNotFound].
+func readRowFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, verrors.ErrNotFound
+		}
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	return data, nil
+}
+
+// writeFileAtomic writes data to a temporary file in dir, fsyncs it, renames it over path, and
+// fsyncs dir so the rename is durable. The temporary file is removed on any failure.
+func writeFileAtomic(dir, path string, data []byte) (err error) {
+	var f *os.File
+	if f, err = os.CreateTemp(dir, fileTempPattern); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	if err = f.Close(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return syncDir(dir)
+}
+
+// syncDir fsyncs a directory so that renames and removals inside it are durable; failures are
+// joined with [verrors.ErrStorage].
+func syncDir(dir string) error {
+	if err := fsutil.SyncDir(dir); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package storage
+
+import "os"
+
+// lockFile is a no-op on platforms without flock; [FileStorage] still serializes goroutines in
+// one process, but concurrent processes sharing a directory are not excluded.
+func lockFile(*os.File) error { return nil }
+
+// unlockFile is a no-op counterpart to lockFile.
+func unlockFile(*os.File) error { return nil }
+
+// tryLockFile is a no-op on platforms without flock; see lockFile.
+func tryLockFile(*os.File) error { return nil }
@@ -0,0 +1,216 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestFileStorage_compliance runs [vaulttest.StorageConforms] against [storage.FileStorage]
+// rooted in a fresh temporary directory per subtest.
+func TestFileStorage_compliance(t *testing.T) {
+	vaulttest.StorageConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		st, err := storage.NewFileStorage(tb.TempDir())
+		assert.Ok(tb, err)
+		return st
+	})
+}
+
+// TestFileStorage_lister runs [vaulttest.ListerConforms] against [storage.FileStorage].
+func TestFileStorage_lister(t *testing.T) {
+	vaulttest.ListerConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		st, err := storage.NewFileStorage(tb.TempDir())
+		assert.Ok(tb, err)
+		return st
+	})
+}
+
+// TestFileStorage_concurrency runs [vaulttest.StorageConcurrencyConforms] against
+// [storage.FileStorage].
+func TestFileStorage_concurrency(t *testing.T) {
+	vaulttest.StorageConcurrencyConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		st, err := storage.NewFileStorage(tb.TempDir())
+		assert.Ok(tb, err)
+		return st
+	})
+}
+
+// BenchmarkFileStorage runs [vaulttest.StorageBenchmarks] against [storage.FileStorage]; every
+// write is fsynced, so results depend heavily on the disk under the temporary directory.
+func BenchmarkFileStorage(b *testing.B) {
+	vaulttest.StorageBenchmarks(b, identifier.HexIdentifier{}, func(tb *testing.B) storage.Storage {
+		st, err := storage.NewFileStorage(tb.TempDir())
+		assert.Ok(tb, err)
+		return st
+	})
+}
+
+// TestFileStorage_listOddNames verifies ids that need encoding list back verbatim, in byte order,
+// and that stray files in a namespace directory are ignored.
+func TestFileStorage_listOddNames(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := storage.NewFileStorage(dir)
+	assert.Ok(t, err)
+
+	ids := []string{"ABC", "abc", "../x", "a/b", "z"}
+	for _, id := range ids {
+		assert.Ok(t, st.Create(ctx, "ns", id, []byte(id)))
+	}
+
+	entries, err := os.ReadDir(dir)
+	assert.Ok(t, err)
+	assert.Len(t, entries, 1)
+	nsDir := filepath.Join(dir, entries[0].Name())
+	assert.Ok(t, os.WriteFile(filepath.Join(nsDir, "r-!!"), nil, 0o600))
+	assert.Ok(t, os.WriteFile(filepath.Join(nsDir, "notes.txt"), nil, 0o600))
+
+	got, next, err := st.ListIDs(ctx, "ns", "", 0)
+	assert.Ok(t, err)
+	assert.Equal(t, "", next)
+	assert.Equal(t, []string{"../x", "ABC", "a/b", "abc", "z"}, got)
+}
+
+// TestNewFileStorage_emptyDir verifies an empty directory path is rejected.
+func TestNewFileStorage_emptyDir(t *testing.T) {
+	_, err := storage.NewFileStorage("")
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	assert.Contains(t, err.Error(), "storage: empty directory")
+}
+
+// TestFileStorage_persistsAcrossInstances verifies rows written by one [storage.FileStorage] are
+// visible to another opened on the same directory, and that no staging files are left behind.
+func TestFileStorage_persistsAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	a, err := storage.NewFileStorage(dir)
+	assert.Ok(t, err)
+	assert.Ok(t, a.Create(ctx, "ns", "row", []byte("v1")))
+	assert.Ok(t, a.Replace(ctx, "ns", "row", []byte("v2")))
+
+	b, err := storage.NewFileStorage(dir)
+	assert.Ok(t, err)
+	got, err := b.Get(ctx, "ns", "row")
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("v2"), got)
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			t.Errorf("staging file left behind: %s", path)
+		}
+		return nil
+	})
+	assert.Ok(t, err)
+}
+
+// TestFileStorage_oddNames verifies namespaces and ids that are not path-safe still round-trip and
+// stay isolated, including ids that differ only by case.
+func TestFileStorage_oddNames(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewFileStorage(t.TempDir())
+	assert.Ok(t, err)
+
+	rows := []struct{ ns, id, blob string }{
+		{"", "", "empty"},
+		{"../escape", "../../etc/passwd", "dots"},
+		{"ns/with/slashes", "id", "slashes"},
+		{"ns", "ABC", "upper"},
+		{"ns", "abc", "lower"},
+		{strings.Repeat("n", 255), "id", "long-namespace"},
+	}
+	for _, r := range rows {
+		assert.Ok(t, st.Create(ctx, r.ns, r.id, []byte(r.blob)), "create %q/%q", r.ns, r.id)
+	}
+	for _, r := range rows {
+		got, err := st.Get(ctx, r.ns, r.id)
+		assert.Ok(t, err, "get %q/%q", r.ns, r.id)
+		assert.Equal(t, []byte(r.blob), got)
+	}
+}
+
+// TestFileStorage_missingNamespace verifies mutations against a namespace that was never written
+// report not-found (or succeed for Delete) without creating directories.
+func TestFileStorage_missingNamespace(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := storage.NewFileStorage(dir)
+	assert.Ok(t, err)
+
+	assert.ErrorIs(t, st.Replace(ctx, "nope", "id", []byte("x")), verrors.ErrNotFound)
+	assert.ErrorIs(t, st.CompareAndSwap(ctx, "nope", "id", []byte("a"), []byte("b")), verrors.ErrNotFound)
+	assert.Ok(t, st.Delete(ctx, "nope", "id"))
+
+	entries, err := os.ReadDir(dir)
+	assert.Ok(t, err)
+	assert.Len(t, entries, 0)
+}
+
+// TestFileStorage_concurrentCAS races compare-and-swap from two [storage.FileStorage] instances
+// on one directory (exercising the cross-process file lock) and expects exactly one winner per round.
+func TestFileStorage_concurrentCAS(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	a, err := storage.NewFileStorage(dir)
+	assert.Ok(t, err)
+	b, err := storage.NewFileStorage(dir)
+	assert.Ok(t, err)
+	assert.Ok(t, a.Create(ctx, "ns", "row", []byte("start")))
+
+	const workers = 16
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		wins int
+	)
+	for i := range workers {
+		st := a
+		if i%2 == 1 {
+			st = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := st.CompareAndSwap(ctx, "ns", "row", []byte("start"), []byte{byte('a' + i)})
+			switch {
+			case err == nil:
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			case !errors.Is(err, verrors.ErrCASFailed):
+				t.Errorf("unexpected CAS error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, wins)
+}
+
+// TestFileStorage_canceledContext verifies operations honor a canceled context.
+func TestFileStorage_canceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	st, err := storage.NewFileStorage(t.TempDir())
+	assert.Ok(t, err)
+	assert.ErrorIs(t, st.Create(ctx, "ns", "id", []byte("x")), context.Canceled)
+	_, err = st.Get(ctx, "ns", "id")
+	assert.ErrorIs(t, err, context.Canceled)
+}
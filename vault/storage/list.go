@@ -0,0 +1,62 @@
+package storage
+
+// Cursor pagination helpers shared by the [Lister] implementations in this package.
+
+import (
+	"context"
+	"iter"
+	"slices"
+)
+
+// DefaultListLimit is the page size used by [Lister.ListIDs] when limit is zero or less.
+const DefaultListLimit = 100
+
+// IDs returns an iterator over every id in namespace, fetching pages of up to pageSize ids from l as
+// the iteration proceeds. A listing error is yielded once with an empty id and ends the iteration.
+func IDs(ctx context.Context, l Lister, namespace string, pageSize int) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		var cursor string
+		for {
+			ids, next, err := l.ListIDs(ctx, namespace, cursor, pageSize)
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			for _, id := range ids {
+				if !yield(id, nil) {
+					return
+				}
+			}
+
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}
+}
+
+// pageIDs sorts ids in place and returns the page of up to limit ids after cursor along with the
+// cursor for the next page ("" when the page reaches the end).
+func pageIDs(ids []string, cursor string, limit int) ([]string, string) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	slices.Sort(ids)
+
+	start := 0
+	if cursor != "" {
+		var found bool
+		if start, found = slices.BinarySearch(ids, cursor); found {
+			start++
+		}
+	}
+	ids = ids[start:]
+
+	if len(ids) <= limit {
+		return ids, ""
+	}
+	ids = ids[:limit:limit]
+	return ids, ids[limit-1]
+}
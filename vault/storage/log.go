@@ -0,0 +1,684 @@
+package storage
+
+// Single-file [Storage]: an append-only log of checksummed records with an in-memory index,
+// compacted by rewriting the live records to a fresh file.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+)
+
+// Defaults for [LogOptions] fields left zero.
+const (
+	DefaultLogCompactRatio    = 0.5
+	DefaultLogCompactMinBytes = 1 << 20
+)
+
+const (
+	// logMagic and logVersion open every log file.
+	logMagic   = "VLOG"
+	logVersion = 1
+	logHdrLen  = len(logMagic) + 1
+
+	// logRecHdrLen is the per-record prefix: CRC-32C then body length, both big-endian uint32.
+	logRecHdrLen = 8
+
+	// logMaxBody bounds a record body so a corrupt length cannot force a huge allocation.
+	logMaxBody = 1 << 30
+
+	// logCompactSuffix names the staging file a compaction writes before renaming it into place.
+	logCompactSuffix = ".compact"
+
+	logOpPut    = 1
+	logOpDelete = 2
+)
+
+var logCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// LogOptions configures [OpenLogStorage].
+type LogOptions struct {
+	// CompactRatio is the fraction of the file taken by superseded records at which a write triggers
+	// compaction. Zero uses [DefaultLogCompactRatio]; a negative value disables automatic compaction
+	// ([LogStorage.Compact] still works).
+	CompactRatio float64
+
+	// CompactMinBytes is the file size below which automatic compaction never runs. Zero or less uses
+	// [DefaultLogCompactMinBytes].
+	CompactMinBytes int64
+}
+
+// LogStats describes a [LogStorage] file.
+type LogStats struct {
+	Rows      int   // live rows across all namespaces
+	FileBytes int64 // current file size
+	LiveBytes int64 // bytes of the header and the records of live rows
+	Truncated int64 // bytes of torn last record dropped when the file was opened
+}
+
+// LogStorage is a single-file [Storage] for small tools that want one vault database file rather
+// than a directory or a SQL server.
+//
+// Every write appends one record (put or delete) holding the namespace, id, and ciphertext, guarded
+// by a CRC-32C checksum, and fsyncs the file before returning, so an acknowledged write survives a
+// crash. An in-memory index maps each live row to its record; Get reads the record back and checks
+// its checksum. Create, Replace, Delete, and CompareAndSwap are serialized by a mutex, so the
+// compare in CompareAndSwap and the append that follows are atomic.
+//
+// Superseded records are reclaimed by compaction, which copies the live records to a staging file,
+// fsyncs it, and renames it over the log; a crash leaves either the old or the new file. Compaction
+// runs after a write once superseded records make up [LogOptions.CompactRatio] of the file, or on
+// demand with [LogStorage.Compact].
+//
+// On open the log is replayed to rebuild the index. A crash can leave a partially written record at
+// the end; a short or failing record that runs to the end of the file is truncated away (see
+// [LogStats.Truncated]). A failing record with more of the log after it was damaged some other way,
+// so the open fails rather than discard the intact records that follow. The file is locked exclusively while open (flock on
+// platforms that support it), so a second [OpenLogStorage] of the same path fails with
+// [verrors.ErrStorageLocked]. Call [LogStorage.Close] when done.
+type LogStorage struct {
+	path         string
+	compactRatio float64
+	compactMin   int64
+	syncDir      func(dir string) error // makes renames in the log's directory durable
+
+	mu        sync.RWMutex
+	f         *os.File
+	size      int64 // end of the log; the next record is written here
+	live      int64 // logHdrLen plus the record sizes of live rows
+	rows      int
+	truncated int64
+	index     map[string]map[string]logRecord // namespace -> id -> record
+	broken    error                           // set when a failed append could not be rolled back
+}
+
+// logRecord locates a put record in the file.
+type logRecord struct {
+	off int64
+	n   int64 // full record size, header included
+}
+
+// LogStorage implements [Storage] and [Lister].
+var (
+	_ Storage = (*LogStorage)(nil)
+	_ Lister  = (*LogStorage)(nil)
+)
+
+// OpenLogStorage opens the log file at path, creating it (mode 0600) if needed, and replays it to
+// build the index. An empty path yields [verrors.ErrInvalidNewArgs]; a file that is not a vault log,
+// or that has a damaged record before its last one, yields [verrors.ErrStorageCorrupt]; a file held open by another [LogStorage] yields
+// [verrors.ErrStorageLocked]. Other failures are joined with [verrors.ErrStorage]. A staging file
+// left by a compaction that crashed before its rename is removed.
+func OpenLogStorage(path string, opts LogOptions) (_ *LogStorage, err error) {
+	if path == "" {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+
+	s := &LogStorage{
+		path:         path,
+		compactRatio: opts.CompactRatio,
+		compactMin:   opts.CompactMinBytes,
+		syncDir:      syncDir,
+	}
+	if s.compactRatio == 0 {
+		s.compactRatio = DefaultLogCompactRatio
+	}
+	if s.compactMin <= 0 {
+		s.compactMin = DefaultLogCompactMinBytes
+	}
+
+	if s.f, err = openLogFile(path, os.O_RDWR|os.O_CREATE); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.f.Close()
+		}
+	}()
+
+	if err = os.Remove(path + logCompactSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	if err = s.replay(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the log file path.
+func (s *LogStorage) Path() string {
+	return s.path
+}
+
+// Stats returns the current row count and file sizes.
+func (s *LogStorage) Stats() LogStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return LogStats{Rows: s.rows, FileBytes: s.size, LiveBytes: s.live, Truncated: s.truncated}
+}
+
+// Close releases the file and its lock. Later calls return [verrors.ErrStorageClosed]; closing
+// twice returns nil.
+func (s *LogStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+
+	err := s.f.Close()
+	s.f, s.index = nil, nil
+	if err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
+
+//=============================================================================
+// Storage
+//=============================================================================
+
+// Create inserts a new row; duplicate (namespace, id) returns [verrors.ErrDuplicateKey].
+func (s *LogStorage) Create(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	if _, ok := s.index[namespace][id]; ok {
+		return verrors.ErrDuplicateKey
+	}
+	return s.put(namespace, id, ciphertext)
+}
+
+// Get returns the stored blob or [verrors.ErrNotFound]. A record that fails its checksum returns
+// [verrors.ErrStorageCorrupt] joined with [verrors.ErrStorage].
+func (s *LogStorage) Get(ctx context.Context, namespace, id string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.f == nil {
+		return nil, verrors.ErrStorageClosed
+	}
+	return s.get(namespace, id)
+}
+
+// Replace overwrites ciphertext for an existing row; missing row returns [verrors.ErrNotFound].
+func (s *LogStorage) Replace(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	if _, ok := s.index[namespace][id]; !ok {
+		return verrors.ErrNotFound
+	}
+	return s.put(namespace, id, ciphertext)
+}
+
+// Delete removes a row if present by appending a delete record; a missing row returns nil without
+// writing.
+func (s *LogStorage) Delete(ctx context.Context, namespace, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	if _, ok := s.index[namespace][id]; !ok {
+		return nil
+	}
+	if _, err := s.appendRecord(logOpDelete, namespace, id, nil); err != nil {
+		return err
+	}
+	s.unindex(namespace, id)
+	s.maybeCompact()
+	return nil
+}
+
+// CompareAndSwap sets newCiphertext only when the stored blob equals oldCiphertext. Wrong old value
+// returns [verrors.ErrCASFailed]; missing row returns [verrors.ErrNotFound]. The compare and the
+// append run under the write lock, and the new record is fsynced before CompareAndSwap returns.
+func (s *LogStorage) CompareAndSwap(ctx context.Context, namespace, id string, oldCiphertext, newCiphertext []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	cur, err := s.get(namespace, id)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(cur, oldCiphertext) {
+		return verrors.ErrCASFailed
+	}
+	return s.put(namespace, id, newCiphertext)
+}
+
+// ListIDs returns up to limit ids in namespace sorting after cursor, in byte-wise ascending order,
+// from the in-memory index.
+func (s *LogStorage) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.f == nil {
+		return nil, "", verrors.ErrStorageClosed
+	}
+
+	rows := s.index[namespace]
+	ids := make([]string, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+
+	ids, next := pageIDs(ids, cursor, limit)
+	return ids, next, nil
+}
+
+//=============================================================================
+// Compaction
+//=============================================================================
+
+// Compact rewrites the log with only the records of live rows. The new file is written and fsynced
+// under a staging name, locked, and renamed over the log, so a crash at any point leaves a complete
+// log. Writers wait while it runs. Failures are joined with [verrors.ErrStorage] and leave the
+// current log in use.
+func (s *LogStorage) Compact(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	return s.compact()
+}
+
+// maybeCompact compacts when superseded records have reached the configured share of the file.
+// Failures are ignored: the log stays valid and the next write tries again.
+func (s *LogStorage) maybeCompact() {
+	if s.compactRatio < 0 || s.size < s.compactMin {
+		return
+	}
+	if float64(s.size-s.live) >= s.compactRatio*float64(s.size) {
+		s.compact()
+	}
+}
+
+// compact implements [LogStorage.Compact]; the caller holds the write lock.
+func (s *LogStorage) compact() (err error) {
+	tmp := s.path + logCompactSuffix
+	var f *os.File
+	if f, err = openLogFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC); err != nil {
+		return err
+	}
+
+	// Until the rename, a failure discards the staging file; after it, f is the log.
+	renamed := false
+	defer func() {
+		if err != nil && !renamed {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	// Copy each live record verbatim; records carry no offsets, so only the index changes.
+	w := bufio.NewWriter(f)
+	if _, err = w.WriteString(logHeader()); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+
+	index := make(map[string]map[string]logRecord, len(s.index))
+	off := int64(logHdrLen)
+	for ns, rows := range s.index {
+		moved := make(map[string]logRecord, len(rows))
+		for id, rec := range rows {
+			if _, err = io.Copy(w, io.NewSectionReader(s.f, rec.off, rec.n)); err != nil {
+				return errors.Join(verrors.ErrStorage, err)
+			}
+			moved[id] = logRecord{off: off, n: rec.n}
+			off += rec.n
+		}
+		index[ns] = moved
+	}
+
+	if err = w.Flush(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	if err = f.Sync(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	renamed = true
+
+	// The new file is in place; switch to it even if the directory sync fails.
+	s.f.Close()
+	s.f, s.index, s.size, s.live = f, index, off, off
+	return s.syncDir(filepath.Dir(s.path))
+}
+
+//=============================================================================
+// Records
+//=============================================================================
+
+// writable reports why the log cannot take writes, if it cannot.
+func (s *LogStorage) writable() error {
+	if s.f == nil {
+		return verrors.ErrStorageClosed
+	}
+	return s.broken
+}
+
+// get reads and verifies the record for (namespace, id); the caller holds the lock.
+func (s *LogStorage) get(namespace, id string) ([]byte, error) {
+	rec, ok := s.index[namespace][id]
+	if !ok {
+		return nil, verrors.ErrNotFound
+	}
+
+	buf := make([]byte, rec.n)
+	if _, err := s.f.ReadAt(buf, rec.off); err != nil {
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	body, ok := checkRecord(buf)
+	if !ok {
+		return nil, errors.Join(verrors.ErrStorage, verrors.ErrStorageCorrupt)
+	}
+	_, _, _, value, ok := decodeBody(body)
+	if !ok {
+		return nil, errors.Join(verrors.ErrStorage, verrors.ErrStorageCorrupt)
+	}
+	return value, nil
+}
+
+// put appends a put record and points the index at it; the caller holds the write lock.
+func (s *LogStorage) put(namespace, id string, ciphertext []byte) error {
+	rec, err := s.appendRecord(logOpPut, namespace, id, ciphertext)
+	if err != nil {
+		return err
+	}
+
+	s.unindex(namespace, id)
+	rows := s.index[namespace]
+	if rows == nil {
+		rows = make(map[string]logRecord)
+		s.index[namespace] = rows
+	}
+	rows[id] = rec
+	s.rows++
+	s.live += rec.n
+	s.maybeCompact()
+	return nil
+}
+
+// unindex drops (namespace, id) from the index if present.
+func (s *LogStorage) unindex(namespace, id string) {
+	rows := s.index[namespace]
+	rec, ok := rows[id]
+	if !ok {
+		return
+	}
+	delete(rows, id)
+	if len(rows) == 0 {
+		delete(s.index, namespace)
+	}
+	s.rows--
+	s.live -= rec.n
+}
+
+// appendRecord writes one record at the end of the log and fsyncs it. If the write or sync fails
+// the file is truncated back so a partial record cannot hide later ones; if that fails too, the log
+// refuses further writes until it is reopened (which drops the partial record).
+func (s *LogStorage) appendRecord(op byte, namespace, id string, value []byte) (logRecord, error) {
+	buf := encodeRecord(op, namespace, id, value)
+	if len(buf)-logRecHdrLen > logMaxBody {
+		return logRecord{}, errors.Join(verrors.ErrStorage, verrors.ErrMalformedParameters)
+	}
+
+	rec := logRecord{off: s.size, n: int64(len(buf))}
+	if _, err := s.f.WriteAt(buf, rec.off); err != nil {
+		return logRecord{}, s.rollback(rec.off, err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return logRecord{}, s.rollback(rec.off, err)
+	}
+	s.size += rec.n
+	return rec, nil
+}
+
+// rollback truncates the log to end after a failed append and returns the append error.
+func (s *LogStorage) rollback(end int64, err error) error {
+	if terr := s.f.Truncate(end); terr != nil {
+		s.broken = errors.Join(verrors.ErrStorage, err, terr)
+		return s.broken
+	}
+	return errors.Join(verrors.ErrStorage, err)
+}
+
+// replay checks the header and rebuilds the index from every intact record, truncating a torn last
+// record. A bad record before the end of the file yields [verrors.ErrStorageCorrupt].
+func (s *LogStorage) replay() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	s.index = make(map[string]map[string]logRecord)
+
+	// A new (or never written) file gets a header.
+	if info.Size() == 0 {
+		if _, err := s.f.WriteAt([]byte(logHeader()), 0); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+		if err := s.f.Sync(); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+		s.size, s.live = int64(logHdrLen), int64(logHdrLen)
+		return nil
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.f, 0, info.Size()))
+	hdr := make([]byte, logHdrLen)
+	if _, err := io.ReadFull(r, hdr); err != nil || string(hdr) != logHeader() {
+		return verrors.ErrStorageCorrupt
+	}
+
+	off := int64(logHdrLen)
+	s.live = off
+	for {
+		var (
+			op     byte
+			ns, id string
+		)
+		buf, ok := readRecord(r)
+		if ok {
+			var body []byte
+			if body, ok = checkRecord(buf); ok {
+				op, ns, id, _, ok = decodeBody(body)
+			}
+		}
+		if !ok {
+			torn, err := s.tornTail(off, info.Size())
+			if err != nil {
+				return err
+			}
+			if !torn {
+				return verrors.ErrStorageCorrupt
+			}
+			break
+		}
+
+		rec := logRecord{off: off, n: int64(len(buf))}
+		s.unindex(ns, id)
+		if op == logOpPut {
+			rows := s.index[ns]
+			if rows == nil {
+				rows = make(map[string]logRecord)
+				s.index[ns] = rows
+			}
+			rows[id] = rec
+			s.rows++
+			s.live += rec.n
+		}
+		off += rec.n
+	}
+
+	s.size = off
+	if s.truncated = info.Size() - off; s.truncated > 0 {
+		if err := s.f.Truncate(off); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+		if err := s.f.Sync(); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+	}
+	return nil
+}
+
+// tornTail reports whether the bad record at off runs to the end of a log of size bytes, as one cut
+// short by a crash does, rather than being followed by more records. A clean end of the log at off
+// counts as torn, with nothing to truncate.
+func (s *LogStorage) tornTail(off, size int64) (bool, error) {
+	hdr := make([]byte, logRecHdrLen)
+	if n, err := s.f.ReadAt(hdr, off); n < logRecHdrLen {
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		return false, errors.Join(verrors.ErrStorage, err)
+	}
+	return off+logRecHdrLen+int64(binary.BigEndian.Uint32(hdr[4:8])) >= size, nil
+}
+
+// openLogFile opens path and takes its exclusive lock without blocking.
+func openLogFile(path string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, fileFileMode)
+	if err != nil {
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	if err := tryLockFile(f); err != nil {
+		f.Close()
+		return nil, verrors.ErrStorageLocked
+	}
+	return f, nil
+}
+
+// logHeader returns the file header.
+func logHeader() string {
+	return logMagic + string(rune(logVersion))
+}
+
+// encodeRecord lays out one record: CRC-32C and body length, then op, uvarint-prefixed namespace and
+// id, and the value to the end of the body. The checksum covers the length and the body.
+func encodeRecord(op byte, namespace, id string, value []byte) []byte {
+	buf := make([]byte, logRecHdrLen, logRecHdrLen+1+2*binary.MaxVarintLen64+len(namespace)+len(id)+len(value))
+	buf = append(buf, op)
+	buf = binary.AppendUvarint(buf, uint64(len(namespace)))
+	buf = append(buf, namespace...)
+	buf = binary.AppendUvarint(buf, uint64(len(id)))
+	buf = append(buf, id...)
+	buf = append(buf, value...)
+
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(buf)-logRecHdrLen))
+	binary.BigEndian.PutUint32(buf[0:4], crc32.Checksum(buf[4:], logCRC))
+	return buf
+}
+
+// readRecord reads the next whole record from r; it reports false at the end of the log or on a
+// short or implausible record.
+func readRecord(r *bufio.Reader) ([]byte, bool) {
+	hdr := make([]byte, logRecHdrLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, false
+	}
+	n := binary.BigEndian.Uint32(hdr[4:8])
+	if n == 0 || n > logMaxBody {
+		return nil, false
+	}
+
+	buf := make([]byte, logRecHdrLen+int(n))
+	copy(buf, hdr)
+	if _, err := io.ReadFull(r, buf[logRecHdrLen:]); err != nil {
+		return nil, false
+	}
+	return buf, true
+}
+
+// checkRecord verifies a whole record's length and checksum and returns its body.
+func checkRecord(buf []byte) ([]byte, bool) {
+	if len(buf) < logRecHdrLen {
+		return nil, false
+	}
+	if int(binary.BigEndian.Uint32(buf[4:8])) != len(buf)-logRecHdrLen {
+		return nil, false
+	}
+	if binary.BigEndian.Uint32(buf[0:4]) != crc32.Checksum(buf[4:], logCRC) {
+		return nil, false
+	}
+	return buf[logRecHdrLen:], true
+}
+
+// decodeBody splits a record body into its fields; value aliases body.
+func decodeBody(body []byte) (op byte, namespace, id string, value []byte, ok bool) {
+	if len(body) == 0 {
+		return 0, "", "", nil, false
+	}
+	op, body = body[0], body[1:]
+	if op != logOpPut && op != logOpDelete {
+		return 0, "", "", nil, false
+	}
+
+	field := func() (string, bool) {
+		n, k := binary.Uvarint(body)
+		if k <= 0 || n > uint64(len(body)-k) {
+			return "", false
+		}
+		v := string(body[k : k+int(n)])
+		body = body[k+int(n):]
+		return v, true
+	}
+	if namespace, ok = field(); !ok {
+		return 0, "", "", nil, false
+	}
+	if id, ok = field(); !ok {
+		return 0, "", "", nil, false
+	}
+	return op, namespace, id, body, true
+}
@@ -0,0 +1,362 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestLogStorage_compliance runs [vaulttest.StorageConforms] against [storage.LogStorage] in a
+// fresh file per subtest, with compaction after nearly every write so it is exercised as well.
+func TestLogStorage_compliance(t *testing.T) {
+	vaulttest.StorageConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return openLog(tb, filepath.Join(tb.TempDir(), "vault.log"), storage.LogOptions{CompactRatio: 0.01, CompactMinBytes: 1})
+	})
+}
+
+// TestLogStorage_lister runs [vaulttest.ListerConforms] against [storage.LogStorage].
+func TestLogStorage_lister(t *testing.T) {
+	vaulttest.ListerConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return openLog(tb, filepath.Join(tb.TempDir(), "vault.log"), storage.LogOptions{})
+	})
+}
+
+// TestLogStorage_concurrency runs [vaulttest.StorageConcurrencyConforms] against
+// [storage.LogStorage].
+func TestLogStorage_concurrency(t *testing.T) {
+	vaulttest.StorageConcurrencyConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return openLog(tb, filepath.Join(tb.TempDir(), "vault.log"), storage.LogOptions{})
+	})
+}
+
+// BenchmarkLogStorage runs [vaulttest.StorageBenchmarks] against [storage.LogStorage]; like
+// [storage.FileStorage], every write is fsynced.
+func BenchmarkLogStorage(b *testing.B) {
+	vaulttest.StorageBenchmarks(b, identifier.HexIdentifier{}, func(tb *testing.B) storage.Storage {
+		return openLog(tb, filepath.Join(tb.TempDir(), "vault.log"), storage.LogOptions{})
+	})
+}
+
+// TestOpenLogStorage_emptyPath verifies an empty path is rejected.
+func TestOpenLogStorage_emptyPath(t *testing.T) {
+	_, err := storage.OpenLogStorage("", storage.LogOptions{})
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+}
+
+// TestLogStorage_persistsAcrossOpens verifies creates, replaces, swaps, and deletes are replayed by
+// the next open.
+func TestLogStorage_persistsAcrossOpens(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vault.log")
+
+	a := openLog(t, path, storage.LogOptions{})
+	assert.Ok(t, a.Create(ctx, "ns", "kept", []byte("v1")))
+	assert.Ok(t, a.Replace(ctx, "ns", "kept", []byte("v2")))
+	assert.Ok(t, a.CompareAndSwap(ctx, "ns", "kept", []byte("v2"), []byte("v3")))
+	assert.Ok(t, a.Create(ctx, "ns", "gone", []byte("x")))
+	assert.Ok(t, a.Delete(ctx, "ns", "gone"))
+	assert.Ok(t, a.Create(ctx, "other", "kept", []byte("elsewhere")))
+	assert.Ok(t, a.Close())
+
+	b := openLog(t, path, storage.LogOptions{})
+	assertRow(t, b, "ns", "kept", "v3")
+	assertRow(t, b, "other", "kept", "elsewhere")
+	_, err := b.Get(ctx, "ns", "gone")
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+	assert.Equal(t, 2, b.Stats().Rows)
+	assert.Equal(t, int64(0), b.Stats().Truncated)
+}
+
+// TestLogStorage_crashRecovery simulates crashes that leave a damaged tail and verifies reopening
+// keeps every intact record, drops the damaged tail, and accepts new writes after it.
+func TestLogStorage_crashRecovery(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, path string, lastRecord int64)
+	}{
+		{"torn_record", func(t *testing.T, path string, lastRecord int64) {
+			info, err := os.Stat(path)
+			assert.Ok(t, err)
+			assert.Ok(t, os.Truncate(path, info.Size()-3))
+		}},
+		{"torn_header", func(t *testing.T, path string, lastRecord int64) {
+			assert.Ok(t, os.Truncate(path, lastRecord+5))
+		}},
+		{"flipped_byte", func(t *testing.T, path string, lastRecord int64) {
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			assert.Ok(t, err)
+			defer f.Close()
+			b := make([]byte, 1)
+			_, err = f.ReadAt(b, lastRecord+10)
+			assert.Ok(t, err)
+			b[0] ^= 0xff
+			_, err = f.WriteAt(b, lastRecord+10)
+			assert.Ok(t, err)
+		}},
+		{"garbage_length", func(t *testing.T, path string, lastRecord int64) {
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			assert.Ok(t, err)
+			defer f.Close()
+			_, err = f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, lastRecord+4)
+			assert.Ok(t, err)
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "vault.log")
+
+			a := openLog(t, path, storage.LogOptions{})
+			assert.Ok(t, a.Create(ctx, "ns", "one", []byte("first")))
+			assert.Ok(t, a.Create(ctx, "ns", "two", []byte("second")))
+			lastRecord := a.Stats().FileBytes
+			assert.Ok(t, a.Replace(ctx, "ns", "two", []byte("lost in the crash")))
+			assert.Ok(t, a.Close())
+
+			tc.damage(t, path, lastRecord)
+
+			b := openLog(t, path, storage.LogOptions{})
+			assert.True(t, b.Stats().Truncated > 0)
+			assert.Equal(t, lastRecord, b.Stats().FileBytes)
+			assertRow(t, b, "ns", "one", "first")
+			assertRow(t, b, "ns", "two", "second")
+
+			assert.Ok(t, b.Replace(ctx, "ns", "two", []byte("third")))
+			assert.Ok(t, b.Close())
+
+			c := openLog(t, path, storage.LogOptions{})
+			assert.Equal(t, int64(0), c.Stats().Truncated)
+			assertRow(t, c, "ns", "two", "third")
+		})
+	}
+}
+
+// TestLogStorage_corruptMiddle verifies a damaged record with intact records after it fails the open
+// instead of being truncated away with everything that follows.
+func TestLogStorage_corruptMiddle(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, f *os.File, record int64)
+	}{
+		{"flipped_byte", func(t *testing.T, f *os.File, record int64) {
+			b := make([]byte, 1)
+			_, err := f.ReadAt(b, record+10)
+			assert.Ok(t, err)
+			b[0] ^= 0xff
+			_, err = f.WriteAt(b, record+10)
+			assert.Ok(t, err)
+		}},
+		{"short_length", func(t *testing.T, f *os.File, record int64) {
+			_, err := f.WriteAt([]byte{0, 0, 0, 1}, record+4)
+			assert.Ok(t, err)
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "vault.log")
+
+			a := openLog(t, path, storage.LogOptions{})
+			assert.Ok(t, a.Create(ctx, "ns", "one", []byte("first")))
+			middle := a.Stats().FileBytes
+			assert.Ok(t, a.Create(ctx, "ns", "two", []byte("second")))
+			assert.Ok(t, a.Create(ctx, "ns", "three", []byte("third")))
+			assert.Ok(t, a.Close())
+
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			assert.Ok(t, err)
+			tc.damage(t, f, middle)
+			assert.Ok(t, f.Close())
+			damaged, err := os.ReadFile(path)
+			assert.Ok(t, err)
+
+			_, err = storage.OpenLogStorage(path, storage.LogOptions{})
+			assert.ErrorIs(t, err, verrors.ErrStorageCorrupt)
+
+			// The file is left as it was for inspection or repair.
+			data, err := os.ReadFile(path)
+			assert.Ok(t, err)
+			assert.Equal(t, damaged, data)
+		})
+	}
+}
+
+// TestLogStorage_corruptRecordOnRead verifies Get reports a record that was damaged after it was
+// indexed instead of returning the damaged bytes.
+func TestLogStorage_corruptRecordOnRead(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vault.log")
+	st := openLog(t, path, storage.LogOptions{})
+	assert.Ok(t, st.Create(ctx, "ns", "id", []byte("ciphertext")))
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	assert.Ok(t, err)
+	_, err = f.WriteAt([]byte("X"), st.Stats().FileBytes-1)
+	assert.Ok(t, err)
+	assert.Ok(t, f.Close())
+
+	_, err = st.Get(ctx, "ns", "id")
+	assert.ErrorIs(t, err, verrors.ErrStorageCorrupt)
+	assert.ErrorIs(t, err, verrors.ErrStorage)
+	assert.ErrorIs(t, st.CompareAndSwap(ctx, "ns", "id", []byte("ciphertext"), []byte("new")), verrors.ErrStorageCorrupt)
+}
+
+// TestLogStorage_badHeader verifies a file that is not a vault log is refused rather than truncated.
+func TestLogStorage_badHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.log")
+	assert.Ok(t, os.WriteFile(path, []byte("SQLite format 3\x00"), 0o600))
+
+	_, err := storage.OpenLogStorage(path, storage.LogOptions{})
+	assert.ErrorIs(t, err, verrors.ErrStorageCorrupt)
+
+	data, err := os.ReadFile(path)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("SQLite format 3\x00"), data)
+}
+
+// TestLogStorage_compact verifies compaction shrinks the file to its live records, survives a
+// reopen, and that a staging file left by a crashed compaction is removed on open.
+func TestLogStorage_compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vault.log")
+
+	st := openLog(t, path, storage.LogOptions{CompactRatio: -1})
+	assert.Ok(t, st.Create(ctx, "ns", "a", []byte("a0")))
+	assert.Ok(t, st.Create(ctx, "ns", "b", []byte("b0")))
+	for i := range 50 {
+		assert.Ok(t, st.Replace(ctx, "ns", "a", []byte{'a', byte(i)}))
+	}
+	assert.Ok(t, st.Delete(ctx, "ns", "b"))
+
+	before := st.Stats()
+	assert.Equal(t, 1, before.Rows)
+	assert.True(t, before.LiveBytes < before.FileBytes)
+
+	assert.Ok(t, st.Compact(ctx))
+	after := st.Stats()
+	assert.Equal(t, 1, after.Rows)
+	assert.Equal(t, before.LiveBytes, after.FileBytes)
+	assert.Equal(t, after.LiveBytes, after.FileBytes)
+	assertRow(t, st, "ns", "a", string([]byte{'a', 49}))
+
+	// Writes after compaction land in the new file.
+	assert.Ok(t, st.Create(ctx, "ns", "c", []byte("c0")))
+	assert.Ok(t, st.Close())
+
+	assert.Ok(t, os.WriteFile(path+".compact", []byte("half-written"), 0o600))
+	re := openLog(t, path, storage.LogOptions{})
+	assertRow(t, re, "ns", "a", string([]byte{'a', 49}))
+	assertRow(t, re, "ns", "c", "c0")
+	_, err := os.Stat(path + ".compact")
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestLogStorage_compactSyncDirFails verifies a directory fsync failure after the rename is
+// reported but leaves the compacted file in use and intact.
+func TestLogStorage_compactSyncDirFails(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vault.log")
+
+	st := openLog(t, path, storage.LogOptions{CompactRatio: -1})
+	assert.Ok(t, st.Create(ctx, "ns", "a", []byte("a0")))
+	assert.Ok(t, st.Replace(ctx, "ns", "a", []byte("a1")))
+
+	syncErr := errors.New("sync failed")
+	storage.SetLogSyncDir(st, func(string) error { return syncErr })
+	err := st.Compact(ctx)
+	assert.ErrorIs(t, err, syncErr)
+	assert.Equal(t, st.Stats().LiveBytes, st.Stats().FileBytes)
+
+	// The renamed file stays open and writable.
+	assertRow(t, st, "ns", "a", "a1")
+	assert.Ok(t, st.Create(ctx, "ns", "b", []byte("b0")))
+	assert.Ok(t, st.Close())
+
+	re := openLog(t, path, storage.LogOptions{})
+	assertRow(t, re, "ns", "a", "a1")
+	assertRow(t, re, "ns", "b", "b0")
+}
+
+// TestLogStorage_autoCompact verifies writes trigger compaction once superseded records pass the
+// configured share of the file.
+func TestLogStorage_autoCompact(t *testing.T) {
+	ctx := context.Background()
+	st := openLog(t, filepath.Join(t.TempDir(), "vault.log"), storage.LogOptions{CompactRatio: 0.5, CompactMinBytes: 512})
+	assert.Ok(t, st.Create(ctx, "ns", "row", make([]byte, 64)))
+	for range 100 {
+		assert.Ok(t, st.Replace(ctx, "ns", "row", make([]byte, 64)))
+	}
+
+	stats := st.Stats()
+	assert.True(t, stats.FileBytes < 1024, "file not compacted: %d bytes", stats.FileBytes)
+	assert.Equal(t, 1, stats.Rows)
+}
+
+// TestLogStorage_locked verifies a second open of the same file fails while the first is open and
+// succeeds after it is closed.
+func TestLogStorage_locked(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("no file locking on " + runtime.GOOS)
+	}
+	path := filepath.Join(t.TempDir(), "vault.log")
+	st := openLog(t, path, storage.LogOptions{})
+
+	_, err := storage.OpenLogStorage(path, storage.LogOptions{})
+	assert.ErrorIs(t, err, verrors.ErrStorageLocked)
+
+	// Compaction moves the lock to the new file.
+	assert.Ok(t, st.Compact(context.Background()))
+	_, err = storage.OpenLogStorage(path, storage.LogOptions{})
+	assert.ErrorIs(t, err, verrors.ErrStorageLocked)
+
+	assert.Ok(t, st.Close())
+	openLog(t, path, storage.LogOptions{})
+}
+
+// TestLogStorage_closed verifies every operation fails after Close and that Close is idempotent.
+func TestLogStorage_closed(t *testing.T) {
+	ctx := context.Background()
+	st := openLog(t, filepath.Join(t.TempDir(), "vault.log"), storage.LogOptions{})
+	assert.Ok(t, st.Create(ctx, "ns", "id", []byte("x")))
+	assert.Ok(t, st.Close())
+	assert.Ok(t, st.Close())
+
+	assert.ErrorIs(t, st.Create(ctx, "ns", "other", []byte("x")), verrors.ErrStorageClosed)
+	_, err := st.Get(ctx, "ns", "id")
+	assert.ErrorIs(t, err, verrors.ErrStorageClosed)
+	assert.ErrorIs(t, st.Replace(ctx, "ns", "id", []byte("y")), verrors.ErrStorageClosed)
+	assert.ErrorIs(t, st.Delete(ctx, "ns", "id"), verrors.ErrStorageClosed)
+	assert.ErrorIs(t, st.CompareAndSwap(ctx, "ns", "id", []byte("x"), []byte("y")), verrors.ErrStorageClosed)
+	_, _, err = st.ListIDs(ctx, "ns", "", 0)
+	assert.ErrorIs(t, err, verrors.ErrStorageClosed)
+	assert.ErrorIs(t, st.Compact(ctx), verrors.ErrStorageClosed)
+}
+
+func openLog(tb testing.TB, path string, opts storage.LogOptions) *storage.LogStorage {
+	tb.Helper()
+	st, err := storage.OpenLogStorage(path, opts)
+	assert.Ok(tb, err)
+	tb.Cleanup(func() { st.Close() })
+	return st
+}
+
+func assertRow(tb testing.TB, st storage.Storage, namespace, id, want string) {
+	tb.Helper()
+	got, err := st.Get(context.Background(), namespace, id)
+	assert.Ok(tb, err, "get %q/%q", namespace, id)
+	assert.Equal(tb, []byte(want), got)
+}
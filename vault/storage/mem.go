@@ -17,8 +17,12 @@ type MemStorage struct {
 	m sync.Map // mapKey -> string (opaque blob)
 }
 
-// MemStorage implements [Storage].
-var _ Storage = (*MemStorage)(nil)
+// MemStorage implements [Storage], [Lister], and [Batcher].
+var (
+	_ Storage = (*MemStorage)(nil)
+	_ Lister  = (*MemStorage)(nil)
+	_ Batcher = (*MemStorage)(nil)
+)
 
 // NewMemStorage returns an empty [MemStorage] ready for use.
 func NewMemStorage() *MemStorage {
@@ -88,6 +92,73 @@ func (s *MemStorage) CompareAndSwap(ctx context.Context, namespace, id string, o
 	return verrors.ErrCASFailed
 }
 
+// ListIDs returns up to limit ids in namespace sorting after cursor, in byte-wise ascending order.
+// Each call scans every row, so it suits tests and small stores rather than large ones.
+func (s *MemStorage) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var ids []string
+	s.m.Range(func(key, _ any) bool {
+		if k := key.(mapKey); k.ns == namespace {
+			ids = append(ids, k.id)
+		}
+		return true
+	})
+
+	ids, next := pageIDs(ids, cursor, limit)
+	return ids, next, nil
+}
+
+// CreateMany inserts every row or none. On a duplicate (namespace, id) the rows already inserted
+// are removed again and [verrors.ErrDuplicateKey] is returned; concurrent readers may briefly see
+// them before they are removed.
+func (s *MemStorage) CreateMany(ctx context.Context, namespace string, rows []Row) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		k := mapKey{ns: namespace, id: row.ID}
+		if _, loaded := s.m.LoadOrStore(k, string(row.Ciphertext)); loaded {
+			// Only remove values this batch stored, in case another writer has since replaced one.
+			for _, done := range rows[:i] {
+				s.m.CompareAndDelete(mapKey{ns: namespace, id: done.ID}, string(done.Ciphertext))
+			}
+			return verrors.ErrDuplicateKey
+		}
+	}
+	return nil
+}
+
+// GetMany returns a fresh copy of each blob, in the order of ids, with nil for missing rows.
+func (s *MemStorage) GetMany(ctx context.Context, namespace string, ids []string) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([][]byte, len(ids))
+	for i, id := range ids {
+		if v, ok := s.m.Load(mapKey{ns: namespace, id: id}); ok {
+			out[i] = []byte(v.(string))
+		}
+	}
+	return out, nil
+}
+
+// DeleteMany removes every listed row that is present.
+func (s *MemStorage) DeleteMany(ctx context.Context, namespace string, ids []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		s.m.Delete(mapKey{ns: namespace, id: id})
+	}
+	return nil
+}
+
 // BypassSemanticsSetBlobForTest overwrites the stored blob for (namespace, id)
 // without checking row existence or duplicate semantics; for tests that need a
 // corrupt or synthetic ciphertext. The name is long and obtuse to discourage
@@ -1,8 +1,10 @@
 package storage_test
 
 import (
+	"context"
 	"testing"
 
+	"go.rtnl.ai/x/assert"
 	"go.rtnl.ai/x/vault/identifier"
 	"go.rtnl.ai/x/vault/storage"
 	"go.rtnl.ai/x/vault/vaulttest"
@@ -16,3 +18,64 @@ func TestMemStorage_compliance(t *testing.T) {
 		return storage.NewMemStorage()
 	})
 }
+
+// TestMemStorage_lister runs [vaulttest.ListerConforms] against [storage.MemStorage].
+func TestMemStorage_lister(t *testing.T) {
+	vaulttest.ListerConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return storage.NewMemStorage()
+	})
+}
+
+// TestMemStorage_batcher runs [vaulttest.BatcherConforms] against [storage.MemStorage].
+func TestMemStorage_batcher(t *testing.T) {
+	vaulttest.BatcherConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return storage.NewMemStorage()
+	})
+}
+
+// TestMemStorage_concurrency runs [vaulttest.StorageConcurrencyConforms] against [storage.MemStorage].
+func TestMemStorage_concurrency(t *testing.T) {
+	vaulttest.StorageConcurrencyConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return storage.NewMemStorage()
+	})
+}
+
+// BenchmarkMemStorage runs [vaulttest.StorageBenchmarks] against [storage.MemStorage].
+func BenchmarkMemStorage(b *testing.B) {
+	vaulttest.StorageBenchmarks(b, identifier.HexIdentifier{}, func(*testing.B) storage.Storage {
+		return storage.NewMemStorage()
+	})
+}
+
+// TestIDs verifies [storage.IDs] walks every page and stops early when the caller breaks.
+func TestIDs(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	for _, id := range []string{"c", "a", "e", "b", "d"} {
+		assert.Ok(t, st.Create(ctx, "ns", id, []byte(id)))
+	}
+
+	var got []string
+	for id, err := range storage.IDs(ctx, st, "ns", 2) {
+		assert.Ok(t, err)
+		got = append(got, id)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, got)
+
+	got = got[:0]
+	for id := range storage.IDs(ctx, st, "ns", 2) {
+		if got = append(got, id); len(got) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	for _, err := range storage.IDs(canceled, st, "ns", 2) {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
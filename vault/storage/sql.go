@@ -0,0 +1,500 @@
+package storage
+
+// database/sql-backed [Storage] with dialect-aware queries and a schema migration helper.
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+)
+
+// DefaultSQLTable is the table name used by [NewSQLStorage] when [SQLOptions.Table] is empty.
+const DefaultSQLTable = "vault_secrets"
+
+// Dialect selects placeholder syntax and column types for [SQLStorage]. The zero value is
+// [DialectSQLite].
+type Dialect uint8
+
+const (
+	DialectSQLite   Dialect = iota // ? placeholders, BLOB ciphertext
+	DialectPostgres                // $n placeholders, BYTEA ciphertext
+	DialectMySQL                   // ? placeholders, VARBINARY keys and LONGBLOB ciphertext
+)
+
+// String returns a stable name for the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case DialectSQLite:
+		return "sqlite"
+	case DialectPostgres:
+		return "postgres"
+	case DialectMySQL:
+		return "mysql"
+	default:
+		return "unknown"
+	}
+}
+
+// SQLOptions configures [NewSQLStorage].
+type SQLOptions struct {
+	// Table is the table holding sealed rows; defaults to [DefaultSQLTable]. It may be schema
+	// qualified ("vault.secrets") but must otherwise be a plain identifier because it is
+	// interpolated into statements.
+	Table string
+
+	// Dialect selects placeholders and DDL column types.
+	Dialect Dialect
+
+	// IsUniqueViolation optionally classifies driver errors from INSERT as primary key
+	// violations. When nil, a best-effort check recognizes SQLSTATE 23505 (via a SQLState
+	// method) and common driver messages. Either way, a failed insert on a row that exists is
+	// reported as [verrors.ErrDuplicateKey].
+	IsUniqueViolation func(error) bool
+}
+
+// SQLStorage is a [Storage] that persists rows through [database/sql] in a single table keyed by
+// (namespace, id). It works with any driver for the supported [Dialect] values; no driver is
+// imported by this package. Use [SQLStorage.Migrate] (or execute [SQLStorage.Migrations] with your
+// own tooling) to create the table before use.
+//
+// Failures are mapped to the vault sentinels: unique violations to [verrors.ErrDuplicateKey],
+// zero-row updates to [verrors.ErrNotFound] or [verrors.ErrCASFailed], and all other driver errors
+// are joined with [verrors.ErrStorage].
+type SQLStorage struct {
+	db       *sql.DB
+	table    string
+	dialect  Dialect
+	isUnique func(error) bool
+
+	getSQL, createSQL, replaceSQL, deleteSQL, casSQL, listSQL string
+}
+
+// SQLStorage implements [Storage], [Lister], and [Batcher].
+var (
+	_ Storage = (*SQLStorage)(nil)
+	_ Lister  = (*SQLStorage)(nil)
+	_ Batcher = (*SQLStorage)(nil)
+)
+
+// sqlBatchIDs bounds the ids bound into one IN list by [SQLStorage.GetMany] and
+// [SQLStorage.DeleteMany], keeping statements under the drivers' placeholder limits.
+const sqlBatchIDs = 500
+
+// sqlTableName restricts table names to (schema-qualified) identifiers since they cannot be bound
+// as parameters.
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// NewSQLStorage returns a [SQLStorage] using db. A nil db yields [verrors.ErrInvalidNewArgs]; a
+// table name that is not a plain identifier yields [verrors.ErrInvalidTableName].
+func NewSQLStorage(db *sql.DB, opts SQLOptions) (*SQLStorage, error) {
+	if db == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+
+	table := opts.Table
+	if table == "" {
+		table = DefaultSQLTable
+	}
+	if !sqlTableName.MatchString(table) {
+		return nil, verrors.ErrInvalidTableName
+	}
+
+	switch opts.Dialect {
+	case DialectSQLite, DialectPostgres, DialectMySQL:
+	default:
+		return nil, verrors.ErrInvalidNewArgs
+	}
+
+	s := &SQLStorage{
+		db:       db,
+		table:    table,
+		dialect:  opts.Dialect,
+		isUnique: opts.IsUniqueViolation,
+	}
+	if s.isUnique == nil {
+		s.isUnique = isUniqueViolation
+	}
+
+	s.getSQL = s.bind("SELECT ciphertext FROM " + table + " WHERE namespace = ? AND id = ?")
+	s.createSQL = s.bind("INSERT INTO " + table + " (namespace, id, ciphertext) VALUES (?, ?, ?)")
+	s.replaceSQL = s.bind("UPDATE " + table + " SET ciphertext = ? WHERE namespace = ? AND id = ?")
+	s.deleteSQL = s.bind("DELETE FROM " + table + " WHERE namespace = ? AND id = ?")
+	s.casSQL = s.bind("UPDATE " + table + " SET ciphertext = ? WHERE namespace = ? AND id = ? AND ciphertext = ?")
+	s.listSQL = s.bind("SELECT id FROM " + table + " WHERE namespace = ? AND id > ? ORDER BY id LIMIT ?")
+	return s, nil
+}
+
+// Table returns the configured table name.
+func (s *SQLStorage) Table() string {
+	return s.table
+}
+
+//=============================================================================
+// Schema
+//=============================================================================
+
+// Migrations returns the ordered DDL statements that create the storage table for the configured
+// dialect. Every statement is idempotent (IF NOT EXISTS), so the list can be applied on each start
+// or copied into an external migration tool.
+func (s *SQLStorage) Migrations() []string {
+	var keyType, blobType string
+	switch s.dialect {
+	case DialectPostgres:
+		keyType, blobType = "TEXT", "BYTEA"
+	case DialectMySQL:
+		keyType, blobType = "VARBINARY(255)", "LONGBLOB"
+	default:
+		keyType, blobType = "TEXT", "BLOB"
+	}
+
+	return []string{
+		"CREATE TABLE IF NOT EXISTS " + s.table + " (" +
+			"namespace " + keyType + " NOT NULL, " +
+			"id " + keyType + " NOT NULL, " +
+			"ciphertext " + blobType + " NOT NULL, " +
+			"PRIMARY KEY (namespace, id))",
+	}
+}
+
+// Migrate applies [SQLStorage.Migrations] in a single transaction. Failures are joined with
+// [verrors.ErrStorage].
+func (s *SQLStorage) Migrate(ctx context.Context) (err error) {
+	var tx *sql.Tx
+	if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, stmt := range s.Migrations() {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
+
+//=============================================================================
+// Storage
+//=============================================================================
+
+// Create inserts a new row; duplicate (namespace, id) returns [verrors.ErrDuplicateKey].
+func (s *SQLStorage) Create(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	if _, err := s.db.ExecContext(ctx, s.createSQL, namespace, id, ciphertext); err != nil {
+		if s.isUnique(err) {
+			return verrors.ErrDuplicateKey
+		}
+
+		// Drivers without a recognizable unique violation still fail the insert; if the row is
+		// there now, the primary key is what rejected it.
+		if _, gerr := s.Get(ctx, namespace, id); gerr == nil {
+			return verrors.ErrDuplicateKey
+		}
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
+
+// Get returns the stored blob or [verrors.ErrNotFound].
+func (s *SQLStorage) Get(ctx context.Context, namespace, id string) ([]byte, error) {
+	var ciphertext []byte
+	if err := s.db.QueryRowContext(ctx, s.getSQL, namespace, id).Scan(&ciphertext); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, verrors.ErrNotFound
+		}
+		return nil, errors.Join(verrors.ErrStorage, err)
+	}
+	return ciphertext, nil
+}
+
+// Replace overwrites ciphertext for an existing row; missing row returns [verrors.ErrNotFound].
+func (s *SQLStorage) Replace(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	n, err := s.exec(ctx, s.replaceSQL, ciphertext, namespace, id)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		// Zero rows means the row is missing, or (MySQL) the value was unchanged.
+		if _, err := s.Get(ctx, namespace, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a row if present; a missing row returns nil.
+func (s *SQLStorage) Delete(ctx context.Context, namespace, id string) error {
+	_, err := s.exec(ctx, s.deleteSQL, namespace, id)
+	return err
+}
+
+// CompareAndSwap sets newCiphertext only when the stored blob equals oldCiphertext, using a single
+// conditional UPDATE. Wrong old value returns [verrors.ErrCASFailed]; missing row returns
+// [verrors.ErrNotFound].
+func (s *SQLStorage) CompareAndSwap(ctx context.Context, namespace, id string, oldCiphertext, newCiphertext []byte) error {
+	n, err := s.exec(ctx, s.casSQL, newCiphertext, namespace, id, oldCiphertext)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// Zero rows: decide between a missing row and a lost race.
+	cur, err := s.Get(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+
+	// MySQL reports zero affected rows when the value did not change (old == new).
+	if bytes.Equal(cur, oldCiphertext) && bytes.Equal(oldCiphertext, newCiphertext) {
+		return nil
+	}
+	return verrors.ErrCASFailed
+}
+
+// ListIDs returns up to limit ids in namespace sorting after cursor, using the primary key index.
+// Order follows the column collation (byte-wise for the MySQL VARBINARY schema and for SQLite; the
+// database collation for Postgres TEXT), which is also what the cursor comparison uses, so paging
+// is consistent either way. Driver errors are joined with [verrors.ErrStorage].
+func (s *SQLStorage) ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	var rows *sql.Rows
+	if rows, err = s.db.QueryContext(ctx, s.listSQL, namespace, cursor, limit+1); err != nil {
+		return nil, "", errors.Join(verrors.ErrStorage, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, "", errors.Join(verrors.ErrStorage, err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", errors.Join(verrors.ErrStorage, err)
+	}
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+		next = ids[limit-1]
+	}
+	return ids, next, nil
+}
+
+//=============================================================================
+// Batcher
+//=============================================================================
+
+// CreateMany inserts every row in one transaction, reusing a single prepared statement. A duplicate
+// (namespace, id) rolls the transaction back and returns [verrors.ErrDuplicateKey].
+func (s *SQLStorage) CreateMany(ctx context.Context, namespace string, rows []Row) (err error) {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var tx *sql.Tx
+	if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var stmt *sql.Stmt
+	if stmt, err = tx.PrepareContext(ctx, s.createSQL); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, namespace, row.ID, row.Ciphertext); err != nil {
+			if s.isUnique(err) {
+				return verrors.ErrDuplicateKey
+			}
+
+			// As in Create: once rolled back, a row that exists is what rejected the insert.
+			// Failed transactions reject further statements on some databases, so check outside it.
+			tx.Rollback()
+			if _, gerr := s.Get(ctx, namespace, row.ID); gerr == nil {
+				return verrors.ErrDuplicateKey
+			}
+			return errors.Join(verrors.ErrStorage, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
+
+// GetMany returns one blob per id, in the order given and with nil for missing rows, selecting up to
+// [sqlBatchIDs] rows per query.
+func (s *SQLStorage) GetMany(ctx context.Context, namespace string, ids []string) ([][]byte, error) {
+	found := make(map[string][]byte, len(ids))
+	for chunk := range slices.Chunk(ids, sqlBatchIDs) {
+		if err := s.getChunk(ctx, namespace, chunk, found); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([][]byte, len(ids))
+	for i, id := range ids {
+		out[i] = found[id]
+	}
+	return out, nil
+}
+
+// getChunk selects the rows for one chunk of ids into found.
+func (s *SQLStorage) getChunk(ctx context.Context, namespace string, ids []string, found map[string][]byte) error {
+	query, args := s.inList("SELECT id, ciphertext FROM "+s.table+" WHERE namespace = ? AND id IN ", namespace, ids)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id         string
+			ciphertext []byte
+		)
+		if err = rows.Scan(&id, &ciphertext); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+		found[id] = ciphertext
+	}
+	if err = rows.Err(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
+
+// DeleteMany removes the listed rows in one transaction, deleting up to [sqlBatchIDs] rows per
+// statement. Missing rows are ignored.
+func (s *SQLStorage) DeleteMany(ctx context.Context, namespace string, ids []string) (err error) {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var tx *sql.Tx
+	if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for chunk := range slices.Chunk(ids, sqlBatchIDs) {
+		query, args := s.inList("DELETE FROM "+s.table+" WHERE namespace = ? AND id IN ", namespace, chunk)
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			return errors.Join(verrors.ErrStorage, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Join(verrors.ErrStorage, err)
+	}
+	return nil
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// inList completes prefix with a parenthesized placeholder list for ids and returns the bound query
+// with its arguments (namespace first).
+func (s *SQLStorage) inList(prefix, namespace string, ids []string) (string, []any) {
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, namespace)
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	sb.WriteByte('(')
+	for i, id := range ids {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('?')
+		args = append(args, id)
+	}
+	sb.WriteByte(')')
+	return s.bind(sb.String()), args
+}
+
+// exec runs a statement and returns the number of affected rows; errors are joined with
+// [verrors.ErrStorage].
+func (s *SQLStorage) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Join(verrors.ErrStorage, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Join(verrors.ErrStorage, err)
+	}
+	return n, nil
+}
+
+// bind rewrites ? placeholders to the dialect's syntax ($1, $2, … for Postgres).
+func (s *SQLStorage) bind(query string) string {
+	if s.dialect != DialectPostgres {
+		return query
+	}
+
+	var (
+		sb strings.Builder
+		n  int
+	)
+	sb.Grow(len(query) + 8)
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// isUniqueViolation is the default [SQLOptions.IsUniqueViolation]: SQLSTATE 23505 (Postgres and
+// other standards-following drivers) or the messages produced by common SQLite and MySQL drivers.
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) && state.SQLState() == "23505" {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint") ||
+		strings.Contains(msg, "duplicate key") ||
+		strings.Contains(msg, "duplicate entry")
+}
@@ -0,0 +1,537 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestSQLStorage_compliance runs [vaulttest.StorageConforms] against [storage.SQLStorage] for each
+// dialect, backed by the in-process fake driver below.
+func TestSQLStorage_compliance(t *testing.T) {
+	dialects := []storage.Dialect{storage.DialectSQLite, storage.DialectPostgres, storage.DialectMySQL}
+	for _, dialect := range dialects {
+		t.Run(dialect.String(), func(t *testing.T) {
+			vaulttest.StorageConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+				tb.Helper()
+				db := newFakeSQL()
+				db.mysqlAffected = dialect == storage.DialectMySQL
+				return testSQLStorage(tb, db, storage.SQLOptions{Dialect: dialect})
+			})
+		})
+	}
+}
+
+// TestSQLStorage_lister runs [vaulttest.ListerConforms] against [storage.SQLStorage] for each dialect.
+func TestSQLStorage_lister(t *testing.T) {
+	dialects := []storage.Dialect{storage.DialectSQLite, storage.DialectPostgres, storage.DialectMySQL}
+	for _, dialect := range dialects {
+		t.Run(dialect.String(), func(t *testing.T) {
+			vaulttest.ListerConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+				tb.Helper()
+				return testSQLStorage(tb, newFakeSQL(), storage.SQLOptions{Dialect: dialect})
+			})
+		})
+	}
+}
+
+// TestSQLStorage_concurrency runs [vaulttest.StorageConcurrencyConforms] against [storage.SQLStorage],
+// which relies on the database's conditional UPDATE for compare-and-swap.
+func TestSQLStorage_concurrency(t *testing.T) {
+	vaulttest.StorageConcurrencyConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		return testSQLStorage(tb, newFakeSQL(), storage.SQLOptions{Dialect: storage.DialectPostgres})
+	})
+}
+
+// TestSQLStorage_batcher runs [vaulttest.BatcherConforms] against [storage.SQLStorage] for each dialect.
+func TestSQLStorage_batcher(t *testing.T) {
+	dialects := []storage.Dialect{storage.DialectSQLite, storage.DialectPostgres, storage.DialectMySQL}
+	for _, dialect := range dialects {
+		t.Run(dialect.String(), func(t *testing.T) {
+			vaulttest.BatcherConforms(t, identifier.HexIdentifier{}, func(tb *testing.T) storage.Storage {
+				tb.Helper()
+				return testSQLStorage(tb, newFakeSQL(), storage.SQLOptions{Dialect: dialect})
+			})
+		})
+	}
+}
+
+// TestNewSQLStorage covers constructor validation.
+func TestNewSQLStorage(t *testing.T) {
+	db := sql.OpenDB(newFakeSQL())
+	t.Cleanup(func() { db.Close() })
+
+	_, err := storage.NewSQLStorage(nil, storage.SQLOptions{})
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+
+	_, err = storage.NewSQLStorage(db, storage.SQLOptions{Dialect: storage.Dialect(42)})
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+
+	for _, bad := range []string{"1table", "t; DROP TABLE x", "a.b.c", "t-1", `"quoted"`} {
+		_, err = storage.NewSQLStorage(db, storage.SQLOptions{Table: bad})
+		assert.ErrorIs(t, err, verrors.ErrInvalidTableName, "table %q", bad)
+	}
+
+	st, err := storage.NewSQLStorage(db, storage.SQLOptions{})
+	assert.Ok(t, err)
+	assert.Equal(t, storage.DefaultSQLTable, st.Table())
+
+	st, err = storage.NewSQLStorage(db, storage.SQLOptions{Table: "vault.secrets"})
+	assert.Ok(t, err)
+	assert.Equal(t, "vault.secrets", st.Table())
+}
+
+// TestSQLStorage_Migrations checks the DDL per dialect and that Migrate is idempotent.
+func TestSQLStorage_Migrations(t *testing.T) {
+	tests := []struct {
+		dialect storage.Dialect
+		want    string
+	}{
+		{storage.DialectSQLite, "CREATE TABLE IF NOT EXISTS secrets (namespace TEXT NOT NULL, id TEXT NOT NULL, ciphertext BLOB NOT NULL, PRIMARY KEY (namespace, id))"},
+		{storage.DialectPostgres, "CREATE TABLE IF NOT EXISTS secrets (namespace TEXT NOT NULL, id TEXT NOT NULL, ciphertext BYTEA NOT NULL, PRIMARY KEY (namespace, id))"},
+		{storage.DialectMySQL, "CREATE TABLE IF NOT EXISTS secrets (namespace VARBINARY(255) NOT NULL, id VARBINARY(255) NOT NULL, ciphertext LONGBLOB NOT NULL, PRIMARY KEY (namespace, id))"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.dialect.String(), func(t *testing.T) {
+			st := testSQLStorage(t, newFakeSQL(), storage.SQLOptions{Table: "secrets", Dialect: tc.dialect})
+			assert.Equal(t, []string{tc.want}, st.Migrations())
+			assert.Ok(t, st.Migrate(context.Background()))
+		})
+	}
+}
+
+// TestSQLStorage_noTable verifies driver errors are joined with [verrors.ErrStorage] when the
+// table has not been migrated.
+func TestSQLStorage_noTable(t *testing.T) {
+	db := sql.OpenDB(newFakeSQL())
+	t.Cleanup(func() { db.Close() })
+
+	st, err := storage.NewSQLStorage(db, storage.SQLOptions{})
+	assert.Ok(t, err)
+
+	_, err = st.Get(context.Background(), "ns", "id")
+	assert.ErrorIs(t, err, verrors.ErrStorage)
+	err = st.Create(context.Background(), "ns", "id", []byte("x"))
+	assert.ErrorIs(t, err, verrors.ErrStorage)
+}
+
+// TestSQLStorage_duplicateClassification verifies duplicate inserts map to [verrors.ErrDuplicateKey]
+// whether the driver error is recognized, classified by a custom hook, or opaque.
+func TestSQLStorage_duplicateClassification(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("opaque_driver_error", func(t *testing.T) {
+		db := newFakeSQL()
+		db.dupErr = errors.New("constraint failed")
+		st := testSQLStorage(t, db, storage.SQLOptions{})
+		assert.Ok(t, st.Create(ctx, "ns", "id", []byte("a")))
+		assert.ErrorIs(t, st.Create(ctx, "ns", "id", []byte("b")), verrors.ErrDuplicateKey)
+	})
+
+	t.Run("sqlstate", func(t *testing.T) {
+		db := newFakeSQL()
+		db.dupErr = sqlStateErr("23505")
+		st := testSQLStorage(t, db, storage.SQLOptions{Dialect: storage.DialectPostgres})
+		assert.Ok(t, st.Create(ctx, "ns", "id", []byte("a")))
+		assert.ErrorIs(t, st.Create(ctx, "ns", "id", []byte("b")), verrors.ErrDuplicateKey)
+	})
+
+	t.Run("custom_hook", func(t *testing.T) {
+		db := newFakeSQL()
+		db.dupErr = errors.New("E1062")
+		var called bool
+		st := testSQLStorage(t, db, storage.SQLOptions{IsUniqueViolation: func(err error) bool {
+			called = true
+			return strings.Contains(err.Error(), "E1062")
+		}})
+		assert.Ok(t, st.Create(ctx, "ns", "id", []byte("a")))
+		assert.ErrorIs(t, st.Create(ctx, "ns", "id", []byte("b")), verrors.ErrDuplicateKey)
+		assert.True(t, called)
+	})
+}
+
+// TestSQLStorage_mysqlUnchanged verifies Replace and CompareAndSwap succeed when MySQL-style
+// drivers report zero affected rows for an unchanged value.
+func TestSQLStorage_mysqlUnchanged(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeSQL()
+	db.mysqlAffected = true
+	st := testSQLStorage(t, db, storage.SQLOptions{Dialect: storage.DialectMySQL})
+
+	assert.Ok(t, st.Create(ctx, "ns", "id", []byte("same")))
+	assert.Ok(t, st.Replace(ctx, "ns", "id", []byte("same")))
+	assert.Ok(t, st.CompareAndSwap(ctx, "ns", "id", []byte("same"), []byte("same")))
+	assert.ErrorIs(t, st.CompareAndSwap(ctx, "ns", "id", []byte("other"), []byte("other")), verrors.ErrCASFailed)
+}
+
+// TestSQLStorage_batchChunks verifies GetMany and DeleteMany split large id lists across statements.
+func TestSQLStorage_batchChunks(t *testing.T) {
+	ctx := context.Background()
+	st := testSQLStorage(t, newFakeSQL(), storage.SQLOptions{Dialect: storage.DialectPostgres})
+
+	rows := make([]storage.Row, 1234)
+	ids := make([]string, len(rows))
+	for i := range rows {
+		ids[i] = strconv.Itoa(i)
+		rows[i] = storage.Row{ID: ids[i], Ciphertext: []byte("blob-" + ids[i])}
+	}
+	assert.Ok(t, st.CreateMany(ctx, "ns", rows))
+
+	got, err := st.GetMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	assert.Len(t, got, len(ids))
+	for i, blob := range got {
+		assert.Equal(t, rows[i].Ciphertext, blob)
+	}
+
+	assert.Ok(t, st.DeleteMany(ctx, "ns", ids))
+	got, err = st.GetMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	for _, blob := range got {
+		assert.Nil(t, blob)
+	}
+}
+
+//=============================================================================
+// Test helpers and fakes
+//=============================================================================
+
+// testSQLStorage opens db, builds a [storage.SQLStorage], and migrates it.
+func testSQLStorage(tb testing.TB, fake *fakeSQL, opts storage.SQLOptions) *storage.SQLStorage {
+	tb.Helper()
+	db := sql.OpenDB(fake)
+	tb.Cleanup(func() { db.Close() })
+
+	st, err := storage.NewSQLStorage(db, opts)
+	assert.Ok(tb, err)
+	assert.Ok(tb, st.Migrate(context.Background()))
+	return st
+}
+
+// sqlStateErr is a driver error exposing a SQLSTATE code like pgx and lib/pq errors do.
+type sqlStateErr string
+
+func (e sqlStateErr) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateErr) SQLState() string { return string(e) }
+
+// fakeSQL is a tiny in-process database/sql driver that understands exactly the statements
+// [storage.SQLStorage] issues (with ? or $n placeholders). It is a [driver.Connector] so each test
+// gets an isolated database without global driver registration.
+type fakeSQL struct {
+	mu     sync.Mutex
+	tables map[string]map[fakeKey][]byte
+
+	// dupErr is returned for primary key violations (default mimics SQLite).
+	dupErr error
+
+	// mysqlAffected counts only changed rows in UPDATE, like MySQL without CLIENT_FOUND_ROWS.
+	mysqlAffected bool
+}
+
+type fakeKey struct{ ns, id string }
+
+func newFakeSQL() *fakeSQL {
+	return &fakeSQL{
+		tables: make(map[string]map[fakeKey][]byte),
+		dupErr: errors.New("UNIQUE constraint failed: vault_secrets.namespace, vault_secrets.id"),
+	}
+}
+
+func (f *fakeSQL) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeSQL) Driver() driver.Driver                        { return fakeDriver{f} }
+
+type fakeDriver struct{ db *fakeSQL }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{db: d.db}, nil }
+
+var (
+	fakePlaceholder = regexp.MustCompile(`\$[0-9]+`)
+	fakeCreateTable = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\S+) \(`)
+	fakeSelect      = regexp.MustCompile(`^SELECT ciphertext FROM (\S+) WHERE namespace = \? AND id = \?$`)
+	fakeInsert      = regexp.MustCompile(`^INSERT INTO (\S+) \(namespace, id, ciphertext\) VALUES \(\?, \?, \?\)$`)
+	fakeUpdate      = regexp.MustCompile(`^UPDATE (\S+) SET ciphertext = \? WHERE namespace = \? AND id = \?( AND ciphertext = \?)?$`)
+	fakeDelete      = regexp.MustCompile(`^DELETE FROM (\S+) WHERE namespace = \? AND id = \?$`)
+	fakeList        = regexp.MustCompile(`^SELECT id FROM (\S+) WHERE namespace = \? AND id > \? ORDER BY id LIMIT \?$`)
+	fakeSelectIn    = regexp.MustCompile(`^SELECT id, ciphertext FROM (\S+) WHERE namespace = \? AND id IN \(\?(, \?)*\)$`)
+	fakeDeleteIn    = regexp.MustCompile(`^DELETE FROM (\S+) WHERE namespace = \? AND id IN \(\?(, \?)*\)$`)
+)
+
+type fakeConn struct {
+	db *fakeSQL
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c: c, q: query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+
+// Begin snapshots all tables; Rollback restores the snapshot.
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	snap := make(map[string]map[fakeKey][]byte, len(c.db.tables))
+	for name, rows := range c.db.tables {
+		snap[name] = maps.Clone(rows)
+	}
+	c.tx = &fakeTx{c: c, snap: snap}
+	return c.tx, nil
+}
+
+type fakeTx struct {
+	c    *fakeConn
+	snap map[string]map[fakeKey][]byte
+}
+
+func (t *fakeTx) Commit() error { t.c.tx = nil; return nil }
+
+func (t *fakeTx) Rollback() error {
+	t.c.db.mu.Lock()
+	defer t.c.db.mu.Unlock()
+	t.c.db.tables = t.snap
+	t.c.tx = nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query, args)
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, args)
+}
+
+type fakeStmt struct {
+	c *fakeConn
+	q string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.db.exec(s.q, named(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.db.query(s.q, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return out
+}
+
+// normalize maps $n placeholders to ? and collapses whitespace.
+func (f *fakeSQL) normalize(query string) string {
+	return strings.Join(strings.Fields(fakePlaceholder.ReplaceAllString(query, "?")), " ")
+}
+
+func (f *fakeSQL) table(name string) (map[fakeKey][]byte, error) {
+	rows, ok := f.tables[name]
+	if !ok {
+		return nil, errors.New("no such table: " + name)
+	}
+	return rows, nil
+}
+
+func (f *fakeSQL) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.normalize(query)
+
+	if m := fakeCreateTable.FindStringSubmatch(q); m != nil {
+		if _, ok := f.tables[m[1]]; !ok {
+			f.tables[m[1]] = make(map[fakeKey][]byte)
+		}
+		return driver.RowsAffected(0), nil
+	}
+
+	if m := fakeInsert.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		k := fakeKey{str(args[0]), str(args[1])}
+		if _, dup := rows[k]; dup {
+			return nil, f.dupErr
+		}
+		rows[k] = blob(args[2])
+		return driver.RowsAffected(1), nil
+	}
+
+	if m := fakeUpdate.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		k := fakeKey{str(args[1]), str(args[2])}
+		cur, ok := rows[k]
+		if !ok {
+			return driver.RowsAffected(0), nil
+		}
+		if m[2] != "" && !bytes.Equal(cur, blob(args[3])) {
+			return driver.RowsAffected(0), nil
+		}
+		next := blob(args[0])
+		rows[k] = next
+		if f.mysqlAffected && bytes.Equal(cur, next) {
+			return driver.RowsAffected(0), nil
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	if m := fakeDelete.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		k := fakeKey{str(args[0]), str(args[1])}
+		if _, ok := rows[k]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		delete(rows, k)
+		return driver.RowsAffected(1), nil
+	}
+
+	if m := fakeDeleteIn.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		for _, arg := range args[1:] {
+			k := fakeKey{str(args[0]), str(arg)}
+			if _, ok := rows[k]; ok {
+				delete(rows, k)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+
+	return nil, errors.New("fake: unsupported exec: " + q)
+}
+
+func (f *fakeSQL) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.normalize(query)
+
+	if m := fakeSelect.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		v, ok := rows[fakeKey{str(args[0]), str(args[1])}]
+		if !ok {
+			return &fakeRows{cols: []string{"ciphertext"}}, nil
+		}
+		return &fakeRows{cols: []string{"ciphertext"}, vals: [][]driver.Value{{bytes.Clone(v)}}}, nil
+	}
+
+	if m := fakeList.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		ns, after := str(args[0]), str(args[1])
+		var ids []string
+		for k := range rows {
+			if k.ns == ns && k.id > after {
+				ids = append(ids, k.id)
+			}
+		}
+		slices.Sort(ids)
+		if limit := int(num(args[2])); len(ids) > limit {
+			ids = ids[:limit]
+		}
+		out := &fakeRows{cols: []string{"id"}}
+		for _, id := range ids {
+			out.vals = append(out.vals, []driver.Value{id})
+		}
+		return out, nil
+	}
+
+	if m := fakeSelectIn.FindStringSubmatch(q); m != nil {
+		rows, err := f.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		out := &fakeRows{cols: []string{"id", "ciphertext"}}
+		seen := make(map[string]bool)
+		for _, arg := range args[1:] {
+			id := str(arg)
+			if v, ok := rows[fakeKey{str(args[0]), id}]; ok && !seen[id] {
+				seen[id] = true
+				out.vals = append(out.vals, []driver.Value{id, bytes.Clone(v)})
+			}
+		}
+		return out, nil
+	}
+
+	return nil, errors.New("fake: unsupported query: " + q)
+}
+
+type fakeRows struct {
+	cols []string
+	vals [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.vals[0])
+	r.vals = r.vals[1:]
+	return nil
+}
+
+func str(v driver.NamedValue) string {
+	switch t := v.Value.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	default:
+		return ""
+	}
+}
+
+func blob(v driver.NamedValue) []byte {
+	switch t := v.Value.(type) {
+	case []byte:
+		return bytes.Clone(t)
+	case string:
+		return []byte(t)
+	default:
+		return nil
+	}
+}
+
+func num(v driver.NamedValue) int64 {
+	n, _ := v.Value.(int64)
+	return n
+}
@@ -1,8 +1,11 @@
 /*
 Package storage defines the [Storage] interface for opaque sealed vault rows and reusable implementations
-for tests and small programs (notably [MemStorage]).
+for tests and small programs (notably [MemStorage], the directory-backed [FileStorage], the single-file [LogStorage], and the
+database/sql-backed [SQLStorage]).
 
-[Storage] abstracts persistence keyed by (namespace, id). Ciphertext values are opaque blobs produced by the
+[Storage] abstracts persistence keyed by (namespace, id). Backends that can enumerate rows also implement the
+optional [Lister] interface; all implementations in this package do. Backends that can apply many rows in one
+transaction implement [Batcher]; [MemStorage] and [SQLStorage] do. Ciphertext values are opaque blobs produced by the
 vault; implementations should map driver-specific failures to the stable sentinels in package
 	go.rtnl.ai/x/vault/errors (not found, duplicate key, CAS failed, storage) where practical.
 */
@@ -28,3 +31,36 @@ type Storage interface {
 	// value returns CAS-failed and a missing row returns not-found.
 	CompareAndSwap(ctx context.Context, namespace, id string, oldCiphertext, newCiphertext []byte) error
 }
+
+// Lister is an optional interface for [Storage] implementations that can enumerate the ids stored in a
+// namespace. Callers discover it with a type assertion.
+type Lister interface {
+	// ListIDs returns up to limit ids in namespace, in ascending order, that sort strictly after
+	// cursor; an empty cursor starts at the beginning. next is the cursor for the following page and
+	// is empty once no ids remain. A limit of zero or less uses [DefaultListLimit]. Rows created or
+	// deleted between pages may or may not be observed, but no row present for the whole walk is
+	// skipped or repeated. Listing assumes non-empty ids, as minted by an identifier.
+	ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error)
+}
+
+// Batcher is an optional interface for [Storage] implementations that can apply many rows of one
+// namespace in a single transaction or query, saving a round trip per row. Each method is all or
+// nothing: on error no row was created or deleted. Callers discover it with a type assertion and
+// fall back to per-row calls when it is missing.
+type Batcher interface {
+	// CreateMany inserts every row, or none; if any (namespace, id) already exists, or appears twice
+	// in rows, the whole batch fails with the duplicate key sentinel.
+	CreateMany(ctx context.Context, namespace string, rows []Row) error
+
+	// GetMany returns one blob per id, in the order given, with nil for ids that have no row.
+	GetMany(ctx context.Context, namespace string, ids []string) (ciphertexts [][]byte, err error)
+
+	// DeleteMany removes every listed row that is present; missing rows are ignored.
+	DeleteMany(ctx context.Context, namespace string, ids []string) error
+}
+
+// Row is one row in a [Batcher.CreateMany] batch.
+type Row struct {
+	ID         string
+	Ciphertext []byte
+}
@@ -15,7 +15,8 @@ import (
 )
 
 // Vault embeds a [rtvault.Vault] and enforces UTF-8 on string plaintext at this API boundary.
-// [MoveNamespace] and [Delete] are promoted from the embedded vault.
+// [MoveNamespace] and [Delete] are promoted from the embedded vault; [Vault.ListIDs] forwards to it
+// when it implements [rtvault.Lister].
 type Vault struct {
 	rtvault.Vault
 }
@@ -68,3 +69,13 @@ func (w *Vault) CompareAndSwap(ctx context.Context, namespace, id string, curren
 	}
 	return w.Vault.CompareAndSwap(ctx, namespace, id, []byte(currentPlain), []byte(newPlain))
 }
+
+// ListIDs lists row ids via the inner vault's [rtvault.Lister], or returns [verrors.ErrListUnsupported]
+// if the inner vault cannot list.
+func (w *Vault) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	lister, ok := w.Vault.(rtvault.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
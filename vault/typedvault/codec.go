@@ -0,0 +1,146 @@
+package typedvault
+
+// Built-in codecs: encoding/json, encoding.BinaryMarshaler, and encoding/gob.
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+
+	"go.rtnl.ai/x/vault/jsonvault"
+)
+
+// Codec converts values of type T to and from plaintext bytes and decides when two values are
+// equal for [Vault.CompareAndSwap]. Implementations must be safe for concurrent use.
+type Codec[T any] interface {
+	// Marshal encodes v.
+	Marshal(v T) ([]byte, error)
+
+	// Unmarshal decodes data produced by Marshal. It must not retain data: the vault zeroes the
+	// decrypted plaintext once Unmarshal returns.
+	Unmarshal(data []byte) (T, error)
+
+	// Equal reports whether a and b are the same value for this codec, which may be looser than
+	// byte equality of their encodings.
+	Equal(a, b T) (bool, error)
+}
+
+// Compile-time checks.
+var (
+	_ Codec[struct{}] = JSON[struct{}]{}
+	_ Codec[struct{}] = Gob[struct{}]{}
+)
+
+//=============================================================================
+// JSON
+//=============================================================================
+
+// JSON encodes values with [encoding/json]. Two values are equal when they marshal to the same JSON
+// ([jsonvault.EqualJSON]), so map order and unexported fields do not matter.
+type JSON[T any] struct{}
+
+// Marshal encodes v with [json.Marshal].
+func (JSON[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes data with [json.Unmarshal].
+func (JSON[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// Equal reports whether a and b marshal to identical JSON.
+func (JSON[T]) Equal(a, b T) (bool, error) {
+	return jsonvault.EqualJSON(a, b)
+}
+
+//=============================================================================
+// Binary
+//=============================================================================
+
+// Binary encodes values whose pointer type PT implements [encoding.BinaryMarshaler] and
+// [encoding.BinaryUnmarshaler]; PT is inferred from T, so write Binary[Token, *Token]{}. Two values
+// are equal when their binary encodings are.
+type Binary[T any, PT interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}] struct{}
+
+// Marshal encodes v with its MarshalBinary method.
+func (Binary[T, PT]) Marshal(v T) ([]byte, error) {
+	return PT(&v).MarshalBinary()
+}
+
+// Unmarshal decodes data into a zero T with its UnmarshalBinary method.
+func (Binary[T, PT]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := PT(&v).UnmarshalBinary(data)
+	return v, err
+}
+
+// Equal reports whether a and b have identical binary encodings.
+func (c Binary[T, PT]) Equal(a, b T) (bool, error) {
+	ab, err := c.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	bb, err := c.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ab, bb), nil
+}
+
+//=============================================================================
+// Gob
+//=============================================================================
+
+// Gob encodes values with [encoding/gob], one self-describing stream per row. Gob does not encode
+// map entries in a stable order, so two values are equal when they are deeply equal
+// ([reflect.DeepEqual]) after a round trip through gob, which also treats nil and empty slices and
+// maps alike, as gob does.
+type Gob[T any] struct{}
+
+// Marshal encodes v as a gob stream.
+func (Gob[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a gob stream produced by Marshal.
+func (Gob[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// Equal reports whether a and b are deeply equal after a gob round trip.
+func (c Gob[T]) Equal(a, b T) (bool, error) {
+	ra, err := c.roundTrip(a)
+	if err != nil {
+		return false, err
+	}
+	rb, err := c.roundTrip(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(ra, rb), nil
+}
+
+// roundTrip encodes and decodes v.
+func (c Gob[T]) roundTrip(v T) (T, error) {
+	data, err := c.Marshal(v)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.Unmarshal(data)
+}
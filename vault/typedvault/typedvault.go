@@ -0,0 +1,227 @@
+/*
+Package typedvault wraps [rtvault.Vault] with a generic, statically typed API: [Vault] of T stores,
+retrieves, updates, and compares values of T, converting them to plaintext bytes with a [Codec].
+Built-in codecs cover [encoding/json] ([JSON]), types implementing [encoding.BinaryMarshaler]
+([Binary]), and [encoding/gob] ([Gob]); rows remain opaque ciphertext in the storage backend.
+
+Unlike [go.rtnl.ai/x/vault/jsonvault], which takes [any], callers get compile-time checks and no
+type assertions:
+
+	tokens := typedvault.New(v, typedvault.JSON[Token]{})
+	id, err := tokens.Store(ctx, "tokens", Token{Scope: "read"})
+	tok, err := tokens.Retrieve(ctx, "tokens", id) // tok is a Token
+*/
+package typedvault
+
+// Typed payloads on top of [rtvault.Vault] through a pluggable [Codec].
+
+import (
+	"context"
+	"errors"
+
+	rtvault "go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+)
+
+// Vault embeds a [rtvault.Vault] and exposes the same operation names with values of T instead of
+// plaintext bytes. [MoveNamespace] and [Delete] are promoted from the embedded vault; [Vault.ListIDs]
+// forwards to it when it implements [rtvault.Lister]. [Vault.StoreMany], [Vault.RetrieveMany], and
+// [Vault.DeleteMany] batch through the inner vault's [rtvault.Batcher] when it has one. Codec
+// failures are joined with [verrors.ErrCodecMarshal] or [verrors.ErrCodecUnmarshal]; vault errors
+// are returned as is.
+type Vault[T any] struct {
+	rtvault.Vault
+	codec Codec[T]
+}
+
+// New wraps a non-nil [rtvault.Vault] (for example from [go.rtnl.ai/x/vault/v1.New]) with a non-nil
+// codec.
+func New[T any](v rtvault.Vault, codec Codec[T]) *Vault[T] {
+	if v == nil || codec == nil {
+		panic("typedvault: New(nil)")
+	}
+	return &Vault[T]{Vault: v, codec: codec}
+}
+
+// Codec returns the codec the vault encodes values with.
+func (w *Vault[T]) Codec() Codec[T] {
+	return w.codec
+}
+
+// Store encodes value and stores it via the inner [rtvault.Vault.Store].
+func (w *Vault[T]) Store(ctx context.Context, namespace string, value T) (string, error) {
+	b, err := w.marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return w.Vault.Store(ctx, namespace, b)
+}
+
+// Retrieve decrypts the row via the inner [rtvault.Vault.Retrieve] and decodes it. On error the
+// zero T is returned.
+func (w *Vault[T]) Retrieve(ctx context.Context, namespace, id string) (T, error) {
+	b, err := w.Vault.Retrieve(ctx, namespace, id)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer keys.Zero(b)
+	return w.unmarshal(b)
+}
+
+// Update encodes value and replaces the row via the inner [rtvault.Vault.Update].
+func (w *Vault[T]) Update(ctx context.Context, namespace, id string, value T) error {
+	b, err := w.marshal(value)
+	if err != nil {
+		return err
+	}
+	return w.Vault.Update(ctx, namespace, id, b)
+}
+
+// CompareAndSwap replaces the row with next only if its current value equals current according to
+// [Codec.Equal], so rows match structurally even when their encoding differs from current's (for
+// example JSON written by another tool, or gob maps encoded in another order). The row is read and
+// decoded, compared, and then swapped through the inner [rtvault.Vault.CompareAndSwap] against the
+// exact plaintext that was read, so a concurrent write in between still fails the swap. A value that
+// does not match yields [verrors.ErrWrongCurrent].
+func (w *Vault[T]) CompareAndSwap(ctx context.Context, namespace, id string, current, next T) error {
+	nextPlain, err := w.marshal(next)
+	if err != nil {
+		return err
+	}
+
+	stored, err := w.Vault.Retrieve(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+	defer keys.Zero(stored)
+
+	value, err := w.unmarshal(stored)
+	if err != nil {
+		return err
+	}
+
+	equal, err := w.codec.Equal(value, current)
+	if err != nil {
+		return errors.Join(verrors.ErrCodecMarshal, err)
+	}
+	if !equal {
+		return verrors.ErrWrongCurrent
+	}
+	return w.Vault.CompareAndSwap(ctx, namespace, id, stored, nextPlain)
+}
+
+// ListIDs lists row ids via the inner vault's [rtvault.Lister], or returns [verrors.ErrListUnsupported]
+// if the inner vault cannot list.
+func (w *Vault[T]) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	lister, ok := w.Vault.(rtvault.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
+
+// BatchResult is the outcome of one item in a typed batch call; see [rtvault.BatchResult].
+type BatchResult[T any] struct {
+	ID    string // StoreMany: the new id, empty on failure; otherwise the requested id
+	Value T      // RetrieveMany only; the zero T when Err is set
+	Err   error  // nil on success
+}
+
+// StoreMany encodes values and stores them with [rtvault.StoreMany], which uses the inner vault's
+// [rtvault.Batcher] or falls back to one Store per value. A value that fails to encode gets its own
+// [verrors.ErrCodecMarshal] result and is not stored; the rest are still written. Like a Batcher,
+// it returns one result per value, in order, and [verrors.ErrBatchIncomplete] when any item failed.
+func (w *Vault[T]) StoreMany(ctx context.Context, namespace string, values []T) ([]BatchResult[T], error) {
+	results := make([]BatchResult[T], len(values))
+	plaintexts := make([][]byte, 0, len(values))
+	index := make([]int, 0, len(values))
+	for i, value := range values {
+		b, err := w.marshal(value)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		plaintexts = append(plaintexts, b)
+		index = append(index, i)
+	}
+
+	stored, err := rtvault.StoreMany(ctx, w.Vault, namespace, plaintexts)
+	if len(stored) != len(plaintexts) {
+		if err == nil {
+			err = verrors.ErrBatchIncomplete
+		}
+		return nil, err
+	}
+	for j, res := range stored {
+		results[index[j]].ID, results[index[j]].Err = res.ID, res.Err
+	}
+	return results, batchErr(results)
+}
+
+// RetrieveMany decrypts rows with [rtvault.RetrieveMany] and decodes each one; a row that fails to
+// decode gets its own [verrors.ErrCodecUnmarshal] result. Plaintexts are zeroed once decoded.
+func (w *Vault[T]) RetrieveMany(ctx context.Context, namespace string, ids []string) ([]BatchResult[T], error) {
+	retrieved, err := rtvault.RetrieveMany(ctx, w.Vault, namespace, ids)
+	if len(retrieved) != len(ids) {
+		for _, res := range retrieved {
+			keys.Zero(res.Plaintext)
+		}
+		if err == nil {
+			err = verrors.ErrBatchIncomplete
+		}
+		return nil, err
+	}
+
+	results := make([]BatchResult[T], len(ids))
+	for i, res := range retrieved {
+		results[i].ID, results[i].Err = res.ID, res.Err
+		if res.Err == nil {
+			results[i].Value, results[i].Err = w.unmarshal(res.Plaintext)
+		}
+		keys.Zero(res.Plaintext)
+	}
+	return results, batchErr(results)
+}
+
+// DeleteMany deletes rows with [rtvault.DeleteMany]. No codec is involved, so the results are
+// those of the inner vault.
+func (w *Vault[T]) DeleteMany(ctx context.Context, namespace string, ids []string) ([]BatchResult[T], error) {
+	deleted, err := rtvault.DeleteMany(ctx, w.Vault, namespace, ids)
+	if deleted == nil {
+		return nil, err
+	}
+	results := make([]BatchResult[T], len(deleted))
+	for i, res := range deleted {
+		results[i].ID, results[i].Err = res.ID, res.Err
+	}
+	return results, err
+}
+
+func (w *Vault[T]) marshal(value T) ([]byte, error) {
+	b, err := w.codec.Marshal(value)
+	if err != nil {
+		return nil, errors.Join(verrors.ErrCodecMarshal, err)
+	}
+	return b, nil
+}
+
+func (w *Vault[T]) unmarshal(b []byte) (T, error) {
+	value, err := w.codec.Unmarshal(b)
+	if err != nil {
+		var zero T
+		return zero, errors.Join(verrors.ErrCodecUnmarshal, err)
+	}
+	return value, nil
+}
+
+// batchErr returns [verrors.ErrBatchIncomplete] if any result failed.
+func batchErr[T any](results []BatchResult[T]) error {
+	for _, res := range results {
+		if res.Err != nil {
+			return verrors.ErrBatchIncomplete
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package typedvault_test
+
+// Tests typedvault codecs and typed operations on top of [vaulttest.TestVault].
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	rtvault "go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	"go.rtnl.ai/x/vault/typedvault"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+type account struct {
+	Name  string            `json:"name"`
+	Roles []string          `json:"roles"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// TestVault_roundtrip stores, retrieves, and updates a value through each built-in codec.
+func TestVault_roundtrip(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		roundtrip(t, typedvault.JSON[account]{},
+			account{Name: "alice", Roles: []string{"admin"}, Tags: map[string]string{"team": "ops"}},
+			account{Name: "alice", Roles: []string{"reader"}})
+	})
+	t.Run("binary", func(t *testing.T) {
+		roundtrip(t, typedvault.Binary[counter, *counter]{}, counter{ID: 7, Count: 1}, counter{ID: 7, Count: 2})
+	})
+	t.Run("gob", func(t *testing.T) {
+		roundtrip(t, typedvault.Gob[account]{},
+			account{Name: "bob", Roles: []string{"ops"}, Tags: map[string]string{"a": "1", "b": "2"}},
+			account{Name: "bob", Tags: map[string]string{"a": "1"}})
+	})
+}
+
+func roundtrip[T any](t *testing.T, codec typedvault.Codec[T], first, second T) {
+	t.Helper()
+	ctx := context.Background()
+	v := typedvault.New(newTestVault(t), codec)
+
+	id, err := v.Store(ctx, "ns", first)
+	assert.Ok(t, err)
+	got, err := v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, first, got)
+
+	assert.Ok(t, v.Update(ctx, "ns", id, second))
+	got, err = v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, second, got)
+
+	ids, _, err := v.ListIDs(ctx, "ns", "", 10)
+	assert.Ok(t, err)
+	assert.Equal(t, []string{id}, ids)
+}
+
+// TestVault_CompareAndSwap checks structural equality: a row written with a different encoding of
+// the expected value still swaps, and a different value does not.
+func TestVault_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	inner := newTestVault(t)
+	v := typedvault.New(inner, typedvault.JSON[account]{})
+
+	// Another writer stored the row with different spacing and key order.
+	id, err := inner.Store(ctx, "ns", []byte(`{ "tags": {"b": "2", "a": "1"}, "roles": ["x"], "name": "carol" }`))
+	assert.Ok(t, err)
+	current := account{Name: "carol", Roles: []string{"x"}, Tags: map[string]string{"a": "1", "b": "2"}}
+
+	err = v.CompareAndSwap(ctx, "ns", id, account{Name: "mallory"}, account{Name: "eve"})
+	assert.ErrorIs(t, err, verrors.ErrWrongCurrent)
+
+	next := account{Name: "carol", Roles: []string{"x", "y"}}
+	assert.Ok(t, v.CompareAndSwap(ctx, "ns", id, current, next))
+	got, err := v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, next, got)
+
+	// The old value no longer matches.
+	assert.ErrorIs(t, v.CompareAndSwap(ctx, "ns", id, current, next), verrors.ErrWrongCurrent)
+
+	// Missing rows fail as the inner vault does.
+	err = v.CompareAndSwap(ctx, "ns", "0123456789abcdef0123456789abcdef", current, next)
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+}
+
+// TestGob_Equal verifies gob equality ignores map order and nil versus empty collections.
+func TestGob_Equal(t *testing.T) {
+	codec := typedvault.Gob[account]{}
+	a := account{Name: "x", Roles: []string{}, Tags: map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"}}
+	b := account{Name: "x", Tags: map[string]string{"k3": "v3", "k2": "v2", "k1": "v1"}}
+
+	equal, err := codec.Equal(a, b)
+	assert.Ok(t, err)
+	assert.True(t, equal)
+
+	b.Tags["k1"] = "changed"
+	equal, err = codec.Equal(a, b)
+	assert.Ok(t, err)
+	assert.False(t, equal)
+}
+
+// TestVault_codecErrors verifies encode and decode failures are joined with the codec sentinels.
+func TestVault_codecErrors(t *testing.T) {
+	ctx := context.Background()
+	inner := newTestVault(t)
+
+	chans := typedvault.New(inner, typedvault.JSON[chan int]{})
+	_, err := chans.Store(ctx, "ns", make(chan int))
+	assert.ErrorIs(t, err, verrors.ErrCodecMarshal)
+	assert.ErrorIs(t, chans.Update(ctx, "ns", "0123456789abcdef0123456789abcdef", make(chan int)), verrors.ErrCodecMarshal)
+
+	id, err := inner.Store(ctx, "ns", []byte("not json"))
+	assert.Ok(t, err)
+	accounts := typedvault.New(inner, typedvault.JSON[account]{})
+	got, err := accounts.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, verrors.ErrCodecUnmarshal)
+	assert.Equal(t, account{}, got)
+	err = accounts.CompareAndSwap(ctx, "ns", id, account{}, account{Name: "x"})
+	assert.ErrorIs(t, err, verrors.ErrCodecUnmarshal)
+
+	counters := typedvault.New(inner, typedvault.Binary[counter, *counter]{})
+	_, err = counters.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, verrors.ErrCodecUnmarshal)
+	assert.ErrorIs(t, err, errCounterLength)
+
+	_, err = accounts.Retrieve(ctx, "ns", "0123456789abcdef0123456789abcdef")
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+	assert.False(t, errors.Is(err, verrors.ErrCodecUnmarshal))
+}
+
+// TestVault_batch verifies typed batches round trip values and report codec failures per item.
+func TestVault_batch(t *testing.T) {
+	ctx := context.Background()
+	inner := newTestVault(t)
+	accounts := typedvault.New(inner, typedvault.JSON[account]{})
+
+	want := []account{{Name: "a", Roles: []string{"admin"}}, {Name: "b"}}
+	stored, err := accounts.StoreMany(ctx, "ns", want)
+	assert.Ok(t, err)
+	assert.Len(t, stored, 2)
+
+	ids := []string{stored[0].ID, stored[1].ID}
+	got, err := accounts.RetrieveMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	assert.Equal(t, want[0], got[0].Value)
+	assert.Equal(t, want[1], got[1].Value)
+
+	// A value that cannot be encoded fails alone; the rest are stored.
+	values := typedvault.New(inner, typedvault.JSON[any]{})
+	mixed, err := values.StoreMany(ctx, "ns", []any{make(chan int), 1})
+	assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+	assert.ErrorIs(t, mixed[0].Err, verrors.ErrCodecMarshal)
+	assert.Equal(t, "", mixed[0].ID)
+	assert.Ok(t, mixed[1].Err)
+
+	// A row that cannot be decoded fails alone.
+	junk, err := inner.Store(ctx, "ns", []byte("not json"))
+	assert.Ok(t, err)
+	got, err = accounts.RetrieveMany(ctx, "ns", []string{ids[0], junk})
+	assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+	assert.Equal(t, want[0], got[0].Value)
+	assert.ErrorIs(t, got[1].Err, verrors.ErrCodecUnmarshal)
+
+	deleted, err := accounts.DeleteMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	assert.Len(t, deleted, 2)
+	got, err = accounts.RetrieveMany(ctx, "ns", ids)
+	assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+	assert.ErrorIs(t, got[0].Err, verrors.ErrNotFound)
+}
+
+// TestVault_withoutLister verifies ListIDs reports an inner vault that cannot list, and that New
+// rejects nil arguments.
+func TestVault_withoutLister(t *testing.T) {
+	v := typedvault.New(struct{ rtvault.Vault }{newTestVault(t)}, typedvault.JSON[account]{})
+	_, _, err := v.ListIDs(context.Background(), "ns", "", 1)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+	assert.Equal(t, typedvault.Codec[account](typedvault.JSON[account]{}), v.Codec())
+
+	assert.PanicsWithValue(t, "typedvault: New(nil)", func() { typedvault.New[account](nil, typedvault.JSON[account]{}) })
+	assert.PanicsWithValue(t, "typedvault: New(nil)", func() { typedvault.New[account](newTestVault(t), nil) })
+}
+
+func newTestVault(tb testing.TB) *vaulttest.TestVault {
+	tb.Helper()
+	return vaulttest.NewTestVault(tb, storage.NewMemStorage(), identifier.HexIdentifier{})
+}
+
+// counter is a fixed-width binary value for the [typedvault.Binary] codec.
+type counter struct {
+	ID    uint32
+	Count uint64
+}
+
+var errCounterLength = errors.New("counter: wrong length")
+
+func (c counter) MarshalBinary() ([]byte, error) {
+	b := binary.BigEndian.AppendUint32(nil, c.ID)
+	return binary.BigEndian.AppendUint64(b, c.Count), nil
+}
+
+func (c *counter) UnmarshalBinary(data []byte) error {
+	if len(data) != 12 {
+		return errCounterLength
+	}
+	c.ID = binary.BigEndian.Uint32(data)
+	c.Count = binary.BigEndian.Uint64(data[4:])
+	return nil
+}
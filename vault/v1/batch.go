@@ -0,0 +1,206 @@
+package v1
+
+// Batch variants of Store, Retrieve, and Delete ([vault.Batcher]).
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/storage"
+)
+
+// StoreMany seals each plaintext under a newly minted id and creates the rows in namespace,
+// returning one result per plaintext in order. Minting and sealing fail per item as in [Store].
+// When the storage implements [storage.Batcher] the sealed rows are created with a single
+// all-or-nothing CreateMany, so a storage failure fails every sealed item with the same error
+// joined with [verrors.ErrStorage]; otherwise each row is created on its own and fails on its own.
+// A failed item has an empty ID. If any item failed the error is [verrors.ErrBatchIncomplete]; a
+// nil vault returns [verrors.ErrNilVault] and no results. None of the rows expire.
+func (v *sealedVault) StoreMany(ctx context.Context, namespace string, plaintexts [][]byte) ([]vault.BatchResult, error) {
+	if v == nil {
+		return nil, verrors.ErrNilVault
+	}
+
+	results := make([]vault.BatchResult, len(plaintexts))
+	batcher, fast := v.st.(storage.Batcher)
+	rows := make([]storage.Row, 0, len(plaintexts))
+	sealed := make([]int, 0, len(plaintexts)) // index into results for each row
+
+	for i, plaintext := range plaintexts {
+		res := &results[i]
+		if res.Err = ctx.Err(); res.Err != nil {
+			continue
+		}
+
+		id, err := v.id.New()
+		if err != nil {
+			res.Err = errors.Join(verrors.ErrInvalidIdentifier, err)
+			continue
+		}
+
+		wire, err := v.sealPlaintext(namespace, plaintext, time.Time{})
+		if err != nil {
+			res.Err = err
+			continue
+		}
+
+		if !fast {
+			if err := v.st.Create(ctx, namespace, id, wire); err != nil {
+				res.Err = errors.Join(verrors.ErrStorage, err)
+				continue
+			}
+			res.ID = id
+			continue
+		}
+
+		res.ID = id
+		rows = append(rows, storage.Row{ID: id, Ciphertext: wire})
+		sealed = append(sealed, i)
+	}
+
+	if fast && len(rows) > 0 {
+		if err := batcher.CreateMany(ctx, namespace, rows); err != nil {
+			err = errors.Join(verrors.ErrStorage, err)
+			for _, i := range sealed {
+				results[i] = vault.BatchResult{Err: err}
+			}
+		}
+	}
+
+	for _, res := range results {
+		if res.Err == nil {
+			v.reportSealed(ctx, res.ID)
+		}
+	}
+	return results, batchErr(results)
+}
+
+// RetrieveMany loads and opens the rows for ids in namespace, returning one result per id in order.
+// Each item fails as [Retrieve] would: bad ids join [verrors.ErrInvalidIdentifier], missing rows
+// join [verrors.ErrStorage] and [verrors.ErrNotFound], and rows that do not open return envelope
+// errors. When the storage implements [storage.Batcher] all valid ids are read with one GetMany, so
+// a storage failure fails each of them; otherwise each row is read on its own. If any item failed
+// the error is [verrors.ErrBatchIncomplete]; a nil vault returns [verrors.ErrNilVault] and no
+// results.
+func (v *sealedVault) RetrieveMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	if v == nil {
+		return nil, verrors.ErrNilVault
+	}
+
+	results, valid := v.parseBatch(ids)
+	wires := make([][]byte, len(ids))
+
+	if batcher, ok := v.st.(storage.Batcher); ok {
+		if len(valid) > 0 {
+			validIDs := make([]string, len(valid))
+			for j, i := range valid {
+				validIDs[j] = ids[i]
+			}
+
+			blobs, err := batcher.GetMany(ctx, namespace, validIDs)
+			if err != nil {
+				err = errors.Join(verrors.ErrStorage, err)
+				for _, i := range valid {
+					results[i].Err = err
+				}
+				return results, verrors.ErrBatchIncomplete
+			}
+			for j, i := range valid {
+				if blobs[j] == nil {
+					results[i].Err = errors.Join(verrors.ErrStorage, verrors.ErrNotFound)
+					continue
+				}
+				wires[i] = blobs[j]
+			}
+		}
+	} else {
+		for _, i := range valid {
+			wire, err := v.st.Get(ctx, namespace, ids[i])
+			if err != nil {
+				results[i].Err = errors.Join(verrors.ErrStorage, err)
+				continue
+			}
+			wires[i] = wire
+		}
+	}
+
+	for _, i := range valid {
+		if results[i].Err != nil {
+			continue
+		}
+		plaintext, meta, err := v.openCiphertext(namespace, wires[i])
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Plaintext = plaintext
+		vault.ReportRowKeyID(ctx, ids[i], meta.KeyID)
+	}
+	return results, batchErr(results)
+}
+
+// DeleteMany removes the rows for ids in namespace, returning one result per id in order. Bad ids
+// join [verrors.ErrInvalidIdentifier] and missing rows succeed, as in [Delete]. When the storage
+// implements [storage.Batcher] all valid ids are deleted with one all-or-nothing DeleteMany, so a
+// storage failure fails each of them; otherwise each row is deleted on its own. If any item failed
+// the error is [verrors.ErrBatchIncomplete]; a nil vault returns [verrors.ErrNilVault] and no
+// results.
+func (v *sealedVault) DeleteMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	if v == nil {
+		return nil, verrors.ErrNilVault
+	}
+
+	results, valid := v.parseBatch(ids)
+
+	if batcher, ok := v.st.(storage.Batcher); ok {
+		if len(valid) > 0 {
+			validIDs := make([]string, len(valid))
+			for j, i := range valid {
+				validIDs[j] = ids[i]
+			}
+
+			if err := batcher.DeleteMany(ctx, namespace, validIDs); err != nil {
+				err = errors.Join(verrors.ErrStorage, err)
+				for _, i := range valid {
+					results[i].Err = err
+				}
+			}
+		}
+	} else {
+		for _, i := range valid {
+			if err := v.st.Delete(ctx, namespace, ids[i]); err != nil {
+				results[i].Err = errors.Join(verrors.ErrStorage, err)
+			}
+		}
+	}
+	return results, batchErr(results)
+}
+
+// parseBatch returns a result per id carrying the id, with parse failures already set, and the
+// indexes of the ids that parsed.
+func (v *sealedVault) parseBatch(ids []string) ([]vault.BatchResult, []int) {
+	results := make([]vault.BatchResult, len(ids))
+	valid := make([]int, 0, len(ids))
+	for i, id := range ids {
+		results[i].ID = id
+		if err := v.id.Parse(id); err != nil {
+			results[i].Err = errors.Join(verrors.ErrInvalidIdentifier, err)
+			continue
+		}
+		valid = append(valid, i)
+	}
+	return results, valid
+}
+
+// batchErr returns [verrors.ErrBatchIncomplete] if any result failed.
+func batchErr(results []vault.BatchResult) error {
+	for _, res := range results {
+		if res.Err != nil {
+			return verrors.ErrBatchIncomplete
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package v1_test
+
+// Tests for the [vault.Batcher] methods of [v1.Vault].
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+)
+
+// TestVault_batch runs the batch methods over storage with and without [storage.Batcher], so both
+// the fast path and the per-row fallback are covered.
+func TestVault_batch(t *testing.T) {
+	backends := []struct {
+		name string
+		st   func() storage.Storage
+	}{
+		{"batcher", func() storage.Storage { return storage.NewMemStorage() }},
+		{"fallback", func() storage.Storage { return struct{ storage.Storage }{storage.NewMemStorage()} }},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := backend.st()
+			v := newBatchVault(t, st)
+
+			plain := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+			stored, err := v.StoreMany(ctx, "ns", plain)
+			assert.Ok(t, err)
+			assert.Len(t, stored, len(plain))
+			for i, res := range stored {
+				assert.Ok(t, res.Err)
+				got, err := v.(vault.Vault).Retrieve(ctx, "ns", res.ID)
+				assert.Ok(t, err)
+				assert.Equal(t, plain[i], got)
+			}
+
+			// Retrieve mixes good, malformed, missing, and wrong-namespace ids.
+			const missing = "0123456789abcdef0123456789abcdef"
+			other, err := v.(vault.Vault).Store(ctx, "other", []byte("elsewhere"))
+			assert.Ok(t, err)
+			ids := []string{stored[1].ID, "not-hex", missing, stored[0].ID, other}
+
+			got, err := v.RetrieveMany(ctx, "ns", ids)
+			assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+			assert.Len(t, got, len(ids))
+			for i, res := range got {
+				assert.Equal(t, ids[i], res.ID)
+			}
+			assert.Ok(t, got[0].Err)
+			assert.Equal(t, []byte("two"), got[0].Plaintext)
+			assert.ErrorIs(t, got[1].Err, verrors.ErrInvalidIdentifier)
+			assert.ErrorIs(t, got[2].Err, verrors.ErrNotFound)
+			assert.ErrorIs(t, got[2].Err, verrors.ErrStorage)
+			assert.Ok(t, got[3].Err)
+			assert.Equal(t, []byte("one"), got[3].Plaintext)
+			assert.ErrorIs(t, got[4].Err, verrors.ErrNotFound)
+			assert.Nil(t, got[4].Plaintext)
+
+			// Delete ignores missing rows and leaves other rows alone.
+			deleted, err := v.DeleteMany(ctx, "ns", []string{stored[0].ID, missing, stored[2].ID})
+			assert.Ok(t, err)
+			assert.Len(t, deleted, 3)
+			for _, res := range deleted {
+				assert.Ok(t, res.Err)
+			}
+			left, err := v.RetrieveMany(ctx, "ns", []string{stored[0].ID, stored[1].ID, stored[2].ID})
+			assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+			assert.ErrorIs(t, left[0].Err, verrors.ErrNotFound)
+			assert.Ok(t, left[1].Err)
+			assert.ErrorIs(t, left[2].Err, verrors.ErrNotFound)
+
+			deleted, err = v.DeleteMany(ctx, "ns", []string{"bad"})
+			assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+			assert.ErrorIs(t, deleted[0].Err, verrors.ErrInvalidIdentifier)
+
+			// Empty batches succeed without touching storage.
+			for _, call := range []func() ([]vault.BatchResult, error){
+				func() ([]vault.BatchResult, error) { return v.StoreMany(ctx, "ns", nil) },
+				func() ([]vault.BatchResult, error) { return v.RetrieveMany(ctx, "ns", nil) },
+				func() ([]vault.BatchResult, error) { return v.DeleteMany(ctx, "ns", nil) },
+			} {
+				res, err := call()
+				assert.Ok(t, err)
+				assert.Len(t, res, 0)
+			}
+		})
+	}
+}
+
+// TestVault_batch_storageFailures verifies a failed batch storage call fails every item that reached
+// it, while the per-row fallback fails only the rows that failed.
+func TestVault_batch_storageFailures(t *testing.T) {
+	ctx := context.Background()
+	errBackend := errors.New("backend down")
+
+	t.Run("batcher", func(t *testing.T) {
+		st := &failingBatcher{MemStorage: storage.NewMemStorage(), err: errBackend}
+		v := newBatchVault(t, st)
+
+		stored, err := v.StoreMany(ctx, "ns", [][]byte{[]byte("a"), []byte("b")})
+		assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+		for _, res := range stored {
+			assert.ErrorIs(t, res.Err, verrors.ErrStorage)
+			assert.ErrorIs(t, res.Err, errBackend)
+			assert.Equal(t, "", res.ID)
+		}
+		ids, _, err := st.ListIDs(ctx, "ns", "", 0)
+		assert.Ok(t, err)
+		assert.Len(t, ids, 0)
+
+		id, err := v.(vault.Vault).Store(ctx, "ns", []byte("single"))
+		assert.Ok(t, err)
+		for _, call := range []func() ([]vault.BatchResult, error){
+			func() ([]vault.BatchResult, error) { return v.RetrieveMany(ctx, "ns", []string{id, "bad"}) },
+			func() ([]vault.BatchResult, error) { return v.DeleteMany(ctx, "ns", []string{id, "bad"}) },
+		} {
+			res, err := call()
+			assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+			assert.ErrorIs(t, res[0].Err, errBackend)
+			assert.ErrorIs(t, res[1].Err, verrors.ErrInvalidIdentifier)
+			assert.False(t, errors.Is(res[1].Err, errBackend))
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		mem := storage.NewMemStorage()
+		v := newBatchVault(t, &failingCreate{Storage: mem, failAt: 2, err: errBackend})
+
+		stored, err := v.StoreMany(ctx, "ns", [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+		assert.Ok(t, stored[0].Err)
+		assert.ErrorIs(t, stored[1].Err, errBackend)
+		assert.Equal(t, "", stored[1].ID)
+		assert.Ok(t, stored[2].Err)
+		ids, _, err := mem.ListIDs(ctx, "ns", "", 0)
+		assert.Ok(t, err)
+		assert.Len(t, ids, 2)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		v := newBatchVault(t, struct{ storage.Storage }{storage.NewMemStorage()})
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		stored, err := v.StoreMany(canceled, "ns", [][]byte{[]byte("a")})
+		assert.ErrorIs(t, err, verrors.ErrBatchIncomplete)
+		assert.ErrorIs(t, stored[0].Err, context.Canceled)
+	})
+
+	t.Run("nil_vault", func(t *testing.T) {
+		nv := v1.NilSealedVault.(vault.Batcher)
+		_, err := nv.StoreMany(ctx, "ns", nil)
+		assert.ErrorIs(t, err, verrors.ErrNilVault)
+		_, err = nv.RetrieveMany(ctx, "ns", nil)
+		assert.ErrorIs(t, err, verrors.ErrNilVault)
+		_, err = nv.DeleteMany(ctx, "ns", nil)
+		assert.ErrorIs(t, err, verrors.ErrNilVault)
+	})
+}
+
+// newBatchVault returns a v1 vault over st as a [vault.Batcher].
+func newBatchVault(tb testing.TB, st storage.Storage) vault.Batcher {
+	tb.Helper()
+	v, err := v1.New(testX25519Key(tb), st, identifier.HexIdentifier{})
+	assert.Ok(tb, err)
+	b, ok := v.(vault.Batcher)
+	assert.True(tb, ok)
+	return b
+}
+
+// failingBatcher is a [storage.MemStorage] whose [storage.Batcher] methods always fail.
+type failingBatcher struct {
+	*storage.MemStorage
+	err error
+}
+
+func (s *failingBatcher) CreateMany(context.Context, string, []storage.Row) error { return s.err }
+
+func (s *failingBatcher) GetMany(context.Context, string, []string) ([][]byte, error) {
+	return nil, s.err
+}
+
+func (s *failingBatcher) DeleteMany(context.Context, string, []string) error { return s.err }
+
+// failingCreate is storage without [storage.Batcher] whose failAt-th Create (1-based) fails.
+type failingCreate struct {
+	storage.Storage
+	failAt, calls int
+	err           error
+}
+
+func (s *failingCreate) Create(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	if s.calls++; s.calls == s.failAt {
+		return s.err
+	}
+	return s.Storage.Create(ctx, namespace, id, ciphertext)
+}
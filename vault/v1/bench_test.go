@@ -0,0 +1,35 @@
+package v1_test
+
+// Benchmarks for the v1 seal and open paths.
+
+import (
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/v1/suite"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// BenchmarkVault runs [vaulttest.VaultBenchmarks] against a v1 vault over [storage.MemStorage] for
+// each cipher suite, so storage cost stays out of the numbers.
+func BenchmarkVault(b *testing.B) {
+	suites := []suite.ID{suite.X25519HKDFSHA256AES256GCM, suite.X25519HKDFSHA256XChaCha20Poly1305}
+	for _, id := range suites {
+		b.Run(id.String(), func(b *testing.B) {
+			kr, err := v1.NewKeyring(testX25519Key(b))
+			assert.Ok(b, err)
+			kr, err = kr.WithSuite(id)
+			assert.Ok(b, err)
+
+			vaulttest.VaultBenchmarks(b, func(b *testing.B) vault.Vault {
+				v, err := v1.NewWithKeyring(kr, storage.NewMemStorage(), identifier.HexIdentifier{})
+				assert.Ok(b, err)
+				return v
+			})
+		})
+	}
+}
@@ -0,0 +1,427 @@
+/*
+Package bundle defines a portable, versioned stream of sealed v1 rows for backups and for moving
+vaults between environments. A bundle carries (namespace, id, sealed blob) records exactly as they
+are stored; nothing is decrypted or re-encrypted, so the destination needs the same long-term key
+(or a keyring holding it) to open the rows.
+
+Every record and the closing manifest carry an HMAC-SHA256 tag chained over everything before them,
+so a reader verifies each record as it streams past and detects reordering, removal, or truncation
+at the manifest. The authentication key is supplied by the caller; [DeriveKey] derives one from the
+vault's X25519 private key so anyone able to open the rows can also check the bundle.
+
+Layout (integers big-endian):
+
+	header:   magic "VLTB" | version u8
+	record:   0x01 | nsLen u16 | namespace | idLen u16 | id | blobLen u32 | blob | tag[32]
+	manifest: 0x02 | nsCount u32 | (nsLen u16 | namespace | count u64)* | records u64 | tag[32]
+
+Manifest namespaces are sorted. Each tag is HMAC-SHA256(key, previous tag || frame bytes before the
+tag), and the first record chains from HMAC-SHA256(key, header). Nothing may follow the manifest.
+
+Use [Export], [Import], and [Verify] for whole-storage operations, or [Writer] and [Reader] to
+stream records directly.
+*/
+package bundle
+
+import (
+	"bufio"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/models"
+)
+
+const (
+	// Magic is the four-byte preamble of every bundle.
+	Magic = "VLTB"
+
+	// Version is the bundle format version written by this package.
+	Version uint8 = 1
+
+	// MinKeyBytes is the minimum authentication key length.
+	MinKeyBytes = 32
+
+	// TagBytes is the size of each HMAC-SHA256 tag.
+	TagBytes = sha256.Size
+
+	// MaxIDBytes is the largest id a record can carry.
+	MaxIDBytes = 0xffff
+
+	// MaxBlobBytes caps a single sealed blob so a corrupt length cannot force a huge allocation.
+	MaxBlobBytes = 16 << 20
+
+	kindRecord   byte = 0x01
+	kindManifest byte = 0x02
+
+	// hkdfKeyInfo is the HKDF context string for [DeriveKey]; it must stay stable across releases.
+	hkdfKeyInfo = "vault/v1/bundle/hmac-sha256"
+)
+
+// Record is one sealed row in a bundle.
+type Record struct {
+	Namespace string
+	ID        string
+	Blob      []byte // sealed v1 wire blob, as stored
+}
+
+// Manifest summarizes the records in a bundle.
+type Manifest struct {
+	Records    int            // total records
+	Namespaces map[string]int // records per namespace
+}
+
+// DeriveKey derives a bundle authentication key from a vault's long-term X25519 private key with
+// HKDF-SHA256. A nil key yields [verrors.ErrNilPrivateKey]; a non-X25519 key yields
+// [verrors.ErrInvalidWrappingKey].
+func DeriveKey(priv *ecdh.PrivateKey) ([]byte, error) {
+	if priv == nil {
+		return nil, verrors.ErrNilPrivateKey
+	}
+	if priv.Curve() != ecdh.X25519() {
+		return nil, verrors.ErrInvalidWrappingKey
+	}
+	return hkdf.Key(sha256.New, priv.Bytes(), nil, hkdfKeyInfo, MinKeyBytes)
+}
+
+//=============================================================================
+// Writer
+//=============================================================================
+
+// Writer streams records into a bundle. Call [Writer.Close] to write the manifest; a bundle
+// without one fails verification as truncated.
+type Writer struct {
+	w        io.Writer
+	chain    tagChain
+	manifest Manifest
+	closed   bool
+}
+
+// NewWriter writes the bundle header to w and returns a [Writer] that authenticates with key. A key
+// shorter than [MinKeyBytes] yields [v1errs.ErrBundleKey].
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	if len(key) < MinKeyBytes {
+		return nil, v1errs.ErrBundleKey
+	}
+
+	header := append([]byte(Magic), Version)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	bw := &Writer{w: w, manifest: Manifest{Namespaces: make(map[string]int)}}
+	bw.chain.init(key, header)
+	return bw, nil
+}
+
+// Write appends rec to the bundle. The blob must parse as a [models.Sealed] row sealed for
+// rec.Namespace, so a bundle never carries bytes the vault could not read back; otherwise the
+// parse error or [v1errs.ErrNamespaceMismatch] is returned and nothing is written.
+func (w *Writer) Write(rec Record) error {
+	if w.closed {
+		return v1errs.ErrBundleMalformed
+	}
+	if err := checkRecord(rec); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 0, 1+2+len(rec.Namespace)+2+len(rec.ID)+4+len(rec.Blob)+TagBytes)
+	frame = append(frame, kindRecord)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(rec.Namespace)))
+	frame = append(frame, rec.Namespace...)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(rec.ID)))
+	frame = append(frame, rec.ID...)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(rec.Blob)))
+	frame = append(frame, rec.Blob...)
+	frame = append(frame, w.chain.next(frame)...)
+
+	if _, err := w.w.Write(frame); err != nil {
+		return err
+	}
+
+	w.manifest.Records++
+	w.manifest.Namespaces[rec.Namespace]++
+	return nil
+}
+
+// Close writes the authenticated manifest and returns it. It does not close the underlying writer.
+func (w *Writer) Close() (Manifest, error) {
+	if w.closed {
+		return Manifest{}, v1errs.ErrBundleMalformed
+	}
+	w.closed = true
+
+	frame := appendManifest([]byte{kindManifest}, w.manifest)
+	frame = append(frame, w.chain.next(frame)...)
+	if _, err := w.w.Write(frame); err != nil {
+		return Manifest{}, err
+	}
+	return w.manifest, nil
+}
+
+//=============================================================================
+// Reader
+//=============================================================================
+
+// Reader streams records out of a bundle, verifying each record's tag before returning it.
+type Reader struct {
+	r        *bufio.Reader
+	chain    tagChain
+	manifest Manifest
+	done     bool
+}
+
+// NewReader reads and checks the bundle header from r. A key shorter than [MinKeyBytes] yields
+// [v1errs.ErrBundleKey]; a bad preamble yields [v1errs.ErrBundleMalformed] or
+// [v1errs.ErrBundleVersion].
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	if len(key) < MinKeyBytes {
+		return nil, v1errs.ErrBundleKey
+	}
+
+	br := &Reader{r: bufio.NewReader(r), manifest: Manifest{Namespaces: make(map[string]int)}}
+	header := make([]byte, len(Magic)+1)
+	if err := br.readFull(header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(Magic)]) != Magic {
+		return nil, v1errs.ErrBundleMalformed
+	}
+	if header[len(Magic)] != Version {
+		return nil, v1errs.ErrBundleVersion
+	}
+
+	br.chain.init(key, header)
+	return br, nil
+}
+
+// Next returns the next authenticated record. After the last record it verifies the manifest
+// against the records read and returns [io.EOF]; [Reader.Manifest] is then available.
+//
+// A stream that ends early yields [v1errs.ErrBundleTruncated]; a tag that does not verify yields
+// [v1errs.ErrBundleAuth]; framing errors, trailing bytes, or a manifest that disagrees with the
+// records yield [v1errs.ErrBundleMalformed]. A blob that does not parse as [models.Sealed] or was
+// sealed for another namespace is reported joined with [v1errs.ErrBundleMalformed].
+func (r *Reader) Next() (Record, error) {
+	if r.done {
+		return Record{}, io.EOF
+	}
+
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return Record{}, r.readErr(err)
+	}
+
+	switch kind {
+	case kindRecord:
+		return r.readRecord()
+	case kindManifest:
+		if err := r.readManifest(); err != nil {
+			return Record{}, err
+		}
+		r.done = true
+		return Record{}, io.EOF
+	default:
+		return Record{}, v1errs.ErrBundleMalformed
+	}
+}
+
+// Manifest returns the verified manifest once [Reader.Next] has returned [io.EOF], and false before.
+func (r *Reader) Manifest() (Manifest, bool) {
+	return r.manifest, r.done
+}
+
+// readRecord reads one record frame after its kind byte.
+func (r *Reader) readRecord() (Record, error) {
+	frame := []byte{kindRecord}
+
+	ns, frame, err := r.readField(frame, 2, MaxIDBytes)
+	if err != nil {
+		return Record{}, err
+	}
+	id, frame, err := r.readField(frame, 2, MaxIDBytes)
+	if err != nil {
+		return Record{}, err
+	}
+	blob, frame, err := r.readField(frame, 4, MaxBlobBytes)
+	if err != nil {
+		return Record{}, err
+	}
+	if err := r.verifyTag(frame); err != nil {
+		return Record{}, err
+	}
+
+	rec := Record{Namespace: string(ns), ID: string(id), Blob: blob}
+	if err := checkRecord(rec); err != nil {
+		return Record{}, errors.Join(v1errs.ErrBundleMalformed, err)
+	}
+
+	r.manifest.Records++
+	r.manifest.Namespaces[rec.Namespace]++
+	return rec, nil
+}
+
+// readManifest reads the manifest frame after its kind byte, checks it against the tallies of the
+// records already read, and requires the stream to end.
+func (r *Reader) readManifest() error {
+	frame := []byte{kindManifest}
+
+	var n [8]byte
+	if err := r.readFull(n[:4]); err != nil {
+		return err
+	}
+	frame = append(frame, n[:4]...)
+	count := int(binary.BigEndian.Uint32(n[:4]))
+	if count != len(r.manifest.Namespaces) {
+		return v1errs.ErrBundleMalformed
+	}
+
+	want := make(map[string]int, count)
+	for range count {
+		var ns []byte
+		var err error
+		if ns, frame, err = r.readField(frame, 2, MaxIDBytes); err != nil {
+			return err
+		}
+		if err := r.readFull(n[:]); err != nil {
+			return err
+		}
+		frame = append(frame, n[:]...)
+		want[string(ns)] = int(binary.BigEndian.Uint64(n[:]))
+	}
+
+	if err := r.readFull(n[:]); err != nil {
+		return err
+	}
+	frame = append(frame, n[:]...)
+	records := int(binary.BigEndian.Uint64(n[:]))
+
+	if err := r.verifyTag(frame); err != nil {
+		return err
+	}
+	if records != r.manifest.Records || !maps.Equal(want, r.manifest.Namespaces) {
+		return v1errs.ErrBundleMalformed
+	}
+
+	// Nothing may follow the manifest.
+	if _, err := r.r.ReadByte(); err != io.EOF {
+		return v1errs.ErrBundleMalformed
+	}
+	return nil
+}
+
+// readField reads a length-prefixed field (lenBytes of 2 or 4), appending the raw bytes to frame.
+func (r *Reader) readField(frame []byte, lenBytes, max int) (field, out []byte, err error) {
+	var n [4]byte
+	if err = r.readFull(n[:lenBytes]); err != nil {
+		return nil, frame, err
+	}
+	frame = append(frame, n[:lenBytes]...)
+
+	var size int
+	if lenBytes == 2 {
+		size = int(binary.BigEndian.Uint16(n[:2]))
+	} else {
+		size = int(binary.BigEndian.Uint32(n[:4]))
+	}
+	if size > max {
+		return nil, frame, v1errs.ErrBundleMalformed
+	}
+
+	field = make([]byte, size)
+	if err = r.readFull(field); err != nil {
+		return nil, frame, err
+	}
+	return field, append(frame, field...), nil
+}
+
+// verifyTag reads the tag following frame and checks it against the chain.
+func (r *Reader) verifyTag(frame []byte) error {
+	tag := make([]byte, TagBytes)
+	if err := r.readFull(tag); err != nil {
+		return err
+	}
+	if !hmac.Equal(tag, r.chain.next(frame)) {
+		return v1errs.ErrBundleAuth
+	}
+	return nil
+}
+
+// readFull fills buf, mapping a short stream to [v1errs.ErrBundleTruncated].
+func (r *Reader) readFull(buf []byte) error {
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return r.readErr(err)
+	}
+	return nil
+}
+
+// readErr maps end-of-stream errors to [v1errs.ErrBundleTruncated] and passes others through.
+func (r *Reader) readErr(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return v1errs.ErrBundleTruncated
+	}
+	return err
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// tagChain computes HMAC-SHA256 tags where each tag also covers the previous one.
+type tagChain struct {
+	mac  hash.Hash
+	prev []byte
+}
+
+// init seeds the chain with the tag of the header.
+func (c *tagChain) init(key, header []byte) {
+	c.mac = hmac.New(sha256.New, key)
+	c.mac.Write(header)
+	c.prev = c.mac.Sum(nil)
+}
+
+// next returns HMAC(key, prev || frame) and makes it the new previous tag.
+func (c *tagChain) next(frame []byte) []byte {
+	c.mac.Reset()
+	c.mac.Write(c.prev)
+	c.mac.Write(frame)
+	c.prev = c.mac.Sum(nil)
+	return c.prev
+}
+
+// checkRecord enforces field caps and that the blob is a v1 row sealed for rec.Namespace.
+func checkRecord(rec Record) error {
+	if len(rec.Namespace) > MaxIDBytes || len(rec.ID) > MaxIDBytes || len(rec.Blob) > MaxBlobBytes {
+		return v1errs.ErrBundleMalformed
+	}
+
+	var msg models.Sealed
+	if err := msg.UnmarshalBinary(rec.Blob); err != nil {
+		return err
+	}
+	if msg.Meta.Namespace != rec.Namespace {
+		return v1errs.ErrNamespaceMismatch
+	}
+	return nil
+}
+
+// appendManifest appends the manifest body (without kind byte or tag) to frame.
+func appendManifest(frame []byte, m Manifest) []byte {
+	namespaces := slices.Sorted(maps.Keys(m.Namespaces))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(namespaces)))
+	for _, ns := range namespaces {
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(ns)))
+		frame = append(frame, ns...)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(m.Namespaces[ns]))
+	}
+	return binary.BigEndian.AppendUint64(frame, uint64(m.Records))
+}
@@ -0,0 +1,244 @@
+package bundle_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/v1/bundle"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+)
+
+//=============================================================================
+// Tests: Export, Import, Verify
+//=============================================================================
+
+// TestExportImport_roundtrip exports two namespaces, verifies the bundle, imports it into empty
+// storage, and opens the rows there with the original key.
+func TestExportImport_roundtrip(t *testing.T) {
+	ctx := context.Background()
+	src, priv, rows := testSource(t)
+	key, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+
+	var buf bytes.Buffer
+	m, err := bundle.Export(ctx, &buf, src, []string{"a", "b", "a", "empty"}, key)
+	assert.Ok(t, err)
+	assert.Equal(t, bundle.Manifest{Records: 3, Namespaces: map[string]int{"a": 2, "b": 1}}, m)
+
+	vm, err := bundle.Verify(bytes.NewReader(buf.Bytes()), key)
+	assert.Ok(t, err)
+	assert.Equal(t, m, vm)
+
+	dst := storage.NewMemStorage()
+	sum, err := bundle.Import(ctx, bytes.NewReader(buf.Bytes()), dst, key, nil)
+	assert.Ok(t, err)
+	assert.Equal(t, bundle.ImportSummary{Manifest: m, Created: 3}, sum)
+
+	v, err := v1.New(priv, dst, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	for _, r := range rows {
+		got, err := v.Retrieve(ctx, r.ns, r.id)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte(r.plain), got)
+	}
+}
+
+// TestImport_conflicts covers the three conflict policies against a destination that already holds
+// a different blob for one of the rows.
+func TestImport_conflicts(t *testing.T) {
+	ctx := context.Background()
+	src, priv, rows := testSource(t)
+	key, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+
+	var buf bytes.Buffer
+	_, err = bundle.Export(ctx, &buf, src, []string{"a", "b"}, key)
+	assert.Ok(t, err)
+
+	newDst := func() *storage.MemStorage {
+		dst := storage.NewMemStorage()
+		assert.Ok(t, dst.Create(ctx, rows[2].ns, rows[2].id, []byte("existing")))
+		return dst
+	}
+
+	dst := newDst()
+	sum, err := bundle.Import(ctx, bytes.NewReader(buf.Bytes()), dst, key, nil)
+	assert.ErrorIs(t, err, verrors.ErrDuplicateKey)
+	assert.Equal(t, 2, sum.Created)
+
+	dst = newDst()
+	sum, err = bundle.Import(ctx, bytes.NewReader(buf.Bytes()), dst, key, &bundle.ImportOptions{Conflict: bundle.ConflictSkip})
+	assert.Ok(t, err)
+	assert.Equal(t, 1, sum.Skipped)
+	got, err := dst.Get(ctx, rows[2].ns, rows[2].id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("existing"), got)
+
+	dst = newDst()
+	sum, err = bundle.Import(ctx, bytes.NewReader(buf.Bytes()), dst, key, &bundle.ImportOptions{Conflict: bundle.ConflictOverwrite})
+	assert.Ok(t, err)
+	assert.Equal(t, 1, sum.Replaced)
+	want, err := src.Get(ctx, rows[2].ns, rows[2].id)
+	assert.Ok(t, err)
+	got, err = dst.Get(ctx, rows[2].ns, rows[2].id)
+	assert.Ok(t, err)
+	assert.Equal(t, want, got)
+}
+
+// TestVerify_tampering flips every byte and truncates at every length; each must fail, and the
+// failures must be classified as bundle errors.
+func TestVerify_tampering(t *testing.T) {
+	ctx := context.Background()
+	src, priv, _ := testSource(t)
+	key, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+
+	var buf bytes.Buffer
+	_, err = bundle.Export(ctx, &buf, src, []string{"a", "b"}, key)
+	assert.Ok(t, err)
+	good := buf.Bytes()
+
+	for i := range good {
+		bad := bytes.Clone(good)
+		bad[i] ^= 0x01
+		_, err := bundle.Verify(bytes.NewReader(bad), key)
+		assert.True(t, isBundleErr(err), "flip at %d: %v", i, err)
+	}
+
+	for n := range len(good) {
+		_, err := bundle.Verify(bytes.NewReader(good[:n]), key)
+		assert.True(t, isBundleErr(err), "truncate at %d: %v", n, err)
+	}
+	_, err = bundle.Verify(bytes.NewReader(good[:len(good)-1]), key)
+	assert.ErrorIs(t, err, v1errs.ErrBundleTruncated)
+
+	_, err = bundle.Verify(bytes.NewReader(append(bytes.Clone(good), 0)), key)
+	assert.ErrorIs(t, err, v1errs.ErrBundleMalformed)
+
+	other := bytes.Repeat([]byte{7}, bundle.MinKeyBytes)
+	_, err = bundle.Verify(bytes.NewReader(good), other)
+	assert.ErrorIs(t, err, v1errs.ErrBundleAuth)
+}
+
+// TestImport_truncated verifies a truncated bundle imports the authenticated records before the cut
+// and then reports truncation.
+func TestImport_truncated(t *testing.T) {
+	ctx := context.Background()
+	src, priv, _ := testSource(t)
+	key, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+
+	var buf bytes.Buffer
+	_, err = bundle.Export(ctx, &buf, src, []string{"a", "b"}, key)
+	assert.Ok(t, err)
+
+	// Drop the manifest: everything after the last record.
+	cut := buf.Len() - (1 + 4 + 2*(2+1+8) + 8 + bundle.TagBytes)
+	sum, err := bundle.Import(ctx, bytes.NewReader(buf.Bytes()[:cut]), storage.NewMemStorage(), key, nil)
+	assert.ErrorIs(t, err, v1errs.ErrBundleTruncated)
+	assert.Equal(t, 3, sum.Created)
+	assert.Equal(t, 0, sum.Manifest.Records)
+}
+
+// TestBundle_args covers key, storage, and record validation.
+func TestBundle_args(t *testing.T) {
+	ctx := context.Background()
+	src, priv, rows := testSource(t)
+	key, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+
+	_, err = bundle.NewWriter(&bytes.Buffer{}, key[:bundle.MinKeyBytes-1])
+	assert.ErrorIs(t, err, v1errs.ErrBundleKey)
+	_, err = bundle.Verify(bytes.NewReader(nil), nil)
+	assert.ErrorIs(t, err, v1errs.ErrBundleKey)
+
+	_, err = bundle.Export(ctx, &bytes.Buffer{}, struct{ storage.Storage }{src}, []string{"a"}, key)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+
+	_, err = bundle.Verify(bytes.NewReader([]byte("NOPE\x01")), key)
+	assert.ErrorIs(t, err, v1errs.ErrBundleMalformed)
+	_, err = bundle.Verify(bytes.NewReader([]byte(bundle.Magic+"\x09")), key)
+	assert.ErrorIs(t, err, v1errs.ErrBundleVersion)
+
+	// The writer refuses blobs the vault could not read back.
+	w, err := bundle.NewWriter(&bytes.Buffer{}, key)
+	assert.Ok(t, err)
+	assert.Error(t, w.Write(bundle.Record{Namespace: "a", ID: "x", Blob: []byte("junk")}))
+	blob, err := src.Get(ctx, rows[0].ns, rows[0].id)
+	assert.Ok(t, err)
+	assert.ErrorIs(t, w.Write(bundle.Record{Namespace: "other", ID: rows[0].id, Blob: blob}), v1errs.ErrNamespaceMismatch)
+}
+
+// TestDeriveKey verifies keys are deterministic per private key and reject bad keys.
+func TestDeriveKey(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	k1, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+	k2, err := bundle.DeriveKey(priv)
+	assert.Ok(t, err)
+	assert.Equal(t, k1, k2)
+	assert.Len(t, k1, bundle.MinKeyBytes)
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	k3, err := bundle.DeriveKey(other)
+	assert.Ok(t, err)
+	assert.NotEqual(t, k1, k3)
+
+	_, err = bundle.DeriveKey(nil)
+	assert.ErrorIs(t, err, verrors.ErrNilPrivateKey)
+	p256, err := ecdh.P256().GenerateKey(rand.Reader)
+	assert.Ok(t, err)
+	_, err = bundle.DeriveKey(p256)
+	assert.ErrorIs(t, err, verrors.ErrInvalidWrappingKey)
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+type testRow struct{ ns, id, plain string }
+
+// testSource returns storage holding three sealed rows (two in "a", one in "b") and the key that
+// sealed them. Rows are ordered by namespace, then id.
+func testSource(tb testing.TB) (*storage.MemStorage, *ecdh.PrivateKey, []testRow) {
+	tb.Helper()
+	ctx := context.Background()
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(tb, err)
+
+	st := storage.NewMemStorage()
+	v, err := v1.New(priv, st, identifier.HexIdentifier{})
+	assert.Ok(tb, err)
+
+	var rows []testRow
+	for _, r := range []testRow{{ns: "a", plain: "one"}, {ns: "a", plain: "two"}, {ns: "b", plain: "three"}} {
+		r.id, err = v.Store(ctx, r.ns, []byte(r.plain))
+		assert.Ok(tb, err)
+		rows = append(rows, r)
+	}
+	if rows[0].id > rows[1].id {
+		rows[0], rows[1] = rows[1], rows[0]
+	}
+	return st, priv, rows
+}
+
+// isBundleErr reports whether err is one of the bundle stream failures.
+func isBundleErr(err error) bool {
+	for _, target := range []error{v1errs.ErrBundleMalformed, v1errs.ErrBundleAuth, v1errs.ErrBundleTruncated, v1errs.ErrBundleVersion} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package bundle
+
+// Whole-storage export, import, and verification on top of [Writer] and [Reader].
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/storage"
+)
+
+// DefaultPageSize is the listing page size [Export] uses when walking each namespace.
+const DefaultPageSize = 100
+
+// Conflict selects what [Import] does when a row already exists in the destination.
+type Conflict uint8
+
+const (
+	ConflictFail      Conflict = iota // stop with [verrors.ErrDuplicateKey] (default)
+	ConflictSkip                      // keep the existing row
+	ConflictOverwrite                 // replace the existing row with the bundled blob
+)
+
+// ImportOptions configures [Import]. A nil *ImportOptions uses the defaults.
+type ImportOptions struct {
+	Conflict Conflict
+}
+
+// ImportSummary reports what [Import] wrote.
+type ImportSummary struct {
+	Manifest Manifest // verified manifest; empty if the stream failed before it
+	Created  int      // rows inserted
+	Replaced int      // existing rows overwritten (ConflictOverwrite)
+	Skipped  int      // existing rows left in place (ConflictSkip)
+}
+
+// Export writes every row in namespaces from st to w as a bundle authenticated with key, returning
+// the manifest. Rows are listed with [storage.Lister], so st must implement it or Export returns
+// [verrors.ErrListUnsupported]; each namespace is walked in id order and repeated namespaces are
+// exported once. Rows deleted between listing and reading are left out. Listing and read failures
+// are joined with [verrors.ErrStorage]; a stored blob that is not a v1 row sealed for its namespace
+// aborts the export with the parse error or [v1errs.ErrNamespaceMismatch].
+func Export(ctx context.Context, w io.Writer, st storage.Storage, namespaces []string, key []byte) (Manifest, error) {
+	lister, ok := st.(storage.Lister)
+	if !ok {
+		return Manifest{}, verrors.ErrListUnsupported
+	}
+
+	bw, err := NewWriter(w, key)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	seen := make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		if _, dup := seen[ns]; dup {
+			continue
+		}
+		seen[ns] = struct{}{}
+
+		for id, err := range storage.IDs(ctx, lister, ns, DefaultPageSize) {
+			if err != nil {
+				return Manifest{}, errors.Join(verrors.ErrStorage, err)
+			}
+
+			blob, err := st.Get(ctx, ns, id)
+			if err != nil {
+				if errors.Is(err, verrors.ErrNotFound) {
+					continue
+				}
+				return Manifest{}, errors.Join(verrors.ErrStorage, err)
+			}
+
+			if err := bw.Write(Record{Namespace: ns, ID: id, Blob: blob}); err != nil {
+				return Manifest{}, err
+			}
+		}
+	}
+	return bw.Close()
+}
+
+// Import reads a bundle from r and creates each record in st. Records are authenticated before they
+// are written, but the stream is imported as it is read: if it turns out to be truncated or
+// tampered part-way through, rows before the failure remain in st and the error is returned with the
+// counts so far. Run [Verify] first when the whole bundle must be checked before anything is
+// written.
+//
+// Existing rows are handled according to [ImportOptions.Conflict]. Storage failures are joined with
+// [verrors.ErrStorage]; bundle errors are returned as from [Reader.Next].
+func Import(ctx context.Context, r io.Reader, st storage.Storage, key []byte, opts *ImportOptions) (ImportSummary, error) {
+	var sum ImportSummary
+	if st == nil {
+		return sum, verrors.ErrInvalidNewArgs
+	}
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	br, err := NewReader(r, key)
+	if err != nil {
+		return sum, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+
+		rec, err := br.Next()
+		if err == io.EOF {
+			sum.Manifest, _ = br.Manifest()
+			return sum, nil
+		}
+		if err != nil {
+			return sum, err
+		}
+
+		err = st.Create(ctx, rec.Namespace, rec.ID, rec.Blob)
+		switch {
+		case err == nil:
+			sum.Created++
+		case errors.Is(err, verrors.ErrDuplicateKey) && opts.Conflict == ConflictSkip:
+			sum.Skipped++
+		case errors.Is(err, verrors.ErrDuplicateKey) && opts.Conflict == ConflictOverwrite:
+			if err := st.Replace(ctx, rec.Namespace, rec.ID, rec.Blob); err != nil {
+				return sum, errors.Join(verrors.ErrStorage, err)
+			}
+			sum.Replaced++
+		default:
+			return sum, errors.Join(verrors.ErrStorage, err)
+		}
+	}
+}
+
+// Verify reads a whole bundle from r without writing anything, checking every tag, the manifest, and
+// that every blob parses as a v1 row sealed for its namespace. Nothing is decrypted, so the vault's
+// private key is not needed beyond deriving key. It returns the verified manifest or the first error
+// from [Reader.Next].
+func Verify(r io.Reader, key []byte) (Manifest, error) {
+	br, err := NewReader(r, key)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	for {
+		if _, err := br.Next(); err != nil {
+			if err == io.EOF {
+				m, _ := br.Manifest()
+				return m, nil
+			}
+			return Manifest{}, err
+		}
+	}
+}
@@ -20,6 +20,12 @@ const (
 	// WrapNonceBytes is the DEK-wrap AES-GCM nonce size in bytes.
 	WrapNonceBytes = 12
 
+	// XChaChaNonceBytes is the inner and DEK-wrap nonce size in bytes for the XChaCha20-Poly1305 suite.
+	XChaChaNonceBytes = 24
+
+	// MaxNonceBytes is the largest nonce any v1 suite uses.
+	MaxNonceBytes = XChaChaNonceBytes
+
 	// X25519PubBytes is the length in bytes of an X25519 public key on the wire.
 	X25519PubBytes = 32
 
@@ -29,12 +35,44 @@ const (
 	// WrapKeyBytes is the HKDF-derived AES-256 wrap key length in bytes.
 	WrapKeyBytes = 32
 
-	// GCMTagBytes is the AES-GCM authentication tag size in bytes.
+	// GCMTagBytes is the AES-GCM authentication tag size in bytes; Poly1305 tags are the same size.
 	GCMTagBytes = 16
 
 	// DekEnvelopeBytes is the fixed on-wire size for the initial v1 suite (32+12+32+16).
 	DekEnvelopeBytes = X25519PubBytes + WrapNonceBytes + DEKBytes + GCMTagBytes
 
+	// XChaChaDekEnvelopeBytes is the fixed on-wire size for the XChaCha20-Poly1305 suite (32+24+32+16).
+	XChaChaDekEnvelopeBytes = X25519PubBytes + XChaChaNonceBytes + DEKBytes + GCMTagBytes
+
+	// MetaExtInnerKeyID tags the optional Meta extension that records the key id bound into the
+	// inner AAD of a row whose DEK was re-wrapped for a different long-term key.
+	MetaExtInnerKeyID uint8 = 0x01
+
+	// MetaExtExpiresAt tags the optional Meta extension carrying a row's expiry as big-endian Unix
+	// seconds ([MetaExpiresAtBytes] bytes).
+	MetaExtExpiresAt uint8 = 0x02
+
+	// MetaExpiresAtBytes is the value length of the [MetaExtExpiresAt] extension.
+	MetaExpiresAtBytes = 8
+
+	// MaxMetaExtBytes is the largest possible encoding of all optional Meta extensions (tag, length, value).
+	MaxMetaExtBytes = (1 + 1 + MaxKeyIDBytes) + (1 + 1 + MetaExpiresAtBytes)
+
 	// MaxMetaWireBytes is the largest possible v1 Meta encoding (bounded decode).
-	MaxMetaWireBytes = 1 + 1 + 1 + MaxKeyIDBytes + 1 + MaxNamespaceBytes
+	MaxMetaWireBytes = 1 + 1 + 1 + MaxKeyIDBytes + 1 + MaxNamespaceBytes + MaxMetaExtBytes
+)
+
+const (
+	// StreamMagic is the four-byte preamble for chunked streams (wire normative).
+	StreamMagic = "VLS1"
+
+	// StreamNoncePrefixBytes is the random per-stream nonce prefix; a 4-byte chunk counter and a
+	// 1-byte final-chunk flag complete the inner AES-GCM nonce.
+	StreamNoncePrefixBytes = InnerNonceBytes - 4 - 1
+
+	// DefaultStreamChunkBytes is the plaintext chunk size used when none is configured.
+	DefaultStreamChunkBytes = 64 << 10
+
+	// MaxStreamChunkBytes bounds the plaintext chunk size a stream header may declare (bounded decode).
+	MaxStreamChunkBytes = 16 << 20
 )
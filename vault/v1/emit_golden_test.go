@@ -6,7 +6,7 @@ package v1_test
 //
 //	go test -tags=emitgolden ./vault/v1 -run TestEmitGoldenV1Wire -v
 //
-// Copy WIRE_HEX and (if you change keys) PRIV_HEX from the log into golden_test.go.
+// Copy WIRE_HEX, XCHACHA_WIRE_HEX, and (if you change keys) PRIV_HEX from the log into golden_test.go.
 
 import (
 	"crypto/ecdh"
@@ -16,6 +16,7 @@ import (
 	"go.rtnl.ai/x/assert"
 	v1 "go.rtnl.ai/x/vault/v1"
 	"go.rtnl.ai/x/vault/v1/constants"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 // TestEmitGoldenV1Wire prints fixed hex for the long-term key and golden sealed wire (build tag emitgolden).
@@ -44,4 +45,16 @@ func TestEmitGoldenV1Wire(t *testing.T) {
 	wire, err := v1.ExportTestBuildSealedRow(priv, "golden-ns", []byte("hello-golden"), dek, innerNonce, ephPriv, wrapNonce)
 	assert.Ok(t, err)
 	t.Logf("WIRE_HEX=%s", hex.EncodeToString(wire))
+
+	// The XChaCha20-Poly1305 row uses the same key, DEK, and ephemeral key with 24-byte nonces
+	// following the same pattern.
+	xInner := make([]byte, constants.XChaChaNonceBytes)
+	xWrap := make([]byte, constants.XChaChaNonceBytes)
+	for i := range xInner {
+		xInner[i] = byte(i + 5)
+		xWrap[i] = byte(i + 7)
+	}
+	wire, err = v1.ExportTestBuildSealedRowSuite(priv, suite.X25519HKDFSHA256XChaCha20Poly1305, "golden-ns", []byte("hello-golden"), dek, xInner, ephPriv, xWrap)
+	assert.Ok(t, err)
+	t.Logf("XCHACHA_WIRE_HEX=%s", hex.EncodeToString(wire))
 }
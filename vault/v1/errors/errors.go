@@ -44,6 +44,9 @@ var (
 
 	// ErrNamespaceMismatch means the row was opened under a namespace that does not match the row metadata.
 	ErrNamespaceMismatch = stderrors.New("vault/v1: namespace mismatch")
+
+	// ErrMetaExpiry means [models.Meta.ExpiresAt] is set but not after the Unix epoch, so it cannot be encoded.
+	ErrMetaExpiry = stderrors.New("vault/v1: invalid expiry")
 )
 
 //=============================================================================
@@ -66,3 +69,59 @@ var (
 	// ErrInvalidSuiteInput means the argument type is not supported for [suite.Parse].
 	ErrInvalidSuiteInput = stderrors.New("vault/v1/suite: invalid input type")
 )
+
+//=============================================================================
+// Chunked streams
+//=============================================================================
+
+var (
+	// ErrStreamTruncated means a stream ended on a chunk boundary before its authenticated final chunk.
+	ErrStreamTruncated = stderrors.New("vault/v1: stream truncated")
+
+	// ErrStreamChunkSize means a stream chunk size is zero or exceeds [constants.MaxStreamChunkBytes].
+	ErrStreamChunkSize = stderrors.New("vault/v1: invalid stream chunk size")
+
+	// ErrStreamTooLong means a stream would exceed the maximum number of chunks its nonce counter allows.
+	ErrStreamTooLong = stderrors.New("vault/v1: stream too long")
+
+	// ErrStreamClosed means a write was attempted after the stream writer was closed.
+	ErrStreamClosed = stderrors.New("vault/v1: stream closed")
+)
+
+//=============================================================================
+// Keyring, key rotation, and expiry sweeps
+//=============================================================================
+
+var (
+	// ErrUnknownKeyID means a row's KeyID does not match any key registered on the [v1.Keyring]. It is
+	// returned joined with [go.rtnl.ai/x/vault/errors.ErrDecrypt], so callers checking only for a
+	// decrypt failure keep working.
+	ErrUnknownKeyID = stderrors.New("vault/v1: unknown key id")
+
+	// ErrRotateIncomplete means [v1.Rotate] finished walking its rows but at least one row could not be re-wrapped.
+	ErrRotateIncomplete = stderrors.New("vault/v1: key rotation incomplete")
+
+	// ErrSweepIncomplete means [v1.Sweep] finished listing its namespace but at least one row could not be checked or deleted.
+	ErrSweepIncomplete = stderrors.New("vault/v1: expiry sweep incomplete")
+)
+
+//=============================================================================
+// Export bundles ([bundle])
+//=============================================================================
+
+var (
+	// ErrBundleMalformed means a bundle stream is corrupt or not in the bundle layout (bad magic, framing, or caps).
+	ErrBundleMalformed = stderrors.New("vault/v1/bundle: malformed bundle")
+
+	// ErrBundleVersion means the bundle format version is not supported by this module.
+	ErrBundleVersion = stderrors.New("vault/v1/bundle: unsupported bundle version")
+
+	// ErrBundleAuth means a record or manifest authentication tag did not verify (wrong key or tampered bytes).
+	ErrBundleAuth = stderrors.New("vault/v1/bundle: authentication failed")
+
+	// ErrBundleTruncated means the stream ended before the authenticated manifest.
+	ErrBundleTruncated = stderrors.New("vault/v1/bundle: bundle truncated")
+
+	// ErrBundleKey means the bundle authentication key is shorter than [bundle.MinKeyBytes].
+	ErrBundleKey = stderrors.New("vault/v1/bundle: invalid authentication key")
+)
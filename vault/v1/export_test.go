@@ -4,23 +4,39 @@ package v1
 
 import (
 	"crypto/ecdh"
+	"time"
 
 	"go.rtnl.ai/x/vault"
 	"go.rtnl.ai/x/vault/v1/constants"
-	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 // ExportTestBuildSealedRow builds a v1 sealed wire blob using fixed DEK, inner and wrap nonces,
 // and an ephemeral X25519 keypair. It matches [sealedVault.sealPlaintext] output for the same
 // inputs and exists for generating golden vector tests.
 func ExportTestBuildSealedRow(priv *ecdh.PrivateKey, namespace string, plaintext, dek []byte, innerNonce [constants.InnerNonceBytes]byte, ephPriv *ecdh.PrivateKey, wrapNonce [constants.WrapNonceBytes]byte) ([]byte, error) {
+	return ExportTestBuildSealedRowSuite(priv, suite.X25519HKDFSHA256AES256GCM, namespace, plaintext, dek, innerNonce[:], ephPriv, wrapNonce[:])
+}
+
+// ExportTestBuildSealedRowSuite is [ExportTestBuildSealedRow] for any suite; the nonces must be the
+// suite's nonce size.
+func ExportTestBuildSealedRowSuite(priv *ecdh.PrivateKey, id suite.ID, namespace string, plaintext, dek, innerNonce []byte, ephPriv *ecdh.PrivateKey, wrapNonce []byte) ([]byte, error) {
 	dekCopy := append([]byte(nil), dek...)
-	meta, err := models.MetaFromPrivKey(priv)
+	kr, err := NewKeyring(priv)
 	if err != nil {
 		return nil, err
 	}
-	v := &sealedVault{priv: priv, template: meta, st: nil, id: nil}
-	return v.sealPlaintextWith(namespace, plaintext, dekCopy, innerNonce, ephPriv, wrapNonce)
+	if kr, err = kr.WithSuite(id); err != nil {
+		return nil, err
+	}
+	v := &sealedVault{kr: kr, st: nil, id: nil}
+	return v.sealPlaintextWith(namespace, time.Time{}, plaintext, dekCopy, innerNonce, ephPriv, wrapNonce)
+}
+
+// SetClock replaces the clock v judges row expiry against; v must come from [New] or
+// [NewWithKeyring].
+func SetClock(v vault.Vault, now func() time.Time) {
+	v.(*sealedVault).now = now
 }
 
 // NilSealedVault is a typed-nil [*sealedVault] as [Vault] for nil-receiver contract tests in package v1_test.
@@ -5,6 +5,10 @@ import (
 	"crypto/cipher"
 
 	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/v1/constants"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/suite"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 // newAEAD constructs an AES-GCM AEAD for key material of an allowed size.
@@ -23,3 +27,30 @@ func newAEAD(key []byte) (cipher.AEAD, error) {
 
 	return cipher.NewGCM(block)
 }
+
+// NewSuiteAEAD constructs the AEAD suite id uses for both the inner payload and the DEK wrap, keyed
+// with a 32-byte DEK or wrap key: AES-256-GCM or XChaCha20-Poly1305. An unknown suite yields
+// [v1errs.ErrUnknownSuite]; a key of the wrong length yields [verrors.ErrMalformedParameters].
+func NewSuiteAEAD(id suite.ID, key []byte) (cipher.AEAD, error) {
+	if len(key) != constants.DEKBytes {
+		return nil, verrors.ErrMalformedParameters
+	}
+
+	switch id {
+	case suite.X25519HKDFSHA256AES256GCM:
+		return newAEAD(key)
+	case suite.X25519HKDFSHA256XChaCha20Poly1305:
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return nil, verrors.ErrInvalidAEADKey
+		}
+		return aead, nil
+	default:
+		return nil, v1errs.ErrUnknownSuite
+	}
+}
+
+// validNonceSize reports whether n is a nonce size some v1 suite uses.
+func validNonceSize(n int) bool {
+	return n == constants.InnerNonceBytes || n == constants.XChaChaNonceBytes
+}
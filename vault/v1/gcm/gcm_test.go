@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	cryptorand "crypto/rand"
+	"encoding/hex"
 	"io"
 	"strings"
 	"testing"
@@ -12,7 +13,9 @@ import (
 	"go.rtnl.ai/x/assert"
 	"go.rtnl.ai/x/vault/v1/constants"
 	verrors "go.rtnl.ai/x/vault/errors"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
 	"go.rtnl.ai/x/vault/v1/gcm"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 //=============================================================================
@@ -233,11 +236,116 @@ func TestGCM_inner_wrongDEK_failsAuth(t *testing.T) {
 	assert.ErrorIs(t, err, verrors.ErrDecrypt)
 }
 
+//=============================================================================
+// NewSuiteAEAD
+//=============================================================================
+
+// TestGCM_NewSuiteAEAD_knownAnswer checks each suite's AEAD against a published vector: AES-256-GCM
+// test case 14 from the GCM specification, and the XChaCha20-Poly1305 vector from
+// draft-irtf-cfrg-xchacha (appendix A.3.1).
+func TestGCM_NewSuiteAEAD_knownAnswer(t *testing.T) {
+	tests := []struct {
+		name                        string
+		id                          suite.ID
+		key, nonce, aad, plain, out string // hex
+	}{
+		{
+			name:  "aes256gcm",
+			id:    suite.X25519HKDFSHA256AES256GCM,
+			key:   strings.Repeat("00", 32),
+			nonce: strings.Repeat("00", 12),
+			plain: strings.Repeat("00", 16),
+			out:   "cea7403d4d606b6e074ec5d3baf39d18" + "d0d1c8a799996bf0265b98b5d48ab919",
+		},
+		{
+			name:  "xchacha20poly1305",
+			id:    suite.X25519HKDFSHA256XChaCha20Poly1305,
+			key:   "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+			nonce: "404142434445464748494a4b4c4d4e4f5051525354555657",
+			aad:   "50515253c0c1c2c3c4c5c6c7",
+			plain: hex.EncodeToString([]byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")),
+			out: "bd6d179d3e83d43b9576579493c0e939572a1700252bfaccbed2902c21396cbb731c7f1b0b4aa6440bf3a82f4eda7e39" +
+				"ae64c6708c54c216cb96b72e1213b4522f8c9ba40db5d945b11b69b982c1bb9e3f3fac2bc369488f76b2383565d3fff9" +
+				"21f9664c97637da9768812f615c68b13b52e" + "c0875924c1c7987947deafd8780acf49",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, nonce, aad, plain, out := mustHex(t, tt.key), mustHex(t, tt.nonce), mustHex(t, tt.aad), mustHex(t, tt.plain), mustHex(t, tt.out)
+
+			aead, err := gcm.NewSuiteAEAD(tt.id, key)
+			assert.Ok(t, err)
+			assert.Equal(t, tt.id.NonceBytes(), aead.NonceSize())
+
+			gotNonce, ct, err := gcm.SealInnerSuiteWithNonce(aead, aad, plain, nonce)
+			assert.Ok(t, err)
+			assert.Equal(t, nonce, gotNonce)
+			assert.Equal(t, out, ct)
+
+			got, err := gcm.OpenInnerSuite(aead, aad, nonce, out)
+			assert.Ok(t, err)
+			assert.Equal(t, plain, got)
+
+			// A nonce of the other suite's size is rejected rather than truncated or padded.
+			other := make([]byte, constants.InnerNonceBytes+constants.XChaChaNonceBytes-len(nonce))
+			_, err = gcm.OpenInnerSuite(aead, aad, other, out)
+			assert.ErrorIs(t, err, verrors.ErrDecrypt)
+			_, _, err = gcm.SealInnerSuiteWithNonce(aead, aad, plain, other)
+			assert.ErrorIs(t, err, verrors.ErrMalformedParameters)
+		})
+	}
+}
+
+// TestGCM_NewSuiteAEAD_errors covers unknown suites and bad key lengths, and checks the suites derive
+// different wrap keys from one shared secret.
+func TestGCM_NewSuiteAEAD_errors(t *testing.T) {
+	_, err := gcm.NewSuiteAEAD(suite.Unknown, make([]byte, 32))
+	assert.ErrorIs(t, err, v1errs.ErrUnknownSuite)
+	_, err = gcm.NewSuiteAEAD(suite.X25519HKDFSHA256XChaCha20Poly1305, make([]byte, 16))
+	assert.ErrorIs(t, err, verrors.ErrMalformedParameters)
+
+	shared := bytes.Repeat([]byte{5}, 32)
+	aesKey, err := gcm.DeriveSuiteWrapKey(suite.X25519HKDFSHA256AES256GCM, shared)
+	assert.Ok(t, err)
+	legacy, err := gcm.DeriveWrapKey(shared)
+	assert.Ok(t, err)
+	assert.Equal(t, legacy, aesKey)
+	xKey, err := gcm.DeriveSuiteWrapKey(suite.X25519HKDFSHA256XChaCha20Poly1305, shared)
+	assert.Ok(t, err)
+	assert.NotEqual(t, aesKey, xKey)
+	_, err = gcm.DeriveSuiteWrapKey(suite.Unknown, shared)
+	assert.ErrorIs(t, err, v1errs.ErrUnknownSuite)
+}
+
 //=============================================================================
 // Test helpers
 //=============================================================================
 
+// mustHex decodes a hex test vector.
+func mustHex(tb testing.TB, s string) []byte {
+	tb.Helper()
+	b, err := hex.DecodeString(s)
+	assert.Ok(tb, err)
+	return b
+}
+
 // Implements io.Reader, always returns EOF (used to simulate random failure in tests).
 type eofReader struct{}
 
 func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
+
+// TestGCM_StreamNonce checks the prefix || counter || final layout and that the final flag changes the nonce.
+func TestGCM_StreamNonce(t *testing.T) {
+	var prefix [constants.StreamNoncePrefixBytes]byte
+	for i := range prefix {
+		prefix[i] = byte(i + 1)
+	}
+
+	n := gcm.StreamNonce(prefix, 0x01020304, false)
+	assert.Equal(t, [constants.InnerNonceBytes]byte{1, 2, 3, 4, 5, 6, 7, 1, 2, 3, 4, 0}, n)
+	f := gcm.StreamNonce(prefix, 0x01020304, true)
+	assert.Equal(t, byte(1), f[constants.InnerNonceBytes-1])
+	assert.NotEqual(t, n, f)
+	assert.Equal(t, append([]byte("vault-stream-v1"), 9), gcm.StreamAAD([]byte{9}))
+}
@@ -72,3 +72,62 @@ func OpenInner(aead cipher.AEAD, aad []byte, nonce [constants.InnerNonceBytes]by
 	}
 	return plain, nil
 }
+
+// SealInnerSuite is [SealInner] for any v1 suite: the random nonce is the AEAD's nonce size, 12 bytes
+// for AES-256-GCM or 24 for XChaCha20-Poly1305, and is returned as a slice.
+func SealInnerSuite(aead cipher.AEAD, aad, plaintext []byte) ([]byte, []byte, error) {
+	// Check if the AEAD instance is nil.
+	if aead == nil {
+		return nil, nil, verrors.ErrNilAEAD
+	}
+
+	// An AEAD from another construction would not match any v1 wire layout.
+	if !validNonceSize(aead.NonceSize()) {
+		return nil, nil, verrors.ErrMalformedParameters
+	}
+
+	// Fresh random nonce per seal; must not repeat for the same DEK.
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, verrors.ErrSealFailed
+	}
+
+	return SealInnerSuiteWithNonce(aead, aad, plaintext, nonce)
+}
+
+// SealInnerSuiteWithNonce encrypts plaintext with the given nonce (random in [SealInnerSuite]), which
+// must be exactly the AEAD's nonce size.
+func SealInnerSuiteWithNonce(aead cipher.AEAD, aad, plaintext, nonce []byte) ([]byte, []byte, error) {
+	// Check if the AEAD instance is nil.
+	if aead == nil {
+		return nil, nil, verrors.ErrNilAEAD
+	}
+
+	// Ensure the AEAD's nonce size is a v1 size and matches the nonce supplied.
+	if !validNonceSize(aead.NonceSize()) || len(nonce) != aead.NonceSize() {
+		return nil, nil, verrors.ErrMalformedParameters
+	}
+
+	// Encrypt the plaintext with the provided nonce and additional authenticated data (aad).
+	ct := aead.Seal(nil, nonce, plaintext, aad)
+	return append([]byte(nil), nonce...), ct, nil
+}
+
+// OpenInnerSuite is [OpenInner] for any v1 suite. A nonce of the wrong size for the AEAD fails like
+// any other decrypt failure.
+func OpenInnerSuite(aead cipher.AEAD, aad, nonce, payload []byte) ([]byte, error) {
+	// Check if the AEAD instance is nil.
+	if aead == nil {
+		return nil, verrors.ErrNilAEAD
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, verrors.ErrDecrypt
+	}
+
+	// Attempt to decrypt payload (ciphertext + tag) using the provided nonce and AAD.
+	plain, err := aead.Open(nil, nonce, payload, aad)
+	if err != nil {
+		return nil, verrors.ErrDecrypt
+	}
+	return plain, nil
+}
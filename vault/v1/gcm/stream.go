@@ -0,0 +1,32 @@
+package gcm
+
+import (
+	"encoding/binary"
+
+	"go.rtnl.ai/x/vault/v1/constants"
+)
+
+// streamAADPrefix binds chunk AEAD to the v1 stream construction (prefix || header).
+const streamAADPrefix = "vault-stream-v1"
+
+// StreamAAD prefixes the raw stream header for use as the additional data of every chunk, so chunks
+// cannot be moved between streams or confused with inner row payloads under the same DEK.
+func StreamAAD(headerRaw []byte) []byte {
+	out := make([]byte, 0, len(streamAADPrefix)+len(headerRaw))
+	out = append(out, streamAADPrefix...)
+	out = append(out, headerRaw...)
+	return out
+}
+
+// StreamNonce builds the inner nonce for chunk counter of a stream: prefix || counter (big-endian
+// uint32) || final flag. The flag makes the final chunk's nonce distinct from any other, so a stream
+// cut at a chunk boundary fails authentication instead of looking complete.
+func StreamNonce(prefix [constants.StreamNoncePrefixBytes]byte, counter uint32, final bool) [constants.InnerNonceBytes]byte {
+	var nonce [constants.InnerNonceBytes]byte
+	copy(nonce[:], prefix[:])
+	binary.BigEndian.PutUint32(nonce[constants.StreamNoncePrefixBytes:], counter)
+	if final {
+		nonce[constants.InnerNonceBytes-1] = 1
+	}
+	return nonce
+}
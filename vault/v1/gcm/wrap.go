@@ -9,6 +9,8 @@ import (
 
 	"go.rtnl.ai/x/vault/v1/constants"
 	verrors "go.rtnl.ai/x/vault/errors"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 // WrappedDEK is the fixed-layout DEK wrap segment (pub, nonce, ciphertext+tag).
@@ -17,9 +19,13 @@ import (
 // Layout on the wire: Pub is the ephemeral X25519 public key (not encrypted). Nonce is the
 // wrap-AEAD nonce. Payload is exactly DEK ciphertext plus GCM tag so total Payload length is
 // DEKBytes + GCMTagBytes.
+//
+// XNonce is the 24-byte wrap nonce of the XChaCha20-Poly1305 suite, set by [SealWrappedDEKSuite]
+// in place of Nonce. It is nil for the AES-256-GCM suite.
 type WrappedDEK struct {
 	Pub     [constants.X25519PubBytes]byte
 	Nonce   [constants.WrapNonceBytes]byte
+	XNonce  []byte
 	Payload [constants.DEKBytes + constants.GCMTagBytes]byte
 }
 
@@ -96,9 +102,15 @@ func OpenWrappedDEK(aead cipher.AEAD, wrapAAD []byte, dek WrappedDEK) ([]byte, e
 		return nil, verrors.ErrNilAEAD
 	}
 
+	// A nonce of the wrong size for this AEAD cannot have come from the matching suite.
+	nonce := dek.nonce()
+	if len(nonce) != aead.NonceSize() {
+		return nil, verrors.ErrDecrypt
+	}
+
 	// Attempt to decrypt the wrapped DEK using the same wrapAAD as at seal time
 	// (usually prefix || metaRaw from [WrapAAD]).
-	plain, err := aead.Open(nil, dek.Nonce[:], dek.Payload[:], wrapAAD)
+	plain, err := aead.Open(nil, nonce, dek.Payload[:], wrapAAD)
 	if err != nil {
 		return nil, verrors.ErrDecrypt
 	}
@@ -116,6 +128,65 @@ func OpenWrappedDEK(aead cipher.AEAD, wrapAAD []byte, dek WrappedDEK) ([]byte, e
 	return out, nil
 }
 
+// SealWrappedDEKSuite is [SealWrappedDEK] for any v1 suite: the random nonce is the AEAD's nonce size
+// and lands in Nonce (AES-256-GCM) or XNonce (XChaCha20-Poly1305).
+func SealWrappedDEKSuite(pub [constants.X25519PubBytes]byte, aead cipher.AEAD, wrapAAD, dek []byte) (WrappedDEK, error) {
+	// Reject nil AEAD instance and AEADs from other constructions before reading entropy.
+	if aead == nil {
+		return WrappedDEK{}, verrors.ErrNilAEAD
+	}
+	if !validNonceSize(aead.NonceSize()) {
+		return WrappedDEK{}, verrors.ErrMalformedParameters
+	}
+
+	// Generate a random nonce for this DEK wrap operation.
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return WrappedDEK{}, verrors.ErrSealFailed
+	}
+
+	return SealWrappedDEKSuiteWithNonce(pub, aead, wrapAAD, dek, nonce)
+}
+
+// SealWrappedDEKSuiteWithNonce wraps dek using the given nonce (random in [SealWrappedDEKSuite]),
+// which must be exactly the AEAD's nonce size.
+func SealWrappedDEKSuiteWithNonce(pub [constants.X25519PubBytes]byte, aead cipher.AEAD, wrapAAD, dek, nonce []byte) (WrappedDEK, error) {
+	// Validate inputs: reject nil AEAD, check DEK length, and confirm AEAD and supplied nonce sizes.
+	if aead == nil {
+		return WrappedDEK{}, verrors.ErrNilAEAD
+	}
+	if len(dek) != constants.DEKBytes {
+		return WrappedDEK{}, verrors.ErrMalformedParameters
+	}
+	if !validNonceSize(aead.NonceSize()) || len(nonce) != aead.NonceSize() {
+		return WrappedDEK{}, verrors.ErrMalformedParameters
+	}
+
+	// The AES-256-GCM suite keeps using the fixed-size path and Nonce field.
+	if len(nonce) == constants.WrapNonceBytes {
+		return SealWrappedDEKWithNonce(pub, aead, wrapAAD, dek, [constants.WrapNonceBytes]byte(nonce))
+	}
+
+	// Seal the DEK bytes with the provided nonce and additional authenticated data.
+	// The output (ciphertext + tag) must fit exactly in the [Payload] field.
+	ct := aead.Seal(nil, nonce, dek, wrapAAD)
+	if len(ct) != constants.DEKBytes+constants.GCMTagBytes {
+		return WrappedDEK{}, verrors.ErrMalformedParameters
+	}
+
+	out := WrappedDEK{Pub: pub, XNonce: append([]byte(nil), nonce...)}
+	copy(out.Payload[:], ct)
+	return out, nil
+}
+
+// nonce returns XNonce if it is set, and Nonce otherwise.
+func (w WrappedDEK) nonce() []byte {
+	if w.XNonce != nil {
+		return w.XNonce
+	}
+	return w.Nonce[:]
+}
+
 //=============================================================================
 // Helpers: wrap-key derivation and DEK-wrap AAD
 //=============================================================================
@@ -127,6 +198,10 @@ const wrapAADPrefix = "vault-wrap-dek-v1"
 // It must stay stable across releases that read the same wire format.
 const hkdfWrapInfo = "vault/v1/x25519-hkdf-sha256-aes256gcm/wrap-key"
 
+// hkdfWrapInfoXChaCha is the HKDF context string for the XChaCha20-Poly1305 suite, so the two suites
+// never derive the same wrap key from one shared secret.
+const hkdfWrapInfoXChaCha = "vault/v1/x25519-hkdf-sha256-xchacha20poly1305/wrap-key"
+
 // WrapAAD prefixes meta-derived AAD for DEK wrapping so it cannot be confused
 // with other uses of the same key material.
 func WrapAAD(metaRaw []byte) []byte {
@@ -142,3 +217,16 @@ func DeriveWrapKey(sharedSecret []byte) ([]byte, error) {
 	// No salt: shared secret is already high-entropy.
 	return hkdf.Key(sha256.New, sharedSecret, nil, hkdfWrapInfo, constants.WrapKeyBytes)
 }
+
+// DeriveSuiteWrapKey derives the wrap key for suite id from an ECDH shared secret using HKDF-SHA256
+// with a per-suite context string. An unknown suite yields [v1errs.ErrUnknownSuite].
+func DeriveSuiteWrapKey(id suite.ID, sharedSecret []byte) ([]byte, error) {
+	switch id {
+	case suite.X25519HKDFSHA256AES256GCM:
+		return DeriveWrapKey(sharedSecret)
+	case suite.X25519HKDFSHA256XChaCha20Poly1305:
+		return hkdf.Key(sha256.New, sharedSecret, nil, hkdfWrapInfoXChaCha, constants.WrapKeyBytes)
+	default:
+		return nil, v1errs.ErrUnknownSuite
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"go.rtnl.ai/x/vault/v1/constants"
 	verrors "go.rtnl.ai/x/vault/errors"
 	"go.rtnl.ai/x/vault/v1/gcm"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 // TestWrap_sealOpen_roundtrip seals a DEK with random wrap nonce and opens with the same AEAD and AAD.
@@ -185,3 +186,39 @@ func TestWrap_distinctNoncesPerSeal(t *testing.T) {
 		t.Fatal("expected distinct wrap seals (nonce randomness)")
 	}
 }
+
+// TestWrap_suite checks [gcm.SealWrappedDEKSuite] puts each suite's nonce in the matching field and
+// that [gcm.OpenWrappedDEK] opens both.
+func TestWrap_suite(t *testing.T) {
+	wrapKey := bytes.Repeat([]byte{8}, 32)
+	dek := bytes.Repeat([]byte{9}, 32)
+	wrapAAD := []byte("suite-aad")
+	var pub [32]byte
+
+	aesAEAD, err := gcm.NewSuiteAEAD(suite.X25519HKDFSHA256AES256GCM, wrapKey)
+	assert.Ok(t, err)
+	wrapped, err := gcm.SealWrappedDEKSuite(pub, aesAEAD, wrapAAD, dek)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte(nil), wrapped.XNonce)
+	got, err := gcm.OpenWrappedDEK(aesAEAD, wrapAAD, wrapped)
+	assert.Ok(t, err)
+	assert.Equal(t, dek, got)
+
+	xAEAD, err := gcm.NewSuiteAEAD(suite.X25519HKDFSHA256XChaCha20Poly1305, wrapKey)
+	assert.Ok(t, err)
+	wrapped, err = gcm.SealWrappedDEKSuite(pub, xAEAD, wrapAAD, dek)
+	assert.Ok(t, err)
+	assert.Len(t, wrapped.XNonce, constants.XChaChaNonceBytes)
+	assert.Equal(t, [constants.WrapNonceBytes]byte{}, wrapped.Nonce)
+	got, err = gcm.OpenWrappedDEK(xAEAD, wrapAAD, wrapped)
+	assert.Ok(t, err)
+	assert.Equal(t, dek, got)
+
+	// Each suite's wrap fails to open with the other's AEAD, and the fixed-size path refuses XChaCha.
+	_, err = gcm.OpenWrappedDEK(aesAEAD, wrapAAD, wrapped)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+	_, err = gcm.SealWrappedDEK(pub, xAEAD, wrapAAD, dek)
+	assert.ErrorIs(t, err, verrors.ErrMalformedParameters)
+	_, err = gcm.SealWrappedDEKSuiteWithNonce(pub, xAEAD, wrapAAD, dek, make([]byte, constants.WrapNonceBytes))
+	assert.ErrorIs(t, err, verrors.ErrMalformedParameters)
+}
@@ -21,6 +21,7 @@ import (
 	v1 "go.rtnl.ai/x/vault/v1"
 	"go.rtnl.ai/x/vault/v1/constants"
 	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 // Long-term X25519 private key bytes that produced goldenSealedV1Hex (32-byte scalar encoding).
@@ -30,6 +31,10 @@ const goldenLongTermPrivHex = "6d4cbdc47df6b0d6c534aa744ab4ba0fdfe90edf9d28a9101
 // and plaintext "hello-golden", built with ExportTestBuildSealedRow and fixed nonces/DEK/ephemeral key.
 const goldenSealedV1Hex = "564c543101002d010120501e2366b36fe14d71a926f2858d155536ce5a173d69f7731b4a6cd5cd07d96509676f6c64656e2d6e73471118583b237cbaadd25a5ba98d0ddd79ff5ceaa3cd31097e426dda877497370708090a0b0c0d0e0f101112f707569cfca3daa51c06264ecd8aab1c53a1e85dcc60d19370bbd9b1402f33c8f993b9a653ae7ae1660aa88b9f1b573505060708090a0b0c0d0e0f1056b695ea0ba7256c4fa01dff854cafa49e54873426b638646a560ea8"
 
+// The same row sealed with the XChaCha20-Poly1305 suite and 24-byte nonces, built with
+// ExportTestBuildSealedRowSuite from the same key, DEK, and ephemeral key.
+const goldenSealedV1XChaChaHex = "564c543101002d010220501e2366b36fe14d71a926f2858d155536ce5a173d69f7731b4a6cd5cd07d96509676f6c64656e2d6e73471118583b237cbaadd25a5ba98d0ddd79ff5ceaa3cd31097e426dda877497370708090a0b0c0d0e0f101112131415161718191a1b1c1d1ea5b69bfd47d9c699e8f09544a4c31772cb72d916028209657986a7ff12124bb9884744ea0f69dc2171f2bdf049c37c4c05060708090a0b0c0d0e0f101112131415161718191a1b1cb9738bc2ad76ce054dab064b3ecdbf72477df56a5e329648522e9a58"
+
 // TestGoldenV1Contract checks a frozen sealed blob still unmarshals and decrypts with current v1 code.
 func TestGoldenV1Contract(t *testing.T) {
 
@@ -69,6 +74,41 @@ func TestGoldenV1Contract(t *testing.T) {
 	assert.Equal(t, wantPlain, got)
 }
 
+// TestGoldenV1Contract_xchacha checks the frozen XChaCha20-Poly1305 row opens with a vault whose
+// keyring seals new rows with the default suite.
+func TestGoldenV1Contract_xchacha(t *testing.T) {
+	privBytes, err := hex.DecodeString(goldenLongTermPrivHex)
+	assert.Ok(t, err)
+	priv, err := ecdh.X25519().NewPrivateKey(privBytes)
+	assert.Ok(t, err)
+
+	wire, err := hex.DecodeString(goldenSealedV1XChaChaHex)
+	assert.Ok(t, err)
+
+	var msg models.Sealed
+	assert.Ok(t, msg.UnmarshalBinary(wire))
+	assert.Equal(t, suite.X25519HKDFSHA256XChaCha20Poly1305, msg.Meta.SuiteID)
+	assert.Len(t, msg.Dek.XNonce, constants.XChaChaNonceBytes)
+	assert.Len(t, msg.Body.XNonce, constants.XChaChaNonceBytes)
+
+	st := storage.NewMemStorage()
+	ctx := context.Background()
+	const rowID = "0123456789abcdef0123456789abcdef"
+	assert.Ok(t, st.Create(ctx, "golden-ns", rowID, wire))
+
+	v, err := v1.New(priv, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	got, err := v.Retrieve(ctx, "golden-ns", rowID)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("hello-golden"), got)
+
+	// Flipping the last inner byte must fail authentication.
+	wire[len(wire)-1] ^= 0x01
+	assert.Ok(t, st.Replace(ctx, "golden-ns", rowID, wire))
+	_, err = v.Retrieve(ctx, "golden-ns", rowID)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+}
+
 // TestGoldenV1Contract_tamperedWireFailsDecrypt flips one sealed byte and expects decrypt failure.
 func TestGoldenV1Contract_tamperedWireFailsDecrypt(t *testing.T) {
 	// Decode the hex-encoded long-term private key and sealed row.
@@ -0,0 +1,162 @@
+package v1
+
+// Keyring of long-term X25519 keys: one active key seals, every registered key can open.
+
+import (
+	"crypto/ecdh"
+	"errors"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	vaultgcm "go.rtnl.ai/x/vault/v1/gcm"
+	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
+)
+
+// Keyring holds the active long-term X25519 key used to seal new rows and any retired keys that
+// are still needed to open rows sealed (or wrapped) before a rotation. Keys are indexed by the
+// KeyID written into row metadata (the X25519 public key bytes). A Keyring is immutable after
+// construction and safe for concurrent use.
+type Keyring struct {
+	active   *ecdh.PrivateKey
+	template models.Meta // metadata template for the active key; namespace set per row
+	keys     map[string]*ecdh.PrivateKey
+	order    []string // KeyIDs in registration order, active first
+}
+
+// NewKeyring returns a [Keyring] that seals with active and opens with active or any of retired.
+// Opening a row whose KeyID matches none of these keys yields [v1errs.ErrUnknownKeyID]. A nil
+// active key yields [verrors.ErrNilPrivateKey]; any non-X25519 key yields
+// [verrors.ErrInvalidWrappingKey]. Nil retired keys are rejected the same way as a nil active key,
+// and repeating a key is harmless.
+func NewKeyring(active *ecdh.PrivateKey, retired ...*ecdh.PrivateKey) (*Keyring, error) {
+	meta, err := models.MetaFromPrivKey(active)
+	if err != nil {
+		return nil, err
+	}
+
+	kr := &Keyring{
+		active:   active,
+		template: meta,
+		keys:     make(map[string]*ecdh.PrivateKey, 1+len(retired)),
+	}
+	kr.keys[string(meta.KeyID)] = active
+	kr.order = append(kr.order, string(meta.KeyID))
+
+	for _, priv := range retired {
+		rmeta, err := models.MetaFromPrivKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := kr.keys[string(rmeta.KeyID)]; !ok {
+			kr.keys[string(rmeta.KeyID)] = priv
+			kr.order = append(kr.order, string(rmeta.KeyID))
+		}
+	}
+	return kr, nil
+}
+
+// WithSuite returns a copy of the keyring that seals new rows with suite id, for example
+// [suite.X25519HKDFSHA256XChaCha20Poly1305]. Opening is unaffected: every row is opened with the suite
+// named in its own metadata, so rows of any registered suite stay readable. The default from
+// [NewKeyring] is [suite.X25519HKDFSHA256AES256GCM]. An unknown suite yields [v1errs.ErrUnknownSuite].
+func (kr *Keyring) WithSuite(id suite.ID) (*Keyring, error) {
+	if !id.Valid() {
+		return nil, v1errs.ErrUnknownSuite
+	}
+	out := *kr
+	out.template.SuiteID = id
+	return &out, nil
+}
+
+// Suite returns the suite new rows are sealed with.
+func (kr *Keyring) Suite() suite.ID {
+	return kr.template.SuiteID
+}
+
+// Active returns the key used to seal new rows.
+func (kr *Keyring) Active() *ecdh.PrivateKey {
+	return kr.active
+}
+
+// ActiveKeyID returns a copy of the KeyID written into rows sealed by the active key.
+func (kr *Keyring) ActiveKeyID() []byte {
+	return append([]byte(nil), kr.template.KeyID...)
+}
+
+// Lookup returns the registered private key whose KeyID matches keyID.
+func (kr *Keyring) Lookup(keyID []byte) (*ecdh.PrivateKey, bool) {
+	priv, ok := kr.keys[string(keyID)]
+	return priv, ok
+}
+
+// Len returns the number of distinct keys on the ring, including the active key.
+func (kr *Keyring) Len() int {
+	return len(kr.keys)
+}
+
+// KeyIDs returns copies of the KeyIDs of every key on the ring, active key first and retired keys
+// in the order they were passed to [NewKeyring].
+func (kr *Keyring) KeyIDs() [][]byte {
+	ids := make([][]byte, 0, len(kr.order))
+	for _, id := range kr.order {
+		ids = append(ids, []byte(id))
+	}
+	return ids
+}
+
+// open returns the key for a row's KeyID, or [v1errs.ErrUnknownKeyID] joined with
+// [verrors.ErrDecrypt] when no registered key matches.
+func (kr *Keyring) open(keyID []byte) (*ecdh.PrivateKey, error) {
+	if priv, ok := kr.Lookup(keyID); ok {
+		return priv, nil
+	}
+	return nil, errors.Join(v1errs.ErrUnknownKeyID, verrors.ErrDecrypt)
+}
+
+// unwrapDEK selects the long-term key by msg.Meta.KeyID, performs ECDH with the row's ephemeral
+// public key, and opens the wrapped DEK authenticated against the row metadata. Callers must zero
+// the returned DEK.
+func (kr *Keyring) unwrapDEK(msg *models.Sealed) ([]byte, error) {
+	// Select the long-term key this row's DEK is wrapped for.
+	priv, err := kr.open(msg.Meta.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Marshal the row metadata for use as associated data.
+	metaRaw, err := msg.Meta.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reconstruct the ephemeral public key for ECDH.
+	epub, err := ecdh.X25519().NewPublicKey(msg.Dek.Pub[:])
+	if err != nil {
+		return nil, verrors.ErrDecrypt
+	}
+
+	// Perform ECDH with our private key and the ephemeral public key.
+	shared, err := priv.ECDH(epub)
+	if err != nil {
+		return nil, verrors.ErrDecrypt
+	}
+
+	// Derive the wrapping key from the shared secret for the row's suite.
+	wrapKey, err := vaultgcm.DeriveSuiteWrapKey(msg.Meta.SuiteID, shared)
+	if err != nil {
+		return nil, err
+	}
+	defer keys.Zero(wrapKey)
+
+	// Build AEAD for unwrapping the DEK.
+	wrapAEAD, err := vaultgcm.NewSuiteAEAD(msg.Meta.SuiteID, wrapKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unwrap and authenticate the DEK using the AEAD and metadata.
+	wrapped := vaultgcm.WrappedDEK{Pub: msg.Dek.Pub, Nonce: msg.Dek.Nonce, XNonce: msg.Dek.XNonce, Payload: msg.Dek.Payload}
+	return vaultgcm.OpenWrappedDEK(wrapAEAD, vaultgcm.WrapAAD(metaRaw), wrapped)
+}
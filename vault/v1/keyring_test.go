@@ -0,0 +1,218 @@
+package v1_test
+
+// Tests for [v1.Keyring] and [v1.NewWithKeyring].
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/v1/constants"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
+)
+
+// TestNewKeyring validates constructor errors and accessors.
+func TestNewKeyring(t *testing.T) {
+	active := testX25519Key(t)
+	retired := testX25519Key(t)
+
+	_, err := v1.NewKeyring(nil)
+	assert.ErrorIs(t, err, verrors.ErrNilPrivateKey)
+	_, err = v1.NewKeyring(active, nil)
+	assert.ErrorIs(t, err, verrors.ErrNilPrivateKey)
+
+	kr, err := v1.NewKeyring(active, retired, retired, active)
+	assert.Ok(t, err)
+	assert.Equal(t, 2, kr.Len())
+	assert.Equal(t, active, kr.Active())
+	assert.Equal(t, active.PublicKey().Bytes(), kr.ActiveKeyID())
+
+	got, ok := kr.Lookup(retired.PublicKey().Bytes())
+	assert.True(t, ok)
+	assert.Equal(t, retired, got)
+	_, ok = kr.Lookup(testX25519Key(t).PublicKey().Bytes())
+	assert.False(t, ok)
+
+	// ActiveKeyID returns a copy.
+	id := kr.ActiveKeyID()
+	id[0] ^= 0xff
+	assert.Equal(t, active.PublicKey().Bytes(), kr.ActiveKeyID())
+
+	// KeyIDs lists the active key first, then retired keys in order, without duplicates.
+	assert.Equal(t, [][]byte{active.PublicKey().Bytes(), retired.PublicKey().Bytes()}, kr.KeyIDs())
+}
+
+// TestKeyring_unknownKeyID verifies a row sealed for a key missing from the ring fails with the
+// distinct [v1errs.ErrUnknownKeyID], while a row whose wrapped DEK was tampered with fails only
+// with [verrors.ErrDecrypt].
+func TestKeyring_unknownKeyID(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	sealer := testX25519Key(t)
+
+	vSealer, err := v1.New(sealer, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	id, err := vSealer.Store(ctx, "ns", []byte("secret"))
+	assert.Ok(t, err)
+
+	kr, err := v1.NewKeyring(testX25519Key(t), testX25519Key(t))
+	assert.Ok(t, err)
+	v, err := v1.NewWithKeyring(kr, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	_, err = v.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, v1errs.ErrUnknownKeyID)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+
+	// A registered key with a corrupted wrapped DEK is a decrypt failure, not an unknown key.
+	wire, err := st.Get(ctx, "ns", id)
+	assert.Ok(t, err)
+	var msg models.Sealed
+	assert.Ok(t, msg.UnmarshalBinary(wire))
+	msg.Dek.Payload[0] ^= 0xff
+	bad, err := msg.MarshalBinary()
+	assert.Ok(t, err)
+	assert.Ok(t, st.Replace(ctx, "ns", id, bad))
+
+	_, err = vSealer.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+	assert.False(t, errors.Is(err, v1errs.ErrUnknownKeyID))
+}
+
+// TestNewWithKeyring_nilArgs verifies missing dependencies are rejected.
+func TestNewWithKeyring_nilArgs(t *testing.T) {
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+
+	_, err = v1.NewWithKeyring(nil, storage.NewMemStorage(), identifier.HexIdentifier{})
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.NewWithKeyring(kr, nil, identifier.HexIdentifier{})
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.NewWithKeyring(kr, storage.NewMemStorage(), nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+}
+
+// TestNewWithKeyring_opensRetiredRows verifies a keyring vault seals with the active key and still
+// opens rows sealed under a retired key, while a vault without the retired key cannot.
+func TestNewWithKeyring_opensRetiredRows(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	oldKey, newKey := testX25519Key(t), testX25519Key(t)
+
+	vOld, err := v1.New(oldKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	oldID, err := vOld.Store(ctx, "ns", []byte("old secret"))
+	assert.Ok(t, err)
+
+	kr, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+	v, err := v1.NewWithKeyring(kr, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+
+	got, err := v.Retrieve(ctx, "ns", oldID)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("old secret"), got)
+
+	// New rows are sealed for the active key only.
+	newID, err := v.Store(ctx, "ns", []byte("new secret"))
+	assert.Ok(t, err)
+	_, err = vOld.Retrieve(ctx, "ns", newID)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+
+	vNew, err := v1.New(newKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	got, err = vNew.Retrieve(ctx, "ns", newID)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("new secret"), got)
+}
+
+// TestKeyring_WithSuite verifies rows are sealed with the keyring's suite, open through a vault of
+// either suite, and keep their suite through a rewrap.
+func TestKeyring_WithSuite(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	oldKey, newKey := testX25519Key(t), testX25519Key(t)
+
+	aesKR, err := v1.NewKeyring(oldKey)
+	assert.Ok(t, err)
+	assert.Equal(t, suite.X25519HKDFSHA256AES256GCM, aesKR.Suite())
+
+	xKR, err := aesKR.WithSuite(suite.X25519HKDFSHA256XChaCha20Poly1305)
+	assert.Ok(t, err)
+	assert.Equal(t, suite.X25519HKDFSHA256XChaCha20Poly1305, xKR.Suite())
+	assert.Equal(t, suite.X25519HKDFSHA256AES256GCM, aesKR.Suite(), "original keyring is unchanged")
+
+	_, err = aesKR.WithSuite(suite.Unknown)
+	assert.ErrorIs(t, err, v1errs.ErrUnknownSuite)
+
+	vAES, err := v1.NewWithKeyring(aesKR, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	vX, err := v1.NewWithKeyring(xKR, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+
+	xID, err := vX.Store(ctx, "ns", []byte("xchacha secret"))
+	assert.Ok(t, err)
+	aesID, err := vAES.Store(ctx, "ns", []byte("aes secret"))
+	assert.Ok(t, err)
+
+	wire, err := st.Get(ctx, "ns", xID)
+	assert.Ok(t, err)
+	var msg models.Sealed
+	assert.Ok(t, msg.UnmarshalBinary(wire))
+	assert.Equal(t, suite.X25519HKDFSHA256XChaCha20Poly1305, msg.Meta.SuiteID)
+	assert.Len(t, msg.Body.XNonce, constants.XChaChaNonceBytes)
+
+	// Each row opens through either vault.
+	for _, v := range []vault.Vault{vAES, vX} {
+		got, err := v.Retrieve(ctx, "ns", xID)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("xchacha secret"), got)
+		got, err = v.Retrieve(ctx, "ns", aesID)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("aes secret"), got)
+	}
+
+	// Rewrapping for a new key keeps the row's suite, whatever the rotating keyring seals with.
+	rotKR, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+	out, rewrapped, err := rotKR.Rewrap(wire)
+	assert.Ok(t, err)
+	assert.True(t, rewrapped)
+	var rot models.Sealed
+	assert.Ok(t, rot.UnmarshalBinary(out))
+	assert.Equal(t, suite.X25519HKDFSHA256XChaCha20Poly1305, rot.Meta.SuiteID)
+	assert.Ok(t, st.Replace(ctx, "ns", xID, out))
+
+	vNew, err := v1.New(newKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	got, err := vNew.Retrieve(ctx, "ns", xID)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("xchacha secret"), got)
+
+	// Streams always use AES-256-GCM, even from an XChaCha keyring.
+	stream := sealStream(t, xKR, "ns", []byte("streamed"), 3)
+	sr, err := v1.NewStreamReader(bytes.NewReader(stream), aesKR, "ns")
+	assert.Ok(t, err)
+	got, err = io.ReadAll(sr)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("streamed"), got)
+}
+
+// testX25519Key returns a fresh X25519 private key.
+func testX25519Key(tb testing.TB) *ecdh.PrivateKey {
+	tb.Helper()
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(tb, err)
+	return priv
+}
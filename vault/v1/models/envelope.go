@@ -5,32 +5,60 @@ import (
 	v1errs "go.rtnl.ai/x/vault/v1/errors"
 )
 
-// DekEnvelope is the ECDH/HKDF/AEAD-wrapped per-row DEK (fixed 92 bytes for the initial v1 suite).
+// DekEnvelope is the ECDH/HKDF/AEAD-wrapped per-row DEK. It is fixed width for a given suite: 92
+// bytes for AES-256-GCM ([constants.DekEnvelopeBytes]) and 104 for XChaCha20-Poly1305
+// ([constants.XChaChaDekEnvelopeBytes]), differing only in nonce size.
 type DekEnvelope struct {
 	Pub     [constants.X25519PubBytes]byte
 	Nonce   [constants.WrapNonceBytes]byte
+	XNonce  []byte                                           // XChaCha20-Poly1305 wrap nonce in place of Nonce; nil for AES-256-GCM
 	Payload [constants.DEKBytes + constants.GCMTagBytes]byte // 48: 32-byte DEK + 16-byte tag
 }
 
-// MarshalBinary encodes DekEnvelope.
+// MarshalBinary encodes DekEnvelope, with XNonce in place of Nonce when it is set.
 func (d DekEnvelope) MarshalBinary() ([]byte, error) {
-	out := make([]byte, 0, constants.DekEnvelopeBytes)
+	if d.XNonce != nil && len(d.XNonce) != constants.XChaChaNonceBytes {
+		return nil, v1errs.ErrMalformedWire
+	}
+
+	nonce := d.Nonce[:]
+	if d.XNonce != nil {
+		nonce = d.XNonce
+	}
+	out := make([]byte, 0, constants.X25519PubBytes+len(nonce)+len(d.Payload))
 	out = append(out, d.Pub[:]...)
-	out = append(out, d.Nonce[:]...)
+	out = append(out, nonce...)
 	out = append(out, d.Payload[:]...)
 	return out, nil
 }
 
-// UnmarshalBinary decodes DekEnvelope; v1 requires exactly [constants.DekEnvelopeBytes].
+// UnmarshalBinary decodes DekEnvelope; the input must be exactly [constants.DekEnvelopeBytes], which
+// fills Nonce, or [constants.XChaChaDekEnvelopeBytes], which fills XNonce. [Sealed] passes the size
+// its suite calls for.
 func (d *DekEnvelope) UnmarshalBinary(data []byte) error {
 	if d == nil {
 		return v1errs.ErrNilDekEnvelopePointer
 	}
-	if len(data) != constants.DekEnvelopeBytes {
+
+	nonceBytes := len(data) - constants.X25519PubBytes - len(d.Payload)
+	if !validNonceSize(nonceBytes) {
 		return v1errs.ErrMalformedWire
 	}
+
+	nonce := data[constants.X25519PubBytes : constants.X25519PubBytes+nonceBytes]
 	copy(d.Pub[:], data[:constants.X25519PubBytes])
-	copy(d.Nonce[:], data[constants.X25519PubBytes:constants.X25519PubBytes+constants.WrapNonceBytes])
-	copy(d.Payload[:], data[constants.X25519PubBytes+constants.WrapNonceBytes:])
+	if nonceBytes == constants.WrapNonceBytes {
+		copy(d.Nonce[:], nonce)
+		d.XNonce = nil
+	} else {
+		d.Nonce = [constants.WrapNonceBytes]byte{}
+		d.XNonce = append([]byte(nil), nonce...)
+	}
+	copy(d.Payload[:], data[constants.X25519PubBytes+nonceBytes:])
 	return nil
 }
+
+// validNonceSize reports whether n is a nonce size some v1 suite uses.
+func validNonceSize(n int) bool {
+	return n == constants.InnerNonceBytes || n == constants.XChaChaNonceBytes
+}
@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"bytes"
 	"testing"
 
 	"go.rtnl.ai/x/assert"
@@ -36,3 +37,21 @@ func TestDekEnvelope_unmarshal_errors(t *testing.T) {
 	var p *models.DekEnvelope
 	assert.ErrorIs(t, p.UnmarshalBinary(make([]byte, constants.DekEnvelopeBytes)), v1errs.ErrNilDekEnvelopePointer)
 }
+
+// TestDekEnvelope_xchacha checks the XChaCha20-Poly1305 wire size round-trips through XNonce.
+func TestDekEnvelope_xchacha(t *testing.T) {
+	d := models.DekEnvelope{XNonce: bytes.Repeat([]byte{7}, constants.XChaChaNonceBytes)}
+	for i := range d.Payload {
+		d.Payload[i] = byte(i + 2)
+	}
+	raw, err := d.MarshalBinary()
+	assert.Ok(t, err)
+	assert.Equal(t, constants.XChaChaDekEnvelopeBytes, len(raw))
+	var got models.DekEnvelope
+	assert.Ok(t, got.UnmarshalBinary(raw))
+	assert.Equal(t, d, got)
+
+	assert.ErrorIs(t, got.UnmarshalBinary(make([]byte, constants.XChaChaDekEnvelopeBytes+1)), v1errs.ErrMalformedWire)
+	_, err = models.DekEnvelope{XNonce: make([]byte, constants.WrapNonceBytes)}.MarshalBinary()
+	assert.ErrorIs(t, err, v1errs.ErrMalformedWire)
+}
@@ -5,35 +5,63 @@ import (
 	v1errs "go.rtnl.ai/x/vault/v1/errors"
 )
 
-// Inner is nonce plus inner ciphertext+tag. GCM additional data is the marshaled row [Meta]
-// (see [Sealed.Meta]).
+// Inner is nonce plus inner ciphertext+tag. AEAD additional data is the marshaled row [Meta]
+// (see [Sealed.Meta]). The AES-256-GCM suite uses Nonce; XChaCha20-Poly1305 uses XNonce instead.
 type Inner struct {
 	Nonce   [constants.InnerNonceBytes]byte
-	Payload []byte // inner ciphertext including GCM tag ([constants.GCMTagBytes] bytes).
+	XNonce  []byte // XChaCha20-Poly1305 nonce in place of Nonce; nil for AES-256-GCM
+	Payload []byte // inner ciphertext including the tag ([constants.GCMTagBytes] bytes).
 }
 
-// MarshalBinary encodes Inner as nonce||payload.
+// SuiteNonce returns XNonce if it is set, and Nonce otherwise.
+func (i Inner) SuiteNonce() []byte {
+	if i.XNonce != nil {
+		return i.XNonce
+	}
+	return i.Nonce[:]
+}
+
+// MarshalBinary encodes Inner as nonce||payload, with XNonce in place of Nonce when it is set.
 func (i Inner) MarshalBinary() ([]byte, error) {
-	out := make([]byte, 0, len(i.Nonce)+len(i.Payload))
-	out = append(out, i.Nonce[:]...)
+	if i.XNonce != nil && len(i.XNonce) != constants.XChaChaNonceBytes {
+		return nil, v1errs.ErrMalformedWire
+	}
+
+	nonce := i.SuiteNonce()
+	out := make([]byte, 0, len(nonce)+len(i.Payload))
+	out = append(out, nonce...)
 	out = append(out, i.Payload...)
 	return out, nil
 }
 
-// UnmarshalBinary decodes Inner; consumes the full slice.
+// UnmarshalBinary decodes Inner with the AES-256-GCM suite's 12-byte nonce; consumes the full slice.
+// The encoding does not record its nonce size, so Inner from any other suite must be decoded with
+// [Inner.UnmarshalWithNonce], as [Sealed.UnmarshalBinary] does.
 func (i *Inner) UnmarshalBinary(data []byte) error {
+	return i.UnmarshalWithNonce(data, constants.InnerNonceBytes)
+}
+
+// UnmarshalWithNonce decodes Inner whose nonce is nonceBytes long, the suite's nonce size; consumes
+// the full slice.
+func (i *Inner) UnmarshalWithNonce(data []byte, nonceBytes int) error {
 	// Check if the receiver is nil.
 	if i == nil {
 		return v1errs.ErrNilInnerPointer
 	}
 
-	// Minimum wire is 12-byte nonce plus a GCM tag (empty plaintext still produces ciphertext length 0 + tag).
-	if len(data) < constants.InnerNonceBytes+constants.GCMTagBytes {
+	// Minimum wire is the nonce plus a tag (empty plaintext still produces ciphertext length 0 + tag).
+	if !validNonceSize(nonceBytes) || len(data) < nonceBytes+constants.GCMTagBytes {
 		return v1errs.ErrMalformedWire
 	}
 
 	// Split fixed prefix (nonce) from tail (everything the inner AEAD produced).
-	copy(i.Nonce[:], data[:constants.InnerNonceBytes])
-	i.Payload = append([]byte(nil), data[constants.InnerNonceBytes:]...)
+	if nonceBytes == constants.InnerNonceBytes {
+		copy(i.Nonce[:], data[:nonceBytes])
+		i.XNonce = nil
+	} else {
+		i.Nonce = [constants.InnerNonceBytes]byte{}
+		i.XNonce = append([]byte(nil), data[:nonceBytes]...)
+	}
+	i.Payload = append([]byte(nil), data[nonceBytes:]...)
 	return nil
 }
@@ -24,6 +24,31 @@ func TestInner_roundtrip(t *testing.T) {
 	assert.Equal(t, in.Payload, got.Payload)
 }
 
+// TestInner_unmarshalWithNonce checks the XChaCha20-Poly1305 nonce goes to XNonce and rejects sizes
+// no suite uses.
+func TestInner_unmarshalWithNonce(t *testing.T) {
+	in := models.Inner{
+		XNonce:  bytes.Repeat([]byte{9}, constants.XChaChaNonceBytes),
+		Payload: bytes.Repeat([]byte{'x'}, constants.GCMTagBytes+3),
+	}
+	raw, err := in.MarshalBinary()
+	assert.Ok(t, err)
+	var got models.Inner
+	assert.Ok(t, got.UnmarshalWithNonce(raw, constants.XChaChaNonceBytes))
+	assert.Equal(t, in, got)
+	assert.Equal(t, in.XNonce, got.SuiteNonce())
+
+	// The AES-256-GCM size fills Nonce and clears XNonce.
+	assert.Ok(t, got.UnmarshalWithNonce(raw, constants.InnerNonceBytes))
+	assert.Equal(t, []byte(nil), got.XNonce)
+	assert.Equal(t, raw[:constants.InnerNonceBytes], got.SuiteNonce())
+
+	assert.ErrorIs(t, got.UnmarshalWithNonce(raw, 16), v1errs.ErrMalformedWire)
+	assert.ErrorIs(t, got.UnmarshalWithNonce(raw[:constants.XChaChaNonceBytes+constants.GCMTagBytes-1], constants.XChaChaNonceBytes), v1errs.ErrMalformedWire)
+	_, err = models.Inner{XNonce: make([]byte, 16)}.MarshalBinary()
+	assert.ErrorIs(t, err, v1errs.ErrMalformedWire)
+}
+
 // TestInner_unmarshal_nil_receiver asserts [*models.Inner.UnmarshalBinary] on a nil receiver returns [v1errs.ErrNilInnerPointer].
 func TestInner_unmarshal_nil_receiver(t *testing.T) {
 	var p *models.Inner
@@ -4,6 +4,9 @@ package models
 
 import (
 	"crypto/ecdh"
+	"encoding/binary"
+	"math"
+	"time"
 
 	"go.rtnl.ai/x/vault/v1/constants"
 	v1errs "go.rtnl.ai/x/vault/v1/errors"
@@ -12,11 +15,23 @@ import (
 )
 
 // Meta is authenticated metadata carried on the wire; trust fields only after AEAD verify.
+//
+// KeyID names the long-term key the DEK is wrapped for. InnerKeyID is empty for freshly sealed
+// rows; after the DEK is re-wrapped for another key it keeps the original KeyID so the inner
+// payload AAD ([Meta.InnerAAD]) is unchanged and the payload does not need to be re-encrypted.
+// ExpiresAt, when set, is authenticated like every other field and survives re-wrapping.
 type Meta struct {
 	PackageVersion uint8
 	SuiteID        suite.ID
 	KeyID          []byte
-	Namespace      string // per-operation; raw []byte(Namespace) participates in caps and AAD
+	Namespace      string    // per-operation; raw []byte(Namespace) participates in caps and AAD
+	InnerKeyID     []byte    // optional extension; set only on re-wrapped rows
+	ExpiresAt      time.Time // optional extension; zero means the row never expires (second precision)
+}
+
+// Expired reports whether the row has an expiry and now is at or after it.
+func (m Meta) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
 }
 
 // WithNamespace returns a copy of [Meta] with [Meta.Namespace] set to namespace.
@@ -48,16 +63,41 @@ func (m Meta) MarshalBinary() ([]byte, error) {
 
 	// Deterministic layout (no padding): version | suite byte | keyID len | keyID | namespace len | namespace UTF-8.
 	// Length bytes are single uint8; KeyID and namespace lengths are bounded by constants.
-	out := make([]byte, 0, 1+1+1+len(m.KeyID)+1+len(ns))
+	out := make([]byte, 0, 1+1+1+len(m.KeyID)+1+len(ns)+constants.MaxMetaExtBytes)
 	out = append(out, m.PackageVersion)
 	out = append(out, byte(m.SuiteID))
 	out = append(out, byte(len(m.KeyID)))
 	out = append(out, m.KeyID...)
 	out = append(out, byte(len(ns)))
 	out = append(out, ns...)
+
+	// Optional extensions follow as tag | len | value in ascending tag order. Rows without any
+	// extension encode exactly as before extensions existed.
+	if len(m.InnerKeyID) > 0 {
+		out = append(out, constants.MetaExtInnerKeyID, byte(len(m.InnerKeyID)))
+		out = append(out, m.InnerKeyID...)
+	}
+	if !m.ExpiresAt.IsZero() {
+		out = append(out, constants.MetaExtExpiresAt, constants.MetaExpiresAtBytes)
+		out = binary.BigEndian.AppendUint64(out, uint64(m.ExpiresAt.Unix()))
+	}
 	return out, nil
 }
 
+// InnerAAD returns the additional data bound into the inner payload AEAD: the marshaled Meta as it
+// was when the row was first sealed. For re-wrapped rows that is this Meta with KeyID set back to
+// InnerKeyID and the extension removed; otherwise it equals [Meta.MarshalBinary].
+func (m Meta) InnerAAD() ([]byte, error) {
+	if len(m.InnerKeyID) == 0 {
+		return m.MarshalBinary()
+	}
+
+	orig := m
+	orig.KeyID = m.InnerKeyID
+	orig.InnerKeyID = nil
+	return orig.MarshalBinary()
+}
+
 // UnmarshalBinary decodes Meta; rejects trailing bytes and invalid wire.
 func (m *Meta) UnmarshalBinary(data []byte) error {
 	if m == nil {
@@ -101,21 +141,57 @@ func (m *Meta) UnmarshalBinary(data []byte) error {
 	m.Namespace = string(data[off : off+ln])
 	off += ln
 
-	// Trailing bytes would mean the encoder and decoder disagree on layout; reject rather than ignore.
-	if off != len(data) {
-		return v1errs.ErrMalformedWire
+	// Optional extensions: tag | len | value, strictly ascending tags. Unknown tags and trailing
+	// bytes would mean the encoder and decoder disagree on layout; reject rather than ignore.
+	m.InnerKeyID = nil
+	m.ExpiresAt = time.Time{}
+	var last uint8
+	for off < len(data) {
+		if off+2 > len(data) {
+			return v1errs.ErrMalformedWire
+		}
+		tag, le := data[off], int(data[off+1])
+		off += 2
+		if tag <= last || off+le > len(data) {
+			return v1errs.ErrMalformedWire
+		}
+		last = tag
+
+		switch tag {
+		case constants.MetaExtInnerKeyID:
+			if le == 0 || le > constants.MaxKeyIDBytes {
+				return v1errs.ErrMalformedWire
+			}
+			m.InnerKeyID = append([]byte(nil), data[off:off+le]...)
+		case constants.MetaExtExpiresAt:
+			if le != constants.MetaExpiresAtBytes {
+				return v1errs.ErrMalformedWire
+			}
+			secs := binary.BigEndian.Uint64(data[off : off+le])
+			if secs == 0 || secs > math.MaxInt64 {
+				return v1errs.ErrMalformedWire
+			}
+			m.ExpiresAt = time.Unix(int64(secs), 0).UTC()
+		default:
+			return v1errs.ErrMalformedWire
+		}
+		off += le
 	}
 	return nil
 }
 
-// validateMetaCaps checks KeyID and Namespace are within their byte-length caps.
+// validateMetaCaps checks KeyID, InnerKeyID, and Namespace are within their byte-length caps and that
+// ExpiresAt, if set, is encodable.
 func validateMetaCaps(m Meta) error {
-	if len(m.KeyID) > constants.MaxKeyIDBytes {
+	if len(m.KeyID) > constants.MaxKeyIDBytes || len(m.InnerKeyID) > constants.MaxKeyIDBytes {
 		return v1errs.ErrMetaKeyIDTooLarge
 	}
 	if len([]byte(m.Namespace)) > constants.MaxNamespaceBytes {
 		return v1errs.ErrMetaNamespaceTooLarge
 	}
+	if !m.ExpiresAt.IsZero() && m.ExpiresAt.Unix() <= 0 {
+		return v1errs.ErrMetaExpiry
+	}
 	return nil
 }
 
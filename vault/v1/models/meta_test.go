@@ -4,9 +4,11 @@ import (
 	"crypto/ecdh"
 	"crypto/rand"
 	"testing"
+	"time"
 
 	"go.rtnl.ai/x/assert"
 	"go.rtnl.ai/x/vault/v1/constants"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
 	verrors "go.rtnl.ai/x/vault/errors"
 	"go.rtnl.ai/x/vault/v1/models"
 	"go.rtnl.ai/x/vault/v1/suite"
@@ -55,3 +57,109 @@ func TestMetaFromPrivKey_nonX25519(t *testing.T) {
 	_, err = models.MetaFromPrivKey(priv)
 	assert.ErrorIs(t, err, verrors.ErrInvalidWrappingKey)
 }
+
+// TestMeta_innerKeyID_roundtrip checks the InnerKeyID extension survives marshal/unmarshal and that
+// [models.Meta.InnerAAD] reproduces the encoding of the row as first sealed.
+func TestMeta_innerKeyID_roundtrip(t *testing.T) {
+	orig := models.Meta{
+		PackageVersion: constants.PackageVersion,
+		SuiteID:        suite.X25519HKDFSHA256AES256GCM,
+		KeyID:          []byte{1, 2, 3},
+		Namespace:      "ns-a",
+	}
+	origRaw, err := orig.MarshalBinary()
+	assert.Ok(t, err)
+
+	rewrapped := orig
+	rewrapped.KeyID = []byte{4, 5, 6, 7}
+	rewrapped.InnerKeyID = orig.KeyID
+	b, err := rewrapped.MarshalBinary()
+	assert.Ok(t, err)
+	assert.Equal(t, len(origRaw)+1+2+len(orig.KeyID), len(b))
+
+	var got models.Meta
+	assert.Ok(t, got.UnmarshalBinary(b))
+	assert.Equal(t, rewrapped.KeyID, got.KeyID)
+	assert.Equal(t, rewrapped.InnerKeyID, got.InnerKeyID)
+	assert.Equal(t, rewrapped.Namespace, got.Namespace)
+
+	aad, err := got.InnerAAD()
+	assert.Ok(t, err)
+	assert.Equal(t, origRaw, aad)
+
+	// Without the extension the inner AAD is the marshaled metadata itself.
+	aad, err = orig.InnerAAD()
+	assert.Ok(t, err)
+	assert.Equal(t, origRaw, aad)
+}
+
+// TestMeta_extension_malformed checks unknown tags, empty values, and truncated extensions are rejected.
+func TestMeta_extension_malformed(t *testing.T) {
+	m := models.Meta{
+		PackageVersion: constants.PackageVersion,
+		SuiteID:        suite.X25519HKDFSHA256AES256GCM,
+		KeyID:          []byte{1, 2, 3},
+		Namespace:      "ns",
+	}
+	base, err := m.MarshalBinary()
+	assert.Ok(t, err)
+
+	for _, ext := range [][]byte{
+		{0x7f, 1, 0},                        // unknown tag
+		{constants.MetaExtInnerKeyID, 0},    // empty value
+		{constants.MetaExtInnerKeyID, 4, 1}, // truncated value
+		{constants.MetaExtInnerKeyID},       // missing length
+		{constants.MetaExtInnerKeyID, 1, 9, constants.MetaExtInnerKeyID, 1, 9}, // repeated tag
+		{constants.MetaExtExpiresAt, 4, 0, 0, 0, 1},                            // short expiry
+		{constants.MetaExtExpiresAt, 8, 0, 0, 0, 0, 0, 0, 0, 0},                // zero expiry
+		{constants.MetaExtExpiresAt, 8, 0x80, 0, 0, 0, 0, 0, 0, 0},             // expiry overflows int64
+	} {
+		var got models.Meta
+		err := got.UnmarshalBinary(append(append([]byte(nil), base...), ext...))
+		assert.ErrorIs(t, err, v1errs.ErrMalformedWire, "ext %x", ext)
+	}
+
+	// Extensions must appear in ascending tag order.
+	outOfOrder := append(append([]byte(nil), base...), constants.MetaExtExpiresAt, 8, 0, 0, 0, 0, 0, 0, 0, 1)
+	outOfOrder = append(outOfOrder, constants.MetaExtInnerKeyID, 1, 9)
+	var got models.Meta
+	assert.ErrorIs(t, got.UnmarshalBinary(outOfOrder), v1errs.ErrMalformedWire)
+}
+
+// TestMeta_expiresAt_roundtrip checks the ExpiresAt extension encodes after InnerKeyID at second
+// precision and is kept in the inner AAD.
+func TestMeta_expiresAt_roundtrip(t *testing.T) {
+	m := models.Meta{
+		PackageVersion: constants.PackageVersion,
+		SuiteID:        suite.X25519HKDFSHA256AES256GCM,
+		KeyID:          []byte{1, 2, 3},
+		Namespace:      "ns",
+		InnerKeyID:     []byte{4},
+		ExpiresAt:      time.Date(2030, 1, 2, 3, 4, 5, 999, time.UTC),
+	}
+	b, err := m.MarshalBinary()
+	assert.Ok(t, err)
+	assert.Equal(t, []byte{constants.MetaExtExpiresAt, constants.MetaExpiresAtBytes}, b[len(b)-10:len(b)-8])
+
+	var got models.Meta
+	assert.Ok(t, got.UnmarshalBinary(b))
+	assert.Equal(t, m.ExpiresAt.Truncate(time.Second), got.ExpiresAt)
+	assert.Equal(t, m.InnerKeyID, got.InnerKeyID)
+
+	assert.False(t, got.Expired(got.ExpiresAt.Add(-time.Second)))
+	assert.True(t, got.Expired(got.ExpiresAt))
+	assert.False(t, models.Meta{}.Expired(time.Now()))
+
+	// The inner AAD drops InnerKeyID but keeps the expiry.
+	aad, err := got.InnerAAD()
+	assert.Ok(t, err)
+	var inner models.Meta
+	assert.Ok(t, inner.UnmarshalBinary(aad))
+	assert.Equal(t, got.ExpiresAt, inner.ExpiresAt)
+	assert.Equal(t, []byte(nil), inner.InnerKeyID)
+
+	// Expiries at or before the epoch cannot be encoded.
+	m.ExpiresAt = time.Unix(0, 0)
+	_, err = m.MarshalBinary()
+	assert.ErrorIs(t, err, v1errs.ErrMetaExpiry)
+}
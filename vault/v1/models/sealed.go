@@ -74,7 +74,7 @@ func (s *Sealed) UnmarshalBinary(data []byte) error {
 		return v1errs.ErrMalformedWire
 	}
 
-	// Ensure the slice is long enough for preamble + meta + fixed DekEnvelope + minimal inner (nonce + tag).
+	// Ensure the slice is long enough for preamble + meta + the smallest DekEnvelope and inner (nonce + tag).
 	if len(data) < sealedPreambleBytes+lenMeta+constants.DekEnvelopeBytes+constants.InnerNonceBytes+constants.GCMTagBytes {
 		return v1errs.ErrMalformedWire
 	}
@@ -93,14 +93,20 @@ func (s *Sealed) UnmarshalBinary(data []byte) error {
 		return v1errs.ErrUnsupportedVersion
 	}
 
-	// DekEnvelope is fixed width for v1 suite; no length prefix between meta and inner.
-	if err := s.Dek.UnmarshalBinary(data[off : off+constants.DekEnvelopeBytes]); err != nil {
+	// DekEnvelope is fixed width for the row's suite; no length prefix between meta and inner. Meta
+	// decoding already rejected unknown suites.
+	envBytes := s.Meta.SuiteID.DekEnvelopeBytes()
+	if len(data) < off+envBytes {
+		return v1errs.ErrMalformedWire
+	}
+	if err := s.Dek.UnmarshalBinary(data[off : off+envBytes]); err != nil {
 		return err
 	}
-	off += constants.DekEnvelopeBytes
+	off += envBytes
 
 	// Remainder is the inner structure (nonce + ciphertext+tag); length varies only with plaintext size inside Inner.
-	if err := s.Body.UnmarshalBinary(data[off:]); err != nil {
+	// Its nonce size is not on the wire, so it comes from the suite rather than [Inner.UnmarshalBinary].
+	if err := s.Body.UnmarshalWithNonce(data[off:], s.Meta.SuiteID.NonceBytes()); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,147 @@
+package models
+
+// Wire framing for the header of a chunked v1 stream: magic, format version, meta length, [Meta],
+// [DekEnvelope], chunk size, and nonce prefix.
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"go.rtnl.ai/x/vault/v1/constants"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/suite"
+)
+
+// streamPreambleBytes is the fixed header before variable-length meta: magic(4) + formatVersion(1) + lenMeta u16 BE(2).
+const streamPreambleBytes = 4 + 1 + 2
+
+// streamTrailerBytes follows the meta: fixed DekEnvelope + chunk size u32 BE + nonce prefix.
+const streamTrailerBytes = constants.DekEnvelopeBytes + 4 + constants.StreamNoncePrefixBytes
+
+// StreamHeader opens a chunked stream; the chunks that follow are sealed under the wrapped DEK. The
+// metadata must name the AES-256-GCM suite, since the chunk nonce layout is built for its nonce size.
+type StreamHeader struct {
+	FormatVersion uint8
+	Meta          Meta
+	Dek           DekEnvelope
+	ChunkSize     uint32 // plaintext bytes per chunk; every chunk but the last is exactly this size
+	NoncePrefix   [constants.StreamNoncePrefixBytes]byte
+}
+
+// MarshalBinary encodes the stream header.
+func (h StreamHeader) MarshalBinary() ([]byte, error) {
+	if h.ChunkSize == 0 || h.ChunkSize > constants.MaxStreamChunkBytes {
+		return nil, v1errs.ErrStreamChunkSize
+	}
+	if h.Meta.SuiteID != suite.X25519HKDFSHA256AES256GCM {
+		return nil, v1errs.ErrUnknownSuite
+	}
+	metaRaw, err := h.Meta.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	dekRaw, err := h.Dek.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: magic | formatVersion | big-endian meta length | meta | DekEnvelope | chunk size | nonce prefix.
+	out := make([]byte, 0, streamPreambleBytes+len(metaRaw)+streamTrailerBytes)
+	out = append(out, constants.StreamMagic...)
+	out = append(out, h.FormatVersion)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(metaRaw)))
+	out = append(out, metaRaw...)
+	out = append(out, dekRaw...)
+	out = binary.BigEndian.AppendUint32(out, h.ChunkSize)
+	out = append(out, h.NoncePrefix[:]...)
+	return out, nil
+}
+
+// UnmarshalBinary decodes a complete stream header; rejects trailing bytes.
+func (h *StreamHeader) UnmarshalBinary(data []byte) error {
+	if h == nil {
+		return v1errs.ErrNilSealedPointer
+	}
+	if len(data) < streamPreambleBytes {
+		return v1errs.ErrMalformedWire
+	}
+	if string(data[0:4]) != constants.StreamMagic {
+		return v1errs.ErrBadMagic
+	}
+	h.FormatVersion = data[4]
+	lenMeta := int(binary.BigEndian.Uint16(data[5:7]))
+	if lenMeta < 4 || lenMeta > constants.MaxMetaWireBytes || len(data) != streamPreambleBytes+lenMeta+streamTrailerBytes {
+		return v1errs.ErrMalformedWire
+	}
+
+	off := streamPreambleBytes
+	if err := h.Meta.UnmarshalBinary(data[off : off+lenMeta]); err != nil {
+		return err
+	}
+	off += lenMeta
+
+	// Outer format byte must match the metadata block's package version, as for sealed rows.
+	if h.FormatVersion != h.Meta.PackageVersion {
+		return v1errs.ErrVersionMismatch
+	}
+	if h.FormatVersion != constants.PackageVersion {
+		return v1errs.ErrUnsupportedVersion
+	}
+
+	if h.Meta.SuiteID != suite.X25519HKDFSHA256AES256GCM {
+		return v1errs.ErrUnknownSuite
+	}
+
+	if err := h.Dek.UnmarshalBinary(data[off : off+constants.DekEnvelopeBytes]); err != nil {
+		return err
+	}
+	off += constants.DekEnvelopeBytes
+
+	h.ChunkSize = binary.BigEndian.Uint32(data[off : off+4])
+	if h.ChunkSize == 0 || h.ChunkSize > constants.MaxStreamChunkBytes {
+		return v1errs.ErrStreamChunkSize
+	}
+	off += 4
+	copy(h.NoncePrefix[:], data[off:])
+	return nil
+}
+
+// ReadStreamHeader reads exactly one stream header from r and returns it with its raw bytes (the
+// chunk AAD is derived from them). A stream that ends inside the header yields
+// [v1errs.ErrMalformedWire]; other read errors are returned as is.
+func ReadStreamHeader(r io.Reader) (StreamHeader, []byte, error) {
+	raw := make([]byte, streamPreambleBytes, streamPreambleBytes+constants.MaxMetaWireBytes+streamTrailerBytes)
+	if err := readFull(r, raw); err != nil {
+		return StreamHeader{}, nil, err
+	}
+	if string(raw[0:4]) != constants.StreamMagic {
+		return StreamHeader{}, nil, v1errs.ErrBadMagic
+	}
+
+	lenMeta := int(binary.BigEndian.Uint16(raw[5:7]))
+	if lenMeta < 4 || lenMeta > constants.MaxMetaWireBytes {
+		return StreamHeader{}, nil, v1errs.ErrMalformedWire
+	}
+	raw = raw[:streamPreambleBytes+lenMeta+streamTrailerBytes]
+	if err := readFull(r, raw[streamPreambleBytes:]); err != nil {
+		return StreamHeader{}, nil, err
+	}
+
+	var h StreamHeader
+	if err := h.UnmarshalBinary(raw); err != nil {
+		return StreamHeader{}, nil, err
+	}
+	return h, raw, nil
+}
+
+// readFull fills buf from r, mapping a short read to [v1errs.ErrMalformedWire].
+func readFull(r io.Reader, buf []byte) error {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return v1errs.ErrMalformedWire
+		}
+		return err
+	}
+	return nil
+}
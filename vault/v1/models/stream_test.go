@@ -0,0 +1,85 @@
+package models_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault/v1/constants"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
+)
+
+// TestStreamHeader_roundtrip checks marshal, unmarshal, and [models.ReadStreamHeader] agree and that
+// the reader consumes only the header.
+func TestStreamHeader_roundtrip(t *testing.T) {
+	h := testStreamHeader()
+	raw, err := h.MarshalBinary()
+	assert.Ok(t, err)
+
+	var got models.StreamHeader
+	assert.Ok(t, got.UnmarshalBinary(raw))
+	assert.Equal(t, h, got)
+
+	r := bytes.NewReader(append(bytes.Clone(raw), "chunks"...))
+	read, readRaw, err := models.ReadStreamHeader(r)
+	assert.Ok(t, err)
+	assert.Equal(t, h, read)
+	assert.Equal(t, raw, readRaw)
+	assert.Equal(t, len("chunks"), r.Len())
+}
+
+// TestStreamHeader_malformed covers bad magic, chunk sizes, versions, and truncated or padded input.
+func TestStreamHeader_malformed(t *testing.T) {
+	h := testStreamHeader()
+	raw, err := h.MarshalBinary()
+	assert.Ok(t, err)
+
+	var got models.StreamHeader
+	for n := range len(raw) {
+		assert.Error(t, got.UnmarshalBinary(raw[:n]), "cut at %d", n)
+		_, _, err := models.ReadStreamHeader(bytes.NewReader(raw[:n]))
+		assert.Error(t, err, "read cut at %d", n)
+	}
+	assert.ErrorIs(t, got.UnmarshalBinary(append(bytes.Clone(raw), 0)), v1errs.ErrMalformedWire)
+
+	bad := bytes.Clone(raw)
+	bad[0] = 'X'
+	assert.ErrorIs(t, got.UnmarshalBinary(bad), v1errs.ErrBadMagic)
+
+	bad = bytes.Clone(raw)
+	bad[4]++
+	assert.ErrorIs(t, got.UnmarshalBinary(bad), v1errs.ErrVersionMismatch)
+
+	for _, size := range []uint32{0, constants.MaxStreamChunkBytes + 1} {
+		h.ChunkSize = size
+		_, err := h.MarshalBinary()
+		assert.ErrorIs(t, err, v1errs.ErrStreamChunkSize)
+
+		bad = bytes.Clone(raw)
+		off := len(raw) - constants.StreamNoncePrefixBytes - 4
+		bad[off], bad[off+1], bad[off+2], bad[off+3] = byte(size>>24), byte(size>>16), byte(size>>8), byte(size)
+		assert.ErrorIs(t, got.UnmarshalBinary(bad), v1errs.ErrStreamChunkSize)
+	}
+}
+
+func testStreamHeader() models.StreamHeader {
+	h := models.StreamHeader{
+		FormatVersion: constants.PackageVersion,
+		Meta: models.Meta{
+			PackageVersion: constants.PackageVersion,
+			SuiteID:        suite.X25519HKDFSHA256AES256GCM,
+			KeyID:          []byte{1, 2, 3},
+			Namespace:      "ns",
+		},
+		ChunkSize: 4096,
+	}
+	for i := range h.Dek.Payload {
+		h.Dek.Payload[i] = byte(i)
+	}
+	for i := range h.NoncePrefix {
+		h.NoncePrefix[i] = byte(0xa0 + i)
+	}
+	return h
+}
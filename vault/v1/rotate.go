@@ -0,0 +1,217 @@
+package v1
+
+// Key rotation: re-wrap each row's DEK for the keyring's active key without touching the payload.
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"io"
+	"iter"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+	"go.rtnl.ai/x/vault/storage"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/models"
+)
+
+// DefaultRotateRetries is the number of times [Rotate] re-reads and re-wraps a row whose
+// compare-and-swap lost a race with a concurrent writer before reporting the row as failed.
+const DefaultRotateRetries = 3
+
+//=============================================================================
+// Rewrap
+//=============================================================================
+
+// Rewrap re-wraps the DEK of a sealed v1 row for the keyring's active key. The row's DEK is
+// unwrapped with the registered key named by its KeyID, wrapped again under a fresh ephemeral key
+// and nonce, and written with KeyID set to the active key. The inner nonce and payload are copied
+// unchanged: the original KeyID is kept in [models.Meta.InnerKeyID] so the payload's associated data
+// still verifies. Rows already wrapped for the active key are returned as-is with rewrapped false.
+//
+// Wire errors are returned from [models.Sealed.UnmarshalBinary]; a row whose KeyID is not on the
+// keyring yields [v1errs.ErrUnknownKeyID] and a wrapped DEK that fails authentication yields
+// [verrors.ErrDecrypt]. The payload is not decrypted, so a corrupt payload is only detected when
+// the row is opened.
+func (kr *Keyring) Rewrap(wire []byte) (out []byte, rewrapped bool, err error) {
+	var msg models.Sealed
+	if err = msg.UnmarshalBinary(wire); err != nil {
+		return nil, false, err
+	}
+
+	// Nothing to do when the DEK is already wrapped for the active key.
+	if bytes.Equal(msg.Meta.KeyID, kr.template.KeyID) {
+		return wire, false, nil
+	}
+
+	// Recover the DEK with whichever registered key it is currently wrapped for.
+	dek, err := kr.unwrapDEK(&msg)
+	if err != nil {
+		return nil, false, err
+	}
+	defer keys.Zero(dek)
+
+	// Point the metadata at the active key, remembering the key the payload was first sealed
+	// under. Rotating back to that key drops the extension, restoring the original encoding.
+	meta := msg.Meta
+	if len(meta.InnerKeyID) == 0 {
+		meta.InnerKeyID = meta.KeyID
+	}
+	meta.KeyID = kr.template.KeyID
+	if bytes.Equal(meta.InnerKeyID, meta.KeyID) {
+		meta.InnerKeyID = nil
+	}
+
+	metaRaw, err := meta.MarshalBinary()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Generate a fresh ephemeral key and wrap nonce for the new envelope.
+	ephPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, false, verrors.ErrSealFailed
+	}
+
+	// The row keeps its suite: the payload is not touched, so it cannot change.
+	wrapNonce := make([]byte, meta.SuiteID.NonceBytes())
+	if _, err = io.ReadFull(rand.Reader, wrapNonce); err != nil {
+		return nil, false, verrors.ErrSealFailed
+	}
+
+	dekEnv, err := wrapDEK(meta.SuiteID, kr.active.PublicKey(), metaRaw, dek, ephPriv, wrapNonce)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Reassemble the row around the untouched inner payload.
+	msg.Meta = meta
+	msg.Dek = dekEnv
+	if out, err = msg.MarshalBinary(); err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+//=============================================================================
+// Rotate
+//=============================================================================
+
+// RotateResult reports the outcome for one row visited by [Rotate].
+type RotateResult struct {
+	Namespace string
+	ID        string
+	Rewrapped bool  // false if the row was already wrapped for the active key (or failed)
+	Err       error // nil on success
+}
+
+// RotateOptions configures [Rotate]. A nil *RotateOptions uses the defaults.
+type RotateOptions struct {
+	// Progress, if set, is called once per visited row, in iteration order.
+	Progress func(RotateResult)
+
+	// StopOnError stops at the first failed row and returns its error. By default Rotate keeps
+	// going and reports failures through Progress and [RotateSummary.Failed].
+	StopOnError bool
+
+	// Retries bounds how often a row is re-read after losing a compare-and-swap race; zero uses
+	// [DefaultRotateRetries].
+	Retries int
+}
+
+// RotateSummary counts the rows visited by [Rotate].
+type RotateSummary struct {
+	Visited   int // rows taken from the iterator
+	Rewrapped int // rows re-wrapped and written back
+	Skipped   int // rows already wrapped for the active key, or deleted during the walk
+	Failed    int // rows that could not be read, re-wrapped, or written
+}
+
+// Rotate walks rows (namespace, id pairs) and re-wraps each stored row's DEK for the keyring's
+// active key with [Keyring.Rewrap], writing the result back with [storage.Storage.CompareAndSwap] so
+// concurrent updates are never overwritten; a lost race re-reads the row and tries again. The inner
+// payload is never decrypted. Rows deleted during the walk are counted as skipped.
+//
+// Nil storage, keyring, or rows yields [verrors.ErrInvalidNewArgs]. Storage failures for a row are
+// joined with [verrors.ErrStorage]. With [RotateOptions.StopOnError] the first row error is returned;
+// otherwise Rotate visits every row and returns [v1errs.ErrRotateIncomplete] if any failed. A
+// canceled context stops the walk and returns the context error. Rotate is idempotent, so an
+// interrupted rotation can simply be run again.
+func Rotate(ctx context.Context, st storage.Storage, kr *Keyring, rows iter.Seq2[string, string], opts *RotateOptions) (RotateSummary, error) {
+	var sum RotateSummary
+	if st == nil || kr == nil || rows == nil {
+		return sum, verrors.ErrInvalidNewArgs
+	}
+	if opts == nil {
+		opts = &RotateOptions{}
+	}
+
+	retries := opts.Retries
+	if retries <= 0 {
+		retries = DefaultRotateRetries
+	}
+
+	for namespace, id := range rows {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+
+		res := RotateResult{Namespace: namespace, ID: id}
+		res.Rewrapped, res.Err = rotateRow(ctx, st, kr, namespace, id, retries)
+
+		sum.Visited++
+		switch {
+		case res.Err != nil:
+			sum.Failed++
+		case res.Rewrapped:
+			sum.Rewrapped++
+		default:
+			sum.Skipped++
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(res)
+		}
+		if res.Err != nil && opts.StopOnError {
+			return sum, res.Err
+		}
+	}
+
+	if sum.Failed > 0 {
+		return sum, v1errs.ErrRotateIncomplete
+	}
+	return sum, nil
+}
+
+// rotateRow re-wraps a single row, retrying when a concurrent writer wins the compare-and-swap.
+func rotateRow(ctx context.Context, st storage.Storage, kr *Keyring, namespace, id string, retries int) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		wire, err := st.Get(ctx, namespace, id)
+		if err != nil {
+			if errors.Is(err, verrors.ErrNotFound) {
+				return false, nil
+			}
+			return false, errors.Join(verrors.ErrStorage, err)
+		}
+
+		out, rewrapped, err := kr.Rewrap(wire)
+		if err != nil || !rewrapped {
+			return false, err
+		}
+
+		err = st.CompareAndSwap(ctx, namespace, id, wire, out)
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, verrors.ErrNotFound):
+			return false, nil
+		case errors.Is(err, verrors.ErrCASFailed) && attempt < retries:
+			continue
+		default:
+			return false, errors.Join(verrors.ErrStorage, err)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package v1_test
+
+// Tests for [v1.Keyring.Rewrap] and [v1.Rotate].
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/models"
+)
+
+//=============================================================================
+// Tests: Rewrap
+//=============================================================================
+
+// TestKeyring_Rewrap verifies a re-wrapped row names the active key, keeps the inner payload
+// byte-for-byte, opens with only the new key, and is left alone by a second re-wrap.
+func TestKeyring_Rewrap(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	oldKey, newKey := testX25519Key(t), testX25519Key(t)
+
+	vOld, err := v1.New(oldKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	id, err := vOld.Store(ctx, "ns", []byte("secret"))
+	assert.Ok(t, err)
+	wire, err := st.Get(ctx, "ns", id)
+	assert.Ok(t, err)
+
+	kr, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+	out, rewrapped, err := kr.Rewrap(wire)
+	assert.Ok(t, err)
+	assert.True(t, rewrapped)
+
+	var before, after models.Sealed
+	assert.Ok(t, before.UnmarshalBinary(wire))
+	assert.Ok(t, after.UnmarshalBinary(out))
+	assert.Equal(t, newKey.PublicKey().Bytes(), after.Meta.KeyID)
+	assert.Equal(t, oldKey.PublicKey().Bytes(), after.Meta.InnerKeyID)
+	assert.Equal(t, before.Body, after.Body)
+	assert.NotEqual(t, before.Dek, after.Dek)
+
+	// Only the new key opens the re-wrapped row.
+	assert.Ok(t, st.Replace(ctx, "ns", id, out))
+	vNew, err := v1.New(newKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	got, err := vNew.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("secret"), got)
+	_, err = vOld.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+
+	// Already under the active key: unchanged.
+	again, rewrapped, err := kr.Rewrap(out)
+	assert.Ok(t, err)
+	assert.False(t, rewrapped)
+	assert.Equal(t, out, again)
+}
+
+// TestKeyring_Rewrap_chain verifies rotating twice keeps the original inner key id, and rotating
+// back to the original key restores the extension-free metadata encoding.
+func TestKeyring_Rewrap_chain(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	k1, k2, k3 := testX25519Key(t), testX25519Key(t), testX25519Key(t)
+
+	v, err := v1.New(k1, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	id, err := v.Store(ctx, "ns", []byte("secret"))
+	assert.Ok(t, err)
+	wire, err := st.Get(ctx, "ns", id)
+	assert.Ok(t, err)
+
+	kr2, err := v1.NewKeyring(k2, k1)
+	assert.Ok(t, err)
+	w2, _, err := kr2.Rewrap(wire)
+	assert.Ok(t, err)
+
+	kr3, err := v1.NewKeyring(k3, k2)
+	assert.Ok(t, err)
+	w3, _, err := kr3.Rewrap(w2)
+	assert.Ok(t, err)
+
+	var msg models.Sealed
+	assert.Ok(t, msg.UnmarshalBinary(w3))
+	assert.Equal(t, k3.PublicKey().Bytes(), msg.Meta.KeyID)
+	assert.Equal(t, k1.PublicKey().Bytes(), msg.Meta.InnerKeyID)
+
+	assert.Ok(t, st.Replace(ctx, "ns", id, w3))
+	v3, err := v1.New(k3, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	got, err := v3.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("secret"), got)
+
+	// Back to k1: metadata matches a freshly sealed row again.
+	kr1, err := v1.NewKeyring(k1, k3)
+	assert.Ok(t, err)
+	w1, _, err := kr1.Rewrap(w3)
+	assert.Ok(t, err)
+	assert.Ok(t, msg.UnmarshalBinary(w1))
+	assert.Equal(t, k1.PublicKey().Bytes(), msg.Meta.KeyID)
+	assert.Len(t, msg.Meta.InnerKeyID, 0)
+
+	assert.Ok(t, st.Replace(ctx, "ns", id, w1))
+	got, err = v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("secret"), got)
+}
+
+// TestKeyring_Rewrap_errors verifies unknown keys, malformed wire, and tampered metadata fail.
+func TestKeyring_Rewrap_errors(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+	id, err := v.Store(ctx, "ns", []byte("secret"))
+	assert.Ok(t, err)
+	wire, err := st.Get(ctx, "ns", id)
+	assert.Ok(t, err)
+
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+	_, _, err = kr.Rewrap(wire)
+	assert.ErrorIs(t, err, v1errs.ErrUnknownKeyID)
+
+	_, _, err = kr.Rewrap([]byte("not a row"))
+	assert.Error(t, err)
+}
+
+//=============================================================================
+// Tests: Rotate
+//=============================================================================
+
+// TestRotate moves a mix of old-key, new-key, and missing rows to the active key and checks the
+// summary, per-row progress, and that every row still decrypts.
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	oldKey, newKey := testX25519Key(t), testX25519Key(t)
+
+	vOld, err := v1.New(oldKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	kr, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+	v, err := v1.NewWithKeyring(kr, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+
+	var rows [][2]string
+	for _, ns := range []string{"a", "b"} {
+		id, err := vOld.Store(ctx, ns, []byte("old-"+ns))
+		assert.Ok(t, err)
+		rows = append(rows, [2]string{ns, id})
+	}
+	id, err := v.Store(ctx, "a", []byte("new-a"))
+	assert.Ok(t, err)
+	rows = append(rows, [2]string{"a", id}, [2]string{"a", "00000000000000000000000000000000"})
+
+	var seen []v1.RotateResult
+	sum, err := v1.Rotate(ctx, st, kr, pairs(rows), &v1.RotateOptions{
+		Progress: func(r v1.RotateResult) { seen = append(seen, r) },
+	})
+	assert.Ok(t, err)
+	assert.Equal(t, v1.RotateSummary{Visited: 4, Rewrapped: 2, Skipped: 2}, sum)
+	assert.Len(t, seen, 4)
+	assert.True(t, seen[0].Rewrapped)
+	assert.True(t, seen[1].Rewrapped)
+	assert.False(t, seen[2].Rewrapped)
+	assert.Equal(t, rows[1][1], seen[1].ID)
+
+	// The retired key is no longer needed.
+	vNew, err := v1.New(newKey, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	for _, r := range rows[:2] {
+		got, err := vNew.Retrieve(ctx, r[0], r[1])
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("old-"+r[0]), got)
+	}
+
+	// A second run is a no-op.
+	sum, err = v1.Rotate(ctx, st, kr, pairs(rows), nil)
+	assert.Ok(t, err)
+	assert.Equal(t, v1.RotateSummary{Visited: 4, Skipped: 4}, sum)
+}
+
+// TestRotate_failures verifies undecryptable rows are reported per row and make the rotation
+// incomplete, or stop it early with StopOnError.
+func TestRotate_failures(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	foreign := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+	badID, err := foreign.Store(ctx, "ns", []byte("unknown key"))
+	assert.Ok(t, err)
+
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+	v, err := v1.NewWithKeyring(kr, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	okID, err := v.Store(ctx, "ns", []byte("ok"))
+	assert.Ok(t, err)
+	rows := [][2]string{{"ns", badID}, {"ns", okID}}
+
+	var failed []v1.RotateResult
+	sum, err := v1.Rotate(ctx, st, kr, pairs(rows), &v1.RotateOptions{
+		Progress: func(r v1.RotateResult) {
+			if r.Err != nil {
+				failed = append(failed, r)
+			}
+		},
+	})
+	assert.ErrorIs(t, err, v1errs.ErrRotateIncomplete)
+	assert.Equal(t, v1.RotateSummary{Visited: 2, Skipped: 1, Failed: 1}, sum)
+	assert.Len(t, failed, 1)
+	assert.Equal(t, badID, failed[0].ID)
+	assert.ErrorIs(t, failed[0].Err, v1errs.ErrUnknownKeyID)
+
+	sum, err = v1.Rotate(ctx, st, kr, pairs(rows), &v1.RotateOptions{StopOnError: true})
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+	assert.Equal(t, v1.RotateSummary{Visited: 1, Failed: 1}, sum)
+}
+
+// TestRotate_casRetry verifies a lost compare-and-swap re-reads the row and retries.
+func TestRotate_casRetry(t *testing.T) {
+	ctx := context.Background()
+	mem := storage.NewMemStorage()
+	oldKey, newKey := testX25519Key(t), testX25519Key(t)
+	vOld, err := v1.New(oldKey, mem, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	id, err := vOld.Store(ctx, "ns", []byte("secret"))
+	assert.Ok(t, err)
+
+	st := &casFailStorage{MemStorage: mem}
+	kr, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+
+	sum, err := v1.Rotate(ctx, st, kr, pairs([][2]string{{"ns", id}}), &v1.RotateOptions{Retries: 2})
+	assert.ErrorIs(t, err, v1errs.ErrRotateIncomplete)
+	assert.Equal(t, 1, sum.Failed)
+
+	sum, err = v1.Rotate(ctx, &flakyCASStorage{Storage: mem, fails: 2}, kr, pairs([][2]string{{"ns", id}}), nil)
+	assert.Ok(t, err)
+	assert.Equal(t, 1, sum.Rewrapped)
+}
+
+// TestRotate_args verifies argument validation and context cancellation.
+func TestRotate_args(t *testing.T) {
+	st := storage.NewMemStorage()
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+	rows := pairs([][2]string{{"ns", "id"}})
+
+	_, err = v1.Rotate(context.Background(), nil, kr, rows, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.Rotate(context.Background(), st, nil, rows, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.Rotate(context.Background(), st, kr, nil, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sum, err := v1.Rotate(ctx, st, kr, rows, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, sum.Visited)
+}
+
+//=============================================================================
+// Rotate helpers
+//=============================================================================
+
+// pairs yields (namespace, id) rows in order.
+func pairs(rows [][2]string) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, r := range rows {
+			if !yield(r[0], r[1]) {
+				return
+			}
+		}
+	}
+}
+
+// flakyCASStorage fails the first fails compare-and-swap calls with [verrors.ErrCASFailed].
+type flakyCASStorage struct {
+	storage.Storage
+	fails int
+}
+
+func (s *flakyCASStorage) CompareAndSwap(ctx context.Context, namespace, id string, oldCipher, newCipher []byte) error {
+	if s.fails > 0 {
+		s.fails--
+		return verrors.ErrCASFailed
+	}
+	return s.Storage.CompareAndSwap(ctx, namespace, id, oldCipher, newCipher)
+}
@@ -0,0 +1,338 @@
+package v1
+
+// Chunked streaming encryption for secrets too large to seal as a single row. A stream reuses the row
+// envelope: a fresh DEK is wrapped for the keyring's active key under the same [models.Meta] and
+// [models.DekEnvelope] layout, and the payload follows as a sequence of AES-256-GCM chunks.
+//
+// Wire layout: [models.StreamHeader] followed by chunks. Every chunk but the last carries exactly
+// ChunkSize plaintext bytes; the last carries 0..ChunkSize. Each chunk is sealed with nonce
+// prefix || counter || final flag (see [vaultgcm.StreamNonce]) and the raw header as additional data
+// (see [vaultgcm.StreamAAD]), so chunks cannot be reordered, dropped, or moved between streams, and a
+// stream cut at a chunk boundary is reported as [v1errs.ErrStreamTruncated].
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+	"go.rtnl.ai/x/vault/v1/constants"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	vaultgcm "go.rtnl.ai/x/vault/v1/gcm"
+	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
+)
+
+// StreamOptions configures [NewStreamWriter]. A nil *StreamOptions uses the defaults.
+type StreamOptions struct {
+	// ChunkSize is the plaintext size of each chunk; zero uses [constants.DefaultStreamChunkBytes].
+	// Larger chunks cost memory on both ends; smaller chunks cost 16 bytes of tag apiece.
+	ChunkSize int
+}
+
+//=============================================================================
+// Writer
+//=============================================================================
+
+// StreamWriter encrypts a stream for the keyring's active key. Data written is buffered into chunks;
+// [StreamWriter.Close] seals the final chunk, which readers require before reporting the end of the
+// stream. A StreamWriter is not safe for concurrent use.
+type StreamWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	aad     []byte
+	prefix  [constants.StreamNoncePrefixBytes]byte
+	buf     []byte // pending plaintext, at most one chunk
+	counter uint32
+	closed  bool
+	err     error // sticky write or seal failure
+}
+
+// NewStreamWriter writes a stream header for namespace to w and returns a writer for the payload.
+// The header binds namespace, the active key's id, and the chunk size; [NewStreamReader] must be
+// given the same namespace. A nil keyring or writer returns [verrors.ErrInvalidNewArgs]; an invalid
+// chunk size returns [v1errs.ErrStreamChunkSize]. Randomness failures return [verrors.ErrSealFailed],
+// and errors writing the header are returned as is.
+func NewStreamWriter(w io.Writer, kr *Keyring, namespace string, opts *StreamOptions) (*StreamWriter, error) {
+	if w == nil || kr == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = constants.DefaultStreamChunkBytes
+	}
+	if chunkSize < 0 || chunkSize > constants.MaxStreamChunkBytes {
+		return nil, v1errs.ErrStreamChunkSize
+	}
+
+	// Prepare stream metadata as for a row sealed in this namespace. Streams always use AES-256-GCM,
+	// whatever the keyring's suite: the chunk nonce layout is built for its nonce size.
+	meta, err := kr.template.WithNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	meta.SuiteID = suite.X25519HKDFSHA256AES256GCM
+	metaRaw, err := meta.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate a fresh DEK and wrap it for the active key, as for a row.
+	dek := make([]byte, constants.DEKBytes)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, verrors.ErrSealFailed
+	}
+	defer keys.Zero(dek)
+
+	ephPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, verrors.ErrSealFailed
+	}
+
+	wrapNonce := make([]byte, constants.WrapNonceBytes)
+	if _, err := io.ReadFull(rand.Reader, wrapNonce); err != nil {
+		return nil, verrors.ErrSealFailed
+	}
+
+	dekEnv, err := wrapDEK(meta.SuiteID, kr.active.PublicKey(), metaRaw, dek, ephPriv, wrapNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// The nonce prefix is random per stream; the DEK is too, so this only adds margin.
+	hdr := models.StreamHeader{
+		FormatVersion: constants.PackageVersion,
+		Meta:          meta,
+		Dek:           dekEnv,
+		ChunkSize:     uint32(chunkSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, hdr.NoncePrefix[:]); err != nil {
+		return nil, verrors.ErrSealFailed
+	}
+
+	hdrRaw, err := hdr.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := vaultgcm.NewInnerAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(hdrRaw); err != nil {
+		return nil, err
+	}
+
+	return &StreamWriter{
+		w:      w,
+		aead:   aead,
+		aad:    vaultgcm.StreamAAD(hdrRaw),
+		prefix: hdr.NoncePrefix,
+		buf:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+// Write buffers p, sealing and writing each full chunk once more data follows it (the last chunk is
+// only sealed by [StreamWriter.Close]). Writing after Close returns [v1errs.ErrStreamClosed]; after a
+// failed write every later call returns the same error.
+func (sw *StreamWriter) Write(p []byte) (n int, err error) {
+	if sw.closed {
+		return 0, v1errs.ErrStreamClosed
+	}
+	if sw.err != nil {
+		return 0, sw.err
+	}
+
+	for len(p) > 0 {
+		// A full buffer is only known not to be final once more data arrives.
+		if len(sw.buf) == cap(sw.buf) {
+			if err := sw.flush(false); err != nil {
+				return n, err
+			}
+		}
+
+		k := copy(sw.buf[len(sw.buf):cap(sw.buf)], p)
+		sw.buf = sw.buf[:len(sw.buf)+k]
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// Close seals and writes the final chunk. It does not close the underlying writer. Calling Close more
+// than once returns nil after the first call, or the sticky error if the stream failed.
+func (sw *StreamWriter) Close() error {
+	if sw.closed {
+		return sw.err
+	}
+	sw.closed = true
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.flush(true)
+}
+
+// flush seals the buffered plaintext as the next chunk and writes it.
+func (sw *StreamWriter) flush(final bool) error {
+	// The final chunk may use the last counter value; a non-final chunk there would leave none for it.
+	if !final && sw.counter == math.MaxUint32 {
+		sw.err = v1errs.ErrStreamTooLong
+		return sw.err
+	}
+
+	nonce := vaultgcm.StreamNonce(sw.prefix, sw.counter, final)
+	_, chunk, err := vaultgcm.SealInnerWithNonce(sw.aead, sw.aad, sw.buf, nonce)
+	keys.Zero(sw.buf)
+	sw.buf = sw.buf[:0]
+	if err != nil {
+		sw.err = err
+		return err
+	}
+
+	if _, err := sw.w.Write(chunk); err != nil {
+		sw.err = err
+		return err
+	}
+	sw.counter++
+	return nil
+}
+
+//=============================================================================
+// Reader
+//=============================================================================
+
+// StreamReader decrypts a stream written by [StreamWriter]. Plaintext is released one authenticated
+// chunk at a time, so a consumer may see the leading chunks of a stream that later fails; only
+// [io.EOF] confirms the stream was complete. A StreamReader is not safe for concurrent use.
+type StreamReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	aad     []byte
+	prefix  [constants.StreamNoncePrefixBytes]byte
+	chunk   []byte // ciphertext buffer, one full chunk plus tag
+	plain   []byte // decrypted bytes not yet returned
+	counter uint32
+	done    bool  // final chunk authenticated
+	err     error // sticky read or authentication failure
+}
+
+// NewStreamReader reads the stream header from r and unwraps its DEK with the keyring key named by
+// the header. A nil reader or keyring returns [verrors.ErrInvalidNewArgs]. Header decoding fails like
+// [models.Sealed] decoding, with a stream that ends inside the header reported as
+// [v1errs.ErrMalformedWire]. A stream sealed for another namespace returns
+// [v1errs.ErrNamespaceMismatch]; one sealed for a key the keyring does not hold returns
+// [v1errs.ErrUnknownKeyID] joined with [verrors.ErrDecrypt].
+func NewStreamReader(r io.Reader, kr *Keyring, namespace string) (*StreamReader, error) {
+	if r == nil || kr == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+
+	br := bufio.NewReader(r)
+	hdr, hdrRaw, err := models.ReadStreamHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if hdr.Meta.Namespace != namespace {
+		return nil, v1errs.ErrNamespaceMismatch
+	}
+
+	dek, err := kr.unwrapDEK(&models.Sealed{FormatVersion: hdr.FormatVersion, Meta: hdr.Meta, Dek: hdr.Dek})
+	if err != nil {
+		return nil, err
+	}
+	defer keys.Zero(dek)
+
+	aead, err := vaultgcm.NewInnerAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StreamReader{
+		r:      br,
+		aead:   aead,
+		aad:    vaultgcm.StreamAAD(hdrRaw),
+		prefix: hdr.NoncePrefix,
+		chunk:  make([]byte, int(hdr.ChunkSize)+aead.Overhead()),
+	}, nil
+}
+
+// Read returns decrypted plaintext. It returns [io.EOF] only after the final chunk authenticates.
+// A stream that ends on a chunk boundary without its final chunk returns
+// [v1errs.ErrStreamTruncated]; a chunk that fails authentication (tampered, reordered, cut
+// mid-chunk, or followed by trailing bytes) returns [verrors.ErrDecrypt]. Errors are sticky.
+func (sr *StreamReader) Read(p []byte) (n int, err error) {
+	for len(sr.plain) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		if err := sr.next(); err != nil {
+			sr.err = err
+		}
+	}
+
+	n = copy(p, sr.plain)
+	sr.plain = sr.plain[n:]
+	return n, nil
+}
+
+// next reads and opens one chunk into sr.plain.
+func (sr *StreamReader) next() error {
+	n, err := io.ReadFull(sr.r, sr.chunk)
+	switch {
+	case errors.Is(err, io.EOF):
+		// Nothing after the previous (non-final) chunk: the final chunk was dropped.
+		return v1errs.ErrStreamTruncated
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// A short chunk can only be the final one.
+		return sr.open(sr.chunk[:n], true)
+	case err != nil:
+		return err
+	}
+
+	// A full-size chunk is final only if nothing follows it.
+	if _, err := sr.r.Peek(1); err == nil {
+		if sr.counter == math.MaxUint32 {
+			return v1errs.ErrStreamTooLong
+		}
+		return sr.open(sr.chunk, false)
+	} else if !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if err := sr.open(sr.chunk, true); err != nil {
+		// A chunk that opens as non-final means the chunks after it were cut off.
+		nonce := vaultgcm.StreamNonce(sr.prefix, sr.counter, false)
+		if _, openErr := vaultgcm.OpenInner(sr.aead, sr.aad, nonce, sr.chunk); openErr == nil {
+			return v1errs.ErrStreamTruncated
+		}
+		return err
+	}
+	return nil
+}
+
+// open authenticates chunk at the current counter and advances the stream.
+func (sr *StreamReader) open(chunk []byte, final bool) error {
+	nonce := vaultgcm.StreamNonce(sr.prefix, sr.counter, final)
+	plain, err := vaultgcm.OpenInner(sr.aead, sr.aad, nonce, chunk)
+	if err != nil {
+		return err
+	}
+	sr.plain = plain
+	sr.counter++
+	sr.done = final
+	return nil
+}
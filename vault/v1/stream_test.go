@@ -0,0 +1,196 @@
+package v1_test
+
+// Tests for [v1.NewStreamWriter] and [v1.NewStreamReader].
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	v1 "go.rtnl.ai/x/vault/v1"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+)
+
+const testChunk = 64
+
+// TestStream_roundtrip covers empty streams, partial and exact final chunks, and writes that straddle
+// chunk boundaries.
+func TestStream_roundtrip(t *testing.T) {
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+
+	hdr := streamHeaderLen(sealStream(t, kr, "ns", nil, 1), 0)
+	for _, size := range []int{0, 1, testChunk - 1, testChunk, testChunk + 1, 3 * testChunk, 3*testChunk + 17} {
+		plain := make([]byte, size)
+		_, err := rand.Read(plain)
+		assert.Ok(t, err)
+
+		wire := sealStream(t, kr, "ns", plain, 7)
+		assert.Equal(t, hdr+streamChunks(size)*16+size, len(wire), "size %d", size)
+
+		sr, err := v1.NewStreamReader(bytes.NewReader(wire), kr, "ns")
+		assert.Ok(t, err)
+		got, err := io.ReadAll(iotest.OneByteReader(sr))
+		assert.Ok(t, err, "size %d", size)
+		assert.True(t, bytes.Equal(plain, got), "size %d", size)
+	}
+}
+
+// TestStream_truncation verifies a stream cut at any chunk boundary reports truncation and a stream
+// cut inside a chunk fails to authenticate.
+func TestStream_truncation(t *testing.T) {
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+
+	for _, size := range []int{2 * testChunk, 2*testChunk + 5} {
+		plain := bytes.Repeat([]byte("x"), size)
+		wire := sealStream(t, kr, "ns", plain, size)
+		hdr := streamHeaderLen(wire, size)
+
+		// Drop the final chunk, then every chunk.
+		for _, cut := range []int{hdr + 2*(testChunk+16), hdr + testChunk + 16, hdr} {
+			if cut >= len(wire) {
+				continue
+			}
+			got, err := readStream(kr, wire[:cut])
+			assert.ErrorIs(t, err, v1errs.ErrStreamTruncated, "size %d cut %d", size, cut)
+			assert.True(t, len(got) < size, "size %d cut %d: read %d bytes", size, cut, len(got))
+		}
+
+		// Cut inside the last chunk.
+		_, err := readStream(kr, wire[:len(wire)-1])
+		assert.ErrorIs(t, err, verrors.ErrDecrypt)
+	}
+}
+
+// TestStream_tampering flips every byte and reorders chunks; each must fail.
+func TestStream_tampering(t *testing.T) {
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+
+	plain := bytes.Repeat([]byte("abc"), testChunk)
+	wire := sealStream(t, kr, "ns", plain, len(plain))
+	for i := range wire {
+		bad := bytes.Clone(wire)
+		bad[i] ^= 0x01
+		_, err := readStream(kr, bad)
+		assert.Error(t, err, "flip at %d", i)
+	}
+
+	// Swap the first two chunks.
+	hdr := streamHeaderLen(wire, len(plain))
+	c := testChunk + 16
+	bad := bytes.Clone(wire)
+	copy(bad[hdr:], wire[hdr+c:hdr+2*c])
+	copy(bad[hdr+c:], wire[hdr:hdr+c])
+	_, err = readStream(kr, bad)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+
+	// Trailing bytes after the final chunk.
+	_, err = readStream(kr, append(bytes.Clone(wire), 0))
+	assert.Error(t, err)
+}
+
+// TestStream_keysAndNamespace verifies namespace binding and keyring selection.
+func TestStream_keysAndNamespace(t *testing.T) {
+	oldKey, newKey := testX25519Key(t), testX25519Key(t)
+	oldKR, err := v1.NewKeyring(oldKey)
+	assert.Ok(t, err)
+	wire := sealStream(t, oldKR, "ns", []byte("secret"), 1)
+
+	_, err = v1.NewStreamReader(bytes.NewReader(wire), oldKR, "other")
+	assert.ErrorIs(t, err, v1errs.ErrNamespaceMismatch)
+
+	newKR, err := v1.NewKeyring(newKey)
+	assert.Ok(t, err)
+	_, err = v1.NewStreamReader(bytes.NewReader(wire), newKR, "ns")
+	assert.ErrorIs(t, err, v1errs.ErrUnknownKeyID)
+	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+
+	rotated, err := v1.NewKeyring(newKey, oldKey)
+	assert.Ok(t, err)
+	got, err := readStream(rotated, wire)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("secret"), got)
+}
+
+// TestStream_args covers constructor validation, header framing, and writes after Close.
+func TestStream_args(t *testing.T) {
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+
+	_, err = v1.NewStreamWriter(nil, kr, "ns", nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.NewStreamWriter(&bytes.Buffer{}, nil, "ns", nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.NewStreamWriter(&bytes.Buffer{}, kr, "ns", &v1.StreamOptions{ChunkSize: -1})
+	assert.ErrorIs(t, err, v1errs.ErrStreamChunkSize)
+	_, err = v1.NewStreamReader(nil, kr, "ns")
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+
+	_, err = v1.NewStreamReader(bytes.NewReader([]byte("NOPE\x01\x00\x10")), kr, "ns")
+	assert.ErrorIs(t, err, v1errs.ErrBadMagic)
+
+	wire := sealStream(t, kr, "ns", nil, 1)
+	for n := range streamHeaderLen(wire, 0) {
+		_, err = v1.NewStreamReader(bytes.NewReader(wire[:n]), kr, "ns")
+		assert.Error(t, err, "header cut at %d", n)
+	}
+
+	var buf bytes.Buffer
+	sw, err := v1.NewStreamWriter(&buf, kr, "ns", nil)
+	assert.Ok(t, err)
+	_, err = sw.Write([]byte("data"))
+	assert.Ok(t, err)
+	assert.Ok(t, sw.Close())
+	assert.Ok(t, sw.Close())
+	_, err = sw.Write([]byte("more"))
+	assert.ErrorIs(t, err, v1errs.ErrStreamClosed)
+
+	got, err := readStream(kr, buf.Bytes())
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("data"), got)
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// sealStream writes plain as a stream with testChunk-sized chunks, in writes of at most step bytes.
+func sealStream(tb testing.TB, kr *v1.Keyring, namespace string, plain []byte, step int) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	sw, err := v1.NewStreamWriter(&buf, kr, namespace, &v1.StreamOptions{ChunkSize: testChunk})
+	assert.Ok(tb, err)
+	for rest := plain; len(rest) > 0; {
+		n := min(step, len(rest))
+		_, err := sw.Write(rest[:n])
+		assert.Ok(tb, err)
+		rest = rest[n:]
+	}
+	assert.Ok(tb, sw.Close())
+	return buf.Bytes()
+}
+
+// readStream decrypts wire and returns the plaintext read before any error.
+func readStream(kr *v1.Keyring, wire []byte) ([]byte, error) {
+	sr, err := v1.NewStreamReader(bytes.NewReader(wire), kr, "ns")
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(sr)
+}
+
+// streamChunks is the number of testChunk-sized chunks a stream of size plaintext bytes holds.
+func streamChunks(size int) int {
+	return max(1, (size+testChunk-1)/testChunk)
+}
+
+// streamHeaderLen is the header length of a complete stream of size plaintext bytes.
+func streamHeaderLen(wire []byte, size int) int {
+	return len(wire) - size - 16*streamChunks(size)
+}
@@ -6,6 +6,7 @@ package suite
 import (
 	"strconv"
 
+	"go.rtnl.ai/x/vault/v1/constants"
 	v1errs "go.rtnl.ai/x/vault/v1/errors"
 )
 
@@ -15,24 +16,47 @@ type ID uint8
 const (
 	Unknown ID = iota
 	X25519HKDFSHA256AES256GCM
+	X25519HKDFSHA256XChaCha20Poly1305
 )
 
 // Names maps [ID] to a stable wire/debug name (index must match ID).
 var Names = []string{
 	"unknown",
 	"x25519_hkdf_sha256_aes256_gcm",
+	"x25519_hkdf_sha256_xchacha20_poly1305",
 }
 
 // Valid reports whether id names a supported v1 suite.
 func (id ID) Valid() bool {
 	switch id {
-	case X25519HKDFSHA256AES256GCM:
+	case X25519HKDFSHA256AES256GCM, X25519HKDFSHA256XChaCha20Poly1305:
 		return true
 	default:
 		return false
 	}
 }
 
+// NonceBytes returns the nonce size id uses for both the inner and DEK-wrap AEADs, or 0 if id is
+// not valid.
+func (id ID) NonceBytes() int {
+	switch id {
+	case X25519HKDFSHA256AES256GCM:
+		return constants.InnerNonceBytes
+	case X25519HKDFSHA256XChaCha20Poly1305:
+		return constants.XChaChaNonceBytes
+	default:
+		return 0
+	}
+}
+
+// DekEnvelopeBytes returns the on-wire size of the wrapped DEK for id, or 0 if id is not valid.
+func (id ID) DekEnvelopeBytes() int {
+	if n := id.NonceBytes(); n > 0 {
+		return constants.X25519PubBytes + n + constants.DEKBytes + constants.GCMTagBytes
+	}
+	return 0
+}
+
 // String returns a stable name for id.
 func (id ID) String() string {
 	if int(id) >= 0 && int(id) < len(Names) {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault/v1/constants"
 	v1errs "go.rtnl.ai/x/vault/v1/errors"
 	"go.rtnl.ai/x/vault/v1/suite"
 )
@@ -23,6 +24,34 @@ func TestSuite_wireRoundTrip(t *testing.T) {
 	assert.Equal(t, "x25519_hkdf_sha256_aes256_gcm", got.String())
 }
 
+// TestSuite_sizes checks the nonce and DEK envelope sizes each suite puts on the wire.
+func TestSuite_sizes(t *testing.T) {
+	cases := []struct {
+		id       suite.ID
+		name     string
+		nonce    int
+		envelope int
+	}{
+		{suite.X25519HKDFSHA256AES256GCM, "x25519_hkdf_sha256_aes256_gcm", constants.InnerNonceBytes, constants.DekEnvelopeBytes},
+		{suite.X25519HKDFSHA256XChaCha20Poly1305, "x25519_hkdf_sha256_xchacha20_poly1305", constants.XChaChaNonceBytes, constants.XChaChaDekEnvelopeBytes},
+		{suite.Unknown, "unknown", 0, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.nonce, tc.id.NonceBytes())
+			assert.Equal(t, tc.envelope, tc.id.DekEnvelopeBytes())
+			assert.Equal(t, tc.name, tc.id.String())
+
+			if !tc.id.Valid() {
+				return
+			}
+			got, err := suite.Parse(tc.name)
+			assert.Ok(t, err)
+			assert.Equal(t, tc.id, got)
+		})
+	}
+}
+
 // TestSuite_UnmarshalBinary_rejectsBadInput covers nil receiver and non-single-byte wire (what Meta parsing relies on).
 func TestSuite_UnmarshalBinary_rejectsBadInput(t *testing.T) {
 	var p *suite.ID
@@ -0,0 +1,160 @@
+package v1
+
+// Expiry sweeps: delete rows whose authenticated expiry has passed.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/keys"
+	"go.rtnl.ai/x/vault/storage"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+	"go.rtnl.ai/x/vault/v1/models"
+)
+
+// SweepResult reports the outcome for one row visited by [Sweep].
+type SweepResult struct {
+	Namespace string
+	ID        string
+	Deleted   bool  // true if the row had expired and was deleted
+	Err       error // nil on success
+}
+
+// SweepOptions configures [Sweep]. A nil *SweepOptions uses the defaults.
+type SweepOptions struct {
+	// Now, if set, replaces [time.Now] as the clock rows are judged against.
+	Now func() time.Time
+
+	// PageSize is the number of ids requested per [storage.Lister.ListIDs] call; zero or less uses
+	// [storage.DefaultListLimit].
+	PageSize int
+
+	// Progress, if set, is called once per visited row, in listing order.
+	Progress func(SweepResult)
+
+	// StopOnError stops at the first failed row and returns its error. By default Sweep keeps going
+	// and reports failures through Progress and [SweepSummary.Failed].
+	StopOnError bool
+}
+
+// SweepSummary counts the rows visited by [Sweep].
+type SweepSummary struct {
+	Visited int // ids taken from the listing
+	Deleted int // expired rows deleted
+	Skipped int // rows not expired, or deleted or rewritten during the sweep
+	Failed  int // rows that could not be read, authenticated, or deleted
+}
+
+// Sweep lists every row in namespace and deletes those whose expiry ([models.Meta.ExpiresAt]) has
+// passed. Expiry is read from the row metadata without decrypting the payload, and an expired row is
+// only deleted after its metadata authenticates by unwrapping the DEK with kr, so a row whose expiry
+// was tampered with in storage fails instead of being deleted. Just before deleting, the row is read
+// again and left alone if it no longer holds the bytes that were checked; storage has no
+// compare-and-delete, so an update landing between that read and the delete can still be lost.
+//
+// Nil storage or keyring yields [verrors.ErrInvalidNewArgs], and storage that does not implement
+// [storage.Lister] yields [verrors.ErrListUnsupported]. A listing failure ends the sweep and is joined
+// with [verrors.ErrStorage], as are storage failures for a row. With [SweepOptions.StopOnError] the
+// first row error is returned; otherwise Sweep visits every row and returns
+// [v1errs.ErrSweepIncomplete] if any failed. A canceled context stops the sweep and returns the
+// context error.
+func Sweep(ctx context.Context, st storage.Storage, kr *Keyring, namespace string, opts *SweepOptions) (SweepSummary, error) {
+	var sum SweepSummary
+	if st == nil || kr == nil {
+		return sum, verrors.ErrInvalidNewArgs
+	}
+	lister, ok := st.(storage.Lister)
+	if !ok {
+		return sum, verrors.ErrListUnsupported
+	}
+	if opts == nil {
+		opts = &SweepOptions{}
+	}
+
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	for id, err := range storage.IDs(ctx, lister, namespace, opts.PageSize) {
+		if err != nil {
+			return sum, errors.Join(verrors.ErrStorage, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+
+		res := SweepResult{Namespace: namespace, ID: id}
+		res.Deleted, res.Err = sweepRow(ctx, st, kr, namespace, id, now())
+
+		sum.Visited++
+		switch {
+		case res.Err != nil:
+			sum.Failed++
+		case res.Deleted:
+			sum.Deleted++
+		default:
+			sum.Skipped++
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(res)
+		}
+		if res.Err != nil && opts.StopOnError {
+			return sum, res.Err
+		}
+	}
+
+	if sum.Failed > 0 {
+		return sum, v1errs.ErrSweepIncomplete
+	}
+	return sum, nil
+}
+
+// sweepRow deletes a single row if it has expired as of now and its metadata authenticates.
+func sweepRow(ctx context.Context, st storage.Storage, kr *Keyring, namespace, id string, now time.Time) (bool, error) {
+	wire, err := st.Get(ctx, namespace, id)
+	if err != nil {
+		if errors.Is(err, verrors.ErrNotFound) {
+			return false, nil
+		}
+		return false, errors.Join(verrors.ErrStorage, err)
+	}
+
+	var msg models.Sealed
+	if err = msg.UnmarshalBinary(wire); err != nil {
+		return false, err
+	}
+	if !msg.Meta.Expired(now) {
+		return false, nil
+	}
+	if msg.Meta.Namespace != namespace {
+		return false, v1errs.ErrNamespaceMismatch
+	}
+
+	// The wrap AAD covers the full metadata, so unwrapping the DEK authenticates the expiry.
+	dek, err := kr.unwrapDEK(&msg)
+	if err != nil {
+		return false, err
+	}
+	keys.Zero(dek)
+
+	// Leave rows that were rewritten since they were checked for the next sweep.
+	current, err := st.Get(ctx, namespace, id)
+	switch {
+	case errors.Is(err, verrors.ErrNotFound):
+		return false, nil
+	case err != nil:
+		return false, errors.Join(verrors.ErrStorage, err)
+	case !bytes.Equal(current, wire):
+		return false, nil
+	}
+
+	if err = st.Delete(ctx, namespace, id); err != nil {
+		return false, errors.Join(verrors.ErrStorage, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,120 @@
+package v1_test
+
+// Tests for [v1.Sweep].
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	v1errs "go.rtnl.ai/x/vault/v1/errors"
+)
+
+// TestSweep deletes only expired rows, judged by the configured clock, across several pages.
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	key := testX25519Key(t)
+	v, err := v1.New(key, st, identifier.HexIdentifier{})
+	assert.Ok(t, err)
+	e := v.(vault.Expirer)
+
+	now := time.Now()
+	var expired, kept []string
+	for i := range 5 {
+		id, err := e.StoreExpiring(ctx, "ns", []byte("x"), now.Add(-time.Duration(i+1)*time.Minute))
+		assert.Ok(t, err)
+		expired = append(expired, id)
+	}
+	id, err := e.StoreExpiring(ctx, "ns", []byte("x"), now.Add(time.Hour))
+	assert.Ok(t, err)
+	kept = append(kept, id)
+	id, err = v.Store(ctx, "ns", []byte("x"))
+	assert.Ok(t, err)
+	kept = append(kept, id)
+
+	kr, err := v1.NewKeyring(key)
+	assert.Ok(t, err)
+	var deleted []string
+	sum, err := v1.Sweep(ctx, st, kr, "ns", &v1.SweepOptions{
+		Now:      func() time.Time { return now },
+		PageSize: 2,
+		Progress: func(r v1.SweepResult) {
+			if r.Deleted {
+				deleted = append(deleted, r.ID)
+			}
+		},
+	})
+	assert.Ok(t, err)
+	assert.Equal(t, v1.SweepSummary{Visited: 7, Deleted: 5, Skipped: 2}, sum)
+	assert.Len(t, deleted, 5)
+	for _, id := range expired {
+		_, err := st.Get(ctx, "ns", id)
+		assert.ErrorIs(t, err, verrors.ErrNotFound)
+	}
+	for _, id := range kept {
+		_, err := v.Retrieve(ctx, "ns", id)
+		assert.Ok(t, err)
+	}
+
+	// A later clock catches the row that was still live, in the vault and in the sweep alike.
+	later := func() time.Time { return now.Add(2 * time.Hour) }
+	v1.SetClock(v, later)
+	_, err = v.Retrieve(ctx, "ns", kept[0])
+	assert.ErrorIs(t, err, verrors.ErrExpired)
+	sum, err = v1.Sweep(ctx, st, kr, "ns", &v1.SweepOptions{Now: later})
+	assert.Ok(t, err)
+	assert.Equal(t, v1.SweepSummary{Visited: 2, Deleted: 1, Skipped: 1}, sum)
+}
+
+// TestSweep_failures checks rows sealed for an unknown key or with a corrupt encoding are reported and
+// left in place.
+func TestSweep_failures(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemStorage()
+	v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+
+	foreign, err := v.(vault.Expirer).StoreExpiring(ctx, "ns", []byte("x"), time.Now().Add(-time.Minute))
+	assert.Ok(t, err)
+	assert.Ok(t, st.Create(ctx, "ns", "corrupt", []byte{1, 2, 3}))
+
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+	var failed int
+	sum, err := v1.Sweep(ctx, st, kr, "ns", &v1.SweepOptions{Progress: func(r v1.SweepResult) {
+		if r.Err != nil {
+			failed++
+		}
+	}})
+	assert.ErrorIs(t, err, v1errs.ErrSweepIncomplete)
+	assert.Equal(t, v1.SweepSummary{Visited: 2, Failed: 2}, sum)
+	assert.Equal(t, 2, failed)
+	_, err = st.Get(ctx, "ns", foreign)
+	assert.Ok(t, err)
+
+	sum, err = v1.Sweep(ctx, st, kr, "ns", &v1.SweepOptions{StopOnError: true})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, v1errs.ErrSweepIncomplete))
+	assert.Equal(t, 1, sum.Visited)
+}
+
+// TestSweep_args covers argument validation and storage that cannot list.
+func TestSweep_args(t *testing.T) {
+	ctx := context.Background()
+	kr, err := v1.NewKeyring(testX25519Key(t))
+	assert.Ok(t, err)
+
+	_, err = v1.Sweep(ctx, nil, kr, "ns", nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.Sweep(ctx, storage.NewMemStorage(), nil, "ns", nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = v1.Sweep(ctx, struct{ storage.Storage }{storage.NewMemStorage()}, kr, "ns", nil)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+}
@@ -7,7 +7,9 @@ methods; each sealed row binds the namespace you pass into authenticated metadat
 different namespace than the row was sealed for fails with [v1errs.ErrNamespaceMismatch].
 
 The library does not register a process-wide singleton; keep the [Vault] returned from [New] for
-the lifetime of your wrapping key and storage wiring.
+the lifetime of your wrapping key and storage wiring. To move to a new long-term key, construct the
+vault with [NewWithKeyring] (new key active, old key retired) and run [Rotate], which re-wraps each
+row's data key for the active key without re-encrypting the payload.
 */
 package v1
 
@@ -22,6 +24,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"io"
+	"time"
 
 	"go.rtnl.ai/x/vault"
 	verrors "go.rtnl.ai/x/vault/errors"
@@ -32,22 +35,30 @@ import (
 	v1errs "go.rtnl.ai/x/vault/v1/errors"
 	vaultgcm "go.rtnl.ai/x/vault/v1/gcm"
 	"go.rtnl.ai/x/vault/v1/models"
+	"go.rtnl.ai/x/vault/v1/suite"
 )
 
 //=============================================================================
 // Vault
 //=============================================================================
 
-// sealedVault implements [Vault] using an X25519 private key.
+// sealedVault implements [Vault] using a [Keyring] of X25519 private keys. Successful writes
+// report the active key id, and successful opens the row's Meta.KeyID, to [vault.WithRowKeyIDs].
 type sealedVault struct {
-	priv     *ecdh.PrivateKey
-	template models.Meta // namespace is set on each Store/Update call
-	st       storage.Storage
-	id       identifier.Identifier
+	kr  *Keyring // active key seals; any key opens by row KeyID
+	st  storage.Storage
+	id  identifier.Identifier
+	now func() time.Time // clock row expiry is judged against
 }
 
-// Ensure sealedVault implements [vault.Vault].
-var _ vault.Vault = (*sealedVault)(nil)
+// Ensure sealedVault implements [vault.Vault] and its optional interfaces.
+var (
+	_ vault.Vault         = (*sealedVault)(nil)
+	_ vault.Lister        = (*sealedVault)(nil)
+	_ vault.KeyIdentifier = (*sealedVault)(nil)
+	_ vault.Expirer       = (*sealedVault)(nil)
+	_ vault.Batcher       = (*sealedVault)(nil)
+)
 
 // New constructs a [Vault] for the v1 envelope suite from an X25519 private key.
 // Nil storage or identifier yields [verrors.ErrInvalidNewArgs]; a nil key yields [verrors.ErrNilPrivateKey];
@@ -57,19 +68,37 @@ func New(priv *ecdh.PrivateKey, st storage.Storage, id identifier.Identifier) (v
 	if st == nil || id == nil {
 		return nil, verrors.ErrInvalidNewArgs
 	}
-	meta, err := models.MetaFromPrivKey(priv)
+	kr, err := NewKeyring(priv)
 	if err != nil {
 		return nil, err
 	}
-	return &sealedVault{priv: priv, template: meta, st: st, id: id}, nil
+	return &sealedVault{kr: kr, st: st, id: id, now: time.Now}, nil
+}
+
+// NewWithKeyring constructs a [Vault] that seals with the keyring's active key and opens rows with
+// whichever registered key matches the row's KeyID, so rows sealed before a key rotation stay
+// readable while [Rotate] re-wraps them. Nil storage, identifier, or keyring yields
+// [verrors.ErrInvalidNewArgs].
+func NewWithKeyring(kr *Keyring, st storage.Storage, id identifier.Identifier) (vault.Vault, error) {
+	if kr == nil || st == nil || id == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+	return &sealedVault{kr: kr, st: st, id: id, now: time.Now}, nil
 }
 
 // Store encrypts plaintext and persists a new row. A nil vault returns [verrors.ErrNilVault].
 // [identifier.Identifier.New] failures are joined with [verrors.ErrInvalidIdentifier]. Problems while sealing
 // (metadata, randomness, or crypto) return that envelope error directly. If [storage.Storage.Create]
 // fails—duplicate key if the minted id collides, or any other backend error—the error is joined
-// with [verrors.ErrStorage].
+// with [verrors.ErrStorage]. The row never expires; see [sealedVault.StoreExpiring].
 func (v *sealedVault) Store(ctx context.Context, namespace string, plaintext []byte) (id string, err error) {
+	return v.StoreExpiring(ctx, namespace, plaintext, time.Time{})
+}
+
+// StoreExpiring is [sealedVault.Store] for a row that stops opening at expiresAt, which is bound into
+// the authenticated metadata at one-second precision. A zero expiresAt never expires; one at or
+// before the Unix epoch yields [v1errs.ErrMetaExpiry].
+func (v *sealedVault) StoreExpiring(ctx context.Context, namespace string, plaintext []byte, expiresAt time.Time) (id string, err error) {
 	// Return error if vault receiver is nil.
 	if v == nil {
 		return "", verrors.ErrNilVault
@@ -82,7 +111,7 @@ func (v *sealedVault) Store(ctx context.Context, namespace string, plaintext []b
 	}
 
 	// Seal (encrypt) the plaintext into a wire-ready format.
-	wire, err := v.sealPlaintext(namespace, plaintext)
+	wire, err := v.sealPlaintext(namespace, plaintext, expiresAt)
 	if err != nil {
 		return "", err
 	}
@@ -93,6 +122,7 @@ func (v *sealedVault) Store(ctx context.Context, namespace string, plaintext []b
 	}
 
 	// Return the generated ID on success.
+	v.reportSealed(ctx, id)
 	return id, nil
 }
 
@@ -101,7 +131,9 @@ func (v *sealedVault) Store(ctx context.Context, namespace string, plaintext []b
 // error. If [storage.Storage.Get] fails—often because the row is absent—the error is joined with
 // [verrors.ErrStorage] and typically chains [verrors.ErrNotFound]. After a blob is loaded, corrupt or mismatched
 // ciphertext surfaces as wire errors from this package or decrypt failures from package gcm
-// (see [gcm]), including [v1errs.ErrNamespaceMismatch], without wrapping in [verrors.ErrStorage].
+// (see [gcm]), including [v1errs.ErrNamespaceMismatch], without wrapping in [verrors.ErrStorage]. A row
+// sealed for a key the vault does not hold yields [v1errs.ErrUnknownKeyID] joined with [verrors.ErrDecrypt].
+// A row whose authenticated expiry has passed yields [verrors.ErrExpired] and no plaintext.
 func (v *sealedVault) Retrieve(ctx context.Context, namespace, id string) (plaintext []byte, err error) {
 	// Validate that the receiver is non-nil.
 	if v == nil {
@@ -120,15 +152,26 @@ func (v *sealedVault) Retrieve(ctx context.Context, namespace, id string) (plain
 	}
 
 	// Decrypt (open) the ciphertext and return the plaintext.
-	return v.openCiphertext(namespace, wire)
+	plaintext, meta, err := v.openCiphertext(namespace, wire)
+	if err != nil {
+		return nil, err
+	}
+	vault.ReportRowKeyID(ctx, id, meta.KeyID)
+	return plaintext, nil
 }
 
 // Update replaces plaintext for an existing row. A nil vault returns [verrors.ErrNilVault].
 // Parse failures join [verrors.ErrInvalidIdentifier]. Re-sealing can fail the same way as during [Store].
 // If [storage.Storage.Replace] fails—most often [verrors.ErrNotFound] when the row does not exist—the error is
-// joined with [verrors.ErrStorage].
+// joined with [verrors.ErrStorage]. The replacement never expires, whatever the old row's expiry; see
+// [sealedVault.UpdateExpiring].
 func (v *sealedVault) Update(ctx context.Context, namespace, id string, plaintext []byte) error {
+	return v.UpdateExpiring(ctx, namespace, id, plaintext, time.Time{})
+}
 
+// UpdateExpiring is [sealedVault.Update] with the replacement row expiring at expiresAt, as in
+// [sealedVault.StoreExpiring]. It also revives an expired row, since the old row is never opened.
+func (v *sealedVault) UpdateExpiring(ctx context.Context, namespace, id string, plaintext []byte, expiresAt time.Time) error {
 	// Validate that the receiver is non-nil.
 	if v == nil {
 		return verrors.ErrNilVault
@@ -140,7 +183,7 @@ func (v *sealedVault) Update(ctx context.Context, namespace, id string, plaintex
 	}
 
 	// Seal (encrypt) the provided plaintext into a wire-ready format.
-	wire, err := v.sealPlaintext(namespace, plaintext)
+	wire, err := v.sealPlaintext(namespace, plaintext, expiresAt)
 	if err != nil {
 		return err
 	}
@@ -151,6 +194,7 @@ func (v *sealedVault) Update(ctx context.Context, namespace, id string, plaintex
 	}
 
 	// Update successful, return nil error.
+	v.reportSealed(ctx, id)
 	return nil
 }
 
@@ -161,7 +205,8 @@ func (v *sealedVault) Update(ctx context.Context, namespace, id string, plaintex
 // [Retrieve], without [verrors.ErrStorage]. A mismatch with currentPlain yields [verrors.ErrWrongCurrent] and
 // leaves storage unchanged. Sealing newPlain can fail like [Store]. Finally, if another writer
 // changed the row or deleted it, [storage.Storage.CompareAndSwap] fails with [verrors.ErrCASFailed] or
-// [verrors.ErrNotFound] (among others), joined with [verrors.ErrStorage].
+// [verrors.ErrNotFound] (among others), joined with [verrors.ErrStorage]. The new row keeps the old row's
+// expiry, and an expired row fails with [verrors.ErrExpired].
 func (v *sealedVault) CompareAndSwap(ctx context.Context, namespace, id string, currentPlain, newPlain []byte) error {
 	// Validate that the receiver is non-nil.
 	if v == nil {
@@ -180,7 +225,7 @@ func (v *sealedVault) CompareAndSwap(ctx context.Context, namespace, id string,
 	}
 
 	// Decrypt the current row to obtain the plaintext.
-	plain, err := v.openCiphertext(namespace, oldWire)
+	plain, meta, err := v.openCiphertext(namespace, oldWire)
 	if err != nil {
 		return err
 	}
@@ -190,8 +235,8 @@ func (v *sealedVault) CompareAndSwap(ctx context.Context, namespace, id string,
 		return verrors.ErrWrongCurrent
 	}
 
-	// Seal (encrypt) the new plaintext.
-	newWire, err := v.sealPlaintext(namespace, newPlain)
+	// Seal (encrypt) the new plaintext, carrying over the row's expiry.
+	newWire, err := v.sealPlaintext(namespace, newPlain, meta.ExpiresAt)
 	if err != nil {
 		return err
 	}
@@ -202,6 +247,7 @@ func (v *sealedVault) CompareAndSwap(ctx context.Context, namespace, id string,
 	}
 
 	// CAS operation was successful.
+	v.reportSealed(ctx, id)
 	return nil
 }
 
@@ -212,7 +258,8 @@ func (v *sealedVault) CompareAndSwap(ctx context.Context, namespace, id string,
 // old blob or sealing for the new namespace propagates envelope errors without [verrors.ErrStorage], as
 // in [Retrieve] and [Store]. If the new row was created but deleting the old key fails, the error
 // joins [verrors.ErrMoveNamespaceIncomplete], [verrors.ErrStorage], and the delete failure—the copy in the new
-// namespace may remain alongside the old row until the application reconciles.
+// namespace may remain alongside the old row until the application reconciles. The moved row keeps its
+// expiry, and an expired row fails with [verrors.ErrExpired] and is not moved.
 func (v *sealedVault) MoveNamespace(ctx context.Context, oldNamespace, newNamespace, id string) error {
 	// Return error if vault receiver is nil.
 	if v == nil {
@@ -236,13 +283,13 @@ func (v *sealedVault) MoveNamespace(ctx context.Context, oldNamespace, newNamesp
 	}
 
 	// Decrypt row contents.
-	plain, err := v.openCiphertext(oldNamespace, oldWire)
+	plain, meta, err := v.openCiphertext(oldNamespace, oldWire)
 	if err != nil {
 		return err
 	}
 
 	// Reseal the same plaintext using the new namespace to bind new AAD/metadata.
-	newWire, err := v.sealPlaintext(newNamespace, plain)
+	newWire, err := v.sealPlaintext(newNamespace, plain, meta.ExpiresAt)
 	if err != nil {
 		return err
 	}
@@ -258,6 +305,7 @@ func (v *sealedVault) MoveNamespace(ctx context.Context, oldNamespace, newNamesp
 		return errors.Join(verrors.ErrMoveNamespaceIncomplete, verrors.ErrStorage, err)
 	}
 
+	v.reportSealed(ctx, id)
 	return nil
 }
 
@@ -285,13 +333,51 @@ func (v *sealedVault) Delete(ctx context.Context, namespace, id string) error {
 	return nil
 }
 
+// ListIDs returns up to limit row ids in namespace sorting after cursor, without reading or opening
+// any ciphertext. A nil vault returns [verrors.ErrNilVault]. If the storage does not implement
+// [storage.Lister] the result is [verrors.ErrListUnsupported]; listing failures are joined with
+// [verrors.ErrStorage].
+func (v *sealedVault) ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error) {
+	// Return error if vault receiver is nil.
+	if v == nil {
+		return nil, "", verrors.ErrNilVault
+	}
+
+	// Listing is optional for storage backends.
+	lister, ok := v.st.(storage.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+
+	// Delegate paging to storage; ids are not secret, so nothing is opened.
+	if ids, next, err = lister.ListIDs(ctx, namespace, cursor, limit); err != nil {
+		return nil, "", errors.Join(verrors.ErrStorage, err)
+	}
+	return ids, next, nil
+}
+
+// ActiveKeyID returns the KeyID new rows are sealed for: the active key's X25519 public key bytes.
+// A nil vault returns nil.
+func (v *sealedVault) ActiveKeyID() []byte {
+	if v == nil {
+		return nil
+	}
+	return v.kr.ActiveKeyID()
+}
+
+// reportSealed reports the active key id for row id, which was just sealed for it; see
+// [vault.WithRowKeyIDs].
+func (v *sealedVault) reportSealed(ctx context.Context, id string) {
+	vault.ReportRowKeyID(ctx, id, v.kr.template.KeyID)
+}
+
 //=============================================================================
 // Envelope seal and open
 //=============================================================================
 
-// sealPlaintext builds row metadata for namespace, seals plaintext, and returns
-// the v1 wire blob.
-func (v *sealedVault) sealPlaintext(namespace string, plaintext []byte) ([]byte, error) {
+// sealPlaintext builds row metadata for namespace and expiresAt (zero for none), seals plaintext, and
+// returns the v1 wire blob.
+func (v *sealedVault) sealPlaintext(namespace string, plaintext []byte, expiresAt time.Time) ([]byte, error) {
 	// Generate a fresh, random Data Encryption Key (DEK) for this row.
 	dek := make([]byte, constants.DEKBytes)
 	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
@@ -299,9 +385,10 @@ func (v *sealedVault) sealPlaintext(namespace string, plaintext []byte) ([]byte,
 	}
 	defer keys.Zero(dek)
 
-	// Generate a unique nonce for the inner AEAD encryption.
-	var innerNonce [constants.InnerNonceBytes]byte
-	if _, err := io.ReadFull(rand.Reader, innerNonce[:]); err != nil {
+	// Generate a unique nonce for the inner AEAD encryption, sized for the sealing suite.
+	nonceBytes := v.kr.template.SuiteID.NonceBytes()
+	innerNonce := make([]byte, nonceBytes)
+	if _, err := io.ReadFull(rand.Reader, innerNonce); err != nil {
 		return nil, verrors.ErrSealFailed
 	}
 
@@ -312,26 +399,27 @@ func (v *sealedVault) sealPlaintext(namespace string, plaintext []byte) ([]byte,
 	}
 
 	// Generate a nonce for the envelope (wrapping) AEAD.
-	var wrapNonce [constants.WrapNonceBytes]byte
-	if _, err := io.ReadFull(rand.Reader, wrapNonce[:]); err != nil {
+	wrapNonce := make([]byte, nonceBytes)
+	if _, err := io.ReadFull(rand.Reader, wrapNonce); err != nil {
 		return nil, verrors.ErrSealFailed
 	}
 
 	// Seal the plaintext and return the complete envelope using fresh keys/nonces.
-	return v.sealPlaintextWith(namespace, plaintext, dek, innerNonce, ephPriv, wrapNonce)
+	return v.sealPlaintextWith(namespace, expiresAt, plaintext, dek, innerNonce, ephPriv, wrapNonce)
 }
 
 // sealPlaintextWith seals plaintext using fixed DEK, nonces, and ephemeral key.
 // NOTE: this is separated from sealPlaintext so we can generate fixed golden
 // vector tests easily.
-func (v *sealedVault) sealPlaintextWith(namespace string, plaintext, dek []byte, innerNonce [constants.InnerNonceBytes]byte, ephPriv *ecdh.PrivateKey, wrapNonce [constants.WrapNonceBytes]byte) ([]byte, error) {
+func (v *sealedVault) sealPlaintextWith(namespace string, expiresAt time.Time, plaintext, dek, innerNonce []byte, ephPriv *ecdh.PrivateKey, wrapNonce []byte) ([]byte, error) {
 	defer keys.Zero(dek)
 
 	// Prepare per-row metadata, copying the template and injecting this operation's namespace.
-	row, err := v.template.WithNamespace(namespace)
+	row, err := v.kr.template.WithNamespace(namespace)
 	if err != nil {
 		return nil, err
 	}
+	row.ExpiresAt = expiresAt
 
 	// Marshal metadata to binary for authenticated encryption and binding.
 	metaRaw, err := row.MarshalBinary()
@@ -339,48 +427,29 @@ func (v *sealedVault) sealPlaintextWith(namespace string, plaintext, dek []byte,
 		return nil, err
 	}
 
-	// Build the AEAD used to encrypt the user's data (inner payload).
-	innerAEAD, err := vaultgcm.NewInnerAEAD(dek)
+	// Build the AEAD used to encrypt the user's data (inner payload) for the sealing suite.
+	innerAEAD, err := vaultgcm.NewSuiteAEAD(row.SuiteID, dek)
 	if err != nil {
 		return nil, err
 	}
 
 	// Encrypt the plaintext (sealing the data and binding metadata as AAD).
-	nonce, payload, err := vaultgcm.SealInnerWithNonce(innerAEAD, metaRaw, plaintext, innerNonce)
+	nonce, payload, err := vaultgcm.SealInnerSuiteWithNonce(innerAEAD, metaRaw, plaintext, innerNonce)
 	if err != nil {
 		return nil, err
 	}
-	body := models.Inner{Nonce: nonce, Payload: payload}
-
-	// ECDH: derive a shared secret from ephemeral private and long-term public key.
-	shared, err := ephPriv.ECDH(v.priv.PublicKey())
-	if err != nil {
-		return nil, err
+	body := models.Inner{Payload: payload}
+	if len(nonce) == constants.InnerNonceBytes {
+		body.Nonce = [constants.InnerNonceBytes]byte(nonce)
+	} else {
+		body.XNonce = nonce
 	}
 
-	// Stretch the shared secret into an envelope wrapping key.
-	wrapKey, err := vaultgcm.DeriveWrapKey(shared)
+	// Wrap the DEK for the active long-term key, binding the metadata as AAD.
+	dekEnv, err := wrapDEK(row.SuiteID, v.kr.active.PublicKey(), metaRaw, dek, ephPriv, wrapNonce)
 	if err != nil {
 		return nil, err
 	}
-	defer keys.Zero(wrapKey)
-
-	// Build AEAD for the envelope (to wrap the DEK).
-	wrapAEAD, err := vaultgcm.NewWrapAEAD(wrapKey)
-	if err != nil {
-		return nil, err
-	}
-
-	// Prepare the ephemeral public key to include in the wire format.
-	var ephPub [constants.X25519PubBytes]byte
-	copy(ephPub[:], ephPriv.PublicKey().Bytes())
-
-	// Encrypt (wrap) the DEK for transport, sealing it with envelope AEAD and AAD (metadata).
-	dekWire, err := vaultgcm.SealWrappedDEKWithNonce(ephPub, wrapAEAD, vaultgcm.WrapAAD(metaRaw), dek, wrapNonce)
-	if err != nil {
-		return nil, err
-	}
-	dekEnv := models.DekEnvelope{Pub: dekWire.Pub, Nonce: dekWire.Nonce, Payload: dekWire.Payload}
 
 	// Assemble the complete sealed wire, including all envelope components.
 	sealed := models.Sealed{
@@ -395,69 +464,86 @@ func (v *sealedVault) sealPlaintextWith(namespace string, plaintext, dek []byte,
 }
 
 // openCiphertext parses wire, unwraps keys, verifies plaintext, and checks
-// namespace matches requestedNS.
-func (v *sealedVault) openCiphertext(requestedNS string, wire []byte) ([]byte, error) {
+// namespace matches requestedNS and that the row has not expired. It also returns the
+// authenticated row metadata.
+func (v *sealedVault) openCiphertext(requestedNS string, wire []byte) ([]byte, models.Meta, error) {
 	// Unmarshal the sealed wire into the Sealed structure.
 	var msg models.Sealed
 	if err := msg.UnmarshalBinary(wire); err != nil {
-		return nil, err
+		return nil, models.Meta{}, err
 	}
 
 	// Ensure that the namespace matches the one requested.
 	if msg.Meta.Namespace != requestedNS {
-		return nil, v1errs.ErrNamespaceMismatch
+		return nil, models.Meta{}, v1errs.ErrNamespaceMismatch
 	}
 
-	// Marshal the row metadata for use as associated data.
-	metaRaw, err := msg.Meta.MarshalBinary()
+	// Unwrap the DEK with the key named by the row metadata.
+	dek, err := v.kr.unwrapDEK(&msg)
 	if err != nil {
-		return nil, err
+		return nil, models.Meta{}, err
 	}
+	defer keys.Zero(dek)
 
-	// Reconstruct the ephemeral public key for ECDH.
-	epub, err := ecdh.X25519().NewPublicKey(msg.Dek.Pub[:])
+	// The inner payload is bound to the metadata as first sealed (differs from the current
+	// metadata only for re-wrapped rows).
+	innerAAD, err := msg.Meta.InnerAAD()
 	if err != nil {
-		return nil, verrors.ErrDecrypt
+		return nil, models.Meta{}, err
 	}
 
-	// Perform ECDH with our private key and the ephemeral public key.
-	shared, err := v.priv.ECDH(epub)
+	// Build AEAD for decrypting the inner ciphertext with the row's own suite.
+	innerAEAD, err := vaultgcm.NewSuiteAEAD(msg.Meta.SuiteID, dek)
 	if err != nil {
-		return nil, verrors.ErrDecrypt
+		return nil, models.Meta{}, err
 	}
 
-	// Derive the wrapping key from the shared secret.
-	wrapKey, err := vaultgcm.DeriveWrapKey(shared)
+	// Open and verify the inner ciphertext with the decrypted DEK and metadata.
+	plain, err := vaultgcm.OpenInnerSuite(innerAEAD, innerAAD, msg.Body.SuiteNonce(), msg.Body.Payload)
 	if err != nil {
-		return nil, err
+		return nil, models.Meta{}, err
 	}
-	defer keys.Zero(wrapKey)
 
-	// Build AEAD for unwrapping the DEK.
-	wrapAEAD, err := vaultgcm.NewWrapAEAD(wrapKey)
-	if err != nil {
-		return nil, err
+	// The expiry is only trusted once the metadata has authenticated; withhold expired plaintext.
+	if msg.Meta.Expired(v.now()) {
+		keys.Zero(plain)
+		return nil, models.Meta{}, verrors.ErrExpired
 	}
 
-	// Unwrap and authenticate the DEK using the AEAD and metadata.
-	wrapped := vaultgcm.WrappedDEK{Pub: msg.Dek.Pub, Nonce: msg.Dek.Nonce, Payload: msg.Dek.Payload}
-	dek, err := vaultgcm.OpenWrappedDEK(wrapAEAD, vaultgcm.WrapAAD(metaRaw), wrapped)
+	return plain, msg.Meta, nil
+}
+
+// wrapDEK performs ECDH between ephPriv and the recipient's long-term public key, derives the
+// wrapping key for suite id, and seals dek with [vaultgcm.WrapAAD] of metaRaw as associated data.
+// wrapNonce must be the suite's nonce size.
+func wrapDEK(id suite.ID, recipient *ecdh.PublicKey, metaRaw, dek []byte, ephPriv *ecdh.PrivateKey, wrapNonce []byte) (models.DekEnvelope, error) {
+	// ECDH: derive a shared secret from ephemeral private and long-term public key.
+	shared, err := ephPriv.ECDH(recipient)
 	if err != nil {
-		return nil, err
+		return models.DekEnvelope{}, err
 	}
-	defer keys.Zero(dek)
 
-	// Build AEAD for decrypting the inner ciphertext.
-	innerAEAD, err := vaultgcm.NewInnerAEAD(dek)
+	// Stretch the shared secret into an envelope wrapping key.
+	wrapKey, err := vaultgcm.DeriveSuiteWrapKey(id, shared)
 	if err != nil {
-		return nil, err
+		return models.DekEnvelope{}, err
 	}
+	defer keys.Zero(wrapKey)
 
-	// Open and verify the inner ciphertext with the decrypted DEK and metadata.
-	plain, err := vaultgcm.OpenInner(innerAEAD, metaRaw, msg.Body.Nonce, msg.Body.Payload)
+	// Build AEAD for the envelope (to wrap the DEK).
+	wrapAEAD, err := vaultgcm.NewSuiteAEAD(id, wrapKey)
 	if err != nil {
-		return nil, err
+		return models.DekEnvelope{}, err
 	}
 
-	return plain, nil
+	// Prepare the ephemeral public key to include in the wire format.
+	var ephPub [constants.X25519PubBytes]byte
+	copy(ephPub[:], ephPriv.PublicKey().Bytes())
+
+	// Encrypt (wrap) the DEK for transport, sealing it with envelope AEAD and AAD (metadata).
+	dekWire, err := vaultgcm.SealWrappedDEKSuiteWithNonce(ephPub, wrapAEAD, vaultgcm.WrapAAD(metaRaw), dek, wrapNonce)
+	if err != nil {
+		return models.DekEnvelope{}, err
+	}
+	return models.DekEnvelope{Pub: dekWire.Pub, Nonce: dekWire.Nonce, XNonce: dekWire.XNonce, Payload: dekWire.Payload}, nil
 }
@@ -8,7 +8,9 @@ import (
 	"crypto/rand"
 	"errors"
 	"io"
+	"slices"
 	"testing"
+	"time"
 
 	"go.rtnl.ai/x/assert"
 	"go.rtnl.ai/x/vault"
@@ -158,6 +160,7 @@ func TestVault_retrieve_wrongLongTermKey(t *testing.T) {
 	assert.Ok(t, err)
 	_, err = vBob.Retrieve(ctx, "ns", id)
 	assert.ErrorIs(t, err, verrors.ErrDecrypt)
+	assert.ErrorIs(t, err, v1errs.ErrUnknownKeyID)
 }
 
 // TestVault_nilReceiver_contract asserts every [v1.Vault] method on a nil [*sealedVault] returns [verrors.ErrNilVault].
@@ -523,6 +526,177 @@ func TestVault_Delete(t *testing.T) {
 	})
 }
 
+// TestVault_ListIDs covers paging ids without opening rows, unsupported storage, and nil receivers.
+func TestVault_ListIDs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("happy", func(t *testing.T) {
+
+		// Ids are listed in order across pages, including rows sealed under another key.
+		st := storage.NewMemStorage()
+		v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+		other := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+		want := make([]string, 0, 3)
+		for _, w := range []vault.Vault{v, v, other} {
+			id, err := w.Store(ctx, "ns", []byte("x"))
+			assert.Ok(t, err)
+			want = append(want, id)
+		}
+		slices.Sort(want)
+
+		l, ok := v.(vault.Lister)
+		assert.True(t, ok)
+		page, next, err := l.ListIDs(ctx, "ns", "", 2)
+		assert.Ok(t, err)
+		assert.Equal(t, want[:2], page)
+		page, next, err = l.ListIDs(ctx, "ns", next, 2)
+		assert.Ok(t, err)
+		assert.Equal(t, want[2:], page)
+		assert.Equal(t, "", next)
+	})
+
+	t.Run("unsupported_storage", func(t *testing.T) {
+
+		// Storage without storage.Lister cannot be listed.
+		st := struct{ storage.Storage }{storage.NewMemStorage()}
+		v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+		_, _, err := v.(vault.Lister).ListIDs(ctx, "ns", "", 0)
+		assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+	})
+
+	t.Run("nil_receiver", func(t *testing.T) {
+		_, _, err := v1.NilSealedVault.(vault.Lister).ListIDs(ctx, "ns", "", 0)
+		assert.ErrorIs(t, err, verrors.ErrNilVault)
+	})
+}
+
+// TestVault_rowKeyIDs verifies successful writes and opens report the row's key id to every
+// function set with vault.WithRowKeyIDs, and that failures and deletes report nothing.
+func TestVault_rowKeyIDs(t *testing.T) {
+	st := storage.NewMemStorage()
+	v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+	keyID := v.(vault.KeyIdentifier).ActiveKeyID()
+
+	var inner, outer []string
+	ctx := vault.WithRowKeyIDs(context.Background(), func(id string, got []byte) {
+		assert.Equal(t, keyID, got)
+		outer = append(outer, id)
+	})
+	ctx = vault.WithRowKeyIDs(ctx, func(id string, _ []byte) { inner = append(inner, id) })
+
+	id, err := v.Store(ctx, "ns", []byte("x"))
+	assert.Ok(t, err)
+	_, err = v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Ok(t, v.Update(ctx, "ns", id, []byte("y")))
+	assert.Ok(t, v.CompareAndSwap(ctx, "ns", id, []byte("y"), []byte("z")))
+	assert.ErrorIs(t, v.CompareAndSwap(ctx, "ns", id, []byte("y"), []byte("z")), verrors.ErrWrongCurrent)
+	assert.Ok(t, v.MoveNamespace(ctx, "ns", "other", id))
+	_, err = v.Retrieve(ctx, "ns", id)
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+	_, err = v.(vault.Batcher).RetrieveMany(ctx, "other", []string{id})
+	assert.Ok(t, err)
+	assert.Ok(t, v.Delete(ctx, "other", id))
+
+	want := []string{id, id, id, id, id, id}
+	assert.Equal(t, want, inner)
+	assert.Equal(t, want, outer)
+}
+
+// TestVault_expiry covers expiring rows: expired rows withhold plaintext, compare-and-swap and moves
+// keep the expiry, Update clears it, and a tampered expiry fails authentication.
+func TestVault_expiry(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	t.Run("expired", func(t *testing.T) {
+		st := storage.NewMemStorage()
+		v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+		e, ok := v.(vault.Expirer)
+		assert.True(t, ok)
+		id, err := e.StoreExpiring(ctx, "ns", []byte("token"), past)
+		assert.Ok(t, err)
+
+		_, err = v.Retrieve(ctx, "ns", id)
+		assert.ErrorIs(t, err, verrors.ErrExpired)
+		assert.ErrorIs(t, v.CompareAndSwap(ctx, "ns", id, []byte("token"), []byte("x")), verrors.ErrExpired)
+		assert.ErrorIs(t, v.MoveNamespace(ctx, "ns", "other", id), verrors.ErrExpired)
+
+		// UpdateExpiring revives the row without opening it.
+		assert.Ok(t, e.UpdateExpiring(ctx, "ns", id, []byte("token-2"), future))
+		got, err := v.Retrieve(ctx, "ns", id)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("token-2"), got)
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		v := testEnvelopeVault(t, storage.NewMemStorage(), identifier.HexIdentifier{})
+		id, err := v.(vault.Expirer).StoreExpiring(ctx, "ns", []byte("token"), future)
+		assert.Ok(t, err)
+
+		v1.SetClock(v, func() time.Time { return future.Add(time.Second) })
+		_, err = v.Retrieve(ctx, "ns", id)
+		assert.ErrorIs(t, err, verrors.ErrExpired)
+
+		v1.SetClock(v, func() time.Time { return future.Add(-time.Second) })
+		got, err := v.Retrieve(ctx, "ns", id)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("token"), got)
+	})
+
+	t.Run("kept_and_cleared", func(t *testing.T) {
+		st := storage.NewMemStorage()
+		v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+		id, err := v.(vault.Expirer).StoreExpiring(ctx, "ns", []byte("a"), future)
+		assert.Ok(t, err)
+
+		expiresAt := func(ns string) time.Time {
+			wire, err := st.Get(ctx, ns, id)
+			assert.Ok(t, err)
+			var msg models.Sealed
+			assert.Ok(t, msg.UnmarshalBinary(wire))
+			return msg.Meta.ExpiresAt
+		}
+		assert.Equal(t, future.Unix(), expiresAt("ns").Unix())
+
+		assert.Ok(t, v.CompareAndSwap(ctx, "ns", id, []byte("a"), []byte("b")))
+		assert.Equal(t, future.Unix(), expiresAt("ns").Unix())
+		assert.Ok(t, v.MoveNamespace(ctx, "ns", "other", id))
+		assert.Equal(t, future.Unix(), expiresAt("other").Unix())
+
+		assert.Ok(t, v.Update(ctx, "other", id, []byte("c")))
+		assert.True(t, expiresAt("other").IsZero())
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+
+		// Pushing the expiry back in storage breaks authentication rather than reviving the row.
+		st := storage.NewMemStorage()
+		v := testEnvelopeVault(t, st, identifier.HexIdentifier{})
+		id, err := v.(vault.Expirer).StoreExpiring(ctx, "ns", []byte("a"), past)
+		assert.Ok(t, err)
+
+		wire, err := st.Get(ctx, "ns", id)
+		assert.Ok(t, err)
+		var msg models.Sealed
+		assert.Ok(t, msg.UnmarshalBinary(wire))
+		msg.Meta.ExpiresAt = future
+		forged, err := msg.MarshalBinary()
+		assert.Ok(t, err)
+		assert.Ok(t, st.Replace(ctx, "ns", id, forged))
+
+		_, err = v.Retrieve(ctx, "ns", id)
+		assert.ErrorIs(t, err, verrors.ErrDecrypt)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		v := testEnvelopeVault(t, storage.NewMemStorage(), identifier.HexIdentifier{})
+		_, err := v.(vault.Expirer).StoreExpiring(ctx, "ns", []byte("a"), time.Unix(0, 0))
+		assert.ErrorIs(t, err, v1errs.ErrMetaExpiry)
+	})
+}
+
 //=============================================================================
 // Test helpers and fakes
 //=============================================================================
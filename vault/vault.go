@@ -4,7 +4,12 @@ implementations live in versioned subpackages (for example [go.rtnl.ai/x/vault/v
 */
 package vault
 
-import "context"
+import (
+	"context"
+	"time"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+)
 
 //=============================================================================
 // Vault
@@ -20,3 +25,138 @@ type Vault interface {
 	MoveNamespace(ctx context.Context, oldNamespace, newNamespace, id string) error
 	Delete(ctx context.Context, namespace, id string) error
 }
+
+// Lister is an optional interface for [Vault] implementations that can enumerate the row ids in a
+// namespace without opening any ciphertext, for audits, exports, and cleanup jobs. Callers discover
+// it with a type assertion. Paging follows [go.rtnl.ai/x/vault/storage.Lister]: up to limit ids
+// sorting after cursor, and a next cursor that is empty once no ids remain.
+type Lister interface {
+	ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error)
+}
+
+// KeyIdentifier is an optional interface for [Vault] implementations that seal with an identifiable
+// long-term key. ActiveKeyID returns the id of the key new rows are sealed for (for v1, the X25519
+// public key bytes stored as the row KeyID); callers must not modify the returned slice.
+type KeyIdentifier interface {
+	ActiveKeyID() []byte
+}
+
+// Expirer is an optional interface for [Vault] implementations that can bind an expiry to a row.
+// After expiresAt, Retrieve (and operations that open the row) fail with
+// [go.rtnl.ai/x/vault/errors.ErrExpired] even though the row is still stored; a zero expiresAt
+// stores a row that never expires. UpdateExpiring replaces the row and its expiry together.
+type Expirer interface {
+	StoreExpiring(ctx context.Context, namespace string, plaintext []byte, expiresAt time.Time) (id string, err error)
+	UpdateExpiring(ctx context.Context, namespace, id string, plaintext []byte, expiresAt time.Time) error
+}
+
+// Batcher is an optional interface for [Vault] implementations with batch variants of Store,
+// Retrieve, and Delete, for imports and exports that would otherwise make one call (and one storage
+// round trip) per row. Every method returns one [BatchResult] per input item, in input order, and
+// [go.rtnl.ai/x/vault/errors.ErrBatchIncomplete] when any item failed; other errors mean the call did
+// not run. Items in one call share a namespace. The package functions [StoreMany], [RetrieveMany],
+// and [DeleteMany] call a vault's Batcher, or fall back to one call per item.
+type Batcher interface {
+	StoreMany(ctx context.Context, namespace string, plaintexts [][]byte) ([]BatchResult, error)
+	RetrieveMany(ctx context.Context, namespace string, ids []string) ([]BatchResult, error)
+	DeleteMany(ctx context.Context, namespace string, ids []string) ([]BatchResult, error)
+}
+
+// BatchResult is the outcome of one item in a [Batcher] call.
+type BatchResult struct {
+	ID        string // StoreMany: the new id, empty on failure; otherwise the requested id
+	Plaintext []byte // RetrieveMany only; nil when Err is set
+	Err       error  // nil on success
+}
+
+// StoreMany stores plaintexts through v's [Batcher], or with one Store per plaintext when v is not
+// a Batcher; the results and errors are the same either way.
+func StoreMany(ctx context.Context, v Vault, namespace string, plaintexts [][]byte) ([]BatchResult, error) {
+	if b, ok := v.(Batcher); ok {
+		return b.StoreMany(ctx, namespace, plaintexts)
+	}
+
+	results := make([]BatchResult, len(plaintexts))
+	for i, plaintext := range plaintexts {
+		if results[i].Err = ctx.Err(); results[i].Err == nil {
+			results[i].ID, results[i].Err = v.Store(ctx, namespace, plaintext)
+		}
+	}
+	return results, batchErr(results)
+}
+
+// RetrieveMany opens the rows for ids through v's [Batcher], or with one Retrieve per id when v is
+// not a Batcher.
+func RetrieveMany(ctx context.Context, v Vault, namespace string, ids []string) ([]BatchResult, error) {
+	if b, ok := v.(Batcher); ok {
+		return b.RetrieveMany(ctx, namespace, ids)
+	}
+
+	results := make([]BatchResult, len(ids))
+	for i, id := range ids {
+		results[i].ID = id
+		if results[i].Err = ctx.Err(); results[i].Err == nil {
+			results[i].Plaintext, results[i].Err = v.Retrieve(ctx, namespace, id)
+		}
+	}
+	return results, batchErr(results)
+}
+
+// DeleteMany removes the rows for ids through v's [Batcher], or with one Delete per id when v is
+// not a Batcher.
+func DeleteMany(ctx context.Context, v Vault, namespace string, ids []string) ([]BatchResult, error) {
+	if b, ok := v.(Batcher); ok {
+		return b.DeleteMany(ctx, namespace, ids)
+	}
+
+	results := make([]BatchResult, len(ids))
+	for i, id := range ids {
+		results[i].ID = id
+		if results[i].Err = ctx.Err(); results[i].Err == nil {
+			results[i].Err = v.Delete(ctx, namespace, id)
+		}
+	}
+	return results, batchErr(results)
+}
+
+// batchErr returns [verrors.ErrBatchIncomplete] if any result failed.
+func batchErr(results []BatchResult) error {
+	for _, res := range results {
+		if res.Err != nil {
+			return verrors.ErrBatchIncomplete
+		}
+	}
+	return nil
+}
+
+//=============================================================================
+// Row key ids
+//=============================================================================
+
+type rowKeyIDKey struct{}
+
+// WithRowKeyIDs returns a context that asks [Vault] implementations to report, through
+// [ReportRowKeyID], the key id of each row an operation seals or opens: the id of the key the new
+// row is sealed for on writes, and the row's own key id (for v1, Meta.KeyID) on reads. It lets
+// wrappers such as [go.rtnl.ai/x/vault/audit] attribute an operation to the key that protected the
+// row, which after a key rotation need not be [KeyIdentifier.ActiveKeyID]. A report already in ctx
+// is still called after report.
+func WithRowKeyIDs(ctx context.Context, report func(id string, keyID []byte)) context.Context {
+	if outer, ok := ctx.Value(rowKeyIDKey{}).(func(string, []byte)); ok {
+		inner := report
+		report = func(id string, keyID []byte) {
+			inner(id, keyID)
+			outer(id, keyID)
+		}
+	}
+	return context.WithValue(ctx, rowKeyIDKey{}, report)
+}
+
+// ReportRowKeyID passes the key id of row id to the function set with [WithRowKeyIDs], if any. It
+// is called by implementations once the row has been sealed or opened, on the caller's goroutine.
+// keyID may alias the row; reports must copy it to keep it.
+func ReportRowKeyID(ctx context.Context, id string, keyID []byte) {
+	if report, ok := ctx.Value(rowKeyIDKey{}).(func(string, []byte)); ok {
+		report(id, keyID)
+	}
+}
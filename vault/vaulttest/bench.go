@@ -0,0 +1,273 @@
+package vaulttest
+
+// Benchmark harnesses for [storage.Storage] backends and [rtvault.Vault] implementations.
+//
+// Like the conformance helpers these are not benchmarks by themselves: call [StorageBenchmarks] or
+// [VaultBenchmarks] from a Benchmark* function in your *_test.go files and compare runs with
+// benchstat. Every sub-benchmark reports allocations and, through b.SetBytes, payload throughput.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	rtvault "go.rtnl.ai/x/vault"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+)
+
+// BenchmarkPayloadSizes are the payload sizes, in bytes, each harness runs at: a small token, a
+// typical JSON document, and a larger blob.
+var BenchmarkPayloadSizes = []int{64, 1 << 10, 16 << 10}
+
+// benchParallelRows is how many rows the parallel read benchmarks spread their reads over.
+const benchParallelRows = 64
+
+// benchPayload returns size bytes of a repeating pattern, with the last byte set to mark so two
+// payloads of one size differ.
+func benchPayload(size int, mark byte) []byte {
+	p := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+	if size > 0 {
+		p[size-1] = mark
+	}
+	return p
+}
+
+// benchSizes runs fn as a sub-benchmark per payload size, named like "1KiB".
+func benchSizes(b *testing.B, fn func(b *testing.B, size int)) {
+	for _, size := range BenchmarkPayloadSizes {
+		name := fmt.Sprintf("%dB", size)
+		if size >= 1<<10 && size%(1<<10) == 0 {
+			name = fmt.Sprintf("%dKiB", size>>10)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			fn(b, size)
+		})
+	}
+}
+
+// mustID mints an id or fails the benchmark.
+func mustID(b *testing.B, idGen identifier.Identifier) string {
+	id, err := idGen.New()
+	if err != nil {
+		b.Fatalf("idGen.New: %v", err)
+	}
+	return id
+}
+
+//=============================================================================
+// Storage
+//=============================================================================
+
+// StorageBenchmarks runs sub-benchmarks of each [storage.Storage] operation against newStorage(b),
+// at every size in [BenchmarkPayloadSizes]: create, get, replace, cas, create_delete, and parallel
+// get and cas (each goroutine swapping its own row, so backends that lock more than one row show
+// contention). newStorage is called once per sub-benchmark and should return an empty backend.
+func StorageBenchmarks(b *testing.B, idGen identifier.Identifier, newStorage func(*testing.B) storage.Storage) {
+	b.Helper()
+	ctx := context.Background()
+	const ns = "bench"
+
+	b.Run("create", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st, blob := newStorage(b), benchPayload(size, 'a')
+			for b.Loop() {
+				if err := st.Create(ctx, ns, mustID(b, idGen), blob); err != nil {
+					b.Fatalf("create: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("get", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st, id := newStorage(b), mustID(b, idGen)
+			if err := st.Create(ctx, ns, id, benchPayload(size, 'a')); err != nil {
+				b.Fatalf("create: %v", err)
+			}
+			for b.Loop() {
+				if _, err := st.Get(ctx, ns, id); err != nil {
+					b.Fatalf("get: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("replace", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st, id, blob := newStorage(b), mustID(b, idGen), benchPayload(size, 'a')
+			if err := st.Create(ctx, ns, id, blob); err != nil {
+				b.Fatalf("create: %v", err)
+			}
+			for b.Loop() {
+				if err := st.Replace(ctx, ns, id, blob); err != nil {
+					b.Fatalf("replace: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("cas", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st, id := newStorage(b), mustID(b, idGen)
+			blobs := [2][]byte{benchPayload(size, 'a'), benchPayload(size, 'b')}
+			if err := st.Create(ctx, ns, id, blobs[0]); err != nil {
+				b.Fatalf("create: %v", err)
+			}
+			for i := 0; b.Loop(); i++ {
+				if err := st.CompareAndSwap(ctx, ns, id, blobs[i%2], blobs[(i+1)%2]); err != nil {
+					b.Fatalf("cas: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("create_delete", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st, id, blob := newStorage(b), mustID(b, idGen), benchPayload(size, 'a')
+			for b.Loop() {
+				if err := st.Create(ctx, ns, id, blob); err != nil {
+					b.Fatalf("create: %v", err)
+				}
+				if err := st.Delete(ctx, ns, id); err != nil {
+					b.Fatalf("delete: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("get_parallel", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st := newStorage(b)
+			ids := make([]string, benchParallelRows)
+			for i := range ids {
+				ids[i] = mustID(b, idGen)
+				if err := st.Create(ctx, ns, ids[i], benchPayload(size, 'a')); err != nil {
+					b.Fatalf("create: %v", err)
+				}
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, err := st.Get(ctx, ns, ids[i%len(ids)]); err != nil {
+						b.Errorf("get: %v", err)
+						return
+					}
+				}
+			})
+		})
+	})
+
+	b.Run("cas_parallel", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			st := newStorage(b)
+			b.RunParallel(func(pb *testing.PB) {
+				id, err := idGen.New()
+				if err != nil {
+					b.Errorf("idGen.New: %v", err)
+					return
+				}
+				blobs := [2][]byte{benchPayload(size, 'a'), benchPayload(size, 'b')}
+				if err := st.Create(ctx, ns, id, blobs[0]); err != nil {
+					b.Errorf("create: %v", err)
+					return
+				}
+				for i := 0; pb.Next(); i++ {
+					if err := st.CompareAndSwap(ctx, ns, id, blobs[i%2], blobs[(i+1)%2]); err != nil {
+						b.Errorf("cas: %v", err)
+						return
+					}
+				}
+			})
+		})
+	})
+}
+
+//=============================================================================
+// Vault
+//=============================================================================
+
+// VaultBenchmarks runs sub-benchmarks of the [rtvault.Vault] seal and open paths against
+// newVault(b), at every size in [BenchmarkPayloadSizes]: store, retrieve, update, cas, and parallel
+// retrieve. Over an in-memory backend these measure the vault's own cost (key agreement, key
+// derivation, AEAD, and encoding), which is what regressions in a wire format implementation show
+// up in. newVault is called once per sub-benchmark.
+func VaultBenchmarks(b *testing.B, newVault func(*testing.B) rtvault.Vault) {
+	b.Helper()
+	ctx := context.Background()
+	const ns = "bench"
+
+	// stored returns a vault holding one row of size bytes, and the row's id.
+	stored := func(b *testing.B, size int) (rtvault.Vault, string) {
+		v := newVault(b)
+		id, err := v.Store(ctx, ns, benchPayload(size, 'a'))
+		if err != nil {
+			b.Fatalf("store: %v", err)
+		}
+		return v, id
+	}
+
+	b.Run("store", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			v, plain := newVault(b), benchPayload(size, 'a')
+			for b.Loop() {
+				if _, err := v.Store(ctx, ns, plain); err != nil {
+					b.Fatalf("store: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("retrieve", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			v, id := stored(b, size)
+			for b.Loop() {
+				if _, err := v.Retrieve(ctx, ns, id); err != nil {
+					b.Fatalf("retrieve: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("update", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			v, id := stored(b, size)
+			plain := benchPayload(size, 'b')
+			for b.Loop() {
+				if err := v.Update(ctx, ns, id, plain); err != nil {
+					b.Fatalf("update: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("cas", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			v, id := stored(b, size)
+			plains := [2][]byte{benchPayload(size, 'a'), benchPayload(size, 'b')}
+			for i := 0; b.Loop(); i++ {
+				if err := v.CompareAndSwap(ctx, ns, id, plains[i%2], plains[(i+1)%2]); err != nil {
+					b.Fatalf("cas: %v", err)
+				}
+			}
+		})
+	})
+
+	b.Run("retrieve_parallel", func(b *testing.B) {
+		benchSizes(b, func(b *testing.B, size int) {
+			v, id := stored(b, size)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := v.Retrieve(ctx, ns, id); err != nil {
+						b.Errorf("retrieve: %v", err)
+						return
+					}
+				}
+			})
+		})
+	})
+}
@@ -0,0 +1,293 @@
+package vaulttest
+
+// Fault-injecting [storage.Storage] wrapper for resilience tests.
+//
+// [FaultStorage] sits between a vault (or any other caller) and a working backend and, with
+// probabilities drawn from a seeded generator, delays calls, fails them, persists torn writes, and
+// reports lost compare-and-swap races. Use it to test retry loops, error classification, and
+// recovery in code built on the vault.
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/storage"
+)
+
+// ErrFaultInjected marks a failure produced by [FaultStorage] rather than the wrapped storage. Injected
+// errors are joined with [verrors.ErrStorage] so they classify like real backend failures.
+var ErrFaultInjected = errors.New("vaulttest: injected storage fault")
+
+// FaultOp is a set of [storage.Storage] operations, used to choose which calls [FaultStorage] may
+// disturb.
+type FaultOp uint8
+
+// Operations [FaultStorage] can target. Combine them with |.
+const (
+	OpCreate FaultOp = 1 << iota
+	OpGet
+	OpReplace
+	OpDelete
+	OpCompareAndSwap
+	OpListIDs
+
+	// OpWrites is every operation that changes a row.
+	OpWrites = OpCreate | OpReplace | OpDelete | OpCompareAndSwap
+
+	// OpAll is every operation.
+	OpAll = OpWrites | OpGet | OpListIDs
+)
+
+// FaultConfig controls what [FaultStorage] injects. Rates are probabilities in [0, 1]; zero disables
+// that fault. The zero value injects nothing and passes every call through.
+type FaultConfig struct {
+	// Seed seeds the generator that decides every fault, so a failing test can be replayed exactly.
+	// Calls made from several goroutines draw in scheduling order and are only reproducible when
+	// that order is.
+	Seed uint64
+
+	// Ops limits faults to these operations; zero means [OpAll].
+	Ops FaultOp
+
+	// Latency is the longest delay added before a call; each targeted call sleeps a uniform
+	// duration in [0, Latency). A context canceled during the delay fails the call with its error.
+	Latency time.Duration
+
+	// ErrorRate is the chance a targeted call fails without reaching the wrapped storage.
+	ErrorRate float64
+
+	// Err is the error returned by injected failures; nil uses [ErrFaultInjected]. Either way it is
+	// joined with [verrors.ErrStorage].
+	Err error
+
+	// TornWriteRate is the chance a targeted Create, Replace, or CompareAndSwap persists only a
+	// prefix of the ciphertext and then fails, as a backend that crashed mid-write might.
+	TornWriteRate float64
+
+	// LostCASRate is the chance a targeted CompareAndSwap returns [verrors.ErrCASFailed] without
+	// writing, as if another writer had swapped the row first.
+	LostCASRate float64
+}
+
+// FaultStats counts what a [FaultStorage] has done since it was created.
+type FaultStats struct {
+	Calls      int // calls received, targeted or not
+	Delayed    int // calls that slept
+	Errors     int // calls failed before reaching the wrapped storage
+	TornWrites int // writes that persisted a prefix
+	LostCAS    int // compare-and-swaps reported as lost races
+}
+
+// FaultStorage wraps a [storage.Storage] and injects faults according to a [FaultConfig]. Calls that
+// are not disturbed go to the wrapped storage unchanged. ListIDs forwards to the wrapped
+// [storage.Lister] and returns [verrors.ErrListUnsupported] if there is none. FaultStorage is safe for
+// concurrent use when the wrapped storage is.
+type FaultStorage struct {
+	inner storage.Storage
+
+	mu    sync.Mutex
+	cfg   FaultConfig
+	rng   *rand.Rand
+	stats FaultStats
+}
+
+// FaultStorage implements [storage.Storage] and [storage.Lister].
+var (
+	_ storage.Storage = (*FaultStorage)(nil)
+	_ storage.Lister  = (*FaultStorage)(nil)
+)
+
+// NewFaultStorage wraps a non-nil inner storage.
+func NewFaultStorage(inner storage.Storage, cfg FaultConfig) *FaultStorage {
+	if inner == nil {
+		panic("vaulttest: NewFaultStorage(nil)")
+	}
+	s := &FaultStorage{inner: inner}
+	s.SetConfig(cfg)
+	return s
+}
+
+// Unwrap returns the wrapped storage, for setting up or inspecting rows without faults.
+func (s *FaultStorage) Unwrap() storage.Storage {
+	return s.inner
+}
+
+// SetConfig replaces the configuration and reseeds the generator from cfg.Seed; counters are kept.
+// A common pattern is to seed rows with the zero config and then turn faults on.
+func (s *FaultStorage) SetConfig(cfg FaultConfig) {
+	if cfg.Ops == 0 {
+		cfg.Ops = OpAll
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.rng = rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+}
+
+// Stats returns the counters so far.
+func (s *FaultStorage) Stats() FaultStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+//=============================================================================
+// Storage
+//=============================================================================
+
+// Create may delay, fail, or persist a torn prefix of ciphertext before failing.
+func (s *FaultStorage) Create(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	f, err := s.begin(ctx, OpCreate, len(ciphertext))
+	if err != nil {
+		return err
+	}
+	if f.torn >= 0 {
+		if err := s.inner.Create(ctx, namespace, id, ciphertext[:f.torn]); err != nil {
+			return err
+		}
+		return s.torn(f)
+	}
+	return s.inner.Create(ctx, namespace, id, ciphertext)
+}
+
+// Get may delay or fail.
+func (s *FaultStorage) Get(ctx context.Context, namespace, id string) ([]byte, error) {
+	if _, err := s.begin(ctx, OpGet, 0); err != nil {
+		return nil, err
+	}
+	return s.inner.Get(ctx, namespace, id)
+}
+
+// Replace may delay, fail, or persist a torn prefix of ciphertext before failing.
+func (s *FaultStorage) Replace(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	f, err := s.begin(ctx, OpReplace, len(ciphertext))
+	if err != nil {
+		return err
+	}
+	if f.torn >= 0 {
+		if err := s.inner.Replace(ctx, namespace, id, ciphertext[:f.torn]); err != nil {
+			return err
+		}
+		return s.torn(f)
+	}
+	return s.inner.Replace(ctx, namespace, id, ciphertext)
+}
+
+// Delete may delay or fail.
+func (s *FaultStorage) Delete(ctx context.Context, namespace, id string) error {
+	if _, err := s.begin(ctx, OpDelete, 0); err != nil {
+		return err
+	}
+	return s.inner.Delete(ctx, namespace, id)
+}
+
+// CompareAndSwap may delay, fail, report a lost race, or swap in a torn prefix of newCiphertext
+// before failing. A lost race leaves the row untouched.
+func (s *FaultStorage) CompareAndSwap(ctx context.Context, namespace, id string, oldCiphertext, newCiphertext []byte) error {
+	f, err := s.begin(ctx, OpCompareAndSwap, len(newCiphertext))
+	if err != nil {
+		return err
+	}
+	if f.lostCAS {
+		return verrors.ErrCASFailed
+	}
+	if f.torn >= 0 {
+		if err := s.inner.CompareAndSwap(ctx, namespace, id, oldCiphertext, newCiphertext[:f.torn]); err != nil {
+			return err
+		}
+		return s.torn(f)
+	}
+	return s.inner.CompareAndSwap(ctx, namespace, id, oldCiphertext, newCiphertext)
+}
+
+// ListIDs may delay or fail, and otherwise lists through the wrapped [storage.Lister].
+func (s *FaultStorage) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	lister, ok := s.inner.(storage.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	if _, err := s.begin(ctx, OpListIDs, 0); err != nil {
+		return nil, "", err
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
+
+//=============================================================================
+// Fault decisions
+//=============================================================================
+
+// fault is what begin decided for one call.
+type fault struct {
+	torn    int   // prefix length to persist before failing, or -1
+	lostCAS bool  // report a lost compare-and-swap race
+	fail    error // error to return after a torn write
+}
+
+// begin counts the call, draws its faults, sleeps any delay, and returns the injected error if the
+// call fails outright. size is the ciphertext length for writes that can tear.
+func (s *FaultStorage) begin(ctx context.Context, op FaultOp, size int) (fault, error) {
+	f := fault{torn: -1}
+
+	s.mu.Lock()
+	s.stats.Calls++
+	cfg := s.cfg
+	if cfg.Ops&op == 0 {
+		s.mu.Unlock()
+		return f, nil
+	}
+
+	var delay time.Duration
+	if cfg.Latency > 0 {
+		delay = time.Duration(s.rng.Int64N(int64(cfg.Latency)))
+		s.stats.Delayed++
+	}
+
+	injected := errors.Join(verrors.ErrStorage, ErrFaultInjected)
+	if cfg.Err != nil {
+		injected = errors.Join(verrors.ErrStorage, cfg.Err)
+	}
+
+	failed := s.chance(cfg.ErrorRate)
+	switch {
+	case failed:
+		s.stats.Errors++
+	case op == OpCompareAndSwap && s.chance(cfg.LostCASRate):
+		f.lostCAS = true
+		s.stats.LostCAS++
+	case op&(OpCreate|OpReplace|OpCompareAndSwap) != 0 && size > 0 && s.chance(cfg.TornWriteRate):
+		f.torn, f.fail = s.rng.IntN(size), injected
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return f, ctx.Err()
+		case <-t.C:
+		}
+	}
+	if failed {
+		return f, injected
+	}
+	return f, nil
+}
+
+// torn counts a torn write that reached the wrapped storage and returns its error.
+func (s *FaultStorage) torn(f fault) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.TornWrites++
+	return f.fail
+}
+
+// chance reports true with probability p; the caller holds s.mu.
+func (s *FaultStorage) chance(p float64) bool {
+	return p > 0 && s.rng.Float64() < p
+}
@@ -0,0 +1,160 @@
+package vaulttest_test
+
+// Tests for [vaulttest.FaultStorage].
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	"go.rtnl.ai/x/vault/vaulttest"
+)
+
+// TestFaultStorage_passThrough verifies the zero config disturbs nothing, so the wrapper itself
+// conforms.
+func TestFaultStorage_passThrough(t *testing.T) {
+	newStorage := func(*testing.T) storage.Storage {
+		return vaulttest.NewFaultStorage(storage.NewMemStorage(), vaulttest.FaultConfig{})
+	}
+	vaulttest.StorageConforms(t, identifier.HexIdentifier{}, newStorage)
+	vaulttest.ListerConforms(t, identifier.HexIdentifier{}, newStorage)
+}
+
+// TestFaultStorage_deterministic verifies the same seed fails the same calls and a different seed
+// fails others.
+func TestFaultStorage_deterministic(t *testing.T) {
+	pattern := func(seed uint64) []bool {
+		st := vaulttest.NewFaultStorage(storage.NewMemStorage(), vaulttest.FaultConfig{Seed: seed, ErrorRate: 0.5})
+		failed := make([]bool, 64)
+		for i := range failed {
+			_, err := st.Get(context.Background(), "ns", "id")
+			failed[i] = errors.Is(err, vaulttest.ErrFaultInjected)
+		}
+		return failed
+	}
+
+	first := pattern(42)
+	assert.Equal(t, first, pattern(42))
+	assert.NotEqual(t, first, pattern(43))
+	assert.True(t, slices.Contains(first, true))
+	assert.True(t, slices.Contains(first, false))
+}
+
+// TestFaultStorage_errors verifies injected failures classify as storage errors, skip the wrapped
+// storage, and only hit the targeted operations.
+func TestFaultStorage_errors(t *testing.T) {
+	ctx := context.Background()
+	mem := storage.NewMemStorage()
+	errDown := errors.New("backend down")
+	st := vaulttest.NewFaultStorage(mem, vaulttest.FaultConfig{Ops: vaulttest.OpWrites, ErrorRate: 1, Err: errDown})
+
+	err := st.Create(ctx, "ns", "id", []byte("x"))
+	assert.ErrorIs(t, err, verrors.ErrStorage)
+	assert.ErrorIs(t, err, errDown)
+	_, err = mem.Get(ctx, "ns", "id")
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+
+	// Reads are not targeted and reach the wrapped storage.
+	_, err = st.Get(ctx, "ns", "id")
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+
+	stats := st.Stats()
+	assert.Equal(t, 2, stats.Calls)
+	assert.Equal(t, 1, stats.Errors)
+
+	// Turning faults off lets writes through.
+	st.SetConfig(vaulttest.FaultConfig{})
+	assert.Ok(t, st.Create(ctx, "ns", "id", []byte("x")))
+	assert.Equal(t, 3, st.Stats().Calls)
+}
+
+// TestFaultStorage_tornWrites verifies a torn write persists a strict prefix and reports failure.
+func TestFaultStorage_tornWrites(t *testing.T) {
+	ctx := context.Background()
+	mem := storage.NewMemStorage()
+	st := vaulttest.NewFaultStorage(mem, vaulttest.FaultConfig{Seed: 7, TornWriteRate: 1})
+	full := []byte("0123456789abcdef")
+
+	assertTorn := func(err error) {
+		t.Helper()
+		assert.ErrorIs(t, err, vaulttest.ErrFaultInjected)
+		got, err := mem.Get(ctx, "ns", "id")
+		assert.Ok(t, err)
+		assert.True(t, len(got) < len(full))
+		assert.Equal(t, full[:len(got)], got)
+	}
+
+	assertTorn(st.Create(ctx, "ns", "id", full))
+	assertTorn(st.Replace(ctx, "ns", "id", full))
+
+	cur, err := mem.Get(ctx, "ns", "id")
+	assert.Ok(t, err)
+	assertTorn(st.CompareAndSwap(ctx, "ns", "id", cur, full))
+
+	// A torn compare-and-swap still compares first.
+	assert.ErrorIs(t, st.CompareAndSwap(ctx, "ns", "id", []byte("stale"), full), verrors.ErrCASFailed)
+	assert.Equal(t, 3, st.Stats().TornWrites)
+}
+
+// TestFaultStorage_lostCAS verifies a retry loop over a vault survives lost races and that a lost
+// race leaves the row untouched.
+func TestFaultStorage_lostCAS(t *testing.T) {
+	ctx := context.Background()
+	st := vaulttest.NewFaultStorage(storage.NewMemStorage(), vaulttest.FaultConfig{})
+	v := vaulttest.NewTestVault(t, st, identifier.HexIdentifier{})
+	id, err := v.Store(ctx, "ns", []byte("v0"))
+	assert.Ok(t, err)
+
+	st.SetConfig(vaulttest.FaultConfig{Seed: 1, Ops: vaulttest.OpCompareAndSwap, LostCASRate: 0.75})
+	attempts := 0
+	for {
+		attempts++
+		cur, err := v.Retrieve(ctx, "ns", id)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("v0"), cur)
+
+		err = v.CompareAndSwap(ctx, "ns", id, cur, []byte("v1"))
+		if err == nil {
+			break
+		}
+		assert.ErrorIs(t, err, verrors.ErrCASFailed)
+	}
+
+	got, err := v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("v1"), got)
+	assert.Equal(t, attempts-1, st.Stats().LostCAS)
+}
+
+// TestFaultStorage_latency verifies delays are bounded and a context canceled during one fails the
+// call without reaching the wrapped storage.
+func TestFaultStorage_latency(t *testing.T) {
+	mem := storage.NewMemStorage()
+	st := vaulttest.NewFaultStorage(mem, vaulttest.FaultConfig{Latency: 5 * time.Millisecond})
+
+	start := time.Now()
+	assert.Ok(t, st.Create(context.Background(), "ns", "a", []byte("x")))
+	assert.True(t, time.Since(start) < time.Second)
+
+	st.SetConfig(vaulttest.FaultConfig{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, st.Create(ctx, "ns", "b", []byte("x")), context.DeadlineExceeded)
+	_, err := mem.Get(context.Background(), "ns", "b")
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+	assert.Equal(t, 2, st.Stats().Delayed)
+}
+
+// TestFaultStorage_withoutLister verifies ListIDs reports wrapped storage that cannot list.
+func TestFaultStorage_withoutLister(t *testing.T) {
+	st := vaulttest.NewFaultStorage(struct{ storage.Storage }{storage.NewMemStorage()}, vaulttest.FaultConfig{})
+	_, _, err := st.ListIDs(context.Background(), "ns", "", 0)
+	assert.ErrorIs(t, err, verrors.ErrListUnsupported)
+	assert.PanicsWithValue(t, "vaulttest: NewFaultStorage(nil)", func() { vaulttest.NewFaultStorage(nil, vaulttest.FaultConfig{}) })
+}
@@ -7,13 +7,19 @@ package vaulttest
 //
 // They encode the semantics the vault expects from [storage.Storage]: namespace-scoped rows, correct
 // [verrors.ErrDuplicateKey] / [verrors.ErrNotFound] / [verrors.ErrCASFailed] sentinels, idempotent Delete on
-// missing rows, and Compare-and-swap that compares full ciphertext blobs.
+// missing rows, and Compare-and-swap that compares full ciphertext blobs. Backends that implement the
+// optional [storage.Lister] are checked with [ListerConforms] and the [CheckLister…] helpers, and backends
+// that implement [storage.Batcher] with [BatcherConforms] and the [CheckBatcher…] helpers.
+// [StorageConcurrencyConforms] and the [CheckStorageConcurrent…] helpers race goroutines against one
+// row to check that compare-and-swap and create stay atomic under contention.
 
 import (
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
 	"testing"
 
 	"go.rtnl.ai/x/assert"
@@ -296,3 +302,592 @@ func StorageConforms(t *testing.T, idGen identifier.Identifier, newStorage func(
 		assert.Ok(t, CheckStorageCompareAndSwapMissingRow(ctx, newStorage(t), idGen))
 	})
 }
+
+//=============================================================================
+// Lister conformance
+//=============================================================================
+
+// asLister returns st as a [storage.Lister] or an error naming the missing interface.
+func asLister(st storage.Storage) (storage.Lister, error) {
+	l, ok := st.(storage.Lister)
+	if !ok {
+		return nil, fmt.Errorf("storage %T does not implement storage.Lister", st)
+	}
+	return l, nil
+}
+
+// listAll pages through namespace with the given limit and returns every id in order along with
+// the size of each page.
+func listAll(ctx context.Context, l storage.Lister, namespace string, limit int) (ids []string, pages []int, err error) {
+	var cursor string
+	for {
+		page, next, err := l.ListIDs(ctx, namespace, cursor, limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("list after %q: %w", cursor, err)
+		}
+		ids = append(ids, page...)
+		pages = append(pages, len(page))
+		if next == "" {
+			return ids, pages, nil
+		}
+		if len(pages) > 1000 {
+			return nil, nil, fmt.Errorf("list: cursor never reached the end (last %q)", next)
+		}
+		cursor = next
+	}
+}
+
+// CheckListerPagination verifies ListIDs pages through every id in a namespace exactly once, in
+// ascending order, with at most limit ids per page, without leaking ids from other namespaces.
+func CheckListerPagination(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	l, err := asLister(st)
+	if err != nil {
+		return err
+	}
+
+	want := make([]string, 0, 7)
+	for range 7 {
+		id, err := idGen.New()
+		if err != nil {
+			return fmt.Errorf("idGen.New: %w", err)
+		}
+		if err := st.Create(ctx, "list", id, []byte("x")); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		want = append(want, id)
+	}
+	for range 2 {
+		id, err := idGen.New()
+		if err != nil {
+			return fmt.Errorf("idGen.New: %w", err)
+		}
+		if err := st.Create(ctx, "list-other", id, []byte("y")); err != nil {
+			return fmt.Errorf("create other: %w", err)
+		}
+	}
+	slices.Sort(want)
+
+	got, pages, err := listAll(ctx, l, "list", 3)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(want, got) {
+		return fmt.Errorf("list: got %q want %q", got, want)
+	}
+	for i, n := range pages {
+		if n > 3 {
+			return fmt.Errorf("list: page %d has %d ids, limit 3", i, n)
+		}
+	}
+	return nil
+}
+
+// CheckListerEmptyNamespace verifies listing a namespace with no rows returns no ids and no cursor.
+func CheckListerEmptyNamespace(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	l, err := asLister(st)
+	if err != nil {
+		return err
+	}
+
+	// A row elsewhere must not make the empty namespace non-empty.
+	id, err := idGen.New()
+	if err != nil {
+		return fmt.Errorf("idGen.New: %w", err)
+	}
+	if err := st.Create(ctx, "occupied", id, []byte("x")); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	ids, next, err := l.ListIDs(ctx, "empty", "", 10)
+	if err != nil {
+		return fmt.Errorf("list empty: %w", err)
+	}
+	if len(ids) != 0 || next != "" {
+		return fmt.Errorf("list empty: got %q next %q want none", ids, next)
+	}
+	return nil
+}
+
+// CheckListerAfterDelete verifies deleted rows are not listed and that a cursor naming a deleted id
+// still resumes with the ids after it.
+func CheckListerAfterDelete(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	l, err := asLister(st)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, 4)
+	for range 4 {
+		id, err := idGen.New()
+		if err != nil {
+			return fmt.Errorf("idGen.New: %w", err)
+		}
+		if err := st.Create(ctx, "n", id, []byte("x")); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Take the first page, then delete the id the cursor points at.
+	page, next, err := l.ListIDs(ctx, "n", "", 2)
+	if err != nil {
+		return fmt.Errorf("list first page: %w", err)
+	}
+	if !slices.Equal(ids[:2], page) || next == "" {
+		return fmt.Errorf("list first page: got %q next %q want %q and a cursor", page, next, ids[:2])
+	}
+	if err := st.Delete(ctx, "n", ids[1]); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	rest, _, err := listAll(ctx, l, "n", 2)
+	if err != nil {
+		return err
+	}
+	if want := []string{ids[0], ids[2], ids[3]}; !slices.Equal(want, rest) {
+		return fmt.Errorf("list after delete: got %q want %q", rest, want)
+	}
+
+	page, _, err = l.ListIDs(ctx, "n", next, 2)
+	if err != nil {
+		return fmt.Errorf("list from deleted cursor: %w", err)
+	}
+	if !slices.Equal(ids[2:], page) {
+		return fmt.Errorf("list from deleted cursor: got %q want %q", page, ids[2:])
+	}
+	return nil
+}
+
+// CheckListerDefaultLimit verifies a limit of zero returns a full page rather than nothing.
+func CheckListerDefaultLimit(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	l, err := asLister(st)
+	if err != nil {
+		return err
+	}
+
+	want := make([]string, 0, 5)
+	for range 5 {
+		id, err := idGen.New()
+		if err != nil {
+			return fmt.Errorf("idGen.New: %w", err)
+		}
+		if err := st.Create(ctx, "n", id, []byte("x")); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		want = append(want, id)
+	}
+	slices.Sort(want)
+
+	got, next, err := l.ListIDs(ctx, "n", "", 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if !slices.Equal(want, got) || next != "" {
+		return fmt.Errorf("list limit 0: got %q next %q want %q", got, next, want)
+	}
+	return nil
+}
+
+// ListerConforms runs subtests that verify newStorage(t) implements [storage.Lister] semantics, using
+// ids minted by idGen. Like [StorageConforms], call it from your own Test_* and return an isolated
+// backend from each newStorage call.
+func ListerConforms(t *testing.T, idGen identifier.Identifier, newStorage func(*testing.T) storage.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("list_pagination", func(t *testing.T) {
+		assert.Ok(t, CheckListerPagination(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("list_empty_namespace", func(t *testing.T) {
+		assert.Ok(t, CheckListerEmptyNamespace(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("list_after_delete", func(t *testing.T) {
+		assert.Ok(t, CheckListerAfterDelete(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("list_default_limit", func(t *testing.T) {
+		assert.Ok(t, CheckListerDefaultLimit(ctx, newStorage(t), idGen))
+	})
+}
+
+//=============================================================================
+// Batcher conformance
+//=============================================================================
+
+// asBatcher returns st as a [storage.Batcher] or an error naming the missing interface.
+func asBatcher(st storage.Storage) (storage.Batcher, error) {
+	b, ok := st.(storage.Batcher)
+	if !ok {
+		return nil, fmt.Errorf("storage %T does not implement storage.Batcher", st)
+	}
+	return b, nil
+}
+
+// newRows mints n rows with distinct ciphertexts.
+func newRows(idGen identifier.Identifier, n int) ([]storage.Row, error) {
+	rows := make([]storage.Row, 0, n)
+	for i := range n {
+		id, err := idGen.New()
+		if err != nil {
+			return nil, fmt.Errorf("idGen.New: %w", err)
+		}
+		rows = append(rows, storage.Row{ID: id, Ciphertext: []byte(fmt.Sprintf("blob-%d", i))})
+	}
+	return rows, nil
+}
+
+// CheckBatcherCreateGetMany verifies CreateMany stores every row in the namespace, readable through
+// Get and GetMany, and that GetMany keeps the order of ids and returns nil for missing ids.
+func CheckBatcherCreateGetMany(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	b, err := asBatcher(st)
+	if err != nil {
+		return err
+	}
+
+	rows, err := newRows(idGen, 5)
+	if err != nil {
+		return err
+	}
+	if err := b.CreateMany(ctx, "batch", rows); err != nil {
+		return fmt.Errorf("create many: %w", err)
+	}
+	for _, row := range rows {
+		got, err := st.Get(ctx, "batch", row.ID)
+		if err != nil {
+			return fmt.Errorf("get %s: %w", row.ID, err)
+		}
+		if !bytes.Equal(row.Ciphertext, got) {
+			return fmt.Errorf("get %s: got %q want %q", row.ID, got, row.Ciphertext)
+		}
+	}
+
+	missing, err := idGen.New()
+	if err != nil {
+		return fmt.Errorf("idGen.New: %w", err)
+	}
+	ids := []string{rows[3].ID, missing, rows[0].ID, rows[3].ID}
+	got, err := b.GetMany(ctx, "batch", ids)
+	if err != nil {
+		return fmt.Errorf("get many: %w", err)
+	}
+	want := [][]byte{rows[3].Ciphertext, nil, rows[0].Ciphertext, rows[3].Ciphertext}
+	if len(got) != len(want) {
+		return fmt.Errorf("get many: got %d blobs want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(want[i], got[i]) || (want[i] == nil) != (got[i] == nil) {
+			return fmt.Errorf("get many [%d]: got %q want %q", i, got[i], want[i])
+		}
+	}
+
+	// Rows are namespace scoped.
+	got, err = b.GetMany(ctx, "batch-other", []string{rows[0].ID})
+	if err != nil {
+		return fmt.Errorf("get many other namespace: %w", err)
+	}
+	if len(got) != 1 || got[0] != nil {
+		return fmt.Errorf("get many other namespace: got %q want [nil]", got)
+	}
+	return nil
+}
+
+// CheckBatcherCreateDuplicate verifies a CreateMany batch containing an existing id fails with
+// [verrors.ErrDuplicateKey], creates none of its rows, and leaves the existing row unchanged.
+func CheckBatcherCreateDuplicate(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	b, err := asBatcher(st)
+	if err != nil {
+		return err
+	}
+
+	rows, err := newRows(idGen, 4)
+	if err != nil {
+		return err
+	}
+	if err := st.Create(ctx, "batch", rows[2].ID, []byte("existing")); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	if err := b.CreateMany(ctx, "batch", rows); !errors.Is(err, verrors.ErrDuplicateKey) {
+		return fmt.Errorf("create many with duplicate: got %v want %v", err, verrors.ErrDuplicateKey)
+	}
+	for i, row := range rows {
+		got, err := st.Get(ctx, "batch", row.ID)
+		switch {
+		case i == 2:
+			if err != nil || !bytes.Equal(got, []byte("existing")) {
+				return fmt.Errorf("existing row after failed batch: got %q, %v", got, err)
+			}
+		case !errors.Is(err, verrors.ErrNotFound):
+			return fmt.Errorf("row %d after failed batch: got %q, %v want %v", i, got, err, verrors.ErrNotFound)
+		}
+	}
+
+	// An id repeated within the batch is a duplicate too.
+	if err := b.CreateMany(ctx, "batch", []storage.Row{rows[0], rows[1], rows[0]}); !errors.Is(err, verrors.ErrDuplicateKey) {
+		return fmt.Errorf("create many with repeated id: got %v want %v", err, verrors.ErrDuplicateKey)
+	}
+	if _, err := st.Get(ctx, "batch", rows[1].ID); !errors.Is(err, verrors.ErrNotFound) {
+		return fmt.Errorf("row after batch with repeated id: got %v want %v", err, verrors.ErrNotFound)
+	}
+	return nil
+}
+
+// CheckBatcherDeleteMany verifies DeleteMany removes the listed rows, ignores missing ids, and leaves
+// other rows and namespaces alone.
+func CheckBatcherDeleteMany(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	b, err := asBatcher(st)
+	if err != nil {
+		return err
+	}
+
+	rows, err := newRows(idGen, 4)
+	if err != nil {
+		return err
+	}
+	if err := b.CreateMany(ctx, "batch", rows); err != nil {
+		return fmt.Errorf("create many: %w", err)
+	}
+	if err := st.Create(ctx, "batch-other", rows[0].ID, []byte("other")); err != nil {
+		return fmt.Errorf("create other: %w", err)
+	}
+
+	missing, err := idGen.New()
+	if err != nil {
+		return fmt.Errorf("idGen.New: %w", err)
+	}
+	if err := b.DeleteMany(ctx, "batch", []string{rows[0].ID, missing, rows[2].ID}); err != nil {
+		return fmt.Errorf("delete many: %w", err)
+	}
+	if err := b.DeleteMany(ctx, "batch", nil); err != nil {
+		return fmt.Errorf("delete many empty: %w", err)
+	}
+
+	for i, row := range rows {
+		_, err := st.Get(ctx, "batch", row.ID)
+		deleted := i == 0 || i == 2
+		if deleted && !errors.Is(err, verrors.ErrNotFound) {
+			return fmt.Errorf("deleted row %d: got %v want %v", i, err, verrors.ErrNotFound)
+		}
+		if !deleted && err != nil {
+			return fmt.Errorf("kept row %d: %w", i, err)
+		}
+	}
+	if _, err := st.Get(ctx, "batch-other", rows[0].ID); err != nil {
+		return fmt.Errorf("other namespace row: %w", err)
+	}
+	return nil
+}
+
+// BatcherConforms runs subtests that verify newStorage(t) implements [storage.Batcher] semantics,
+// using ids minted by idGen. Like [StorageConforms], call it from your own Test_* and return an
+// isolated backend from each newStorage call.
+func BatcherConforms(t *testing.T, idGen identifier.Identifier, newStorage func(*testing.T) storage.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("batch_create_get", func(t *testing.T) {
+		assert.Ok(t, CheckBatcherCreateGetMany(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("batch_create_duplicate", func(t *testing.T) {
+		assert.Ok(t, CheckBatcherCreateDuplicate(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("batch_delete", func(t *testing.T) {
+		assert.Ok(t, CheckBatcherDeleteMany(ctx, newStorage(t), idGen))
+	})
+}
+
+//=============================================================================
+// Concurrency conformance
+//=============================================================================
+
+// DefaultConcurrencyWorkers is the number of goroutines the [CheckStorageConcurrent…] helpers race
+// against one row.
+const DefaultConcurrencyWorkers = 16
+
+// concurrencyRounds is how many times each worker in [CheckStorageConcurrentCreateDelete] claims
+// and releases the row.
+const concurrencyRounds = 20
+
+// race runs fn(i) for i in [0, workers) on separate goroutines released together, and returns the
+// joined non-nil results.
+func race(workers int, fn func(i int) error) error {
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		errs  = make([]error, workers)
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// workerBlob is the distinct ciphertext worker i writes.
+func workerBlob(i int) []byte {
+	return []byte(fmt.Sprintf("worker-%02d", i))
+}
+
+// CheckStorageConcurrentCAS races [DefaultConcurrencyWorkers] compare-and-swaps from the same old
+// value on one row and verifies exactly one succeeds, the rest fail with [verrors.ErrCASFailed], and
+// the row holds the winner's value.
+func CheckStorageConcurrentCAS(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	const ns = "ns"
+	id, err := idGen.New()
+	if err != nil {
+		return fmt.Errorf("idGen.New: %w", err)
+	}
+	start := []byte("start")
+	if err := st.Create(ctx, ns, id, start); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	won := make([]bool, DefaultConcurrencyWorkers)
+	err = race(DefaultConcurrencyWorkers, func(i int) error {
+		err := st.CompareAndSwap(ctx, ns, id, start, workerBlob(i))
+		switch {
+		case err == nil:
+			won[i] = true
+		case !errors.Is(err, verrors.ErrCASFailed):
+			return fmt.Errorf("worker %d: cas: want ErrCASFailed or nil, got %w", i, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return checkOneWinner(ctx, st, ns, id, "cas", won)
+}
+
+// CheckStorageConcurrentCreate races [DefaultConcurrencyWorkers] creates of one (namespace, id) and
+// verifies exactly one succeeds, the rest fail with [verrors.ErrDuplicateKey], and the row holds the
+// winner's value.
+func CheckStorageConcurrentCreate(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	const ns = "ns"
+	id, err := idGen.New()
+	if err != nil {
+		return fmt.Errorf("idGen.New: %w", err)
+	}
+
+	won := make([]bool, DefaultConcurrencyWorkers)
+	err = race(DefaultConcurrencyWorkers, func(i int) error {
+		err := st.Create(ctx, ns, id, workerBlob(i))
+		switch {
+		case err == nil:
+			won[i] = true
+		case !errors.Is(err, verrors.ErrDuplicateKey):
+			return fmt.Errorf("worker %d: create: want ErrDuplicateKey or nil, got %w", i, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return checkOneWinner(ctx, st, ns, id, "create", won)
+}
+
+// checkOneWinner verifies exactly one worker won and the row holds its blob.
+func checkOneWinner(ctx context.Context, st storage.Storage, ns, id, op string, won []bool) error {
+	winner := -1
+	for i, ok := range won {
+		if !ok {
+			continue
+		}
+		if winner >= 0 {
+			return fmt.Errorf("%s: workers %d and %d both succeeded, want exactly one", op, winner, i)
+		}
+		winner = i
+	}
+	if winner < 0 {
+		return fmt.Errorf("%s: no worker succeeded, want exactly one", op)
+	}
+
+	got, err := st.Get(ctx, ns, id)
+	if err != nil {
+		return fmt.Errorf("get after %s: %w", op, err)
+	}
+	if want := workerBlob(winner); !bytes.Equal(got, want) {
+		return fmt.Errorf("get after %s: got %q want winner's %q", op, got, want)
+	}
+	return nil
+}
+
+// CheckStorageConcurrentCreateDelete uses one row as a lock: [DefaultConcurrencyWorkers] goroutines
+// repeatedly claim it with Create and release it with Delete. A worker whose Create succeeds must
+// read back its own value and win a compare-and-swap on it before releasing; a failed Create must be
+// [verrors.ErrDuplicateKey]. Afterwards the row is gone.
+func CheckStorageConcurrentCreateDelete(ctx context.Context, st storage.Storage, idGen identifier.Identifier) error {
+	const ns = "ns"
+	id, err := idGen.New()
+	if err != nil {
+		return fmt.Errorf("idGen.New: %w", err)
+	}
+
+	err = race(DefaultConcurrencyWorkers, func(i int) error {
+		mine := workerBlob(i)
+		for range concurrencyRounds {
+			err := st.Create(ctx, ns, id, mine)
+			if errors.Is(err, verrors.ErrDuplicateKey) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("worker %d: create: %w", i, err)
+			}
+
+			got, err := st.Get(ctx, ns, id)
+			if err != nil {
+				return fmt.Errorf("worker %d: get own row: %w", i, err)
+			}
+			if !bytes.Equal(got, mine) {
+				return fmt.Errorf("worker %d: get own row: got %q want %q", i, got, mine)
+			}
+			if err := st.CompareAndSwap(ctx, ns, id, mine, mine); err != nil {
+				return fmt.Errorf("worker %d: cas own row: %w", i, err)
+			}
+			if err := st.Delete(ctx, ns, id); err != nil {
+				return fmt.Errorf("worker %d: delete: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := st.Get(ctx, ns, id); !errors.Is(err, verrors.ErrNotFound) {
+		return fmt.Errorf("get after race: want ErrNotFound, got %v", err)
+	}
+	return nil
+}
+
+// StorageConcurrencyConforms runs subtests that race goroutines against newStorage(t) and verify
+// [storage.Storage] stays atomic under contention: one winner per compare-and-swap or create, and
+// safe create/delete cycles. Run it with -race. Like [StorageConforms], call it from your own Test_*
+// and return an isolated backend from each newStorage call.
+func StorageConcurrencyConforms(t *testing.T, idGen identifier.Identifier, newStorage func(*testing.T) storage.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("concurrent_cas", func(t *testing.T) {
+		assert.Ok(t, CheckStorageConcurrentCAS(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("concurrent_create", func(t *testing.T) {
+		assert.Ok(t, CheckStorageConcurrentCreate(ctx, newStorage(t), idGen))
+	})
+
+	t.Run("concurrent_create_delete", func(t *testing.T) {
+		assert.Ok(t, CheckStorageConcurrentCreateDelete(ctx, newStorage(t), idGen))
+	})
+}
@@ -11,6 +11,8 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	verrors "go.rtnl.ai/x/vault/errors"
@@ -90,6 +92,42 @@ func TestStorageConformance_negative(t *testing.T) {
 			st:   storCASMissingWrong{},
 			fn:   vaulttest.CheckStorageCompareAndSwapMissingRow,
 		},
+		{
+			// Storage without ListIDs cannot pass any lister check.
+			name: "list_unsupported",
+			st:   newStorAllowDup(),
+			fn:   vaulttest.CheckListerPagination,
+		},
+		{
+			name: "list_pagination",
+			st:   &storListIgnoresLimit{MemStorage: storage.NewMemStorage()},
+			fn:   vaulttest.CheckListerPagination,
+		},
+		{
+			name: "list_empty_namespace",
+			st:   &storListAllNamespaces{MemStorage: storage.NewMemStorage()},
+			fn:   vaulttest.CheckListerEmptyNamespace,
+		},
+		{
+			name: "list_default_limit",
+			st:   &storListZeroLimitEmpty{MemStorage: storage.NewMemStorage()},
+			fn:   vaulttest.CheckListerDefaultLimit,
+		},
+		{
+			name: "concurrent_cas",
+			st:   newStorRacy(false, true),
+			fn:   vaulttest.CheckStorageConcurrentCAS,
+		},
+		{
+			name: "concurrent_create",
+			st:   newStorRacy(true, false),
+			fn:   vaulttest.CheckStorageConcurrentCreate,
+		},
+		{
+			name: "concurrent_create_delete",
+			st:   newStorRacy(true, false),
+			fn:   vaulttest.CheckStorageConcurrentCreateDelete,
+		},
 	}
 
 	for _, tc := range cases {
@@ -501,3 +539,118 @@ func (storCASMissingWrong) Delete(context.Context, string, string) error { retur
 func (storCASMissingWrong) CompareAndSwap(context.Context, string, string, []byte, []byte) error {
 	return verrors.ErrCASFailed
 }
+
+// storListIgnoresLimit returns every id in one page regardless of limit.
+type storListIgnoresLimit struct{ *storage.MemStorage }
+
+func (s *storListIgnoresLimit) ListIDs(ctx context.Context, ns, cursor string, _ int) ([]string, string, error) {
+	return s.MemStorage.ListIDs(ctx, ns, cursor, 1000)
+}
+
+// storListAllNamespaces lists the "occupied" namespace no matter which namespace is requested.
+type storListAllNamespaces struct{ *storage.MemStorage }
+
+func (s *storListAllNamespaces) ListIDs(ctx context.Context, _ string, cursor string, limit int) ([]string, string, error) {
+	return s.MemStorage.ListIDs(ctx, "occupied", cursor, limit)
+}
+
+// storListZeroLimitEmpty treats a zero limit as "return nothing" instead of the default page size.
+type storListZeroLimitEmpty struct{ *storage.MemStorage }
+
+func (s *storListZeroLimitEmpty) ListIDs(ctx context.Context, ns, cursor string, limit int) ([]string, string, error) {
+	if limit == 0 {
+		return nil, "", nil
+	}
+	return s.MemStorage.ListIDs(ctx, ns, cursor, limit)
+}
+
+// storRacy checks and then acts in separate critical sections, so Create and CompareAndSwap are not
+// atomic. To lose the race every time rather than by luck, the first
+// [vaulttest.DefaultConcurrencyWorkers] racy calls all finish their check before any of them writes,
+// and all finish writing before any returns; every one succeeds and all but the last write are
+// lost, which the [vaulttest.CheckStorageConcurrent…] helpers must catch.
+type storRacy struct {
+	mu         sync.Mutex
+	m          map[string][]byte
+	racyCreate bool
+	racyCAS    bool
+	arrived    [2]atomic.Int32
+	gate       [2]sync.WaitGroup
+}
+
+func newStorRacy(racyCreate, racyCAS bool) *storRacy {
+	s := &storRacy{m: make(map[string][]byte), racyCreate: racyCreate, racyCAS: racyCAS}
+	s.gate[0].Add(vaulttest.DefaultConcurrencyWorkers)
+	s.gate[1].Add(vaulttest.DefaultConcurrencyWorkers)
+	return s
+}
+
+// wait holds the first DefaultConcurrencyWorkers callers at phase (0 before writing, 1 after) until
+// all of them have arrived.
+func (s *storRacy) wait(phase int) {
+	if s.arrived[phase].Add(1) <= vaulttest.DefaultConcurrencyWorkers {
+		s.gate[phase].Done()
+		s.gate[phase].Wait()
+	}
+}
+
+func (s *storRacy) Create(_ context.Context, ns, id string, ct []byte) error {
+	s.mu.Lock()
+	_, dup := s.m[storK(ns, id)]
+	s.mu.Unlock()
+	if dup {
+		return verrors.ErrDuplicateKey
+	}
+	if s.racyCreate {
+		s.wait(0)
+		defer s.wait(1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[storK(ns, id)] = append([]byte(nil), ct...)
+	return nil
+}
+
+func (s *storRacy) Get(_ context.Context, ns, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[storK(ns, id)]
+	if !ok {
+		return nil, verrors.ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+func (s *storRacy) Replace(_ context.Context, ns, id string, ct []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := storK(ns, id)
+	if _, ok := s.m[k]; !ok {
+		return verrors.ErrNotFound
+	}
+	s.m[k] = append([]byte(nil), ct...)
+	return nil
+}
+
+func (s *storRacy) Delete(_ context.Context, ns, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, storK(ns, id))
+	return nil
+}
+
+func (s *storRacy) CompareAndSwap(ctx context.Context, ns, id string, old, newCt []byte) error {
+	cur, err := s.Get(ctx, ns, id)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(cur, old) {
+		return verrors.ErrCASFailed
+	}
+	if s.racyCAS {
+		s.wait(0)
+		defer s.wait(1)
+	}
+	return s.Replace(ctx, ns, id, newCt)
+}
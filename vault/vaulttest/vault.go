@@ -4,7 +4,8 @@ helpers for testing [storage.Storage] and [identifier.Identifier] implementation
 
 Storage checks ([StorageConforms] and [CheckStorageCreateGetRoundtrip], etc.) live in
 storage.go. Identifier checks ([IdentifierConforms] and [CheckIdentifierNewManyDistinct], etc.)
-live in identifier.go.
+live in identifier.go. [FaultStorage] (fault.go) wraps a working backend and injects seeded
+errors, latency, torn writes, and lost compare-and-swap races for resilience tests.
 */
 package vaulttest
 
@@ -29,8 +30,11 @@ type TestVault struct {
 	Id identifier.Identifier
 }
 
-// TestVault implements [rtvault.Vault].
-var _ rtvault.Vault = (*TestVault)(nil)
+// TestVault implements [rtvault.Vault] and [rtvault.Lister].
+var (
+	_ rtvault.Vault  = (*TestVault)(nil)
+	_ rtvault.Lister = (*TestVault)(nil)
+)
 
 // NewTestVault returns a plaintext-through-storage vault for tests.
 func NewTestVault(tb testing.TB, st storage.Storage, id identifier.Identifier) *TestVault {
@@ -137,3 +141,16 @@ func (tv *TestVault) Delete(ctx context.Context, namespace, id string) error {
 	}
 	return tv.St.Delete(ctx, namespace, id)
 }
+
+// ListIDs lists ids with [storage.Lister.ListIDs], or returns [verrors.ErrListUnsupported] if the
+// storage cannot list.
+func (tv *TestVault) ListIDs(ctx context.Context, namespace, cursor string, limit int) ([]string, string, error) {
+	if tv == nil {
+		return nil, "", verrors.ErrNilVault
+	}
+	lister, ok := tv.St.(storage.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
@@ -0,0 +1,360 @@
+/*
+Package versioned keeps the previous sealed versions of each row so a bad credential change can be
+rolled back. [Storage] wraps any [storage.Storage]: every Replace or CompareAndSwap first copies the
+current blob into the row's history, keeping the newest N versions. Because history holds sealed
+blobs exactly as stored, nothing is decrypted or re-encrypted to keep it. [Vault] wraps a vault built
+over that storage and adds [Vault.RetrieveVersion], [Vault.Versions], [Vault.Rollback], and
+[Vault.Prune].
+
+Versions are numbered from 1 per row: a new row is version 1 and each write adds one. History for
+namespace ns lives in storage under the reserved namespace [HistoryPrefix]+ns, alongside a small
+version index per row, so listing ns never shows it. Deleting a row deletes its history; moving a
+row to another namespace starts a fresh history there, since rows sealed for the old namespace
+cannot be opened under the new one.
+
+History writes are serialized within one [Storage], and the row itself is always written with
+[storage.Storage.CompareAndSwap], so concurrent writers never lose the current value. History is not
+transactional with the row, though: a crash, or writers in other processes sharing the backend, can
+drop a history entry.
+*/
+package versioned
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/storage"
+)
+
+const (
+	// DefaultKeep is the number of previous versions kept per row when [NewStorage] is given keep <= 0.
+	DefaultKeep = 5
+
+	// DefaultRetries bounds how often [Storage.Replace] re-reads the row after losing a race with
+	// another writer.
+	DefaultRetries = 3
+
+	// HistoryPrefix is prepended to a namespace to form the reserved namespace holding its history.
+	// The unit separators keep it out of the way of ordinary namespace names.
+	HistoryPrefix = "\x1fhistory\x1f"
+)
+
+const (
+	indexFormat     = 1
+	versionIDSep    = "\x1f"
+	indexHeaderSize = 1 + 8 + 4
+)
+
+// Storage is a [storage.Storage] that keeps the previous versions of each row. It also implements
+// [storage.Lister] when the wrapped storage does.
+type Storage struct {
+	st   storage.Storage
+	keep int
+	mu   sync.Mutex // serializes history updates
+}
+
+// Compile-time checks.
+var (
+	_ storage.Storage = (*Storage)(nil)
+	_ storage.Lister  = (*Storage)(nil)
+)
+
+// NewStorage wraps st, keeping up to keep previous versions of each row ([DefaultKeep] when keep <= 0).
+// A nil st returns [verrors.ErrInvalidNewArgs].
+func NewStorage(st storage.Storage, keep int) (*Storage, error) {
+	if st == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Storage{st: st, keep: keep}, nil
+}
+
+//=============================================================================
+// storage.Storage
+//=============================================================================
+
+// Create inserts a new row as version 1.
+func (s *Storage) Create(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	return s.st.Create(ctx, namespace, id, ciphertext)
+}
+
+// Get returns the current version of a row.
+func (s *Storage) Get(ctx context.Context, namespace, id string) ([]byte, error) {
+	return s.st.Get(ctx, namespace, id)
+}
+
+// Replace archives the current version and writes ciphertext as the next one. A missing row returns
+// [verrors.ErrNotFound] as from the wrapped storage.
+func (s *Storage) Replace(ctx context.Context, namespace, id string, ciphertext []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(ctx, namespace, id, true, nil, ciphertext)
+}
+
+// CompareAndSwap archives expected and writes ciphertext as the next version if the row still holds
+// expected; otherwise it returns [verrors.ErrCASFailed] and history is unchanged.
+func (s *Storage) CompareAndSwap(ctx context.Context, namespace, id string, expected, ciphertext []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(ctx, namespace, id, false, expected, ciphertext)
+}
+
+// Delete removes a row and its history. History goes first, so a failure part-way leaves the row
+// with less history rather than history for a row that no longer exists.
+func (s *Storage) Delete(ctx context.Context, namespace, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+	if err := s.deleteVersions(ctx, namespace, id, idx.history); err != nil {
+		return err
+	}
+	if err := s.st.Delete(ctx, HistoryPrefix+namespace, id); err != nil && !errors.Is(err, verrors.ErrNotFound) {
+		return err
+	}
+	return s.st.Delete(ctx, namespace, id)
+}
+
+// ListIDs lists current rows only; history is kept in another namespace. It returns
+// [verrors.ErrListUnsupported] if the wrapped storage does not implement [storage.Lister].
+func (s *Storage) ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error) {
+	lister, ok := s.st.(storage.Lister)
+	if !ok {
+		return nil, "", verrors.ErrListUnsupported
+	}
+	return lister.ListIDs(ctx, namespace, cursor, limit)
+}
+
+//=============================================================================
+// History
+//=============================================================================
+
+// Versions returns the current version number of a row and the versions kept in its history, oldest
+// first. A missing row returns [verrors.ErrNotFound] as from the wrapped storage.
+func (s *Storage) Versions(ctx context.Context, namespace, id string) (current uint64, history []uint64, err error) {
+	if _, err := s.st.Get(ctx, namespace, id); err != nil {
+		return 0, nil, err
+	}
+
+	idx, err := s.loadIndex(ctx, namespace, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return idx.current, idx.history, nil
+}
+
+// GetVersion returns the sealed blob of one version of a row, current or kept. A version that is
+// neither returns [verrors.ErrVersionNotFound].
+func (s *Storage) GetVersion(ctx context.Context, namespace, id string, version uint64) ([]byte, error) {
+	blob, err := s.st.Get(ctx, namespace, id)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := s.loadIndex(ctx, namespace, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case version == idx.current:
+		return blob, nil
+	case slices.Contains(idx.history, version):
+		blob, err := s.st.Get(ctx, HistoryPrefix+namespace, versionID(id, version))
+		if errors.Is(err, verrors.ErrNotFound) {
+			return nil, verrors.ErrVersionNotFound
+		}
+		return blob, err
+	default:
+		return nil, verrors.ErrVersionNotFound
+	}
+}
+
+// Rollback writes the blob of version as the row's next version; the version being replaced is
+// archived like any other write, so a rollback can itself be rolled back. Rolling back to the current
+// version does nothing.
+func (s *Storage) Rollback(ctx context.Context, namespace, id string, version uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.st.Get(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+	idx, err := s.loadIndex(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+	if version == idx.current {
+		return nil
+	}
+	if !slices.Contains(idx.history, version) {
+		return verrors.ErrVersionNotFound
+	}
+
+	blob, err := s.st.Get(ctx, HistoryPrefix+namespace, versionID(id, version))
+	if err != nil {
+		if errors.Is(err, verrors.ErrNotFound) {
+			return verrors.ErrVersionNotFound
+		}
+		return err
+	}
+	return s.write(ctx, namespace, id, false, current, blob)
+}
+
+// Prune deletes all but the newest keep versions from a row's history (all of them when keep <= 0)
+// and returns how many were removed. The current version is never pruned.
+func (s *Storage) Prune(ctx context.Context, namespace, id string, keep int) (removed int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(ctx, namespace, id)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := idx.trim(max(keep, 0))
+	if len(dropped) == 0 {
+		return 0, nil
+	}
+	if err := s.saveIndex(ctx, namespace, id, idx); err != nil {
+		return 0, err
+	}
+	return len(dropped), s.deleteVersions(ctx, namespace, id, dropped)
+}
+
+//=============================================================================
+// Helpers
+//=============================================================================
+
+// write archives the current blob and swaps in ciphertext. With replace it ignores expected and
+// retries when another writer changes the row first; otherwise the row must hold expected, so a nil
+// expected matches only an empty blob. Callers hold s.mu.
+func (s *Storage) write(ctx context.Context, namespace, id string, replace bool, expected, ciphertext []byte) error {
+	for attempt := 0; ; attempt++ {
+		current, err := s.st.Get(ctx, namespace, id)
+		if err != nil {
+			return err
+		}
+		if !replace && !bytes.Equal(current, expected) {
+			return verrors.ErrCASFailed
+		}
+
+		idx, err := s.loadIndex(ctx, namespace, id)
+		if err != nil {
+			return err
+		}
+
+		// Archive first: if the swap below loses, the archived blob is unreferenced and is
+		// overwritten by the next write of this version number.
+		if err := s.put(ctx, HistoryPrefix+namespace, versionID(id, idx.current), current); err != nil {
+			return err
+		}
+
+		err = s.st.CompareAndSwap(ctx, namespace, id, current, ciphertext)
+		if errors.Is(err, verrors.ErrCASFailed) && replace && attempt < DefaultRetries {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		idx.history = append(idx.history, idx.current)
+		idx.current++
+		dropped := idx.trim(s.keep)
+		if err := s.saveIndex(ctx, namespace, id, idx); err != nil {
+			return err
+		}
+		return s.deleteVersions(ctx, namespace, id, dropped)
+	}
+}
+
+// put creates or replaces a row in the wrapped storage.
+func (s *Storage) put(ctx context.Context, namespace, id string, blob []byte) error {
+	err := s.st.Create(ctx, namespace, id, blob)
+	if errors.Is(err, verrors.ErrDuplicateKey) {
+		return s.st.Replace(ctx, namespace, id, blob)
+	}
+	return err
+}
+
+// deleteVersions removes archived blobs, ignoring ones already gone.
+func (s *Storage) deleteVersions(ctx context.Context, namespace, id string, versions []uint64) error {
+	for _, v := range versions {
+		if err := s.st.Delete(ctx, HistoryPrefix+namespace, versionID(id, v)); err != nil && !errors.Is(err, verrors.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionID names the archived blob of one version of a row in the history namespace.
+func versionID(id string, version uint64) string {
+	return fmt.Sprintf("%s%s%020d", id, versionIDSep, version)
+}
+
+// index records a row's current version number and the versions kept in history, oldest first.
+type index struct {
+	current uint64
+	history []uint64
+}
+
+// trim drops the oldest history versions beyond keep and returns them.
+func (idx *index) trim(keep int) []uint64 {
+	if len(idx.history) <= keep {
+		return nil
+	}
+	n := len(idx.history) - keep
+	dropped := slices.Clone(idx.history[:n])
+	idx.history = slices.Delete(idx.history, 0, n)
+	return dropped
+}
+
+// loadIndex reads a row's version index; a row that was never rewritten has none and is version 1.
+func (s *Storage) loadIndex(ctx context.Context, namespace, id string) (index, error) {
+	raw, err := s.st.Get(ctx, HistoryPrefix+namespace, id)
+	if errors.Is(err, verrors.ErrNotFound) {
+		return index{current: 1}, nil
+	}
+	if err != nil {
+		return index{}, err
+	}
+
+	// Index: format | current u64 | count u32 | count × version u64, all big-endian.
+	if len(raw) < indexHeaderSize || raw[0] != indexFormat {
+		return index{}, verrors.ErrVersionIndex
+	}
+	n := binary.BigEndian.Uint32(raw[9:13])
+	if uint64(len(raw)) != indexHeaderSize+8*uint64(n) {
+		return index{}, verrors.ErrVersionIndex
+	}
+
+	idx := index{current: binary.BigEndian.Uint64(raw[1:9]), history: make([]uint64, 0, n)}
+	for off := indexHeaderSize; off < len(raw); off += 8 {
+		idx.history = append(idx.history, binary.BigEndian.Uint64(raw[off:]))
+	}
+	return idx, nil
+}
+
+// saveIndex writes a row's version index.
+func (s *Storage) saveIndex(ctx context.Context, namespace, id string, idx index) error {
+	raw := make([]byte, 0, indexHeaderSize+8*len(idx.history))
+	raw = append(raw, indexFormat)
+	raw = binary.BigEndian.AppendUint64(raw, idx.current)
+	raw = binary.BigEndian.AppendUint32(raw, uint32(len(idx.history)))
+	for _, v := range idx.history {
+		raw = binary.BigEndian.AppendUint64(raw, v)
+	}
+	return s.put(ctx, HistoryPrefix+namespace, id, raw)
+}
@@ -0,0 +1,150 @@
+package versioned
+
+// Vault wrapper adding version retrieval and rollback on top of a vault built over [Storage].
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/storage"
+)
+
+// Opener builds a vault over st, for example by closing over a key and identifier:
+//
+//	func(st storage.Storage) (vault.Vault, error) { return v1.New(priv, st, identifier.HexIdentifier{}) }
+//
+// [New] calls it once for the versioned storage, and [Vault.RetrieveVersion] calls it for a
+// one-row snapshot holding the requested version, so it should be cheap.
+type Opener func(st storage.Storage) (vault.Vault, error)
+
+// Vault is a [vault.Vault] whose writes keep history in a [Storage]. Store, Retrieve, Update,
+// CompareAndSwap, MoveNamespace, and Delete behave as in the wrapped vault; Update and CompareAndSwap
+// additionally archive the version they replace. It implements [vault.Expirer] by forwarding to the
+// wrapped vault, archiving like Update, or returns [verrors.ErrExpiryUnsupported] if the wrapped
+// vault lacks it. It implements [vault.Batcher] through the wrapped vault's Batcher or one call per
+// item; DeleteMany removes history as Delete does.
+type Vault struct {
+	vault.Vault
+	st   *Storage
+	open Opener
+}
+
+// Compile-time checks.
+var (
+	_ vault.Vault   = (*Vault)(nil)
+	_ vault.Lister  = (*Vault)(nil)
+	_ vault.Expirer = (*Vault)(nil)
+	_ vault.Batcher = (*Vault)(nil)
+)
+
+// New builds a vault over st with open and returns it with the versioning methods. A nil st or open
+// returns [verrors.ErrInvalidNewArgs]; errors from open are returned as is.
+func New(st *Storage, open Opener) (*Vault, error) {
+	if st == nil || open == nil {
+		return nil, verrors.ErrInvalidNewArgs
+	}
+
+	inner, err := open(st)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{Vault: inner, st: st, open: open}, nil
+}
+
+// RetrieveVersion decrypts one version of a row, current or kept in history. A version that is
+// neither returns [verrors.ErrVersionNotFound]; a missing row joins [verrors.ErrStorage] like
+// Retrieve. Opening fails like Retrieve on the wrapped vault.
+func (v *Vault) RetrieveVersion(ctx context.Context, namespace, id string, version uint64) ([]byte, error) {
+	blob, err := v.st.GetVersion(ctx, namespace, id, version)
+	if err != nil {
+		return nil, storageErr(err)
+	}
+
+	// Open the archived blob through the wrapped vault so it gets the same checks as a current row.
+	snapshot := storage.NewMemStorage()
+	if err := snapshot.Create(ctx, namespace, id, blob); err != nil {
+		return nil, err
+	}
+	opened, err := v.open(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return opened.Retrieve(ctx, namespace, id)
+}
+
+// Versions returns the current version number of a row and the versions kept in its history, oldest
+// first. See [Storage.Versions].
+func (v *Vault) Versions(ctx context.Context, namespace, id string) (current uint64, history []uint64, err error) {
+	current, history, err = v.st.Versions(ctx, namespace, id)
+	return current, history, storageErr(err)
+}
+
+// Rollback makes the sealed blob of version the row's current value again. See [Storage.Rollback].
+func (v *Vault) Rollback(ctx context.Context, namespace, id string, version uint64) error {
+	return storageErr(v.st.Rollback(ctx, namespace, id, version))
+}
+
+// Prune deletes all but the newest keep versions from a row's history. See [Storage.Prune].
+func (v *Vault) Prune(ctx context.Context, namespace, id string, keep int) (removed int, err error) {
+	removed, err = v.st.Prune(ctx, namespace, id, keep)
+	return removed, storageErr(err)
+}
+
+// ListIDs lists current rows in namespace; see [vault.Lister]. It returns
+// [verrors.ErrListUnsupported] if neither the wrapped vault nor the storage can list.
+func (v *Vault) ListIDs(ctx context.Context, namespace, cursor string, limit int) (ids []string, next string, err error) {
+	if lister, ok := v.Vault.(vault.Lister); ok {
+		return lister.ListIDs(ctx, namespace, cursor, limit)
+	}
+	return v.st.ListIDs(ctx, namespace, cursor, limit)
+}
+
+// StoreExpiring stores an expiring row through the wrapped [vault.Expirer].
+func (v *Vault) StoreExpiring(ctx context.Context, namespace string, plaintext []byte, expiresAt time.Time) (string, error) {
+	e, ok := v.Vault.(vault.Expirer)
+	if !ok {
+		return "", verrors.ErrExpiryUnsupported
+	}
+	return e.StoreExpiring(ctx, namespace, plaintext, expiresAt)
+}
+
+// UpdateExpiring replaces a row and its expiry through the wrapped [vault.Expirer], archiving the
+// version it replaces as Update does.
+func (v *Vault) UpdateExpiring(ctx context.Context, namespace, id string, plaintext []byte, expiresAt time.Time) error {
+	e, ok := v.Vault.(vault.Expirer)
+	if !ok {
+		return verrors.ErrExpiryUnsupported
+	}
+	return e.UpdateExpiring(ctx, namespace, id, plaintext, expiresAt)
+}
+
+// StoreMany stores plaintexts through the wrapped vault; see [vault.StoreMany].
+func (v *Vault) StoreMany(ctx context.Context, namespace string, plaintexts [][]byte) ([]vault.BatchResult, error) {
+	return vault.StoreMany(ctx, v.Vault, namespace, plaintexts)
+}
+
+// RetrieveMany opens current rows through the wrapped vault; see [vault.RetrieveMany].
+func (v *Vault) RetrieveMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	return vault.RetrieveMany(ctx, v.Vault, namespace, ids)
+}
+
+// DeleteMany removes rows and their history through the wrapped vault; see [vault.DeleteMany].
+func (v *Vault) DeleteMany(ctx context.Context, namespace string, ids []string) ([]vault.BatchResult, error) {
+	return vault.DeleteMany(ctx, v.Vault, namespace, ids)
+}
+
+// storageErr joins backend failures with [verrors.ErrStorage], as the vaults do, leaving versioning
+// sentinels bare.
+func storageErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, verrors.ErrVersionNotFound), errors.Is(err, verrors.ErrVersionIndex):
+		return err
+	default:
+		return errors.Join(verrors.ErrStorage, err)
+	}
+}
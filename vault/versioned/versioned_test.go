@@ -0,0 +1,272 @@
+package versioned_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/x/assert"
+	"go.rtnl.ai/x/vault"
+	verrors "go.rtnl.ai/x/vault/errors"
+	"go.rtnl.ai/x/vault/identifier"
+	"go.rtnl.ai/x/vault/storage"
+	v1 "go.rtnl.ai/x/vault/v1"
+	"go.rtnl.ai/x/vault/vaulttest"
+	"go.rtnl.ai/x/vault/versioned"
+)
+
+// TestStorage_compliance runs the storage and lister contracts against [versioned.Storage].
+func TestStorage_compliance(t *testing.T) {
+	newStorage := func(tb *testing.T) storage.Storage {
+		tb.Helper()
+		st, err := versioned.NewStorage(storage.NewMemStorage(), 2)
+		assert.Ok(tb, err)
+		return st
+	}
+	vaulttest.StorageConforms(t, identifier.HexIdentifier{}, newStorage)
+	vaulttest.ListerConforms(t, identifier.HexIdentifier{}, newStorage)
+}
+
+// TestVault_history writes several versions and checks retrieval, the keep limit, and listing.
+func TestVault_history(t *testing.T) {
+	ctx := context.Background()
+	v, mem := testVault(t, 3)
+
+	id, err := v.Store(ctx, "ns", []byte("v1"))
+	assert.Ok(t, err)
+	current, history, err := v.Versions(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, uint64(1), current)
+	assert.Len(t, history, 0)
+
+	for _, p := range []string{"v2", "v3", "v4", "v5"} {
+		assert.Ok(t, v.Update(ctx, "ns", id, []byte(p)))
+	}
+	assert.Ok(t, v.CompareAndSwap(ctx, "ns", id, []byte("v5"), []byte("v6")))
+	assert.ErrorIs(t, v.CompareAndSwap(ctx, "ns", id, []byte("v5"), []byte("nope")), verrors.ErrWrongCurrent)
+
+	current, history, err = v.Versions(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, uint64(6), current)
+	assert.Equal(t, []uint64{3, 4, 5}, history)
+
+	for _, n := range []uint64{3, 4, 5, 6} {
+		got, err := v.RetrieveVersion(ctx, "ns", id, n)
+		assert.Ok(t, err)
+		assert.Equal(t, []byte("v"+string(rune('0'+n))), got)
+	}
+	_, err = v.RetrieveVersion(ctx, "ns", id, 2)
+	assert.ErrorIs(t, err, verrors.ErrVersionNotFound)
+	_, err = v.RetrieveVersion(ctx, "ns", "0123456789abcdef0123456789abcdef", 1)
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+
+	// History lives outside the namespace, and only the kept versions remain.
+	ids, _, err := v.ListIDs(ctx, "ns", "", 10)
+	assert.Ok(t, err)
+	assert.Equal(t, []string{id}, ids)
+	hist, _, err := mem.ListIDs(ctx, versioned.HistoryPrefix+"ns", "", 10)
+	assert.Ok(t, err)
+	assert.Len(t, hist, 4) // three versions and the index
+}
+
+// TestVault_rollback restores an old version and checks the rollback itself is recorded.
+func TestVault_rollback(t *testing.T) {
+	ctx := context.Background()
+	v, _ := testVault(t, 5)
+
+	id, err := v.Store(ctx, "ns", []byte("good"))
+	assert.Ok(t, err)
+	assert.Ok(t, v.Update(ctx, "ns", id, []byte("bad")))
+
+	assert.Ok(t, v.Rollback(ctx, "ns", id, 1))
+	got, err := v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("good"), got)
+
+	current, history, err := v.Versions(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, uint64(3), current)
+	assert.Equal(t, []uint64{1, 2}, history)
+
+	assert.Ok(t, v.Rollback(ctx, "ns", id, 3))
+	assert.ErrorIs(t, v.Rollback(ctx, "ns", id, 9), verrors.ErrVersionNotFound)
+
+	assert.Ok(t, v.Rollback(ctx, "ns", id, 2))
+	got, err = v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("bad"), got)
+}
+
+// TestVault_pruneDeleteMove checks pruning, that Delete removes history, and that a moved row
+// starts a fresh history.
+func TestVault_pruneDeleteMove(t *testing.T) {
+	ctx := context.Background()
+	v, mem := testVault(t, 5)
+
+	id, err := v.Store(ctx, "ns", []byte("a"))
+	assert.Ok(t, err)
+	for _, p := range []string{"b", "c", "d"} {
+		assert.Ok(t, v.Update(ctx, "ns", id, []byte(p)))
+	}
+
+	removed, err := v.Prune(ctx, "ns", id, 1)
+	assert.Ok(t, err)
+	assert.Equal(t, 2, removed)
+	_, history, err := v.Versions(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []uint64{3}, history)
+	removed, err = v.Prune(ctx, "ns", id, 1)
+	assert.Ok(t, err)
+	assert.Equal(t, 0, removed)
+
+	assert.Ok(t, v.MoveNamespace(ctx, "ns", "other", id))
+	current, history, err := v.Versions(ctx, "other", id)
+	assert.Ok(t, err)
+	assert.Equal(t, uint64(1), current)
+	assert.Len(t, history, 0)
+	hist, _, err := mem.ListIDs(ctx, versioned.HistoryPrefix+"ns", "", 10)
+	assert.Ok(t, err)
+	assert.Len(t, hist, 0)
+
+	assert.Ok(t, v.Update(ctx, "other", id, []byte("e")))
+	assert.Ok(t, v.Delete(ctx, "other", id))
+	hist, _, err = mem.ListIDs(ctx, versioned.HistoryPrefix+"other", "", 10)
+	assert.Ok(t, err)
+	assert.Len(t, hist, 0)
+	_, _, err = v.Versions(ctx, "other", id)
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+}
+
+// TestVault_expiring checks expiring writes keep history like Update, and fail without an Expirer.
+func TestVault_expiring(t *testing.T) {
+	ctx := context.Background()
+	v, _ := testVault(t, 5)
+
+	expiresAt := time.Now().Add(time.Hour)
+	id, err := v.StoreExpiring(ctx, "ns", []byte("v1"), expiresAt)
+	assert.Ok(t, err)
+	assert.Ok(t, v.UpdateExpiring(ctx, "ns", id, []byte("v2"), expiresAt))
+	assert.Ok(t, v.UpdateExpiring(ctx, "ns", id, []byte("v3"), time.Time{}))
+
+	current, history, err := v.Versions(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, uint64(3), current)
+	assert.Equal(t, []uint64{1, 2}, history)
+	got, err := v.RetrieveVersion(ctx, "ns", id, 1)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("v1"), got)
+	got, err = v.Retrieve(ctx, "ns", id)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("v3"), got)
+
+	st, err := versioned.NewStorage(storage.NewMemStorage(), 1)
+	assert.Ok(t, err)
+	plain, err := versioned.New(st, func(st storage.Storage) (vault.Vault, error) {
+		return vaulttest.NewTestVault(t, st, identifier.HexIdentifier{}), nil
+	})
+	assert.Ok(t, err)
+	_, err = plain.StoreExpiring(ctx, "ns", []byte("x"), expiresAt)
+	assert.ErrorIs(t, err, verrors.ErrExpiryUnsupported)
+	assert.ErrorIs(t, plain.UpdateExpiring(ctx, "ns", id, []byte("x"), expiresAt), verrors.ErrExpiryUnsupported)
+}
+
+// TestVault_batch checks batch writes keep history per row and DeleteMany removes it.
+func TestVault_batch(t *testing.T) {
+	ctx := context.Background()
+	v, mem := testVault(t, 5)
+
+	stored, err := v.StoreMany(ctx, "ns", [][]byte{[]byte("a1"), []byte("b1")})
+	assert.Ok(t, err)
+	ids := []string{stored[0].ID, stored[1].ID}
+	for _, id := range ids {
+		assert.Ok(t, v.Update(ctx, "ns", id, []byte("2")))
+	}
+
+	results, err := v.RetrieveMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	for _, res := range results {
+		assert.Equal(t, []byte("2"), res.Plaintext)
+	}
+	got, err := v.RetrieveVersion(ctx, "ns", ids[1], 1)
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("b1"), got)
+
+	results, err = v.DeleteMany(ctx, "ns", ids)
+	assert.Ok(t, err)
+	assert.Len(t, results, 2)
+	hist, _, err := mem.ListIDs(ctx, versioned.HistoryPrefix+"ns", "", 10)
+	assert.Ok(t, err)
+	assert.Len(t, hist, 0)
+}
+
+// TestStorage_corruptIndex verifies an undecodable index is reported rather than overwritten.
+func TestStorage_corruptIndex(t *testing.T) {
+	ctx := context.Background()
+	mem := storage.NewMemStorage()
+	st, err := versioned.NewStorage(mem, 0)
+	assert.Ok(t, err)
+
+	assert.Ok(t, st.Create(ctx, "ns", "id", []byte("a")))
+	assert.Ok(t, mem.Create(ctx, versioned.HistoryPrefix+"ns", "id", []byte{9}))
+	assert.ErrorIs(t, st.Replace(ctx, "ns", "id", []byte("b")), verrors.ErrVersionIndex)
+
+	got, err := st.Get(ctx, "ns", "id")
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("a"), got)
+}
+
+// TestStorage_compareAndSwapNil verifies a nil expected value only matches an empty row, and that a
+// failed swap leaves the row and its history alone.
+func TestStorage_compareAndSwapNil(t *testing.T) {
+	ctx := context.Background()
+	mem := storage.NewMemStorage()
+	st, err := versioned.NewStorage(mem, 0)
+	assert.Ok(t, err)
+
+	assert.Ok(t, st.Create(ctx, "ns", "id", []byte("a")))
+	assert.ErrorIs(t, st.CompareAndSwap(ctx, "ns", "id", nil, []byte("b")), verrors.ErrCASFailed)
+
+	got, err := st.Get(ctx, "ns", "id")
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("a"), got)
+	_, err = mem.Get(ctx, versioned.HistoryPrefix+"ns", "id")
+	assert.ErrorIs(t, err, verrors.ErrNotFound)
+
+	assert.Ok(t, st.Create(ctx, "ns", "empty", []byte{}))
+	assert.Ok(t, st.CompareAndSwap(ctx, "ns", "empty", nil, []byte("c")))
+	got, err = st.Get(ctx, "ns", "empty")
+	assert.Ok(t, err)
+	assert.Equal(t, []byte("c"), got)
+}
+
+// TestNew_args covers constructor validation.
+func TestNew_args(t *testing.T) {
+	_, err := versioned.NewStorage(nil, 1)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+
+	st, err := versioned.NewStorage(storage.NewMemStorage(), 1)
+	assert.Ok(t, err)
+	_, err = versioned.New(nil, func(storage.Storage) (vault.Vault, error) { return nil, nil })
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+	_, err = versioned.New(st, nil)
+	assert.ErrorIs(t, err, verrors.ErrInvalidNewArgs)
+}
+
+// testVault returns a v1-backed versioned vault keeping keep versions, and the memory storage under it.
+func testVault(tb testing.TB, keep int) (*versioned.Vault, *storage.MemStorage) {
+	tb.Helper()
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.Ok(tb, err)
+
+	mem := storage.NewMemStorage()
+	st, err := versioned.NewStorage(mem, keep)
+	assert.Ok(tb, err)
+
+	v, err := versioned.New(st, func(st storage.Storage) (vault.Vault, error) {
+		return v1.New(priv, st, identifier.HexIdentifier{})
+	})
+	assert.Ok(tb, err)
+	return v, mem
+}